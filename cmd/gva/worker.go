@@ -0,0 +1,51 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gva
+
+import (
+	"os"
+
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/tasks"
+	"github.com/dnsjia/luban/tools"
+
+	"github.com/gookit/color"
+	"github.com/spf13/cobra"
+)
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "LuBan 鲁班后台任务",
+	Long: `独立运行云资产同步、告警评估等后台任务的 worker 与周期任务调度,
+此时服务端配置 system.task-worker 应设为 false。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := cmd.Flags().GetString("path")
+		common.VP = tools.Viper(path)
+		common.LOG = tools.Zap()
+		common.DB = common.GormMysql()
+		common.MysqlTables(common.DB)
+		if err := tasks.Run(); err != nil {
+			color.Warn.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(workerCmd)
+	workerCmd.Flags().StringP("path", "p", "./config.yaml", "自定配置文件路径(绝对路径)")
+}
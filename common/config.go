@@ -32,6 +32,8 @@ type Server struct {
 	System  System        `mapstructure:"system" json:"system" yaml:"system"`
 	Redis   Redis         `mapstructure:"redis"  json:"redis" yaml:"redis"`
 	Crontab Crontab       `mapstructure:"crontab" json:"crontab" yaml:"crontab"`
+
+	Kubernetes Kubernetes `mapstructure:"kubernetes" json:"kubernetes" yaml:"kubernetes"`
 }
 
 type contactKey struct {
@@ -18,4 +18,8 @@ package common
 
 type Crontab struct {
 	AliYun string `mapstructure:"aliyun" json:"aliyun" yaml:"aliyun"`
+	// 云账号默认同步周期, 账号未单独设置时使用, 为空时使用 aliyun
+	CloudSync string `mapstructure:"cloud-sync" json:"cloudSync" yaml:"cloud-sync"`
+	// 告警规则评估周期, 默认每分钟
+	Alert string `mapstructure:"alert" json:"alert" yaml:"alert"`
 }
@@ -34,9 +34,19 @@ func MysqlTables(db *gorm.DB) {
 		models.Role{},
 		models.Dept{},
 		models.K8SCluster{},
+		models.PodTerminalRecord{},
+		models.PortForwardRecord{},
+		models.FileTransferRecord{},
+		models.EventRecord{},
+		models.AlertChannel{},
+		models.AlertRule{},
+		models.AlertSilence{},
+		models.AlertRecord{},
+		models.NodeDrainJob{},
 		//models.ClusterVersion{},
 		cmdb.CloudPlatform{},
 		cmdb.VirtualMachine{},
+		cmdb.CloudSyncJob{},
 		cmdb.TreeMenu{},
 		cmdb.SSHRecord{},
 		cmdb.SSHGlobalConfig{},
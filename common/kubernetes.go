@@ -0,0 +1,29 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+// Kubernetes 容器相关配置, 文件与终端录像大小单位为MB, 未配置时使用默认值.
+// DebugImages 为允许使用的调试镜像, 第一个为默认镜像, 未配置时不限制镜像.
+// EventArchive 开启后持续将各集群的事件写入数据库, 保留 EventRetentionDays 天
+type Kubernetes struct {
+	MaxUploadSize      int64    `mapstructure:"max-upload-size" json:"maxUploadSize" yaml:"max-upload-size"`
+	MaxDownloadSize    int64    `mapstructure:"max-download-size" json:"maxDownloadSize" yaml:"max-download-size"`
+	MaxRecordSize      int64    `mapstructure:"max-record-size" json:"maxRecordSize" yaml:"max-record-size"`
+	DebugImages        []string `mapstructure:"debug-images" json:"debugImages" yaml:"debug-images"`
+	EventArchive       bool     `mapstructure:"event-archive" json:"eventArchive" yaml:"event-archive"`
+	EventRetentionDays int      `mapstructure:"event-retention-days" json:"eventRetentionDays" yaml:"event-retention-days"`
+}
@@ -20,4 +20,8 @@ type System struct {
 	Env    string `mapstructure:"env" json:"env" yaml:"env"`
 	Addr   int    `mapstructure:"addr" json:"addr" yaml:"addr"`
 	DbType string `mapstructure:"db-type" json:"dbType" yaml:"db-type"`
+	// TaskWorker 在服务进程中运行任务 worker 与周期任务调度, 使用 gva worker 独立运行时关闭
+	TaskWorker bool `mapstructure:"task-worker" json:"taskWorker" yaml:"task-worker"`
+	// AllowedOrigins 允许跨域发起 websocket 连接的来源, 例如 http://localhost:8080, 同源请求无需配置
+	AllowedOrigins []string `mapstructure:"allowed-origins" json:"allowedOrigins" yaml:"allowed-origins"`
 }
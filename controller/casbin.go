@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"github.com/dnsjia/luban/common"
 	"github.com/dnsjia/luban/controller/response"
+	"github.com/dnsjia/luban/models"
 	"github.com/dnsjia/luban/services"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -40,9 +41,8 @@ func AddCasBin(c *gin.Context) {
 		return
 	}
 	e := services.Casbin()
-	err = e.LoadPolicy()
-	if err != nil {
-		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+	if e == nil {
+		response.FailWithMessage(response.InternalServerError, "", c)
 		return
 	}
 	if len(policy) > 0 {
@@ -63,23 +63,50 @@ func AddCasBin(c *gin.Context) {
 
 }
 
+// DeleteCasBin 删除角色的指定权限
 func DeleteCasBin(c *gin.Context) {
-	e := services.Casbin()
-	group := ""
-	url := ""
-	method := ""
-	if ok, _ := e.RemovePolicy(group, url, method); !ok {
-		fmt.Println("Policy不存在")
-	} else {
-		fmt.Println("删除成功")
-		response.Ok(c)
+	var receive models.CasBinInReceive
+	err := CheckParams(c, &receive)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, "", c)
 		return
 	}
+	if receive.Role == "" || len(receive.CasBinInfos) == 0 {
+		response.FailWithMessage(response.ParamError, "角色和权限不能为空", c)
+		return
+	}
+	if err := services.RemoveCasBin(receive.Role, receive.CasBinInfos); err != nil {
+		common.LOG.Warn(fmt.Sprintf("角色：%v, 删除权限失败", receive.Role), zap.Any("err", err))
+		response.FailWithMessage(response.ERROR, err.Error(), c)
+		return
+	}
+	response.Ok(c)
 }
 
+// UpdateCasBin 替换角色的全部权限
+func UpdateCasBin(c *gin.Context) {
+	var receive models.CasBinInReceive
+	err := CheckParams(c, &receive)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, "", c)
+		return
+	}
+	if receive.Role == "" {
+		response.FailWithMessage(response.ParamError, "角色不能为空", c)
+		return
+	}
+	if err := services.UpdateCasBin(receive.Role, receive.CasBinInfos); err != nil {
+		common.LOG.Error(fmt.Sprintf("角色：%v, 更新权限失败", receive.Role), zap.Any("err", err))
+		response.FailWithMessage(response.ERROR, err.Error(), c)
+		return
+	}
+	response.Ok(c)
+}
+
+// GetCasBin 获取权限列表, 可通过 role 参数过滤指定角色
 func GetCasBin(c *gin.Context) {
-	e := services.Casbin()
-	data := e.GetPolicy()
+	role := c.Query("role")
+	data := services.GetPolicyPathByRole(role)
 	response.OkWithData(data, c)
 	return
 }
@@ -0,0 +1,32 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/dnsjia/luban/common"
+	"github.com/gin-gonic/gin"
+)
+
+// GetClaims 获取当前登录用户的token信息, 未登录时返回空的claims
+func GetClaims(c *gin.Context) *common.CustomClaims {
+	if claims, ok := c.Get("claims"); ok {
+		if waitUse, ok := claims.(*common.CustomClaims); ok {
+			return waitUse
+		}
+	}
+	return &common.CustomClaims{}
+}
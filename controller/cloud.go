@@ -20,18 +20,18 @@ import (
 	"fmt"
 	"github.com/dnsjia/luban/common"
 	"github.com/dnsjia/luban/controller/response"
-	"github.com/dnsjia/luban/inner/cloud/cloudsync"
 	"github.com/dnsjia/luban/inner/cloud/cloudvendor"
 	"github.com/dnsjia/luban/models/cmdb"
 	"github.com/dnsjia/luban/models/request"
 	"github.com/dnsjia/luban/services"
+	"github.com/dnsjia/luban/tasks"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"strconv"
 )
 
 var (
 	pageInfo request.PageInfo
-	account  cmdb.CloudPlatform
 )
 
 func ListPlatform(c *gin.Context) {
@@ -55,6 +55,7 @@ func ListPlatform(c *gin.Context) {
 
 // CloudPlatformAccount 云平台账号
 func CloudPlatformAccount(c *gin.Context) {
+	var account cmdb.CloudPlatform
 	_ = c.ShouldBindJSON(&account)
 
 	// 校验云厂商客户端
@@ -63,6 +64,10 @@ func CloudPlatformAccount(c *gin.Context) {
 		response.FailWithMessage(500, fmt.Sprintf("AccountVerify GetVendorClient failed，%v", err), c)
 		return
 	}
+	if err := tasks.ValidateCloudSyncCron(account.SyncCron); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
 	// TODO 校验AccessKey
 
 	// 创建云账号
@@ -71,30 +76,89 @@ func CloudPlatformAccount(c *gin.Context) {
 		response.FailWithMessage(500, fmt.Sprintf("创建云平台账号异常，%v", err1), c)
 		return
 	}
+	tasks.ReloadCloudSchedule()
 
-	// 开启协程，后台同步ecs
-	taskChan := make(chan *cmdb.CloudPlatform)
-	SyncCloudResource(taskChan)
+	// 后台同步云资源
+	if _, err := tasks.SyncCloudNow(&account, GetClaims(c).Username); err != nil {
+		common.LOG.Error("创建云资产同步任务失败", zap.Any("err", err))
+	}
 
 	response.OkWithDetailed("null", "添加成功, 任务正在后台同步云资源", c)
 	return
 }
 
-// SyncCloudResource 同步云资源
-func SyncCloudResource(taskChan chan *cmdb.CloudPlatform) {
-	go func() {
-		if task, ok := <-taskChan; ok {
-			switch task.Type {
-			case "aliyun":
-				cloudsync.SyncAliYunHost(task)
-			case "tencent":
-				return
-			case "aws":
-				return
-			default:
-				common.LOG.Error(fmt.Sprintf("unknown resource type:%v, ignore it!", task.Type))
-			}
-		}
-	}()
-	taskChan <- &account
+// SyncCloudAccount 立即同步云账号
+func SyncCloudAccount(c *gin.Context) {
+	var data cmdb.CloudSyncData
+	if err := CheckParams(c, &data); err != nil {
+		return
+	}
+	account, err := services.GetCloudAccount(data.ID)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, "云账号不存在", c)
+		return
+	}
+	job, err := tasks.SyncCloudNow(&account, GetClaims(c).Username)
+	if err != nil {
+		common.LOG.Error("创建云资产同步任务失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "创建云资产同步任务失败", c)
+		return
+	}
+	response.OkWithDetailed(job, "任务正在后台同步云资源", c)
+}
+
+// UpdateCloudSchedule 修改云账号的同步周期与启用状态, 调度器随之重新注册定时同步
+func UpdateCloudSchedule(c *gin.Context) {
+	var data cmdb.CloudScheduleData
+	if err := CheckParams(c, &data); err != nil {
+		return
+	}
+	if err := tasks.ValidateCloudSyncCron(data.SyncCron); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	if err := services.UpdateCloudAccountSchedule(data); err != nil {
+		common.LOG.Error("更新云账号同步周期失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "更新云账号同步周期失败", c)
+		return
+	}
+	tasks.ReloadCloudSchedule()
+	response.Ok(c)
+}
+
+// ListCloudSyncJob 云资产同步记录, 列表不包含同步日志
+func ListCloudSyncJob(c *gin.Context) {
+	query := cmdb.CloudSyncJobQuery{}
+	if c.ShouldBindQuery(&query) != nil {
+		response.FailWithMessage(response.ParamError, response.ParamErrorMsg, c)
+		return
+	}
+
+	jobs := make([]cmdb.CloudSyncJob, 0)
+	if err := services.ListCloudSyncJob(&query, &jobs); err != nil {
+		common.LOG.Error("获取云资产同步记录失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "获取云资产同步记录失败", c)
+		return
+	}
+	response.OkWithData(response.PageResult{
+		Data:  jobs,
+		Total: query.Total,
+		Size:  query.Size,
+		Page:  query.Page,
+	}, c)
+}
+
+// GetCloudSyncJob 云资产同步记录详情, 包含同步日志
+func GetCloudSyncJob(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		response.FailWithMessage(response.ParamError, response.ParamErrorMsg, c)
+		return
+	}
+	job, err := services.GetCloudSyncJob(id)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, "同步记录不存在", c)
+		return
+	}
+	response.OkWithData(job, c)
 }
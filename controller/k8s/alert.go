@@ -0,0 +1,262 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/controller"
+	"github.com/dnsjia/luban/controller/response"
+	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/pkg/k8s/alert"
+	"github.com/dnsjia/luban/services"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ListAlertChannelController 告警通知渠道列表, 不返回密钥与SMTP密码
+func ListAlertChannelController(c *gin.Context) {
+	channels := make([]models.AlertChannel, 0)
+	if err := services.ListAlertChannel(&channels); err != nil {
+		common.LOG.Error("获取告警通知渠道失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "获取告警通知渠道失败", c)
+		return
+	}
+	for i := range channels {
+		channels[i].Secret = ""
+		channels[i].SmtpPassword = ""
+	}
+	response.OkWithData(channels, c)
+}
+
+func CreateAlertChannelController(c *gin.Context) {
+	var channel models.AlertChannel
+	if err := controller.CheckParams(c, &channel); err != nil {
+		return
+	}
+	if err := alert.ValidateChannel(channel); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	if err := services.CreateAlertChannel(&channel); err != nil {
+		common.LOG.Error("创建告警通知渠道失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "创建告警通知渠道失败", c)
+		return
+	}
+	response.Ok(c)
+}
+
+// UpdateAlertChannelController 更新告警通知渠道, 密钥与SMTP密码为空时不修改
+func UpdateAlertChannelController(c *gin.Context) {
+	var channel models.AlertChannel
+	if err := controller.CheckParams(c, &channel); err != nil {
+		return
+	}
+	if channel.ID == 0 {
+		response.FailWithMessage(response.ParamError, response.ParamErrorMsg, c)
+		return
+	}
+	// 校验时使用已保存的密钥
+	stored, err := services.GetAlertChannel(channel.ID)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, "告警通知渠道不存在", c)
+		return
+	}
+	check := channel
+	if check.Secret == "" {
+		check.Secret = stored.Secret
+	}
+	if check.SmtpPassword == "" {
+		check.SmtpPassword = stored.SmtpPassword
+	}
+	if err := alert.ValidateChannel(check); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	if err := services.UpdateAlertChannel(&channel); err != nil {
+		common.LOG.Error("更新告警通知渠道失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "更新告警通知渠道失败", c)
+		return
+	}
+	response.Ok(c)
+}
+
+func DeleteAlertChannelController(c *gin.Context) {
+	var data models.RemoveAlertData
+	if err := controller.CheckParams(c, &data); err != nil {
+		return
+	}
+	if err := services.DeleteAlertChannel(data.ID); err != nil {
+		common.LOG.Error("删除告警通知渠道失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "删除告警通知渠道失败", c)
+		return
+	}
+	response.Ok(c)
+}
+
+// TestAlertChannelController 向已保存的渠道发送一条测试消息
+func TestAlertChannelController(c *gin.Context) {
+	var data models.RemoveAlertData
+	if err := controller.CheckParams(c, &data); err != nil {
+		return
+	}
+	channel, err := services.GetAlertChannel(data.ID)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, "告警通知渠道不存在", c)
+		return
+	}
+	testAlert := alert.Alert{
+		RuleName: "测试",
+		Title:    fmt.Sprintf("[测试] 告警通知渠道 %s", channel.Name),
+		Content:  fmt.Sprintf("- 发送人: %s\n- 这是一条测试消息, 收到说明渠道配置正确", controller.GetClaims(c).Username),
+		FiredAt:  time.Now(),
+	}
+	if err := alert.Send(channel, testAlert); err != nil {
+		response.FailWithMessage(response.InternalServerError, fmt.Sprintf("发送测试消息失败: %v", err), c)
+		return
+	}
+	response.OkWithMessage("发送测试消息成功", c)
+}
+
+func ListAlertRuleController(c *gin.Context) {
+	rules := make([]models.AlertRule, 0)
+	if err := services.ListAlertRule(&rules); err != nil {
+		common.LOG.Error("获取告警规则失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "获取告警规则失败", c)
+		return
+	}
+	response.OkWithData(rules, c)
+}
+
+func CreateAlertRuleController(c *gin.Context) {
+	var rule models.AlertRule
+	if err := controller.CheckParams(c, &rule); err != nil {
+		return
+	}
+	if err := alert.ValidateRule(rule); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	if err := services.CreateAlertRule(&rule); err != nil {
+		common.LOG.Error("创建告警规则失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "创建告警规则失败", c)
+		return
+	}
+	response.Ok(c)
+}
+
+func UpdateAlertRuleController(c *gin.Context) {
+	var rule models.AlertRule
+	if err := controller.CheckParams(c, &rule); err != nil {
+		return
+	}
+	if rule.ID == 0 {
+		response.FailWithMessage(response.ParamError, response.ParamErrorMsg, c)
+		return
+	}
+	if err := alert.ValidateRule(rule); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	if err := services.UpdateAlertRule(&rule); err != nil {
+		common.LOG.Error("更新告警规则失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "更新告警规则失败", c)
+		return
+	}
+	response.Ok(c)
+}
+
+func DeleteAlertRuleController(c *gin.Context) {
+	var data models.RemoveAlertData
+	if err := controller.CheckParams(c, &data); err != nil {
+		return
+	}
+	if err := services.DeleteAlertRule(data.ID); err != nil {
+		common.LOG.Error("删除告警规则失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "删除告警规则失败", c)
+		return
+	}
+	response.Ok(c)
+}
+
+func ListAlertSilenceController(c *gin.Context) {
+	silences := make([]models.AlertSilence, 0)
+	if err := services.ListAlertSilence(&silences); err != nil {
+		common.LOG.Error("获取告警静默失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "获取告警静默失败", c)
+		return
+	}
+	response.OkWithData(silences, c)
+}
+
+// CreateAlertSilenceController 创建告警静默, 未指定开始时间时立即生效
+func CreateAlertSilenceController(c *gin.Context) {
+	var silence models.AlertSilence
+	if err := controller.CheckParams(c, &silence); err != nil {
+		return
+	}
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = models.LocalTime{Time: time.Now()}
+	}
+	if !silence.EndsAt.After(silence.StartsAt.Time) {
+		response.FailWithMessage(response.ParamError, "结束时间必须晚于开始时间", c)
+		return
+	}
+	silence.CreatedBy = controller.GetClaims(c).Username
+	if err := services.CreateAlertSilence(&silence); err != nil {
+		common.LOG.Error("创建告警静默失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "创建告警静默失败", c)
+		return
+	}
+	response.Ok(c)
+}
+
+func DeleteAlertSilenceController(c *gin.Context) {
+	var data models.RemoveAlertData
+	if err := controller.CheckParams(c, &data); err != nil {
+		return
+	}
+	if err := services.DeleteAlertSilence(data.ID); err != nil {
+		common.LOG.Error("删除告警静默失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "删除告警静默失败", c)
+		return
+	}
+	response.Ok(c)
+}
+
+func ListAlertRecordController(c *gin.Context) {
+	query := models.AlertRecordQuery{}
+	if c.ShouldBindQuery(&query) != nil {
+		response.FailWithMessage(response.ParamError, response.ParamErrorMsg, c)
+		return
+	}
+
+	records := make([]models.AlertRecord, 0)
+	if err := services.ListAlertRecord(&query, &records); err != nil {
+		common.LOG.Error("获取告警记录失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "获取告警记录失败", c)
+		return
+	}
+	response.OkWithData(response.PageResult{
+		Data:  records,
+		Total: query.Total,
+		Size:  query.Size,
+		Page:  query.Page,
+	}, c)
+}
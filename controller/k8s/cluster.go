@@ -24,6 +24,7 @@ import (
 	"github.com/dnsjia/luban/models"
 	"github.com/dnsjia/luban/pkg/k8s/Init"
 	"github.com/dnsjia/luban/pkg/k8s/cluster"
+	"github.com/dnsjia/luban/pkg/k8s/informer"
 	"github.com/dnsjia/luban/services"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -37,6 +38,10 @@ func CreateK8SCluster(c *gin.Context) {
 	if err != nil {
 		return
 	}
+	if err := cluster.ValidateMetricsSource(K8sCluster.MetricsSource); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
 	client, err := Init.GetK8sClient(K8sCluster.KubeConfig)
 	if err != nil {
 		response.FailWithMessage(response.CreateK8SClusterError, err.Error(), c)
@@ -101,10 +106,39 @@ func DelK8SCluster(c *gin.Context) {
 		response.FailWithMessage(response.InternalServerError, "删除失败！", c)
 		return
 	}
+	for _, clusterId := range clusterIdsOf(id.Data) {
+		informer.Invalidate(clusterId)
+	}
 	response.Ok(c)
 	return
 }
 
+// clusterIdsOf 解析删除请求中的集群ID, 兼容单个ID与ID数组
+func clusterIdsOf(data interface{}) []uint {
+	var ids []uint
+	switch v := data.(type) {
+	case float64:
+		ids = append(ids, uint(v))
+	case []interface{}:
+		for _, item := range v {
+			if id, ok := item.(float64); ok {
+				ids = append(ids, uint(id))
+			}
+		}
+	}
+	return ids
+}
+
+// GetClusterCacheStatus 获取集群informer缓存的同步状态
+func GetClusterCacheStatus(c *gin.Context) {
+	clusterId, err := Init.GetClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	response.OkWithData(informer.Status(clusterId), c)
+}
+
 func ClusterSecret(c *gin.Context) {
 	clusterId := c.DefaultQuery("clusterId", "1")
 	clusterIdUint, err := strconv.ParseUint(clusterId, 10, 32)
@@ -126,7 +160,37 @@ func GetK8SClusterDetail(c *gin.Context) {
 		response.FailWithMessage(response.InternalServerError, err.Error(), c)
 		return
 	}
-	data := cluster.GetClusterInfo(client)
+	clusterId, err := Init.GetClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	clusterConfig, err := services.GetK8sCluster(clusterId)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	data := cluster.GetClusterInfo(client, clusterConfig.MetricsSource)
 	response.OkWithData(data, c)
 
 }
+
+// UpdateK8SClusterMetricsSource 更新集群概览的指标来源
+func UpdateK8SClusterMetricsSource(c *gin.Context) {
+
+	var data models.ClusterMetricsSourceData
+	err := controller.CheckParams(c, &data)
+	if err != nil {
+		return
+	}
+	if err := cluster.ValidateMetricsSource(data.MetricsSource); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	if err := services.UpdateK8SClusterMetricsSource(data.ID, data.MetricsSource); err != nil {
+		common.LOG.Error("更新集群指标来源失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "更新集群指标来源失败", c)
+		return
+	}
+	response.Ok(c)
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"net/http"
+	"strconv"
 )
 
 func GetDeploymentList(c *gin.Context) {
@@ -218,3 +219,96 @@ func RollBackDeploymentController(c *gin.Context) {
 	}
 	response.Ok(c)
 }
+
+func GetDeploymentRevisionsController(c *gin.Context) {
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	namespace := parser.ParseNamespaceParameter(c)
+	name := parser.ParseNameParameter(c)
+
+	data, err := deployment.GetDeploymentRevisions(client, namespace, name)
+	if err != nil {
+		response.FailWithMessage(response.ERROR, err.Error(), c)
+		return
+	}
+	response.OkWithData(data, c)
+}
+
+func DiffDeploymentRevisionController(c *gin.Context) {
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	namespace := parser.ParseNamespaceParameter(c)
+	name := parser.ParseNameParameter(c)
+	from, fromErr := strconv.ParseInt(c.Query("from"), 10, 64)
+	to, toErr := strconv.ParseInt(c.Query("to"), 10, 64)
+	if fromErr != nil || toErr != nil {
+		response.FailWithMessage(response.ParamError, "from/to 版本号不正确", c)
+		return
+	}
+
+	data, err := deployment.DiffDeploymentRevisions(client, namespace, name, from, to)
+	if err != nil {
+		response.FailWithMessage(response.ERROR, err.Error(), c)
+		return
+	}
+	response.OkWithData(data, c)
+}
+
+func PauseDeploymentController(c *gin.Context) {
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	var pause k8s.PauseDeployment
+	if err := controller.CheckParams(c, &pause); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	if err := deployment.PauseDeployment(client, pause.DeploymentName, pause.Namespace); err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.Ok(c)
+}
+
+func ResumeDeploymentController(c *gin.Context) {
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	var resume k8s.PauseDeployment
+	if err := controller.CheckParams(c, &resume); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	if err := deployment.ResumeDeployment(client, resume.DeploymentName, resume.Namespace); err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.Ok(c)
+}
+
+func GetDeploymentRolloutStatusController(c *gin.Context) {
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	namespace := parser.ParseNamespaceParameter(c)
+	name := parser.ParseNameParameter(c)
+
+	data, err := deployment.GetRolloutStatus(client, namespace, name)
+	if err != nil {
+		response.FailWithMessage(response.ERROR, err.Error(), c)
+		return
+	}
+	response.OkWithData(data, c)
+}
@@ -18,11 +18,16 @@ package k8s
 
 import (
 	"fmt"
+	"github.com/dnsjia/luban/common"
 	"github.com/dnsjia/luban/controller/response"
+	"github.com/dnsjia/luban/models"
 	"github.com/dnsjia/luban/pkg/k8s/Init"
 	"github.com/dnsjia/luban/pkg/k8s/event"
+	"github.com/dnsjia/luban/pkg/k8s/eventarchive"
 	"github.com/dnsjia/luban/pkg/k8s/parser"
+	"github.com/dnsjia/luban/services"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 func Events(c *gin.Context) {
@@ -42,3 +47,63 @@ func Events(c *gin.Context) {
 	response.OkWithData(data, c)
 	return
 }
+
+// ListEventRecordController 查询归档的集群事件, 未指定 clusterId 时查询所有集群
+func ListEventRecordController(c *gin.Context) {
+	query := models.EventRecordQuery{}
+	if c.ShouldBindQuery(&query) != nil {
+		response.FailWithMessage(response.ParamError, response.ParamErrorMsg, c)
+		return
+	}
+
+	var records []models.EventRecord
+	if err := services.ListEventRecord(&query, &records); err != nil {
+		common.LOG.Error("获取归档事件失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.OkWithData(response.PageResult{
+		Data:  records,
+		Total: query.Total,
+		Size:  query.Size,
+		Page:  query.Page,
+	}, c)
+}
+
+// ListWorkloadWarningRecordController 工作负载详情页查询已归档的告警事件, 包含其 ReplicaSet 与 Pod 的事件
+func ListWorkloadWarningRecordController(c *gin.Context) {
+	query := models.WorkloadWarningQuery{}
+	if c.ShouldBindQuery(&query) != nil {
+		response.FailWithMessage(response.ParamError, response.ParamErrorMsg, c)
+		return
+	}
+	clusterId, err := Init.GetClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	query.ClusterID = clusterId
+
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	objects, err := eventarchive.ResolveWorkloadObjects(client, query.Namespace, query.Kind, query.Name)
+	if err != nil {
+		// 集群不可达时仍可按名称格式查询历史事件
+		common.LOG.Warn("查询工作负载关联对象失败", zap.Any("err", err))
+	}
+	query.ReplicaSets = objects.ReplicaSets
+	query.Pods = objects.Pods
+	query.ReplicaSetPattern = objects.ReplicaSetPattern
+	query.PodPattern = objects.PodPattern
+
+	records := make([]models.EventRecord, 0)
+	if err := services.ListWorkloadWarningRecord(&query, &records); err != nil {
+		common.LOG.Error("获取归档告警事件失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.OkWithData(records, c)
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/controller"
+	"github.com/dnsjia/luban/controller/response"
+	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/pkg/k8s/Init"
+	"github.com/dnsjia/luban/pkg/k8s/filecopy"
+	"github.com/dnsjia/luban/pkg/k8s/parser"
+	"github.com/dnsjia/luban/services"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"path"
+	"strconv"
+)
+
+// fileCopyOptions 解析目标容器并校验 pods/exec 权限, 文件复制与 kubectl cp 一样通过 exec 实现
+func fileCopyOptions(c *gin.Context) (*filecopy.Options, bool) {
+	clusterId, err := Init.GetClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return nil, false
+	}
+	namespace := parser.ParseNamespaceParameter(c)
+	podName := c.Query("pod")
+	if namespace == "" || podName == "" || c.Query("path") == "" {
+		response.FailWithMessage(response.ParamError, "namespace、pod和path不能为空", c)
+		return nil, false
+	}
+
+	claims := controller.GetClaims(c)
+	if !services.EnforceNamespace(claims.Role, clusterId, namespace, "pods/exec", "POST") {
+		common.LOG.Warn(fmt.Sprintf("用户：%v, 无权限复制容器文件 %s/%s", claims.Username, namespace, podName))
+		response.FailWithMessage(response.Forbidden, "", c)
+		return nil, false
+	}
+	return &filecopy.Options{
+		ClusterID: clusterId,
+		Namespace: namespace,
+		PodName:   podName,
+		Container: c.Query("container"),
+		UserName:  claims.Username,
+		ClientIP:  c.ClientIP(),
+	}, true
+}
+
+// UploadContainerFileController 上传文件到容器目录, 参数 path 为容器内目标目录, extract=true 时解压上传的tar包
+func UploadContainerFileController(c *gin.Context) {
+	options, ok := fileCopyOptions(c)
+	if !ok {
+		return
+	}
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	restConfig, err := Init.ClusterRestConfig(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+
+	// 预留1MB给multipart的其他字段
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, filecopy.MaxUploadSize()+1<<20)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.FailWithMessage(response.ParamError, fmt.Sprintf("读取上传文件失败: %v", err), c)
+		return
+	}
+	if fileHeader.Size > filecopy.MaxUploadSize() {
+		response.FailWithMessage(response.ParamError, filecopy.ErrSizeLimitExceeded.Error(), c)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	defer file.Close()
+
+	extract, _ := strconv.ParseBool(c.Query("extract"))
+	err = filecopy.Upload(client, restConfig, *options, c.Query("path"), fileHeader.Filename, file, fileHeader.Size, extract)
+	if err != nil {
+		response.FailWithMessage(response.ERROR, err.Error(), c)
+		return
+	}
+	response.Ok(c)
+}
+
+// DownloadContainerFileController 下载容器内的文件或目录, format 为 tar(默认) 或 zip
+func DownloadContainerFileController(c *gin.Context) {
+	options, ok := fileCopyOptions(c)
+	if !ok {
+		return
+	}
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	restConfig, err := Init.ClusterRestConfig(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+
+	format := c.DefaultQuery("format", filecopy.FormatTar)
+	srcPath := c.Query("path")
+	writer := &downloadWriter{c: c, fileName: fmt.Sprintf("%s.%s", path.Base(srcPath), format)}
+	_, err = filecopy.Download(client, restConfig, *options, srcPath, format, writer)
+	if err != nil {
+		if !writer.started {
+			response.FailWithMessage(response.ERROR, err.Error(), c)
+			return
+		}
+		// 已经开始传输, 无法再返回错误信息, 截断的tar/zip包在客户端解包时会报错
+		common.LOG.Error("下载容器文件失败", zap.Any("err", err))
+		c.Abort()
+		return
+	}
+	if !writer.started {
+		writer.start()
+	}
+}
+
+// downloadWriter 在第一次写入时才发送下载响应头, 传输开始前出错时仍可返回JSON错误
+type downloadWriter struct {
+	c        *gin.Context
+	fileName string
+	started  bool
+}
+
+func (w *downloadWriter) start() {
+	w.started = true
+	w.c.Header("Content-Type", "application/octet-stream")
+	w.c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.fileName))
+	w.c.Status(http.StatusOK)
+}
+
+func (w *downloadWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.start()
+	}
+	return w.c.Writer.Write(p)
+}
+
+// ListFileTransferRecordController 容器文件传输审计记录列表
+func ListFileTransferRecordController(c *gin.Context) {
+	query := models.FileTransferRecordQuery{}
+	if c.ShouldBindQuery(&query) != nil {
+		response.FailWithMessage(response.ParamError, response.ParamErrorMsg, c)
+		return
+	}
+
+	var records []models.FileTransferRecord
+	if err := services.ListFileTransferRecord(&query, &records); err != nil {
+		common.LOG.Error("获取文件传输记录失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.OkWithData(response.PageResult{
+		Data:  records,
+		Total: query.Total,
+		Size:  query.Size,
+		Page:  query.Page,
+	}, c)
+}
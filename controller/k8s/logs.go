@@ -17,16 +17,53 @@ limitations under the License.
 package k8s
 
 import (
+	"context"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/controller"
 	"github.com/dnsjia/luban/controller/response"
 	"github.com/dnsjia/luban/pkg/k8s/Init"
 	"github.com/dnsjia/luban/pkg/k8s/logs"
 	"github.com/dnsjia/luban/pkg/k8s/pods"
+	"github.com/dnsjia/luban/services"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"io"
 	v1 "k8s.io/api/core/v1"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
+var logUpGrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024 * 1024,
+	CheckOrigin:     checkOrigin,
+}
+
+// checkOrigin 只允许同源或 system.allowed-origins 中配置的来源发起 websocket 连接, 非浏览器客户端不携带 Origin
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) || strings.EqualFold(u.Host, r.Header.Get("X-Forwarded-Host")) {
+		return true
+	}
+	for _, allowed := range common.CONFIG.System.AllowedOrigins {
+		if strings.EqualFold(strings.TrimRight(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
 func GetLogSourcesController(c *gin.Context) {
 	client, err := Init.ClusterID(c)
 	if err != nil {
@@ -115,6 +152,66 @@ func GetLogFileController(c *gin.Context) {
 	handleDownload(c, logStream)
 }
 
+// FollowLogController 通过 websocket 实时推送日志, 资源为 deployment/statefulset/daemonset 等时聚合所有 pod 的日志
+func FollowLogController(c *gin.Context) {
+	clusterId, err := Init.GetClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	namespace := c.Param("namespace")
+	resourceName := c.Param("resourceName")
+	resourceType := c.Param("resourceType")
+
+	claims := controller.GetClaims(c)
+	if !services.EnforceNamespace(claims.Role, clusterId, namespace, "pods/log", "GET") {
+		common.LOG.Warn(fmt.Sprintf("用户：%v, 无权限查看实时日志 %s/%s", claims.Username, namespace, resourceName))
+		response.FailWithMessage(response.Forbidden, "", c)
+		return
+	}
+
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+
+	opts := pods.FollowOptions{Container: c.Query("container")}
+	if tailLines, err := strconv.ParseInt(c.Query("tailLines"), 10, 64); err == nil && tailLines >= 0 {
+		opts.TailLines = &tailLines
+	}
+	if sinceSeconds, err := strconv.ParseInt(c.Query("sinceSeconds"), 10, 64); err == nil && sinceSeconds > 0 {
+		opts.SinceSeconds = &sinceSeconds
+	}
+
+	ws, err := logUpGrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		common.LOG.Error(fmt.Sprintf("创建消息连接失败: %v", err))
+		return
+	}
+	defer ws.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	// 客户端只读不写, 读取失败即认为连接已关闭
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	err = pods.FollowLogs(ctx, client, namespace, resourceName, resourceType, opts, func(lines []pods.FollowLogLine) error {
+		_ = ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return ws.WriteJSON(lines)
+	})
+	if err != nil && ctx.Err() == nil {
+		_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, err.Error()))
+	}
+}
+
 func handleDownload(c *gin.Context, result io.ReadCloser) {
 	c.Writer.Header().Add("Content-Type", "text/plain")
 	defer result.Close()
@@ -0,0 +1,56 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dnsjia/luban/common"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	saved := common.CONFIG.System.AllowedOrigins
+	defer func() { common.CONFIG.System.AllowedOrigins = saved }()
+	common.CONFIG.System.AllowedOrigins = []string{"http://localhost:8080/"}
+
+	cases := []struct {
+		origin        string
+		forwardedHost string
+		expected      bool
+	}{
+		{origin: "", expected: true},
+		{origin: "https://luban.example.com", expected: true},
+		{origin: "https://proxy.example.com", forwardedHost: "proxy.example.com", expected: true},
+		{origin: "http://localhost:8080", expected: true},
+		{origin: "http://localhost:9090", expected: false},
+		{origin: "https://evil.example.com", expected: false},
+		{origin: "://bad", expected: false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "http://luban.example.com/api/v1/k8s/log/follow", nil)
+		if c.origin != "" {
+			r.Header.Set("Origin", c.origin)
+		}
+		if c.forwardedHost != "" {
+			r.Header.Set("X-Forwarded-Host", c.forwardedHost)
+		}
+		if got := checkOrigin(r); got != c.expected {
+			t.Errorf("checkOrigin(%q) == %v, expected %v", c.origin, got, c.expected)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/controller"
+	"github.com/dnsjia/luban/controller/response"
+	"github.com/dnsjia/luban/models/k8s"
+	"github.com/dnsjia/luban/pkg/k8s/Init"
+	"github.com/dnsjia/luban/pkg/k8s/manifest"
+	"github.com/dnsjia/luban/pkg/k8s/parser"
+	"github.com/gin-gonic/gin"
+)
+
+func ApplyManifestController(c *gin.Context) {
+	restConfig, err := Init.ClusterRestConfig(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+
+	var applyData k8s.ApplyManifest
+	err = controller.CheckParams(c, &applyData)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+
+	common.LOG.Info(fmt.Sprintf("用户：%v, 提交资源清单, dryRun: %v, force: %v", controller.GetClaims(c).Username, applyData.DryRun, applyData.Force))
+
+	data, err := manifest.ApplyManifest(restConfig, applyData.Content, applyData.Namespace, applyData.DryRun, applyData.Force)
+	if err != nil {
+		response.FailWithMessage(response.ERROR, err.Error(), c)
+		return
+	}
+	if manifest.HasConflict(data) {
+		response.FailWithDetailed(data, response.K8SResourceConflict, "部分字段由其他管理者持有, 确认后可使用强制apply接管", c)
+		return
+	}
+	response.OkWithData(data, c)
+}
+
+// GetResourceYAMLController 获取任意资源的YAML, 通过 kind、namespace、name 定位资源
+func GetResourceYAMLController(c *gin.Context) {
+	restConfig, err := Init.ClusterRestConfig(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	kind := c.Query("kind")
+	name := parser.ParseNameParameter(c)
+	if kind == "" || name == "" {
+		response.FailWithMessage(response.ParamError, "kind和name不能为空", c)
+		return
+	}
+	namespace := parser.ParseNamespaceParameter(c)
+
+	data, err := manifest.GetResourceYAML(restConfig, c.Query("group"), kind, namespace, name)
+	if err != nil {
+		response.FailWithMessage(response.ERROR, err.Error(), c)
+		return
+	}
+	response.OkWithData(data, c)
+}
+
+// UpdateResourceYAMLController 使用YAML更新资源, resourceVersion 不一致时返回冲突错误
+func UpdateResourceYAMLController(c *gin.Context) {
+	restConfig, err := Init.ClusterRestConfig(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+
+	var updateData k8s.UpdateResourceYAML
+	err = controller.CheckParams(c, &updateData)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+
+	common.LOG.Info(fmt.Sprintf("用户：%v, 编辑资源 %s %s/%s", controller.GetClaims(c).Username, updateData.Kind, updateData.Namespace, updateData.Name))
+	data, err := manifest.UpdateResourceYAML(restConfig, updateData.Group, updateData.Kind, updateData.Namespace, updateData.Name, updateData.Content)
+	if err != nil {
+		failWithUpdateError(err, c)
+		return
+	}
+	response.OkWithData(data, c)
+}
+
+// failWithUpdateError resourceVersion 冲突返回 K8SResourceConflict, 前端据此提示刷新后重新编辑
+func failWithUpdateError(err error, c *gin.Context) {
+	if errors.Is(err, manifest.ErrResourceConflict) {
+		response.FailWithMessage(response.K8SResourceConflict, "", c)
+		return
+	}
+	response.FailWithMessage(response.ERROR, err.Error(), c)
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dnsjia/luban/controller/response"
+	"github.com/dnsjia/luban/pkg/k8s/manifest"
+	"github.com/gin-gonic/gin"
+)
+
+func TestFailWithUpdateError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		err          error
+		expectedCode int
+	}{
+		{err: manifest.ErrResourceConflict, expectedCode: response.K8SResourceConflict},
+		{err: fmt.Errorf("update: %w", manifest.ErrResourceConflict), expectedCode: response.K8SResourceConflict},
+		{err: errors.New("metadata.resourceVersion 不能为空"), expectedCode: response.ERROR},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		failWithUpdateError(c.err, ctx)
+
+		var resp response.Response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Code != c.expectedCode {
+			t.Errorf("failWithUpdateError(%v) errCode == %d, expected %d", c.err, resp.Code, c.expectedCode)
+		}
+	}
+}
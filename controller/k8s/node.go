@@ -22,7 +22,9 @@ import (
 	"github.com/dnsjia/luban/pkg/k8s/Init"
 	"github.com/dnsjia/luban/pkg/k8s/node"
 	"github.com/gin-gonic/gin"
+	v1 "k8s.io/api/core/v1"
 	"net/http"
+	"strconv"
 )
 
 func GetNodes(c *gin.Context) {
@@ -84,6 +86,21 @@ func NodeUnschedulable(c *gin.Context) {
 	return
 }
 
+// parseDrainOptions 从请求参数中解析排空选项
+func parseDrainOptions(c *gin.Context) node.DrainOptions {
+	options := node.DrainOptions{
+		DeleteEmptyDirData: c.Query("deleteEmptyDirData") == "true",
+		Force:              c.Query("force") == "true",
+	}
+	if gracePeriod, err := strconv.ParseInt(c.Query("gracePeriodSeconds"), 10, 64); err == nil && gracePeriod >= 0 {
+		options.GracePeriodSeconds = &gracePeriod
+	}
+	if timeout, err := strconv.ParseInt(c.Query("timeoutSeconds"), 10, 64); err == nil && timeout > 0 {
+		options.TimeoutSeconds = timeout
+	}
+	return options
+}
+
 func CordonNode(c *gin.Context) {
 	nodeName := c.Query("node_name")
 
@@ -92,12 +109,14 @@ func CordonNode(c *gin.Context) {
 		response.FailWithMessage(response.InternalServerError, err.Error(), c)
 		return
 	}
+	clusterId, _ := Init.GetClusterID(c)
 
-	if ok, err := node.CordonNode(client, nodeName); !ok {
+	job, err := node.CordonNode(client, clusterId, nodeName, parseDrainOptions(c))
+	if err != nil {
 		response.FailWithMessage(response.InternalServerError, err.Error(), c)
 		return
 	}
-	response.Ok(c)
+	response.OkWithData(job, c)
 }
 
 func RemoveNode(c *gin.Context) {
@@ -112,17 +131,72 @@ func RemoveNode(c *gin.Context) {
 		response.FailWithMessage(response.InternalServerError, err.Error(), c)
 		return
 	}
-	go node.RemoveNode(client, nodeName)
-	//if ok, err := node.RemoveNode(client, nodeName); !ok {
-	//	response.FailWithMessage(response.InternalServerError, err.Error(), c)
-	//	return
-	//}
-	response.Ok(c)
+	clusterId, _ := Init.GetClusterID(c)
 
+	job, err := node.RemoveNode(client, clusterId, nodeName, parseDrainOptions(c))
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.OkWithData(job, c)
+}
+
+type drainNode struct {
+	NodeName string `json:"node_name" binding:"required"`
+	node.DrainOptions
+}
+
+// DrainNode 排空节点, 在后台执行并返回排空任务, 通过 GetDrainJob 查询进度
+func DrainNode(c *gin.Context) {
+	var drain drainNode
+	err := controller.CheckParams(c, &drain)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	clusterId, _ := Init.GetClusterID(c)
+
+	job, err := node.StartDrainJob(client, clusterId, drain.NodeName, drain.DrainOptions)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.OkWithData(job, c)
+}
+
+// GetDrainJob 查询排空任务及每个pod的驱逐进度
+func GetDrainJob(c *gin.Context) {
+	clusterId, err := Init.GetClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	job, err := node.GetDrainJob(clusterId, c.Query("id"))
+	if err != nil {
+		response.FailWithMessage(http.StatusNotFound, err.Error(), c)
+		return
+	}
+	response.OkWithData(job, c)
+}
+
+// ListDrainJobs 查询集群中的排空任务
+func ListDrainJobs(c *gin.Context) {
+	clusterId, err := Init.GetClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	response.OkWithData(node.ListDrainJobs(clusterId), c)
 }
 
 type collectionNode struct {
 	NodeName []string `json:"node_name"`
+	node.DrainOptions
 }
 
 func CollectionNodeUnschedule(c *gin.Context) {
@@ -158,11 +232,165 @@ func CollectionCordonNode(c *gin.Context) {
 		response.FailWithMessage(response.InternalServerError, err.Error(), c)
 		return
 	}
-	err2 := node.CollectionCordonNode(client, nodeUnscheduled.NodeName)
+	clusterId, _ := Init.GetClusterID(c)
+	jobs, err2 := node.CollectionCordonNode(client, clusterId, nodeUnscheduled.NodeName, nodeUnscheduled.DrainOptions)
 	if err2 != nil {
 		response.FailWithMessage(response.InternalServerError, err2.Error(), c)
 		return
 	}
-	response.Ok(c)
+	response.OkWithData(jobs, c)
 	return
 }
+
+type nodeMetadata struct {
+	NodeName string `json:"node_name" binding:"required"`
+	node.MetadataChange
+}
+
+type collectionNodeMetadata struct {
+	NodeName []string `json:"node_name" binding:"required"`
+	node.MetadataChange
+}
+
+type nodeTaints struct {
+	NodeName string `json:"node_name" binding:"required"`
+	node.TaintChange
+}
+
+type collectionNodeTaints struct {
+	NodeName []string `json:"node_name" binding:"required"`
+	node.TaintChange
+}
+
+type taintPreview struct {
+	NodeName []string   `json:"node_name" binding:"required"`
+	Taints   []v1.Taint `json:"taints" binding:"required"`
+}
+
+func UpdateNodeLabels(c *gin.Context) {
+	var labels nodeMetadata
+	if err := controller.CheckParams(c, &labels); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	if err := node.UpdateNodeLabels(client, labels.NodeName, labels.MetadataChange); err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.Ok(c)
+}
+
+func CollectionUpdateNodeLabels(c *gin.Context) {
+	var labels collectionNodeMetadata
+	if err := controller.CheckParams(c, &labels); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	if err := node.CollectionUpdateNodeLabels(client, labels.NodeName, labels.MetadataChange); err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.Ok(c)
+}
+
+func UpdateNodeAnnotations(c *gin.Context) {
+	var annotations nodeMetadata
+	if err := controller.CheckParams(c, &annotations); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	if err := node.UpdateNodeAnnotations(client, annotations.NodeName, annotations.MetadataChange); err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.Ok(c)
+}
+
+func CollectionUpdateNodeAnnotations(c *gin.Context) {
+	var annotations collectionNodeMetadata
+	if err := controller.CheckParams(c, &annotations); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	if err := node.CollectionUpdateNodeAnnotations(client, annotations.NodeName, annotations.MetadataChange); err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.Ok(c)
+}
+
+func UpdateNodeTaints(c *gin.Context) {
+	var taints nodeTaints
+	if err := controller.CheckParams(c, &taints); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	if err := node.UpdateNodeTaints(client, taints.NodeName, taints.TaintChange); err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.Ok(c)
+}
+
+func CollectionUpdateNodeTaints(c *gin.Context) {
+	var taints collectionNodeTaints
+	if err := controller.CheckParams(c, &taints); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	if err := node.CollectionUpdateNodeTaints(client, taints.NodeName, taints.TaintChange); err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.Ok(c)
+}
+
+// PreviewNodeTaints 预览添加 NoExecute 污点后会被驱逐的pod
+func PreviewNodeTaints(c *gin.Context) {
+	var preview taintPreview
+	if err := controller.CheckParams(c, &preview); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	data, err := node.PreviewNoExecuteTaints(client, preview.NodeName, preview.Taints)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.OkWithData(data, c)
+}
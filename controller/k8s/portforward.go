@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/controller"
+	"github.com/dnsjia/luban/controller/response"
+	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/pkg/k8s/Init"
+	"github.com/dnsjia/luban/pkg/k8s/parser"
+	"github.com/dnsjia/luban/pkg/k8s/portforward"
+	"github.com/dnsjia/luban/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+// portForwardUpGrader 端口转发隧道的 websocket 升级器, 缓冲区与隧道单次读取的大小一致
+var portForwardUpGrader = websocket.Upgrader{
+	ReadBufferSize:  32 * 1024,
+	WriteBufferSize: 32 * 1024,
+	CheckOrigin:     checkOrigin,
+}
+
+// PortForwardController 通过WebSocket隧道转发到Pod或Service端口, 每条WebSocket连接对应一条TCP连接.
+// 参数: namespace, pod 或 service, port(端口号或端口名称), idle_timeout(空闲超时秒数, 可选)
+func PortForwardController(c *gin.Context) {
+	clusterId, err := Init.GetClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	namespace := parser.ParseNamespaceParameter(c)
+	podName := c.Query("pod")
+	serviceName := c.Query("service")
+	port := c.Query("port")
+	if namespace == "" || port == "" || (podName == "") == (serviceName == "") {
+		response.FailWithMessage(response.ParamError, "namespace、port不能为空, pod和service必须且只能指定一个", c)
+		return
+	}
+	var idleTimeout time.Duration
+	if v := c.Query("idle_timeout"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds <= 0 {
+			response.FailWithMessage(response.ParamError, "idle_timeout不合法", c)
+			return
+		}
+		idleTimeout = time.Duration(seconds) * time.Second
+	}
+
+	claims := controller.GetClaims(c)
+	if !services.EnforceNamespace(claims.Role, clusterId, namespace, "pods/portforward", "POST") {
+		common.LOG.Warn(fmt.Sprintf("用户：%v, 无权限在名称空间 %s 进行端口转发", claims.Username, namespace))
+		response.FailWithMessage(response.Forbidden, "", c)
+		return
+	}
+
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	restConfig, err := Init.ClusterRestConfig(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+
+	var target *portforward.Target
+	if podName != "" {
+		target, err = portforward.ResolvePodTarget(client, namespace, podName, port)
+	} else {
+		target, err = portforward.ResolveServiceTarget(client, namespace, serviceName, port)
+	}
+	if err != nil {
+		response.FailWithMessage(response.ERROR, err.Error(), c)
+		return
+	}
+
+	ws, err := portForwardUpGrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		common.LOG.Error(fmt.Sprintf("创建端口转发连接失败: %v", err))
+		return
+	}
+	_ = portforward.Forward(c.Request.Context(), client, restConfig, target, ws, portforward.Options{
+		ClusterID:   clusterId,
+		UserName:    claims.Username,
+		ClientIP:    c.ClientIP(),
+		IdleTimeout: idleTimeout,
+	})
+}
+
+// ListPortForwardRecordController 端口转发审计记录列表
+func ListPortForwardRecordController(c *gin.Context) {
+	query := models.PortForwardRecordQuery{}
+	if c.ShouldBindQuery(&query) != nil {
+		response.FailWithMessage(response.ParamError, response.ParamErrorMsg, c)
+		return
+	}
+
+	var records []models.PortForwardRecord
+	if err := services.ListPortForwardRecord(&query, &records); err != nil {
+		common.LOG.Error("获取端口转发记录失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.OkWithData(response.PageResult{
+		Data:  records,
+		Total: query.Total,
+		Size:  query.Size,
+		Page:  query.Page,
+	}, c)
+}
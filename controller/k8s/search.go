@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/controller/response"
+	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/models/k8s"
+	"github.com/dnsjia/luban/pkg/k8s/informer"
+	"github.com/dnsjia/luban/pkg/k8s/search"
+	"github.com/dnsjia/luban/services"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SearchController 在所有集群中搜索 Pod、Service、Node, 按集群分组返回
+func SearchController(c *gin.Context) {
+	var query k8s.SearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.FailWithMessage(response.ParamError, response.ParamErrorMsg, c)
+		return
+	}
+
+	clusters, err := services.ListAllK8SCluster()
+	if err != nil {
+		common.LOG.Error("获取集群失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, "获取集群失败", c)
+		return
+	}
+
+	clientFor := func(cluster models.K8SCluster) (kubernetes.Interface, error) {
+		cc, err := informer.Get(cluster)
+		if err != nil {
+			return nil, err
+		}
+		return cc.Client(), nil
+	}
+	data, err := search.Search(clusters, clientFor, query)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	response.OkWithData(data, c)
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/controller"
+	"github.com/dnsjia/luban/controller/response"
+	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/models/k8s"
+	"github.com/dnsjia/luban/pkg/k8s/Init"
+	"github.com/dnsjia/luban/pkg/k8s/pods"
+	"github.com/dnsjia/luban/pkg/k8s/terminal"
+	"github.com/dnsjia/luban/pkg/utils"
+	"github.com/dnsjia/luban/services"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+// sockJSHandler 处理前端的SockJS连接, 通过会话ID与已创建的终端会话绑定
+var sockJSHandler = terminal.CreateAttachHandler("/api/v1/k8s/sockjs")
+
+// PodTerminalController 创建容器终端会话, 返回的会话ID用于SockJS绑定
+func PodTerminalController(c *gin.Context) {
+	clusterId, err := Init.GetClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	var data k8s.PodTerminalData
+	if err := controller.CheckParams(c, &data); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	namespace, podName := data.Namespace, data.PodName
+
+	claims := controller.GetClaims(c)
+	if !services.EnforceNamespace(claims.Role, clusterId, namespace, "pods/exec", "POST") {
+		common.LOG.Warn(fmt.Sprintf("用户：%v, 无权限进入容器终端 %s/%s", claims.Username, namespace, podName))
+		response.FailWithMessage(response.Forbidden, "", c)
+		return
+	}
+
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	restConfig, err := Init.ClusterRestConfig(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+
+	sessionId, err := terminal.NewTerminalSession(client, restConfig, terminal.ExecOptions{
+		ClusterID:     clusterId,
+		Namespace:     namespace,
+		PodName:       podName,
+		ContainerName: data.Container,
+		Shell:         data.Shell,
+		UserName:      claims.Username,
+	})
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	common.LOG.Info(fmt.Sprintf("用户：%v, 创建容器终端会话 %s, pod: %s/%s", claims.Username, sessionId, namespace, podName))
+	response.OkWithData(gin.H{"id": sessionId}, c)
+}
+
+// DebugPodController 为Pod添加临时调试容器并创建终端会话, 用于无shell的distroless镜像.
+// 返回的会话ID与 PodTerminalController 相同, 用于SockJS绑定
+func DebugPodController(c *gin.Context) {
+	clusterId, err := Init.GetClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+	var data k8s.DebugContainerData
+	if err := controller.CheckParams(c, &data); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+
+	claims := controller.GetClaims(c)
+	if !services.EnforceNamespace(claims.Role, clusterId, data.Namespace, "pods/ephemeralcontainers", "POST") ||
+		!services.EnforceNamespace(claims.Role, clusterId, data.Namespace, "pods/exec", "POST") {
+		common.LOG.Warn(fmt.Sprintf("用户：%v, 无权限调试容器 %s/%s", claims.Username, data.Namespace, data.PodName))
+		response.FailWithMessage(response.Forbidden, "", c)
+		return
+	}
+	if data.Image, err = pods.DebugImage(data.Image); err != nil {
+		response.FailWithMessage(response.ParamError, err.Error(), c)
+		return
+	}
+
+	client, err := Init.ClusterID(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	restConfig, err := Init.ClusterRestConfig(c)
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+
+	containerName, err := pods.CreateDebugContainer(client, data)
+	if err != nil {
+		response.FailWithMessage(response.ERROR, err.Error(), c)
+		return
+	}
+	if err := pods.WaitForDebugContainer(client, data.Namespace, data.PodName, containerName); err != nil {
+		response.FailWithMessage(response.ERROR, err.Error(), c)
+		return
+	}
+
+	sessionId, err := terminal.NewTerminalSession(client, restConfig, terminal.ExecOptions{
+		ClusterID:     clusterId,
+		Namespace:     data.Namespace,
+		PodName:       data.PodName,
+		ContainerName: containerName,
+		Shell:         data.Shell,
+		UserName:      claims.Username,
+	})
+	if err != nil {
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	common.LOG.Info(fmt.Sprintf("用户：%v, 创建调试容器 %s 终端会话 %s, pod: %s/%s", claims.Username, containerName, sessionId, data.Namespace, data.PodName))
+	response.OkWithData(gin.H{"id": sessionId, "container": containerName}, c)
+}
+
+// TerminalSockJSController SockJS连接入口
+func TerminalSockJSController(c *gin.Context) {
+	sockJSHandler.ServeHTTP(c.Writer, c.Request, controller.GetClaims(c).Username)
+}
+
+// ListPodTerminalRecordController 容器终端操作记录列表
+func ListPodTerminalRecordController(c *gin.Context) {
+	query := models.PodTerminalRecordQuery{}
+	if c.ShouldBindQuery(&query) != nil {
+		response.FailWithMessage(response.ParamError, response.ParamErrorMsg, c)
+		return
+	}
+
+	var records []models.PodTerminalRecord
+	if err := services.ListPodTerminalRecord(&query, &records); err != nil {
+		common.LOG.Error("获取容器终端操作记录失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.OkWithData(response.PageResult{
+		Data:  records,
+		Total: query.Total,
+		Size:  query.Size,
+		Page:  query.Page,
+	}, c)
+}
+
+// PodTerminalRecordReplayController 获取容器终端操作记录的 asciicast v2 内容, 用于前端回放
+func PodTerminalRecordReplayController(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Query("id"), 10, 32)
+	if err != nil {
+		response.FailWithMessage(response.ParamError, response.ParamErrorMsg, c)
+		return
+	}
+	record, err := services.GetPodTerminalRecord(uint(id))
+	if err != nil {
+		response.FailWithMessage(response.ERROR, err.Error(), c)
+		return
+	}
+	cast, err := utils.ZlibUnCompress(record.Records)
+	if err != nil {
+		common.LOG.Error("解压容器终端操作记录失败", zap.Any("err", err))
+		response.FailWithMessage(response.InternalServerError, err.Error(), c)
+		return
+	}
+	response.OkWithData(gin.H{"record": record, "cast": string(cast)}, c)
+}
@@ -50,6 +50,7 @@ const (
 	InternalServerError = http.StatusInternalServerError
 
 	CreateK8SClusterError = 2000
+	K8SResourceConflict   = 2001
 
 	LDAPUserLoginFailed = 3000
 	LDAPUserNotFound    = 3001
@@ -70,6 +71,7 @@ const (
 	InternalServerErrorMsg = "服务器内部错误"
 
 	CreateK8SClusterErrorMsg = "创建K8S集群失败"
+	K8SResourceConflictMsg   = "资源已被他人修改, 请刷新后重新编辑"
 
 	LDAPUserLoginFailedMsg = "登录失败，请检查您的用户名和密码!"
 	LDAPUserNotFoundMsg    = "用户不存在"
@@ -90,6 +92,7 @@ var CustomError = map[int]string{
 	InternalServerError: InternalServerErrorMsg,
 
 	CreateK8SClusterError: CreateK8SClusterErrorMsg,
+	K8SResourceConflict:   K8SResourceConflictMsg,
 
 	LDAPUserLoginFailed: LDAPUserLoginFailedMsg,
 	LDAPUserNotFound:    LDAPUserNotFoundMsg,
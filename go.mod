@@ -20,7 +20,10 @@ require (
 	github.com/lestrrat-go/strftime v1.0.5 // indirect
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/pelletier/go-toml v1.9.4 // indirect
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/prometheus/client_model v0.2.0
 	github.com/prometheus/common v0.31.1
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/satori/go.uuid v1.2.1-0.20181028125025-b2ce2384e17b
 	github.com/spf13/cast v1.4.1 // indirect
 	github.com/spf13/cobra v1.2.1
@@ -42,4 +45,5 @@ require (
 	k8s.io/apimachinery v0.22.3
 	k8s.io/client-go v0.22.3
 	k8s.io/kubectl v0.22.3
+	sigs.k8s.io/yaml v1.2.0
 )
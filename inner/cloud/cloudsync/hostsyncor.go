@@ -17,7 +17,12 @@ limitations under the License.
 package cloudsync
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
+
 	"github.com/dnsjia/luban/common"
 	"github.com/dnsjia/luban/inner/cloud/cloudvendor"
 	"github.com/dnsjia/luban/models/cmdb"
@@ -25,133 +30,309 @@ import (
 	"gorm.io/gorm"
 )
 
-var (
-	aliClient cloudvendor.VendorClient
+const (
+	// regionConcurrency 同时查询的地域数
+	regionConcurrency = 4
+	// batchSize 批量查询与写入的大小
+	batchSize = 500
+	// staleInProgress 同步中状态超过该时间视为上次同步异常退出, 允许重新同步
+	staleInProgress = time.Hour
 )
 
-// SyncAliYunHost 同步阿里云主机
-func SyncAliYunHost(task *cmdb.CloudPlatform) {
+// hostDiff 一个云账号需要新增、更新、删除的主机
+type hostDiff struct {
+	add       []cmdb.VirtualMachine
+	update    []cmdb.VirtualMachine
+	remove    []int
+	unchanged int
+}
+
+// Sync 通过已注册的云厂商客户端同步云账号下的主机:
+// 批量对比本地与云上的主机, 新增和更新有变化的主机, 软删除成功同步的地域中已不存在的主机,
+// 并将同步状态与结果记录到云账号
+func Sync(account *cmdb.CloudPlatform) *cmdb.CloudSyncSummary {
+	summary := &cmdb.CloudSyncSummary{
+		PlatformID: account.ID,
+		Vendor:     account.Type,
+		StartedAt:  time.Now(),
+	}
+
+	started, err := markInProgress(account.ID)
+	if err != nil || !started {
+		summary.Status = cmdb.CloudSyncFail
+		summary.Error = "云账号正在同步中"
+		if err != nil {
+			summary.Error = fmt.Sprintf("更新同步状态失败: %v", err)
+		}
+		summary.Duration = time.Since(summary.StartedAt).String()
+		logf(summary, "%s", summary.Error)
+		return summary
+	}
+	logf(summary, "开始同步云账号%s(%s)", account.Name, account.Type)
+
 	defer func() {
 		if err := recover(); err != nil {
 			common.LOG.Error(fmt.Sprintf("sync panic err: %v", err))
+			summary.Error = fmt.Sprintf("同步异常: %v", err)
 		}
+		finish(summary)
 	}()
 
-	// 获取cloud账户
-	conf := cmdb.CloudPlatform{
-		Type:      cmdb.AliYun,
-		AccessKey: task.AccessKey,
-		SecretKey: task.SecretKey,
+	if err := syncHosts(account, summary); err != nil {
+		summary.Error = err.Error()
+		logf(summary, "同步失败: %v", err)
+	}
+	return summary
+}
+
+func syncHosts(account *cmdb.CloudPlatform, summary *cmdb.CloudSyncSummary) error {
+	client, err := cloudvendor.GetVendorClient(account)
+	if err != nil {
+		return err
+	}
+	regionSet, err := client.GetRegions()
+	if err != nil {
+		return fmt.Errorf("获取地域列表失败: %v", err)
+	}
+
+	logf(summary, "获取到%d个地域", len(regionSet))
+
+	var mu sync.Mutex
+	log := func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		logf(summary, format, args...)
+	}
+	remote, synced, regionErrors := fetchInstances(client, account.ID, regionSet, log)
+	summary.Regions = len(synced)
+	summary.RegionErrors = regionErrors
+	summary.Total = len(remote)
+
+	local, err := getLocalHosts(account.ID, remote)
+	if err != nil {
+		return fmt.Errorf("获取本地主机失败: %v", err)
 	}
 
-	aliClient, _ = cloudvendor.GetVendorClient(&conf)
+	diff := diffHosts(account.ID, remote, local, synced)
+	if err := applyDiff(diff); err != nil {
+		return fmt.Errorf("保存主机失败: %v", err)
+	}
+	summary.Added = len(diff.add)
+	summary.Updated = len(diff.update)
+	summary.Removed = len(diff.remove)
+	summary.Unchanged = diff.unchanged
+	logf(summary, "云上主机%d台, 新增%d, 更新%d, 删除%d, 未变化%d",
+		summary.Total, summary.Added, summary.Updated, summary.Removed, summary.Unchanged)
+	return nil
+}
+
+// fetchInstances 并发查询各地域的主机, 返回云上主机、成功同步的地域与失败地域的错误
+func fetchInstances(client cloudvendor.VendorClient, platformID int, regionSet []*cmdb.Region,
+	log func(format string, args ...interface{})) (
+	map[string]cmdb.VirtualMachine, map[string]bool, map[string]string) {
 
-	// 获取所有可用区
-	regionSet, _ := aliClient.GetRegions()
+	var (
+		mu           sync.Mutex
+		wg           sync.WaitGroup
+		sem          = make(chan struct{}, regionConcurrency)
+		remote       = make(map[string]cmdb.VirtualMachine)
+		synced       = make(map[string]bool)
+		regionErrors = make(map[string]string)
+	)
 	for _, region := range regionSet {
-		// 获取所有区域下的ecs主机
-		instancesInfo, err := aliClient.GetInstances(region.RegionId)
-		if err != nil {
-			common.LOG.Error(fmt.Sprintf("同步资产发生错误, err: %v", err))
+		if !region.Enable {
+			continue
 		}
-		// 判断区域下是否有ecs
-		if len(instancesInfo) != 0 {
-			for _, i := range instancesInfo {
-				// 根据主机实例id获取db中的主机信息,并获取有差异的主机
-				diffHosts, _ := getDiffHosts(&i)
-				if len(diffHosts) != 0 {
-					// 有差异的更新任务状态为同步中
-					//updateTaskState(diffHosts)
-
-					// 同步有差异的主机数据
-					syncDiffHosts(diffHosts)
-				}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(regionId string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			instances, err := client.GetInstances(regionId)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log("地域%s同步失败: %v", regionId, err)
+				regionErrors[regionId] = err.Error()
+				return
 			}
-		}
+			log("地域%s获取到%d台主机", regionId, len(instances))
+			synced[regionId] = true
+			for _, vm := range instances {
+				vm.PlatformID = platformID
+				vm.CloudRegion = regionId
+				remote[vm.UUID] = vm
+			}
+		}(region.RegionId)
 	}
-	return
+	wg.Wait()
+	return remote, synced, regionErrors
 }
 
-// getDiffHosts 根据主机实例id获取db中的主机信息,并获取有差异的主机
-func getDiffHosts(remoteHosts *cmdb.VirtualMachine) (map[string][]*cmdb.VirtualMachine, error) {
-	// 本地已有主机
-	localHosts, _ := getLocalHosts(remoteHosts)
-
-	localIdHostsMap := make(map[string]*cmdb.VirtualMachine)
-	for _, h := range localHosts {
-		localIdHostsMap[h.UUID] = h
+// getLocalHosts 获取云账号下的本地主机(包括已软删除的), 以及未关联云账号但实例ID与云上一致的历史主机
+func getLocalHosts(platformID int, remote map[string]cmdb.VirtualMachine) ([]cmdb.VirtualMachine, error) {
+	local := make([]cmdb.VirtualMachine, 0)
+	if err := common.DB.Unscoped().Where("platform_id = ?", platformID).Find(&local).Error; err != nil {
+		return nil, err
 	}
 
-	// 有差异的主机
-	diffHosts := make(map[string][]*cmdb.VirtualMachine)
-
-	// 本地需要同步新增和更新的主机
-	if _, ok := localIdHostsMap[remoteHosts.UUID]; ok {
-		lh := localIdHostsMap[remoteHosts.UUID]
-		// 判断云主机和本地主机是否有差异，有则需要更新
-		if remoteHosts.HostName != lh.HostName || remoteHosts.PublicAddr != lh.PublicAddr ||
-			remoteHosts.PrivateAddr != lh.PrivateAddr || remoteHosts.VmExpiredTime != lh.VmExpiredTime ||
-			remoteHosts.Status != lh.Status || remoteHosts.Mem != lh.Mem || remoteHosts.CPU != lh.CPU ||
-			remoteHosts.BandWidth != lh.BandWidth {
-			diffHosts["update"] = append(diffHosts["update"], remoteHosts)
+	uuids := make([]string, 0, len(remote))
+	for uuid := range remote {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	for start := 0; start < len(uuids); start += batchSize {
+		end := start + batchSize
+		if end > len(uuids) {
+			end = len(uuids)
+		}
+		var legacy []cmdb.VirtualMachine
+		if err := common.DB.Unscoped().Where("platform_id = ? AND uuid IN ?", 0, uuids[start:end]).
+			Find(&legacy).Error; err != nil {
+			return nil, err
 		}
-	} else {
-		diffHosts["add"] = append(diffHosts["add"], remoteHosts)
+		local = append(local, legacy...)
 	}
-
-	return diffHosts, nil
+	return local, nil
 }
 
-// getLocalHosts 获取本地主机
-func getLocalHosts(hostResource *cmdb.VirtualMachine) ([]*cmdb.VirtualMachine, error) {
-	result := make([]*cmdb.VirtualMachine, 0)
-	var c cmdb.VirtualMachine
+// diffHosts 对比云上与本地主机. 本地主机只在其所属地域本次同步成功时才会被删除,
+// 避免地域查询失败时误删主机; 已软删除的主机重新出现时恢复
+func diffHosts(platformID int, remote map[string]cmdb.VirtualMachine, local []cmdb.VirtualMachine, synced map[string]bool) hostDiff {
+	var diff hostDiff
+	localByUUID := make(map[string]cmdb.VirtualMachine, len(local))
+	for _, lh := range local {
+		// 同一实例存在多条记录时优先使用未删除的记录
+		if existing, ok := localByUUID[lh.UUID]; ok && !existing.DeletedAt.Valid {
+			continue
+		}
+		localByUUID[lh.UUID] = lh
+	}
+
+	uuids := make([]string, 0, len(remote))
+	for uuid := range remote {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	for _, uuid := range uuids {
+		rh := remote[uuid]
+		lh, ok := localByUUID[uuid]
+		if !ok {
+			diff.add = append(diff.add, rh)
+			continue
+		}
+		if hostChanged(rh, lh) {
+			rh.ID = lh.ID
+			diff.update = append(diff.update, rh)
+		} else {
+			diff.unchanged++
+		}
+	}
 
-	results := common.DB.Table("cloud_virtual_machine").Where("uuid = ?", hostResource.UUID).First(&c)
-	if results.Error != nil {
-		if results.Error == gorm.ErrRecordNotFound {
-			return nil, gorm.ErrRecordNotFound
+	for _, lh := range local {
+		if lh.PlatformID != platformID || lh.DeletedAt.Valid || !synced[lh.CloudRegion] {
+			continue
+		}
+		if _, ok := remote[lh.UUID]; !ok {
+			diff.remove = append(diff.remove, lh.ID)
 		}
 	}
-	result = append(result, &c)
-	return result, nil
+	return diff
 }
 
-// addHost 添加云主机
-func addHost(hostResource []*cmdb.VirtualMachine) error {
-	if err := common.DB.Create(&hostResource); err != nil {
-		return err.Error
-	}
-	return nil
+// hostChanged 判断云主机和本地主机是否有差异
+func hostChanged(rh, lh cmdb.VirtualMachine) bool {
+	return lh.DeletedAt.Valid || rh.PlatformID != lh.PlatformID || rh.CloudRegion != lh.CloudRegion ||
+		rh.HostName != lh.HostName || rh.PublicAddr != lh.PublicAddr || rh.PrivateAddr != lh.PrivateAddr ||
+		rh.VmExpiredTime != lh.VmExpiredTime || rh.Status != lh.Status || rh.Mem != lh.Mem || rh.CPU != lh.CPU ||
+		rh.BandWidth != lh.BandWidth || rh.OS != lh.OS || rh.Region != lh.Region
 }
 
-// syncDiffHosts 更新变化的主机
-func syncDiffHosts(diff map[string][]*cmdb.VirtualMachine) {
-
-	for k, v := range diff {
-		switch k {
-		case "update":
-			for _, host := range v {
-				// HostName、PublicAddr、PrivateAddr、VmExpiredTime、Status、Mem、CPU、BandWidth
-				results := common.DB.Table("cloud_virtual_machine").
-					Where("uuid = ?", &host.UUID).Updates(map[string]interface{}{
-					"hostname":        &host.HostName,
-					"public_addr":     &host.PublicAddr,
-					"private_addr":    &host.PrivateAddr,
-					"vm_expired_time": &host.VmExpiredTime,
-					"status":          &host.Status,
-					"mem":             &host.Mem,
-					"cpu":             &host.CPU,
-					"bandwidth":       &host.BandWidth,
-				})
-				if results.Error != nil {
-					common.LOG.Error("更新主机资源失败", zap.Any("err", results.Error))
-				}
+// applyDiff 在一个事务中写入新增、更新与删除的主机
+func applyDiff(diff hostDiff) error {
+	return common.DB.Transaction(func(tx *gorm.DB) error {
+		if len(diff.add) > 0 {
+			if err := tx.CreateInBatches(diff.add, 100).Error; err != nil {
+				return err
+			}
+		}
+		for _, host := range diff.update {
+			if err := tx.Unscoped().Model(&cmdb.VirtualMachine{}).Where("id = ?", host.ID).Updates(map[string]interface{}{
+				"hostname":        host.HostName,
+				"public_addr":     host.PublicAddr,
+				"private_addr":    host.PrivateAddr,
+				"vm_expired_time": host.VmExpiredTime,
+				"status":          host.Status,
+				"mem":             host.Mem,
+				"cpu":             host.CPU,
+				"bandwidth":       host.BandWidth,
+				"os":              host.OS,
+				"region":          host.Region,
+				"platform_id":     host.PlatformID,
+				"cloud_region":    host.CloudRegion,
+				"deleted_at":      nil,
+			}).Error; err != nil {
+				return err
 			}
-		case "add":
-			if err := addHost(v); err != nil {
-				common.LOG.Error("同步新增主机失败", zap.Any("err", err.Error()))
+		}
+		if len(diff.remove) > 0 {
+			if err := tx.Where("id IN ?", diff.remove).Delete(&cmdb.VirtualMachine{}).Error; err != nil {
+				return err
 			}
 		}
+		return nil
+	})
+}
+
+// markInProgress 将云账号标记为同步中, 同一账号同时只允许一个同步任务
+func markInProgress(platformID int) (bool, error) {
+	result := common.DB.Model(&cmdb.CloudPlatform{}).
+		Where("id = ? AND (sync_status IS NULL OR sync_status <> ? OR updated_at < ?)",
+			platformID, cmdb.CloudSyncInProgress, time.Now().Add(-staleInProgress)).
+		Updates(map[string]interface{}{"sync_status": cmdb.CloudSyncInProgress, "updated_at": time.Now()})
+	return result.RowsAffected > 0, result.Error
+}
+
+// finish 记录同步状态、同步时间与同步结果
+func finish(summary *cmdb.CloudSyncSummary) {
+	summary.Status = cmdb.CloudSyncSuccess
+	if summary.Error != "" || len(summary.RegionErrors) > 0 {
+		summary.Status = cmdb.CloudSyncFail
+	}
+	summary.Duration = time.Since(summary.StartedAt).Truncate(time.Millisecond).String()
+
+	data, _ := json.Marshal(summary)
+	now := time.Now()
+	if err := common.DB.Model(&cmdb.CloudPlatform{}).Where("id = ?", summary.PlatformID).Updates(map[string]interface{}{
+		"sync_status":  summary.Status,
+		"sync_time":    &now,
+		"sync_summary": string(data),
+		"msg":          summaryMessage(summary),
+		"updated_at":   now,
+	}).Error; err != nil {
+		common.LOG.Error("更新云账号同步状态失败", zap.Any("err", err))
+	}
+	common.LOG.Info(fmt.Sprintf("云账号%d同步完成: %s", summary.PlatformID, summaryMessage(summary)))
+}
+
+// logf 记录同步日志
+func logf(summary *cmdb.CloudSyncSummary, format string, args ...interface{}) {
+	line := time.Now().Format("2006-01-02 15:04:05") + " " + fmt.Sprintf(format, args...)
+	summary.Logs = append(summary.Logs, line)
+}
+
+// summaryMessage 同步结果摘要, 长度不超过 msg 字段
+func summaryMessage(summary *cmdb.CloudSyncSummary) string {
+	msg := fmt.Sprintf("新增%d, 更新%d, 删除%d, 未变化%d, 失败地域%d, 耗时%s",
+		summary.Added, summary.Updated, summary.Removed, summary.Unchanged, len(summary.RegionErrors), summary.Duration)
+	if summary.Error != "" {
+		msg = "同步失败: " + summary.Error
+	}
+	if runes := []rune(msg); len(runes) > 180 {
+		msg = string(runes[:180])
 	}
+	return msg
 }
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudsync
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dnsjia/luban/models/cmdb"
+	"gorm.io/gorm"
+)
+
+func TestDiffHosts(t *testing.T) {
+	remote := map[string]cmdb.VirtualMachine{
+		"i-new":      {UUID: "i-new", PlatformID: 1, CloudRegion: "cn-hangzhou", Status: "Running"},
+		"i-same":     {UUID: "i-same", PlatformID: 1, CloudRegion: "cn-hangzhou", Status: "Running"},
+		"i-changed":  {UUID: "i-changed", PlatformID: 1, CloudRegion: "cn-hangzhou", Status: "Stopped"},
+		"i-legacy":   {UUID: "i-legacy", PlatformID: 1, CloudRegion: "cn-hangzhou", Status: "Running"},
+		"i-restored": {UUID: "i-restored", PlatformID: 1, CloudRegion: "cn-hangzhou", Status: "Running"},
+	}
+	deleted := gorm.DeletedAt{Time: time.Now(), Valid: true}
+	local := []cmdb.VirtualMachine{
+		{ID: 1, UUID: "i-same", PlatformID: 1, CloudRegion: "cn-hangzhou", Status: "Running"},
+		{ID: 2, UUID: "i-changed", PlatformID: 1, CloudRegion: "cn-hangzhou", Status: "Running"},
+		// 未关联云账号的历史主机
+		{ID: 3, UUID: "i-legacy", Status: "Running"},
+		{ID: 4, UUID: "i-restored", PlatformID: 1, CloudRegion: "cn-hangzhou", Status: "Running", DeletedAt: deleted},
+		// 云上已不存在
+		{ID: 5, UUID: "i-gone", PlatformID: 1, CloudRegion: "cn-hangzhou"},
+		// 所属地域同步失败, 不能删除
+		{ID: 6, UUID: "i-failed-region", PlatformID: 1, CloudRegion: "cn-beijing"},
+		// 已删除的主机不重复删除
+		{ID: 7, UUID: "i-deleted", PlatformID: 1, CloudRegion: "cn-hangzhou", DeletedAt: deleted},
+	}
+	synced := map[string]bool{"cn-hangzhou": true}
+
+	diff := diffHosts(1, remote, local, synced)
+
+	if len(diff.add) != 1 || diff.add[0].UUID != "i-new" {
+		t.Errorf("unexpected add %+v", diff.add)
+	}
+	var updated []int
+	for _, host := range diff.update {
+		updated = append(updated, host.ID)
+	}
+	if !reflect.DeepEqual(updated, []int{2, 3, 4}) {
+		t.Errorf("unexpected update %v", updated)
+	}
+	if !reflect.DeepEqual(diff.remove, []int{5}) {
+		t.Errorf("unexpected remove %v", diff.remove)
+	}
+	if diff.unchanged != 1 {
+		t.Errorf("expected 1 unchanged host, got %d", diff.unchanged)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudsync
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/cmdb"
+	"github.com/dnsjia/luban/services"
+	"go.uber.org/zap"
+)
+
+// NewJob 创建一条待执行的同步记录
+func NewJob(account *cmdb.CloudPlatform, trigger, operator string) (*cmdb.CloudSyncJob, error) {
+	job := &cmdb.CloudSyncJob{
+		PlatformID:   account.ID,
+		PlatformName: account.Name,
+		Vendor:       account.Type,
+		Trigger:      trigger,
+		Operator:     operator,
+		Status:       cmdb.CloudSyncPending,
+	}
+	if err := services.CreateCloudSyncJob(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// RunJob 同步云账号并将结果与日志记录到同步记录
+func RunJob(account *cmdb.CloudPlatform, job *cmdb.CloudSyncJob) error {
+	started := time.Now()
+	job.Status = cmdb.CloudSyncInProgress
+	job.StartedAt = &started
+	if err := services.SaveCloudSyncJob(job); err != nil {
+		common.LOG.Error("更新同步记录失败", zap.Any("err", err))
+	}
+
+	summary := Sync(account)
+
+	finished := time.Now()
+	job.Status = summary.Status
+	job.Regions = summary.Regions
+	job.Total = summary.Total
+	job.Added = summary.Added
+	job.Updated = summary.Updated
+	job.Removed = summary.Removed
+	job.Error = summary.Error
+	if job.Error == "" && len(summary.RegionErrors) > 0 {
+		job.Error = fmt.Sprintf("%d个地域同步失败", len(summary.RegionErrors))
+	}
+	if runes := []rune(job.Error); len(runes) > 1000 {
+		job.Error = string(runes[:1000])
+	}
+	job.Log = strings.Join(summary.Logs, "\n")
+	job.FinishedAt = &finished
+	job.Duration = summary.Duration
+	if err := services.SaveCloudSyncJob(job); err != nil {
+		common.LOG.Error("更新同步记录失败", zap.Any("err", err))
+	}
+
+	if job.Status != cmdb.CloudSyncSuccess {
+		return fmt.Errorf("云账号%s同步失败: %s", account.Name, job.Error)
+	}
+	return nil
+}
@@ -68,6 +68,9 @@ func (a *aliClient) GetRegions() ([]*cmdb.Region, error) {
 		regionSet = append(regionSet, &cmdb.Region{
 			RegionId:   region.RegionId,
 			RegionName: region.LocalName,
+			Type:       cmdb.AliYun,
+			// 售罄(soldOut)的地域仍可能有存量实例
+			Enable: true,
 		})
 	}
 	return regionSet, nil
@@ -110,6 +113,7 @@ func (a *aliClient) GetInstances(region string) ([]cmdb.VirtualMachine, error) {
 	if response.TotalCount > 0 {
 		for i := 0; i < response.TotalCount/100+1; i++ {
 			request.PageSize = MaxPageSize
+			request.PageNumber = requests.NewInteger(i + 1)
 			r, err := client.DescribeInstances(request)
 			if err != nil {
 				fmt.Printf("查询ECS实例列表失败，%v", err)
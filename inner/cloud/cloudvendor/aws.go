@@ -0,0 +1,270 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudvendor
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnsjia/luban/models/cmdb"
+)
+
+func init() {
+	Register(cmdb.AWS, &awsClient{vendorName: cmdb.AWS})
+}
+
+const (
+	awsEndpoint      = "https://ec2.%s.amazonaws.com"
+	awsDefaultRegion = "us-east-1"
+	awsService       = "ec2"
+	awsAPIVersion    = "2016-11-15"
+	awsPageSize      = 100
+)
+
+type awsClient struct {
+	vendorName string
+	secretID   string
+	secretKey  string
+	// endpoint 为空时按地域使用 awsEndpoint
+	endpoint string
+}
+
+// NewVendorClient 创建云厂商客户端
+func (a *awsClient) NewVendorClient(secretID, secretKey string) VendorClient {
+	return &awsClient{
+		vendorName: cmdb.AWS,
+		secretID:   secretID,
+		secretKey:  secretKey,
+		endpoint:   a.endpoint,
+	}
+}
+
+type awsErrorResponse struct {
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Errors>Error"`
+}
+
+// call 使用 Signature Version 4 签名调用 EC2 Query API
+// API文档：https://docs.aws.amazon.com/general/latest/gr/sigv4-signed-request-examples.html
+func (a *awsClient) call(action, region string, params url.Values, result interface{}) error {
+	u, err := endpointURL(a.endpoint, fmt.Sprintf(awsEndpoint, region))
+	if err != nil {
+		return err
+	}
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("Action", action)
+	params.Set("Version", awsAPIVersion)
+	query := canonicalQuery(params)
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	canonicalRequest := fmt.Sprintf("GET\n/\n%s\nhost:%s\nx-amz-date:%s\n\nhost;x-amz-date\n%s",
+		query, u.Host, amzDate, sha256Hex(nil))
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, region, awsService)
+	stringToSign := fmt.Sprintf("AWS4-HMAC-SHA256\n%s\n%s\n%s", amzDate, scope, sha256Hex([]byte(canonicalRequest)))
+	kDate := hmacSHA256([]byte("AWS4"+a.secretKey), date)
+	kRegion := hmacSHA256(kDate, region)
+	kService := hmacSHA256(kRegion, awsService)
+	kSigning := hmacSHA256(kService, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(kSigning, stringToSign))
+
+	u.Path = "/"
+	u.RawQuery = query
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=host;x-amz-date, Signature=%s",
+		a.secretID, scope, signature))
+
+	body, status, err := doRequest(req)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		var resp awsErrorResponse
+		if err := xml.Unmarshal(body, &resp); err == nil && len(resp.Errors) > 0 {
+			return fmt.Errorf("%s 失败, %s: %s", action, resp.Errors[0].Code, resp.Errors[0].Message)
+		}
+		return fmt.Errorf("%s 返回状态码%d: %s", action, status, string(body))
+	}
+	return xml.Unmarshal(body, result)
+}
+
+// GetRegions 获取地域列表
+// API文档：https://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeRegions.html
+func (a *awsClient) GetRegions() ([]*cmdb.Region, error) {
+	var resp struct {
+		Regions []struct {
+			RegionName  string `xml:"regionName"`
+			OptInStatus string `xml:"optInStatus"`
+		} `xml:"regionInfo>item"`
+	}
+	if err := a.call("DescribeRegions", awsDefaultRegion, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	regionSet := make([]*cmdb.Region, 0)
+	for _, region := range resp.Regions {
+		regionSet = append(regionSet, &cmdb.Region{
+			RegionId:   region.RegionName,
+			RegionName: region.RegionName,
+			Type:       cmdb.AWS,
+			Enable:     region.OptInStatus != "not-opted-in",
+		})
+	}
+	return regionSet, nil
+}
+
+type awsInstance struct {
+	InstanceId   string `xml:"instanceId"`
+	InstanceType string `xml:"instanceType"`
+	Placement    struct {
+		AvailabilityZone string `xml:"availabilityZone"`
+	} `xml:"placement"`
+	PrivateIpAddress string `xml:"privateIpAddress"`
+	IpAddress        string `xml:"ipAddress"`
+	State            struct {
+		Name string `xml:"name"`
+	} `xml:"instanceState"`
+	LaunchTime      string `xml:"launchTime"`
+	Platform        string `xml:"platform"`
+	PlatformDetails string `xml:"platformDetails"`
+	Tags            []struct {
+		Key   string `xml:"key"`
+		Value string `xml:"value"`
+	} `xml:"tagSet>item"`
+}
+
+// name 实例名称取 Name 标签, 未设置时使用实例ID
+func (i awsInstance) name() string {
+	for _, tag := range i.Tags {
+		if tag.Key == "Name" {
+			return tag.Value
+		}
+	}
+	return i.InstanceId
+}
+
+type awsInstanceType struct {
+	CPU    int
+	Memory int
+}
+
+// GetInstances 获取实例列表, CPU与内存通过 DescribeInstanceTypes 按实例规格查询
+// API文档：https://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstances.html
+func (a *awsClient) GetInstances(region string) ([]cmdb.VirtualMachine, error) {
+	var instances []awsInstance
+	token := ""
+	for page := 0; page < maxPages; page++ {
+		var resp struct {
+			Instances []awsInstance `xml:"reservationSet>item>instancesSet>item"`
+			NextToken string        `xml:"nextToken"`
+		}
+		params := url.Values{}
+		params.Set("MaxResults", strconv.Itoa(awsPageSize))
+		if token != "" {
+			params.Set("NextToken", token)
+		}
+		if err := a.call("DescribeInstances", region, params, &resp); err != nil {
+			return nil, err
+		}
+		instances = append(instances, resp.Instances...)
+		if token = resp.NextToken; token == "" {
+			break
+		}
+	}
+
+	types, err := a.getInstanceTypes(region, instances)
+	if err != nil {
+		return nil, err
+	}
+
+	instancesInfo := make([]cmdb.VirtualMachine, 0, len(instances))
+	for _, i := range instances {
+		osName := i.PlatformDetails
+		if osName == "" {
+			osName = i.Platform
+		}
+		instancesInfo = append(instancesInfo, cmdb.VirtualMachine{
+			Groups:        defaultGroup(),
+			UUID:          i.InstanceId,
+			HostName:      i.name(),
+			CPU:           types[i.InstanceType].CPU,
+			Mem:           types[i.InstanceType].Memory,
+			OS:            osName,
+			OSType:        osType(i.Platform),
+			PrivateAddr:   i.PrivateIpAddress,
+			PublicAddr:    i.IpAddress,
+			Status:        i.State.Name,
+			Region:        i.Placement.AvailabilityZone,
+			VmCreatedTime: i.LaunchTime,
+			Source:        cmdb.AWS,
+		})
+	}
+	return instancesInfo, nil
+}
+
+// getInstanceTypes 查询实例规格的 vCPU 与内存(MiB)
+// API文档：https://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstanceTypes.html
+func (a *awsClient) getInstanceTypes(region string, instances []awsInstance) (map[string]awsInstanceType, error) {
+	types := make(map[string]awsInstanceType)
+	var names []string
+	for _, i := range instances {
+		if _, ok := types[i.InstanceType]; !ok && i.InstanceType != "" {
+			types[i.InstanceType] = awsInstanceType{}
+			names = append(names, i.InstanceType)
+		}
+	}
+
+	for start := 0; start < len(names); start += awsPageSize {
+		end := start + awsPageSize
+		if end > len(names) {
+			end = len(names)
+		}
+		params := url.Values{}
+		for n, name := range names[start:end] {
+			params.Set("InstanceType."+strconv.Itoa(n+1), name)
+		}
+		var resp struct {
+			InstanceTypes []struct {
+				InstanceType string `xml:"instanceType"`
+				VCpus        int    `xml:"vCpuInfo>defaultVCpus"`
+				MemoryMiB    int    `xml:"memoryInfo>sizeInMiB"`
+			} `xml:"instanceTypeSet>item"`
+		}
+		if err := a.call("DescribeInstanceTypes", region, params, &resp); err != nil {
+			return nil, fmt.Errorf("查询实例规格%s失败: %v", strings.Join(names[start:end], ","), err)
+		}
+		for _, t := range resp.InstanceTypes {
+			types[t.InstanceType] = awsInstanceType{CPU: t.VCpus, Memory: t.MemoryMiB}
+		}
+	}
+	return types, nil
+}
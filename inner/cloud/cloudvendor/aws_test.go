@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudvendor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dnsjia/luban/models/cmdb"
+)
+
+func newAWSTestClient(t *testing.T) (VendorClient, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checkAuthorization(t, r, "AWS4-HMAC-SHA256", "Credential=AKIAtest/")
+		query := r.URL.Query()
+		if query.Get("Version") != awsAPIVersion {
+			t.Errorf("unexpected version %q", query.Get("Version"))
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), "/us-east-1/ec2/aws4_request") {
+			serveFixture(t, w, http.StatusUnauthorized, "aws_error.xml")
+			return
+		}
+		switch query.Get("Action") {
+		case "DescribeRegions":
+			serveFixture(t, w, http.StatusOK, "aws_regions.xml")
+		case "DescribeInstances":
+			if query.Get("NextToken") == "page2" {
+				serveFixture(t, w, http.StatusOK, "aws_instances_page2.xml")
+			} else {
+				serveFixture(t, w, http.StatusOK, "aws_instances_page1.xml")
+			}
+		case "DescribeInstanceTypes":
+			if query.Get("InstanceType.1") == "" || query.Get("InstanceType.2") == "" {
+				t.Errorf("expected two instance types, got %v", query)
+			}
+			serveFixture(t, w, http.StatusOK, "aws_instance_types.xml")
+		default:
+			t.Errorf("unexpected action %q", query.Get("Action"))
+		}
+	}))
+	client := (&awsClient{endpoint: server.URL}).NewVendorClient("AKIAtest", "secret")
+	return client, server.Close
+}
+
+func TestAWSGetRegions(t *testing.T) {
+	client, stop := newAWSTestClient(t)
+	defer stop()
+
+	regionSet, err := client.GetRegions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regionSet) != 2 || regionSet[0].RegionId != "us-east-1" || !regionSet[0].Enable || regionSet[1].Enable {
+		t.Errorf("unexpected regions %+v", regionSet)
+	}
+}
+
+func TestAWSGetInstances(t *testing.T) {
+	client, stop := newAWSTestClient(t)
+	defer stop()
+
+	instances, err := client.GetInstances("us-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("expected 2 instances across pages, got %d", len(instances))
+	}
+	vm := instances[0]
+	if vm.UUID != "i-1234567890abcdef0" || vm.HostName != "api-01" || vm.CPU != 2 || vm.Mem != 4096 ||
+		vm.PrivateAddr != "172.31.1.10" || vm.PublicAddr != "54.194.252.215" || vm.Status != "running" ||
+		vm.Region != "us-east-1a" || vm.OSType != "linux" || vm.Source != cmdb.AWS {
+		t.Errorf("unexpected instance %+v", vm)
+	}
+	if vm := instances[1]; vm.HostName != "i-0598c7d356eba48d7" || vm.Mem != 8192 || vm.OSType != "windows" {
+		t.Errorf("unexpected instance %+v", vm)
+	}
+
+	if _, err := client.GetInstances("eu-west-1"); err == nil || !strings.Contains(err.Error(), "AuthFailure") {
+		t.Errorf("expected api error, got %v", err)
+	}
+}
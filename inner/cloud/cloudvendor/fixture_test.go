@@ -0,0 +1,45 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudvendor
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serveFixture 返回 testdata 中录制的云厂商接口响应
+func serveFixture(t *testing.T, w http.ResponseWriter, status int, name string) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Errorf("read fixture %s: %v", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// checkAuthorization 校验请求带有指定算法的签名
+func checkAuthorization(t *testing.T, r *http.Request, algorithm, credential string) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, algorithm+" ") || !strings.Contains(auth, credential) || !strings.Contains(auth, "Signature=") {
+		t.Errorf("unexpected Authorization header %q", auth)
+	}
+}
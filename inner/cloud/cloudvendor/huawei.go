@@ -0,0 +1,277 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudvendor
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnsjia/luban/models/cmdb"
+)
+
+func init() {
+	Register(cmdb.HuaWei, &huaweiClient{vendorName: cmdb.HuaWei})
+}
+
+const (
+	huaweiIAMEndpoint = "https://iam.myhuaweicloud.com"
+	huaweiECSEndpoint = "https://ecs.%s.myhuaweicloud.com"
+	huaweiPageSize    = 100
+)
+
+type huaweiClient struct {
+	vendorName string
+	secretID   string
+	secretKey  string
+	// endpoint 为空时 IAM 与 ECS 使用各自的官方地址
+	endpoint string
+}
+
+// NewVendorClient 创建云厂商客户端
+func (h *huaweiClient) NewVendorClient(secretID, secretKey string) VendorClient {
+	return &huaweiClient{
+		vendorName: cmdb.HuaWei,
+		secretID:   secretID,
+		secretKey:  secretKey,
+		endpoint:   h.endpoint,
+	}
+}
+
+type huaweiError struct {
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	ErrorCode string `json:"error_code"`
+	ErrorMsg  string `json:"error_msg"`
+}
+
+func (e huaweiError) String() string {
+	if e.Error != nil {
+		return fmt.Sprintf("%s: %s", e.Error.Code, e.Error.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.ErrorCode, e.ErrorMsg)
+}
+
+// get 使用 AK/SK 签名(SDK-HMAC-SHA256)发送 GET 请求
+// API文档：https://support.huaweicloud.com/devg-apisign/api-sign-algorithm.html
+func (h *huaweiClient) get(baseURL, path string, query url.Values, result interface{}) error {
+	u, err := endpointURL(h.endpoint, baseURL)
+	if err != nil {
+		return err
+	}
+	u.Path = path
+	u.RawQuery = canonicalQuery(query)
+
+	sdkDate := time.Now().UTC().Format("20060102T150405Z")
+	canonicalURI := path
+	if !strings.HasSuffix(canonicalURI, "/") {
+		canonicalURI += "/"
+	}
+	canonicalRequest := fmt.Sprintf("GET\n%s\n%s\nhost:%s\nx-sdk-date:%s\n\nhost;x-sdk-date\n%s",
+		canonicalURI, u.RawQuery, u.Host, sdkDate, sha256Hex(nil))
+	stringToSign := fmt.Sprintf("SDK-HMAC-SHA256\n%s\n%s", sdkDate, sha256Hex([]byte(canonicalRequest)))
+	signature := hex.EncodeToString(hmacSHA256([]byte(h.secretKey), stringToSign))
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sdk-Date", sdkDate)
+	req.Header.Set("Authorization", fmt.Sprintf("SDK-HMAC-SHA256 Access=%s, SignedHeaders=host;x-sdk-date, Signature=%s",
+		h.secretID, signature))
+
+	body, status, err := doRequest(req)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		var resp huaweiError
+		if err := json.Unmarshal(body, &resp); err == nil && (resp.Error != nil || resp.ErrorCode != "") {
+			return fmt.Errorf("请求%s失败, %s", path, resp)
+		}
+		return fmt.Errorf("请求%s返回状态码%d: %s", path, status, string(body))
+	}
+	return json.Unmarshal(body, result)
+}
+
+type huaweiProject struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+func (h *huaweiClient) listProjects(name string) ([]huaweiProject, error) {
+	query := url.Values{}
+	if name != "" {
+		query.Set("name", name)
+	}
+	var resp struct {
+		Projects []huaweiProject `json:"projects"`
+	}
+	if err := h.get(huaweiIAMEndpoint, "/v3/projects", query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Projects, nil
+}
+
+// GetRegions 获取地域列表, 华为云资源按项目隔离, 返回账号下的项目名称(即地域, 子项目为 地域_子项目名)
+// API文档：https://support.huaweicloud.com/api-iam/iam_06_0001.html
+func (h *huaweiClient) GetRegions() ([]*cmdb.Region, error) {
+	projects, err := h.listProjects("")
+	if err != nil {
+		return nil, err
+	}
+
+	regionSet := make([]*cmdb.Region, 0)
+	for _, project := range projects {
+		// 跳过 MOS 等非地域的系统项目
+		if !strings.Contains(project.Name, "-") {
+			continue
+		}
+		regionName := project.Description
+		if regionName == "" {
+			regionName = project.Name
+		}
+		regionSet = append(regionSet, &cmdb.Region{
+			RegionId:   project.Name,
+			RegionName: regionName,
+			Type:       cmdb.HuaWei,
+			Enable:     project.Enabled,
+		})
+	}
+	return regionSet, nil
+}
+
+type huaweiServer struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	Status           string `json:"status"`
+	Created          string `json:"created"`
+	AvailabilityZone string `json:"OS-EXT-AZ:availability_zone"`
+	Flavor           struct {
+		Vcpus string `json:"vcpus"`
+		// 内存, 单位MB
+		Ram string `json:"ram"`
+	} `json:"flavor"`
+	Metadata  map[string]string `json:"metadata"`
+	Addresses map[string][]struct {
+		Addr    string `json:"addr"`
+		Type    string `json:"OS-EXT-IPS:type"`
+		Version string `json:"version"`
+	} `json:"addresses"`
+	AutoTerminateTime string `json:"auto_terminate_time"`
+}
+
+// ips 返回第一个私网地址与弹性公网地址, 多网卡时按网络ID排序保证每次同步结果一致
+func (s huaweiServer) ips() (private, public string) {
+	networks := make([]string, 0, len(s.Addresses))
+	for network := range s.Addresses {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+	for _, network := range networks {
+		for _, address := range s.Addresses[network] {
+			if address.Version != "4" && address.Version != "" {
+				continue
+			}
+			switch address.Type {
+			case "fixed":
+				if private == "" {
+					private = address.Addr
+				}
+			case "floating":
+				if public == "" {
+					public = address.Addr
+				}
+			}
+		}
+	}
+	return private, public
+}
+
+// GetInstances 获取实例列表
+// API文档：https://support.huaweicloud.com/api-ecs/ecs_02_0103.html
+func (h *huaweiClient) GetInstances(region string) ([]cmdb.VirtualMachine, error) {
+	projects, err := h.listProjects(region)
+	if err != nil {
+		return nil, err
+	}
+	projectID := ""
+	for _, project := range projects {
+		if project.Name == region {
+			projectID = project.ID
+		}
+	}
+	if projectID == "" {
+		return nil, fmt.Errorf("未找到地域%s对应的项目", region)
+	}
+	// 子项目使用所属地域的 ECS 地址
+	ecsRegion := strings.SplitN(region, "_", 2)[0]
+
+	var servers []huaweiServer
+	for page := 1; page <= maxPages; page++ {
+		var resp struct {
+			Count   int            `json:"count"`
+			Servers []huaweiServer `json:"servers"`
+		}
+		query := url.Values{}
+		query.Set("limit", strconv.Itoa(huaweiPageSize))
+		query.Set("offset", strconv.Itoa(page))
+		path := fmt.Sprintf("/v1/%s/cloudservers/detail", projectID)
+		if err := h.get(fmt.Sprintf(huaweiECSEndpoint, ecsRegion), path, query, &resp); err != nil {
+			return nil, err
+		}
+		servers = append(servers, resp.Servers...)
+		if len(resp.Servers) < huaweiPageSize || len(servers) >= resp.Count {
+			break
+		}
+	}
+
+	instancesInfo := make([]cmdb.VirtualMachine, 0, len(servers))
+	for _, s := range servers {
+		cpu, _ := strconv.Atoi(s.Flavor.Vcpus)
+		mem, _ := strconv.Atoi(s.Flavor.Ram)
+		privateAddr, publicAddr := s.ips()
+		instancesInfo = append(instancesInfo, cmdb.VirtualMachine{
+			Groups:        defaultGroup(),
+			UUID:          s.ID,
+			HostName:      s.Name,
+			CPU:           cpu,
+			Mem:           mem,
+			OS:            s.Metadata["image_name"],
+			OSType:        osType(s.Metadata["os_type"]),
+			PrivateAddr:   privateAddr,
+			PublicAddr:    publicAddr,
+			Status:        s.Status,
+			Region:        s.AvailabilityZone,
+			VmCreatedTime: s.Created,
+			VmExpiredTime: s.AutoTerminateTime,
+			Source:        cmdb.HuaWei,
+		})
+	}
+	return instancesInfo, nil
+}
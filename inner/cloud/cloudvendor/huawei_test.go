@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudvendor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dnsjia/luban/models/cmdb"
+)
+
+func newHuaweiTestClient(t *testing.T) (VendorClient, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checkAuthorization(t, r, "SDK-HMAC-SHA256", "Access=HWtest,")
+		if r.Header.Get("X-Sdk-Date") == "" {
+			t.Error("missing X-Sdk-Date header")
+		}
+		switch {
+		case r.URL.Path == "/v3/projects":
+			if name := r.URL.Query().Get("name"); name == "cn-east-3" {
+				w.Write([]byte(`{"projects": []}`))
+				return
+			}
+			serveFixture(t, w, http.StatusOK, "huawei_projects.json")
+		case r.URL.Path == "/v1/0b95c8e6b4ce4ff6b2b7c1e2d3f4a5b6/cloudservers/detail":
+			if r.URL.Query().Get("offset") != "1" {
+				t.Errorf("unexpected offset %q", r.URL.Query().Get("offset"))
+			}
+			serveFixture(t, w, http.StatusOK, "huawei_servers.json")
+		default:
+			serveFixture(t, w, http.StatusUnauthorized, "huawei_error.json")
+		}
+	}))
+	client := (&huaweiClient{endpoint: server.URL}).NewVendorClient("HWtest", "secret")
+	return client, server.Close
+}
+
+func TestHuaweiGetRegions(t *testing.T) {
+	client, stop := newHuaweiTestClient(t)
+	defer stop()
+
+	regionSet, err := client.GetRegions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regionSet) != 2 || regionSet[0].RegionId != "cn-north-4" || regionSet[0].RegionName != "华北-北京四" ||
+		regionSet[1].RegionId != "cn-north-4_dev" {
+		t.Errorf("unexpected regions %+v", regionSet)
+	}
+}
+
+func TestHuaweiGetInstances(t *testing.T) {
+	client, stop := newHuaweiTestClient(t)
+	defer stop()
+
+	instances, err := client.GetInstances("cn-north-4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 1 {
+		t.Fatalf("expected 1 instance, got %d", len(instances))
+	}
+	vm := instances[0]
+	if vm.UUID != "7f2d8c1e-3b4a-4c5d-9e6f-0a1b2c3d4e5f" || vm.HostName != "db-01" || vm.CPU != 2 || vm.Mem != 4096 ||
+		vm.PrivateAddr != "192.168.0.10" || vm.PublicAddr != "121.36.10.10" || vm.Status != "ACTIVE" ||
+		vm.Region != "cn-north-4a" || vm.OSType != "linux" || vm.Source != cmdb.HuaWei {
+		t.Errorf("unexpected instance %+v", vm)
+	}
+
+	if _, err := client.GetInstances("cn-east-3"); err == nil {
+		t.Error("expected error for region without project")
+	}
+	if _, err := client.GetInstances("cn-north-4_dev"); err == nil || !strings.Contains(err.Error(), "APIGW.0301") {
+		t.Errorf("expected api error, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudvendor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dnsjia/luban/models/cmdb"
+)
+
+// 腾讯云、华为云、AWS 客户端直接调用 OpenAPI, 请求签名在各自文件中实现
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// maxPages 分页查询的最大页数, 防止接口返回异常时死循环
+const maxPages = 1000
+
+func doRequest(req *http.Request) ([]byte, int, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	return body, resp.StatusCode, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery 按参数名排序并使用 RFC 3986 编码, AWS 与华为云签名要求一致
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, escape(k)+"="+escape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func escape(s string) string {
+	return strings.Replace(strings.Replace(url.QueryEscape(s), "+", "%20", -1), "%7E", "~", -1)
+}
+
+// endpointURL 指定了 endpoint 时所有请求发送到该地址, 用于私有化部署或测试
+func endpointURL(endpoint, defaultURL string) (*url.URL, error) {
+	if endpoint != "" {
+		defaultURL = endpoint
+	}
+	u, err := url.Parse(defaultURL)
+	if err != nil {
+		return nil, fmt.Errorf("endpoint不合法: %v", err)
+	}
+	return u, nil
+}
+
+// defaultGroup 同步的云资产放到默认的Default分组
+func defaultGroup() []*cmdb.TreeMenu {
+	return []*cmdb.TreeMenu{{ID: 1}}
+}
+
+func osType(osName string) string {
+	if strings.Contains(strings.ToLower(osName), "windows") {
+		return "windows"
+	}
+	return "linux"
+}
@@ -15,3 +15,202 @@ limitations under the License.
 */
 
 package cloudvendor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dnsjia/luban/models/cmdb"
+)
+
+func init() {
+	Register(cmdb.Tencent, &tencentClient{vendorName: cmdb.Tencent})
+}
+
+const (
+	tencentEndpoint   = "https://cvm.tencentcloudapi.com"
+	tencentService    = "cvm"
+	tencentAPIVersion = "2017-03-12"
+	tencentPageSize   = 100
+)
+
+type tencentClient struct {
+	vendorName string
+	secretID   string
+	secretKey  string
+	// endpoint 为空时使用 tencentEndpoint
+	endpoint string
+}
+
+// NewVendorClient 创建云厂商客户端
+func (t *tencentClient) NewVendorClient(secretID, secretKey string) VendorClient {
+	return &tencentClient{
+		vendorName: cmdb.Tencent,
+		secretID:   secretID,
+		secretKey:  secretKey,
+		endpoint:   t.endpoint,
+	}
+}
+
+type tencentError struct {
+	Code    string `json:"Code"`
+	Message string `json:"Message"`
+}
+
+// call 使用 TC3-HMAC-SHA256 签名调用云服务器 API
+// API文档：https://cloud.tencent.com/document/api/213/30654
+func (t *tencentClient) call(action, region string, params interface{}, result interface{}) error {
+	u, err := endpointURL(t.endpoint, tencentEndpoint)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	date := now.Format("2006-01-02")
+	contentType := "application/json; charset=utf-8"
+	canonicalRequest := fmt.Sprintf("POST\n/\n\ncontent-type:%s\nhost:%s\n\ncontent-type;host\n%s",
+		contentType, u.Host, sha256Hex(payload))
+	scope := fmt.Sprintf("%s/%s/tc3_request", date, tencentService)
+	stringToSign := fmt.Sprintf("TC3-HMAC-SHA256\n%s\n%s\n%s", timestamp, scope, sha256Hex([]byte(canonicalRequest)))
+	secretDate := hmacSHA256([]byte("TC3"+t.secretKey), date)
+	secretService := hmacSHA256(secretDate, tencentService)
+	secretSigning := hmacSHA256(secretService, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(secretSigning, stringToSign))
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Host", u.Host)
+	req.Header.Set("X-TC-Action", action)
+	req.Header.Set("X-TC-Timestamp", timestamp)
+	req.Header.Set("X-TC-Version", tencentAPIVersion)
+	if region != "" {
+		req.Header.Set("X-TC-Region", region)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("TC3-HMAC-SHA256 Credential=%s/%s, SignedHeaders=content-type;host, Signature=%s",
+		t.secretID, scope, signature))
+
+	body, status, err := doRequest(req)
+	if err != nil {
+		return err
+	}
+	// 腾讯云接口错误也返回200, 错误信息在 Response.Error 中
+	var resp struct {
+		Response struct {
+			Error *tencentError `json:"Error"`
+		} `json:"Response"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("%s 返回状态码%d, 解析失败: %v", action, status, err)
+	}
+	if resp.Response.Error != nil {
+		return fmt.Errorf("%s 失败, %s: %s", action, resp.Response.Error.Code, resp.Response.Error.Message)
+	}
+	return json.Unmarshal(body, result)
+}
+
+// GetRegions 获取地域列表
+// API文档：https://cloud.tencent.com/document/api/213/15708
+func (t *tencentClient) GetRegions() ([]*cmdb.Region, error) {
+	var resp struct {
+		Response struct {
+			RegionSet []struct {
+				Region      string `json:"Region"`
+				RegionName  string `json:"RegionName"`
+				RegionState string `json:"RegionState"`
+			} `json:"RegionSet"`
+		} `json:"Response"`
+	}
+	if err := t.call("DescribeRegions", "", struct{}{}, &resp); err != nil {
+		return nil, err
+	}
+
+	regionSet := make([]*cmdb.Region, 0)
+	for _, region := range resp.Response.RegionSet {
+		regionSet = append(regionSet, &cmdb.Region{
+			RegionId:   region.Region,
+			RegionName: region.RegionName,
+			Type:       cmdb.Tencent,
+			Enable:     region.RegionState == "AVAILABLE",
+		})
+	}
+	return regionSet, nil
+}
+
+type tencentInstance struct {
+	InstanceId   string `json:"InstanceId"`
+	InstanceName string `json:"InstanceName"`
+	CPU          int    `json:"CPU"`
+	// 内存, 单位GB
+	Memory    int    `json:"Memory"`
+	OsName    string `json:"OsName"`
+	Placement struct {
+		Zone string `json:"Zone"`
+	} `json:"Placement"`
+	PrivateIpAddresses []string `json:"PrivateIpAddresses"`
+	PublicIpAddresses  []string `json:"PublicIpAddresses"`
+	InternetAccessible struct {
+		InternetMaxBandwidthOut int `json:"InternetMaxBandwidthOut"`
+	} `json:"InternetAccessible"`
+	InstanceState string `json:"InstanceState"`
+	CreatedTime   string `json:"CreatedTime"`
+	ExpiredTime   string `json:"ExpiredTime"`
+	Uuid          string `json:"Uuid"`
+}
+
+// GetInstances 获取实例列表
+// API文档：https://cloud.tencent.com/document/api/213/15728
+func (t *tencentClient) GetInstances(region string) ([]cmdb.VirtualMachine, error) {
+	var instances []tencentInstance
+	for page := 0; page < maxPages; page++ {
+		var resp struct {
+			Response struct {
+				TotalCount  int               `json:"TotalCount"`
+				InstanceSet []tencentInstance `json:"InstanceSet"`
+			} `json:"Response"`
+		}
+		params := map[string]int{"Offset": page * tencentPageSize, "Limit": tencentPageSize}
+		if err := t.call("DescribeInstances", region, params, &resp); err != nil {
+			return nil, err
+		}
+		instances = append(instances, resp.Response.InstanceSet...)
+		if len(resp.Response.InstanceSet) < tencentPageSize || len(instances) >= resp.Response.TotalCount {
+			break
+		}
+	}
+
+	instancesInfo := make([]cmdb.VirtualMachine, 0, len(instances))
+	for _, i := range instances {
+		instancesInfo = append(instancesInfo, cmdb.VirtualMachine{
+			Groups:        defaultGroup(),
+			UUID:          i.InstanceId,
+			HostName:      i.InstanceName,
+			CPU:           i.CPU,
+			Mem:           i.Memory * 1024,
+			OS:            i.OsName,
+			OSType:        osType(i.OsName),
+			PrivateAddr:   getInstanceIP(i.PrivateIpAddresses),
+			PublicAddr:    getInstanceIP(i.PublicIpAddresses),
+			SN:            i.Uuid,
+			BandWidth:     i.InternetAccessible.InternetMaxBandwidthOut,
+			Status:        i.InstanceState,
+			Region:        i.Placement.Zone,
+			VmCreatedTime: i.CreatedTime,
+			VmExpiredTime: i.ExpiredTime,
+			Source:        cmdb.Tencent,
+		})
+	}
+	return instancesInfo, nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudvendor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dnsjia/luban/models/cmdb"
+)
+
+func newTencentTestClient(t *testing.T) (VendorClient, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checkAuthorization(t, r, "TC3-HMAC-SHA256", "Credential=AKIDtest/")
+		if r.Header.Get("X-TC-Version") != tencentAPIVersion {
+			t.Errorf("unexpected version %q", r.Header.Get("X-TC-Version"))
+		}
+		switch r.Header.Get("X-TC-Action") {
+		case "DescribeRegions":
+			serveFixture(t, w, http.StatusOK, "tencent_regions.json")
+		case "DescribeInstances":
+			if r.Header.Get("X-TC-Region") == "ap-guangzhou" {
+				serveFixture(t, w, http.StatusOK, "tencent_instances.json")
+			} else {
+				serveFixture(t, w, http.StatusOK, "tencent_error.json")
+			}
+		default:
+			t.Errorf("unexpected action %q", r.Header.Get("X-TC-Action"))
+		}
+	}))
+	client := (&tencentClient{endpoint: server.URL}).NewVendorClient("AKIDtest", "secret")
+	return client, server.Close
+}
+
+func TestTencentGetRegions(t *testing.T) {
+	client, stop := newTencentTestClient(t)
+	defer stop()
+
+	regionSet, err := client.GetRegions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regionSet) != 2 || regionSet[0].RegionId != "ap-guangzhou" || !regionSet[0].Enable {
+		t.Errorf("unexpected regions %+v", regionSet)
+	}
+}
+
+func TestTencentGetInstances(t *testing.T) {
+	client, stop := newTencentTestClient(t)
+	defer stop()
+
+	instances, err := client.GetInstances("ap-guangzhou")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 1 {
+		t.Fatalf("expected 1 instance, got %d", len(instances))
+	}
+	vm := instances[0]
+	if vm.UUID != "ins-r8hr2upy" || vm.HostName != "web-01" || vm.CPU != 2 || vm.Mem != 4096 ||
+		vm.PrivateAddr != "10.0.0.12" || vm.PublicAddr != "118.25.10.20" || vm.BandWidth != 5 ||
+		vm.Region != "ap-guangzhou-3" || vm.OSType != "linux" || vm.Source != cmdb.Tencent {
+		t.Errorf("unexpected instance %+v", vm)
+	}
+
+	if _, err := client.GetInstances("ap-beijing"); err == nil || !strings.Contains(err.Error(), "AuthFailure.SecretIdNotFound") {
+		t.Errorf("expected api error, got %v", err)
+	}
+}
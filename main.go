@@ -23,9 +23,12 @@ import (
 	phttp "github.com/dnsjia/luban/http"
 	"github.com/dnsjia/luban/middleware"
 	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/pkg/k8s/eventarchive"
 	"github.com/dnsjia/luban/routers"
 	"github.com/dnsjia/luban/routers/cmdb"
+	"github.com/dnsjia/luban/tasks"
 	"github.com/dnsjia/luban/tools"
+	"go.uber.org/zap"
 	"io"
 	"os"
 	"os/signal"
@@ -98,9 +101,14 @@ func InitServer() {
 		routers.InitWebSocketRouter(PrivateGroup)
 
 	}
-	// 任务调度
-	//go tasks.TaskBeta()
-	//go tasks.TaskWorker()
+	// 集群事件归档
+	eventarchive.Start()
+	// 任务调度, 使用 gva worker 独立运行时关闭 system.task-worker
+	if common.CONFIG.System.TaskWorker {
+		if err := tasks.Start(); err != nil {
+			common.LOG.Error("启动任务调度失败", zap.Any("err", err))
+		}
+	}
 	address := fmt.Sprintf(":%d", common.CONFIG.System.Addr)
 	err := r.Run(address)
 
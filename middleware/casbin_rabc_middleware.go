@@ -22,30 +22,51 @@ import (
 	"github.com/dnsjia/luban/controller/response"
 	"github.com/dnsjia/luban/services"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
+// CasBinHandler 基于用户角色的接口权限校验, develop 环境下跳过校验
 func CasBinHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if common.CONFIG.System.Env == "develop" {
+			c.Next()
+			return
+		}
+		claims, ok := c.Get("claims")
+		if !ok {
+			c.JSON(response.Forbidden, gin.H{"errCode": 403, "errMsg": "权限不足", "data": gin.H{}, "msg": ""})
+			c.Abort()
+			return
+		}
+		waitUse, ok := claims.(*common.CustomClaims)
+		if !ok {
+			c.JSON(response.Forbidden, gin.H{"errCode": 403, "errMsg": "权限不足", "data": gin.H{}, "msg": ""})
+			c.Abort()
+			return
+		}
+		// 获取请求的URI
+		obj := c.Request.URL.RequestURI()
+		// 获取请求方法
+		act := c.Request.Method
+		// 获取用户的角色
+		sub := waitUse.Role
+		e := services.Casbin()
+		if e == nil {
+			c.JSON(response.InternalServerError, gin.H{"errCode": 500, "errMsg": "权限服务初始化失败", "data": gin.H{}, "msg": ""})
+			c.Abort()
+			return
+		}
+		// 判断策略中是否存在
+		success, err := e.Enforce(sub, obj, act)
+		if err != nil {
+			common.LOG.Error("权限校验出错", zap.Any("err", err))
+		}
+		common.LOG.Debug(fmt.Sprintf("用户：%v, URL：%v, Method：%v, Role：%v, 权限校验：%v", waitUse.Username, obj, act, sub, success))
+		if !success {
+			c.JSON(response.Forbidden, gin.H{"errCode": 403, "errMsg": "权限不足", "data": gin.H{}, "msg": ""})
+			c.Abort()
+			return
+		}
 		c.Next()
-		// claims, _ := c.Get("claims")
-		// waitUse := claims.(*common.CustomClaims)
-		// // 获取请求的URI
-		// obj := c.Request.URL.RequestURI()
-		// // 获取请求方法
-		// act := c.Request.Method
-		// // 获取用户的角色
-		// sub := waitUse.Role
-		// common.LOG.Info(fmt.Sprintf("URL：%v, Method：%v, Role：%v", obj, act, sub))
-		// e := services.Casbin()
-		// // 判断策略中是否存在
-		// success, _ := e.Enforce(sub, obj, act)
-		// common.LOG.Debug(fmt.Sprintf("用户：%v, 权限校验：%v", waitUse.Username, success))
-		// if common.CONFIG.System.Env == "develop" || success {
-		// 	c.Next()
-		// } else {
-		// 	c.JSON(response.Forbidden, gin.H{"errCode": 403, "errMsg": "权限不足", "data": gin.H{}, "msg": ""})
-		// 	c.Abort()
-		// 	return
-		// }
 	}
 }
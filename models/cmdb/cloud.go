@@ -17,6 +17,7 @@ limitations under the License.
 package cmdb
 
 import (
+	"encoding/json"
 	"github.com/dnsjia/luban/models"
 	"gorm.io/gorm"
 	"time"
@@ -38,6 +39,13 @@ const (
 	CloudSyncSuccess    string = "cloud_sync_success"
 	CloudSyncFail       string = "cloud_sync_fail"
 	CloudSyncInProgress string = "cloud_sync_in_progress"
+	CloudSyncPending    string = "cloud_sync_pending"
+)
+
+// 云同步任务触发方式
+const (
+	CloudSyncTriggerSchedule string = "schedule"
+	CloudSyncTriggerManual   string = "manual"
 )
 
 // Region 云资产地域信息
@@ -63,6 +71,12 @@ type CloudPlatform struct {
 	DeletedAt gorm.DeletedAt   `json:"-"`
 	UpdatedAt models.LocalTime `json:"updated_at"`
 	SyncTime  *time.Time       `json:"sync_time"`
+	// SyncCron 同步周期, 为空时使用配置文件中的默认周期
+	SyncCron string `json:"sync_cron" gorm:"comment:同步周期;size:64"`
+	// SyncStatus 最近一次同步的状态 CloudSyncSuccess/CloudSyncFail/CloudSyncInProgress
+	SyncStatus string `json:"sync_status" gorm:"comment:同步状态;size:64"`
+	// SyncSummary 最近一次同步结果 CloudSyncSummary
+	SyncSummary json.RawMessage `json:"sync_summary" gorm:"comment:同步结果;type:text"`
 	//VirtualMachines []*VirtualMachine `gorm:"many2many:cloud_platform_virtual_machines;"`
 }
 
@@ -93,6 +107,8 @@ type VirtualMachine struct {
 	Source        string           `json:"source"`
 	VmCreatedTime string           `json:"vm_created_time"`
 	VmExpiredTime string           `json:"vm_expired_time"`
+	PlatformID    int              `gorm:"comment:'云账号ID';index" json:"platform_id"`
+	CloudRegion   string           `gorm:"comment:'云地域'" json:"cloud_region"`
 	CreatedAt     models.LocalTime `json:"created_at"`
 	DeletedAt     gorm.DeletedAt   `json:"-"`
 	UpdatedAt     models.LocalTime `json:"updated_at"`
@@ -101,3 +117,68 @@ type VirtualMachine struct {
 func (v VirtualMachine) TableName() string {
 	return "cloud_virtual_machine"
 }
+
+// CloudSyncSummary 一次云资产同步的结果
+type CloudSyncSummary struct {
+	PlatformID int    `json:"platform_id"`
+	Vendor     string `json:"vendor"`
+	Status     string `json:"status"`
+	// Regions 成功同步的地域数
+	Regions   int `json:"regions"`
+	Total     int `json:"total"`
+	Added     int `json:"added"`
+	Updated   int `json:"updated"`
+	Removed   int `json:"removed"`
+	Unchanged int `json:"unchanged"`
+	// Error 导致同步中止的错误, 单个地域的错误记录在 RegionErrors
+	Error        string            `json:"error,omitempty"`
+	RegionErrors map[string]string `json:"region_errors,omitempty"`
+	StartedAt    time.Time         `json:"started_at"`
+	Duration     string            `json:"duration"`
+	Logs         []string          `json:"logs,omitempty"`
+}
+
+// CloudSyncJob 云资产同步记录
+type CloudSyncJob struct {
+	ID           int              `json:"id" gorm:"column:id;AUTO_INCREMENT;comment:主键"`
+	PlatformID   int              `json:"platform_id" gorm:"comment:云账号ID;index"`
+	PlatformName string           `json:"platform_name" gorm:"comment:云账号名称;size:128"`
+	Vendor       string           `json:"vendor" gorm:"comment:云厂商;size:32"`
+	Trigger      string           `json:"trigger" gorm:"comment:触发方式 schedule/manual;size:32"`
+	Operator     string           `json:"operator" gorm:"comment:触发人;size:128"`
+	Status       string           `json:"status" gorm:"comment:同步状态;size:64;index"`
+	Regions      int              `json:"regions" gorm:"comment:成功同步的地域数"`
+	Total        int              `json:"total" gorm:"comment:云上主机数"`
+	Added        int              `json:"added" gorm:"comment:新增主机数"`
+	Updated      int              `json:"updated" gorm:"comment:更新主机数"`
+	Removed      int              `json:"removed" gorm:"comment:删除主机数"`
+	Error        string           `json:"error" gorm:"comment:错误信息;size:1024"`
+	Log          string           `json:"log" gorm:"comment:同步日志;type:text"`
+	StartedAt    *time.Time       `json:"started_at" gorm:"comment:开始时间"`
+	FinishedAt   *time.Time       `json:"finished_at" gorm:"comment:结束时间"`
+	Duration     string           `json:"duration" gorm:"comment:耗时;size:64"`
+	CreatedAt    models.LocalTime `json:"created_at"`
+	UpdatedAt    models.LocalTime `json:"updated_at"`
+}
+
+func (j CloudSyncJob) TableName() string {
+	return "cloud_sync_job"
+}
+
+type CloudSyncJobQuery struct {
+	models.PaginationQ
+	PlatformID int    `form:"platform_id" json:"platform_id"`
+	Status     string `form:"status" json:"status"`
+}
+
+// CloudSyncData 立即同步云账号
+type CloudSyncData struct {
+	ID int `json:"id" binding:"required"`
+}
+
+// CloudScheduleData 修改云账号的同步周期与启用状态
+type CloudScheduleData struct {
+	ID       int    `json:"id" binding:"required"`
+	SyncCron string `json:"sync_cron"`
+	Enable   *bool  `json:"enable"`
+}
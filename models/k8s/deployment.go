@@ -44,3 +44,8 @@ type RollbackDeployment struct {
 	DeploymentName string `json:"deploymentName" binding:"required"`
 	ReVersion      *int64 `json:"reVersion" binding:"required"`
 }
+
+type PauseDeployment struct {
+	Namespace      string `json:"namespace" binding:"required"`
+	DeploymentName string `json:"deploymentName" binding:"required"`
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	autoscaling "k8s.io/api/autoscaling/v2beta2"
+)
+
+const (
+	AutoscalingV1      = "autoscaling/v1"
+	AutoscalingV2beta2 = "autoscaling/v2beta2"
+)
+
+// HorizontalPodAutoscalerData 创建或修改HPA的参数, APIVersion 为 autoscaling/v1 时只使用 TargetCPUUtilizationPercentage,
+// 为 autoscaling/v2beta2(默认) 时使用 Metrics 与 Behavior
+type HorizontalPodAutoscalerData struct {
+	APIVersion      string `json:"apiVersion"`
+	Namespace       string `json:"namespace" binding:"required"`
+	Name            string `json:"name" binding:"required"`
+	ScaleTargetKind string `json:"scaleTargetKind" binding:"required"`
+	ScaleTargetName string `json:"scaleTargetName" binding:"required"`
+	// ScaleTargetAPIVersion 默认为 apps/v1
+	ScaleTargetAPIVersion string `json:"scaleTargetAPIVersion"`
+	MinReplicas           *int32 `json:"minReplicas"`
+	MaxReplicas           int32  `json:"maxReplicas" binding:"required"`
+
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage"`
+
+	Metrics  []autoscaling.MetricSpec                     `json:"metrics"`
+	Behavior *autoscaling.HorizontalPodAutoscalerBehavior `json:"behavior"`
+}
+
+type RemoveHorizontalPodAutoscalerData struct {
+	Namespace string `json:"namespace" binding:"required"`
+	Name      string `json:"name" binding:"required"`
+}
@@ -0,0 +1,33 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+type ApplyManifest struct {
+	Namespace string `json:"namespace"`
+	Content   string `json:"content" binding:"required"`
+	DryRun    bool   `json:"dryRun"`
+	// Force 强制接管其他管理者持有的字段
+	Force bool `json:"force"`
+}
+
+type UpdateResourceYAML struct {
+	Group     string `json:"group"`
+	Kind      string `json:"kind" binding:"required"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name" binding:"required"`
+	Content   string `json:"content" binding:"required"`
+}
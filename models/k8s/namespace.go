@@ -0,0 +1,56 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+type CreateNamespaceData struct {
+	Name   string            `json:"name" binding:"required"`
+	Labels map[string]string `json:"labels"`
+}
+
+// RemoveNamespaceData 删除名称空间, Confirm 必须与 Name 一致, 防止误删
+type RemoveNamespaceData struct {
+	Name    string `json:"name" binding:"required"`
+	Confirm string `json:"confirm" binding:"required"`
+}
+
+type NamespaceLabelsData struct {
+	Name   string            `json:"name" binding:"required"`
+	Labels map[string]string `json:"labels"`
+}
+
+type ResourceQuotaData struct {
+	Namespace string               `json:"namespace" binding:"required"`
+	Name      string               `json:"name" binding:"required"`
+	Labels    map[string]string    `json:"labels"`
+	Spec      v1.ResourceQuotaSpec `json:"spec"`
+}
+
+type LimitRangeData struct {
+	Namespace string            `json:"namespace" binding:"required"`
+	Name      string            `json:"name" binding:"required"`
+	Labels    map[string]string `json:"labels"`
+	Spec      v1.LimitRangeSpec `json:"spec"`
+}
+
+type RemoveNamespacedResourceData struct {
+	Namespace string `json:"namespace" binding:"required"`
+	Name      string `json:"name" binding:"required"`
+}
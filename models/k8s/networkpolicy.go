@@ -0,0 +1,38 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	networking "k8s.io/api/networking/v1"
+)
+
+type NetworkPolicyData struct {
+	Namespace string                       `json:"namespace" binding:"required"`
+	Name      string                       `json:"name" binding:"required"`
+	Labels    map[string]string            `json:"labels"`
+	Spec      networking.NetworkPolicySpec `json:"spec"`
+}
+
+// TrafficAnalysisData 网络策略连通性分析, Port 为 0 时表示任意端口, Protocol 默认 TCP
+type TrafficAnalysisData struct {
+	SourceNamespace      string `json:"source_namespace" binding:"required"`
+	SourcePod            string `json:"source_pod" binding:"required"`
+	DestinationNamespace string `json:"destination_namespace" binding:"required"`
+	DestinationPod       string `json:"destination_pod" binding:"required"`
+	Port                 int32  `json:"port"`
+	Protocol             string `json:"protocol"`
+}
@@ -20,3 +20,21 @@ type RemovePodsData struct {
 	Namespace string `json:"namespace"  binding:"required"`
 	PodName   string `json:"podName"  binding:"required"`
 }
+
+// PodTerminalData 创建容器终端会话, Container 为空时使用第一个容器, Shell 为空时自动探测可用的shell
+type PodTerminalData struct {
+	Namespace string `json:"namespace" binding:"required"`
+	PodName   string `json:"podName" binding:"required"`
+	Container string `json:"container"`
+	Shell     string `json:"shell"`
+}
+
+// DebugContainerData 为Pod添加临时调试容器, TargetContainer 为共享进程命名空间的业务容器, 默认为第一个容器
+type DebugContainerData struct {
+	Namespace       string   `json:"namespace" binding:"required"`
+	PodName         string   `json:"podName" binding:"required"`
+	Image           string   `json:"image"`
+	TargetContainer string   `json:"targetContainer"`
+	Command         []string `json:"command"`
+	Shell           string   `json:"shell"`
+}
@@ -0,0 +1,26 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+// SearchQuery 跨集群搜索资源, By 为 name/label/image/ip, Kinds 为逗号分隔的 pod,service,node, 为空时全部搜索
+type SearchQuery struct {
+	By      string `form:"by"`
+	Keyword string `form:"keyword" binding:"required"`
+	Kinds   string `form:"kinds"`
+	// 单个集群的超时时间(秒)
+	Timeout int `form:"timeout"`
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+// 告警通知渠道类型
+const (
+	AlertChannelWebhook  = "webhook"
+	AlertChannelEmail    = "email"
+	AlertChannelDingTalk = "dingtalk"
+	AlertChannelWeCom    = "wecom"
+)
+
+// 告警规则类型
+const (
+	// AlertRuleEvent 匹配归档的集群事件, 依赖事件归档
+	AlertRuleEvent = "event"
+	// AlertRuleNodeNotReady 节点持续 NotReady 超过指定时间
+	AlertRuleNodeNotReady = "node_not_ready"
+)
+
+// 告警记录状态
+const (
+	AlertStatusSent     = "sent"
+	AlertStatusFailed   = "failed"
+	AlertStatusSilenced = "silenced"
+)
+
+// AlertChannel 告警通知渠道, 列表接口不返回 Secret 与 SmtpPassword, 更新时为空表示不修改
+type AlertChannel struct {
+	GModel
+	Name   string `gorm:"comment:'渠道名称';size:128" json:"name" binding:"required"`
+	Type   string `gorm:"comment:'渠道类型 webhook/email/dingtalk/wecom';size:32" json:"type" binding:"required"`
+	URL    string `gorm:"comment:'Webhook或机器人地址';size:512" json:"url"`
+	Secret string `gorm:"comment:'钉钉机器人加签密钥';size:256" json:"secret"`
+	// 邮件
+	SmtpHost     string `gorm:"comment:'SMTP地址';size:256" json:"smtpHost"`
+	SmtpPort     int    `gorm:"comment:'SMTP端口'" json:"smtpPort"`
+	SmtpUsername string `gorm:"comment:'SMTP用户名';size:256" json:"smtpUsername"`
+	SmtpPassword string `gorm:"comment:'SMTP密码';size:256" json:"smtpPassword"`
+	From         string `gorm:"comment:'发件人';size:256" json:"from"`
+	// 收件人, 多个以逗号分隔
+	To     string `gorm:"comment:'收件人';size:1024" json:"to"`
+	Enable bool   `gorm:"comment:'是否启用'" json:"enable"`
+}
+
+func (r AlertChannel) TableName() string {
+	return r.GModel.TableName("k8s_alert_channel")
+}
+
+// AlertRule 告警规则, ClusterID 为0时匹配所有集群, Namespace 为空时匹配所有命名空间
+type AlertRule struct {
+	GModel
+	Name      string `gorm:"comment:'规则名称';size:128" json:"name" binding:"required"`
+	Type      string `gorm:"comment:'规则类型 event/node_not_ready';size:32" json:"type" binding:"required"`
+	ClusterID uint   `gorm:"comment:'集群Id'" json:"clusterId"`
+	Namespace string `gorm:"comment:'命名空间';size:128" json:"namespace"`
+	// 事件规则: 事件类型, 原因(逗号分隔), 关联对象类型
+	EventType    string `gorm:"comment:'事件类型';size:32" json:"eventType"`
+	Reasons      string `gorm:"comment:'事件原因, 逗号分隔';size:512" json:"reasons"`
+	InvolvedKind string `gorm:"comment:'关联对象类型';size:64" json:"involvedKind"`
+	// 节点规则: NotReady 持续分钟数
+	NotReadyMinutes int `gorm:"comment:'NotReady持续分钟数'" json:"notReadyMinutes"`
+	// 去重窗口, 同一对象在窗口内只通知一次
+	DedupMinutes int `gorm:"comment:'去重窗口分钟数'" json:"dedupMinutes"`
+	// 通知渠道ID, 逗号分隔
+	ChannelIDs string `gorm:"comment:'通知渠道';size:256" json:"channelIds"`
+	Enable     bool   `gorm:"comment:'是否启用'" json:"enable"`
+	// 事件规则已处理到的位置
+	EvaluatedAt LocalTime `gorm:"comment:'上次评估时间'" json:"evaluatedAt"`
+}
+
+func (r AlertRule) TableName() string {
+	return r.GModel.TableName("k8s_alert_rule")
+}
+
+// AlertSilence 静默规则, 在生效时间内匹配的告警只记录不通知. RuleID、ClusterID 为0, Namespace、Object 为空时表示不限制
+type AlertSilence struct {
+	GModel
+	RuleID    uint      `gorm:"comment:'规则Id'" json:"ruleId"`
+	ClusterID uint      `gorm:"comment:'集群Id'" json:"clusterId"`
+	Namespace string    `gorm:"comment:'命名空间';size:128" json:"namespace"`
+	Object    string    `gorm:"comment:'对象名称前缀';size:256" json:"object"`
+	StartsAt  LocalTime `gorm:"comment:'开始时间'" json:"startsAt"`
+	EndsAt    LocalTime `gorm:"comment:'结束时间';index" json:"endsAt" binding:"required"`
+	Comment   string    `gorm:"comment:'备注';size:512" json:"comment"`
+	CreatedBy string    `gorm:"comment:'创建人';size:128" json:"createdBy"`
+}
+
+func (r AlertSilence) TableName() string {
+	return r.GModel.TableName("k8s_alert_silence")
+}
+
+// AlertRecord 告警记录, 去重窗口内的重复告警只累加 Suppressed
+type AlertRecord struct {
+	GModel
+	RuleID      uint      `gorm:"comment:'规则Id';index:idx_k8s_alert_fingerprint" json:"rule_id"`
+	RuleName    string    `gorm:"comment:'规则名称';size:128" json:"rule_name"`
+	ClusterID   uint      `gorm:"comment:'集群Id';index" json:"cluster_id"`
+	Namespace   string    `gorm:"comment:'命名空间';size:128" json:"namespace"`
+	Object      string    `gorm:"comment:'告警对象';size:256" json:"object"`
+	Fingerprint string    `gorm:"comment:'告警指纹';size:64;index:idx_k8s_alert_fingerprint" json:"fingerprint"`
+	Title       string    `gorm:"comment:'标题';size:256" json:"title"`
+	Content     string    `gorm:"comment:'内容';type:text" json:"content"`
+	Status      string    `gorm:"comment:'状态 sent/failed/silenced';size:32" json:"status"`
+	Error       string    `gorm:"comment:'通知失败原因';size:1024" json:"error"`
+	Suppressed  int       `gorm:"comment:'窗口内被去重的次数'" json:"suppressed"`
+	FiredAt     LocalTime `gorm:"comment:'触发时间';index:idx_k8s_alert_fingerprint" json:"fired_at"`
+}
+
+func (r AlertRecord) TableName() string {
+	return r.GModel.TableName("k8s_alert_record")
+}
+
+type AlertRecordQuery struct {
+	PaginationQ
+	RuleID    uint   `form:"ruleId" json:"ruleId"`
+	ClusterID uint   `form:"clusterId" json:"clusterId"`
+	Namespace string `form:"namespace" json:"namespace"`
+	Status    string `form:"status" json:"status"`
+}
+
+// RemoveAlertData 删除告警渠道、规则、静默
+type RemoveAlertData struct {
+	ID uint `json:"id" binding:"required"`
+}
@@ -23,6 +23,31 @@ type K8SCluster struct {
 	KubeConfig     string `json:"kubeConfig" gorm:"comment:集群凭证;type:varchar(12800)" binding:"required"`
 	ClusterVersion string `json:"clusterVersion" gorm:"comment:集群版本"`
 	NodeNumber     int    `json:"nodeNumber" gorm:"comment:节点数"`
+
+	MetricsSource ClusterMetricsSource `json:"metricsSource" gorm:"embedded;embeddedPrefix:metrics_"`
+}
+
+const (
+	MetricsSourceKSM        = "ksm"
+	MetricsSourcePrometheus = "prometheus"
+)
+
+// ClusterMetricsSource 集群概览的指标来源, 类型为空时按 ksm 处理并自动探测 kube-state-metrics 服务
+type ClusterMetricsSource struct {
+	Type          string `json:"type" gorm:"comment:指标来源 ksm/prometheus"`
+	KsmService    string `json:"ksmService" gorm:"comment:kube-state-metrics服务, 格式 namespace/name:port"`
+	PrometheusURL string `json:"prometheusUrl" gorm:"comment:Prometheus地址"`
+	// 自定义PromQL, 为空时根据指标命名自动选择
+	CpuRequestQuery     string `json:"cpuRequestQuery" gorm:"type:varchar(1024);comment:CPU请求量PromQL"`
+	CpuCapacityQuery    string `json:"cpuCapacityQuery" gorm:"type:varchar(1024);comment:CPU容量PromQL"`
+	MemoryRequestQuery  string `json:"memoryRequestQuery" gorm:"type:varchar(1024);comment:内存请求量PromQL"`
+	MemoryCapacityQuery string `json:"memoryCapacityQuery" gorm:"type:varchar(1024);comment:内存容量PromQL"`
+}
+
+// ClusterMetricsSourceData 更新集群指标来源
+type ClusterMetricsSourceData struct {
+	ID            uint                 `json:"id" binding:"required"`
+	MetricsSource ClusterMetricsSource `json:"metricsSource"`
 }
 
 func (ks K8SCluster) TableName() string {
@@ -64,4 +89,8 @@ type ClusterNodesStatus struct {
 	MemoryUsage     float64 `json:"memory_usage" desc:"内存使用率"`
 	MemoryUsed      float64 `json:"memory_used"`
 	MemoryTotal     float64 `json:"memory_total"`
+	// 指标获取失败时 MetricsAvailable 为false, 资源相关字段为0
+	MetricsAvailable bool   `json:"metrics_available"`
+	MetricsMessage   string `json:"metrics_message,omitempty"`
+	MetricsSource    string `json:"metrics_source,omitempty" desc:"实际使用的指标来源"`
 }
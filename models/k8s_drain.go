@@ -0,0 +1,38 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "time"
+
+// NodeDrainJob 节点排空任务, 执行任务的实例会定期写入进度, 其他实例据此查询状态.
+// Options 与 Pods 以JSON保存
+type NodeDrainJob struct {
+	GModel
+	JobID      string     `gorm:"comment:'任务标识';size:64;uniqueIndex" json:"job_id"`
+	ClusterID  uint       `gorm:"comment:'集群Id';index" json:"cluster_id"`
+	NodeName   string     `gorm:"comment:'节点名称';size:256" json:"node_name"`
+	Status     string     `gorm:"comment:'任务状态 running/succeeded/failed';size:32" json:"status"`
+	Message    string     `gorm:"comment:'任务结果';size:1024" json:"message"`
+	Options    string     `gorm:"comment:'排空选项';type:text" json:"options"`
+	Pods       string     `gorm:"comment:'pod驱逐进度';type:mediumtext" json:"pods"`
+	StartedAt  time.Time  `gorm:"comment:'开始时间';index" json:"started_at"`
+	FinishedAt *time.Time `gorm:"comment:'结束时间'" json:"finished_at"`
+}
+
+func (r NodeDrainJob) TableName() string {
+	return r.GModel.TableName("k8s_node_drain_job")
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "time"
+
+// EventRecord 归档的集群事件, 同一集群内按事件UID去重, Count 增加时更新
+type EventRecord struct {
+	GModel
+	ClusterID      uint      `gorm:"comment:'集群Id';uniqueIndex:idx_k8s_event_uid;index:idx_k8s_event_object" json:"cluster_id"`
+	UID            string    `gorm:"comment:'事件UID';size:64;uniqueIndex:idx_k8s_event_uid" json:"uid"`
+	Namespace      string    `gorm:"comment:'命名空间';size:128;index:idx_k8s_event_object" json:"namespace"`
+	InvolvedKind   string    `gorm:"comment:'关联对象类型';size:64;index:idx_k8s_event_object" json:"involved_kind"`
+	InvolvedName   string    `gorm:"comment:'关联对象名称';size:256;index:idx_k8s_event_object" json:"involved_name"`
+	InvolvedUID    string    `gorm:"comment:'关联对象UID';size:64;index" json:"involved_uid"`
+	Reason         string    `gorm:"comment:'原因';size:128;index" json:"reason"`
+	Type           string    `gorm:"comment:'类型 Normal/Warning';size:32" json:"type"`
+	Message        string    `gorm:"comment:'事件内容';type:text" json:"message"`
+	Source         string    `gorm:"comment:'事件来源';size:256" json:"source"`
+	Count          int32     `gorm:"comment:'发生次数'" json:"count"`
+	FirstTimestamp LocalTime `gorm:"comment:'首次发生时间'" json:"first_timestamp"`
+	LastTimestamp  LocalTime `gorm:"comment:'最近发生时间';index" json:"last_timestamp"`
+}
+
+func (r EventRecord) TableName() string {
+	return r.GModel.TableName("k8s_event_record")
+}
+
+type EventRecordQuery struct {
+	PaginationQ
+	ClusterID uint      `form:"clusterId" json:"clusterId"`
+	Namespace string    `form:"namespace" json:"namespace"`
+	Kind      string    `form:"kind" json:"kind"`
+	Name      string    `form:"name" json:"name"`
+	Reason    string    `form:"reason" json:"reason"`
+	Type      string    `form:"type" json:"type"`
+	StartTime time.Time `form:"startTime" json:"startTime" time_format:"2006-01-02 15:04:05"`
+	EndTime   time.Time `form:"endTime" json:"endTime" time_format:"2006-01-02 15:04:05"`
+}
+
+// WorkloadWarningQuery 工作负载及其 ReplicaSet/Pod 的归档告警事件
+type WorkloadWarningQuery struct {
+	ClusterID uint      `form:"clusterId" json:"clusterId"`
+	Namespace string    `form:"namespace" json:"namespace" binding:"required"`
+	Kind      string    `form:"kind" json:"kind" binding:"required"`
+	Name      string    `form:"name" json:"name" binding:"required"`
+	StartTime time.Time `form:"startTime" json:"startTime" time_format:"2006-01-02 15:04:05"`
+	Limit     int       `form:"limit" json:"limit"`
+
+	// 以下字段由控制器根据 ownerReferences 与名称格式填充, 不从请求中读取
+	ReplicaSets       []string `form:"-" json:"-"`
+	Pods              []string `form:"-" json:"-"`
+	ReplicaSetPattern string   `form:"-" json:"-"`
+	PodPattern        string   `form:"-" json:"-"`
+}
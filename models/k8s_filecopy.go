@@ -0,0 +1,62 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+// 文件传输方向
+const (
+	FileTransferUpload   = "upload"
+	FileTransferDownload = "download"
+)
+
+// 文件传输结果
+const (
+	FileTransferSuccess = "success"
+	FileTransferFailed  = "failed"
+)
+
+// FileTransferRecord 容器文件上传下载审计记录
+type FileTransferRecord struct {
+	GModel
+	ClusterID uint      `gorm:"comment:'集群Id';index" json:"cluster_id"`
+	Namespace string    `gorm:"comment:'命名空间';size:128" json:"namespace"`
+	PodName   string    `gorm:"comment:'Pod名称';size:256" json:"pod_name"`
+	Container string    `gorm:"comment:'容器名称';size:128" json:"container"`
+	UserName  string    `gorm:"comment:'操作用户';size:128;index" json:"user_name"`
+	ClientIP  string    `gorm:"comment:'客户端IP';size:64" json:"client_ip"`
+	Direction string    `gorm:"comment:'传输方向 upload/download';size:16" json:"direction"`
+	Path      string    `gorm:"comment:'容器内路径';size:1024" json:"path"`
+	FileName  string    `gorm:"comment:'上传文件名';size:256" json:"file_name"`
+	Format    string    `gorm:"comment:'打包格式 tar/zip';size:16" json:"format"`
+	Size      int64     `gorm:"comment:'传输字节数'" json:"size"`
+	Status    string    `gorm:"comment:'传输结果';size:16" json:"status"`
+	Message   string    `gorm:"comment:'失败原因';size:1024" json:"message"`
+	StartTime LocalTime `gorm:"index;comment:'开始时间'" json:"start_time"`
+	EndTime   LocalTime `gorm:"comment:'结束时间'" json:"end_time"`
+}
+
+func (r FileTransferRecord) TableName() string {
+	return r.GModel.TableName("k8s_file_transfer_record")
+}
+
+type FileTransferRecordQuery struct {
+	PaginationQ
+	ClusterID uint   `form:"clusterId" json:"clusterId"`
+	Namespace string `form:"namespace" json:"namespace"`
+	PodName   string `form:"pod" json:"pod"`
+	UserName  string `form:"username" json:"username"`
+	Direction string `form:"direction" json:"direction"`
+}
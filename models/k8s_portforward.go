@@ -0,0 +1,47 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+// PortForwardRecord 端口转发审计记录, 每条WebSocket隧道对应一条记录
+type PortForwardRecord struct {
+	GModel
+	SessionID   string    `gorm:"comment:'会话标识';size:64;index" json:"session_id"`
+	ClusterID   uint      `gorm:"comment:'集群Id';index" json:"cluster_id"`
+	Namespace   string    `gorm:"comment:'命名空间';size:128" json:"namespace"`
+	PodName     string    `gorm:"comment:'Pod名称';size:256" json:"pod_name"`
+	ServiceName string    `gorm:"comment:'Service名称, 直接转发Pod时为空';size:256" json:"service_name"`
+	Port        int32     `gorm:"comment:'Pod端口'" json:"port"`
+	UserName    string    `gorm:"comment:'操作用户';size:128;index" json:"user_name"`
+	ClientIP    string    `gorm:"comment:'客户端IP';size:64" json:"client_ip"`
+	ConnectTime LocalTime `gorm:"index;comment:'接入时间'" json:"connect_time"`
+	CloseTime   LocalTime `gorm:"index;comment:'断开时间'" json:"close_time"`
+	BytesIn     int64     `gorm:"comment:'客户端发送字节数'" json:"bytes_in"`
+	BytesOut    int64     `gorm:"comment:'Pod返回字节数'" json:"bytes_out"`
+	CloseReason string    `gorm:"comment:'断开原因';size:512" json:"close_reason"`
+}
+
+func (r PortForwardRecord) TableName() string {
+	return r.GModel.TableName("k8s_port_forward_record")
+}
+
+type PortForwardRecordQuery struct {
+	PaginationQ
+	ClusterID uint   `form:"clusterId" json:"clusterId"`
+	Namespace string `form:"namespace" json:"namespace"`
+	PodName   string `form:"pod" json:"pod"`
+	UserName  string `form:"username" json:"username"`
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+// PodTerminalRecord 容器终端操作记录, 以 asciicast v2 格式压缩存储
+type PodTerminalRecord struct {
+	GModel
+	SessionID   string    `gorm:"comment:'会话标识';size:64;index" json:"session_id"`
+	ClusterID   uint      `gorm:"comment:'集群Id';index" json:"cluster_id"`
+	Namespace   string    `gorm:"comment:'命名空间';size:128" json:"namespace"`
+	PodName     string    `gorm:"comment:'Pod名称';size:256" json:"pod_name"`
+	Container   string    `gorm:"comment:'容器名称';size:128" json:"container"`
+	UserName    string    `gorm:"comment:'操作用户';size:128" json:"user_name"`
+	ConnectTime LocalTime `gorm:"index;comment:'接入时间'" json:"connect_time"`
+	LogoutTime  LocalTime `gorm:"index;comment:'注销时间'" json:"logout_time"`
+	Records     []byte    `gorm:"type:longblob;comment:'操作记录(二进制存储)'" json:"-"`
+	Truncated   bool      `gorm:"comment:'录像超过大小限制被截断'" json:"truncated"`
+}
+
+func (r PodTerminalRecord) TableName() string {
+	return r.GModel.TableName("k8s_pod_terminal_record")
+}
+
+type PodTerminalRecordQuery struct {
+	PaginationQ
+	ClusterID uint   `form:"clusterId" json:"clusterId"`
+	Namespace string `form:"namespace" json:"namespace"`
+	PodName   string `form:"pod" json:"pod"`
+	UserName  string `form:"username" json:"username"`
+}
@@ -44,7 +44,7 @@ func NewCastV2(meta CastV2Header, stream *bytes.Buffer) (*CastV2Header, *bytes.B
 	c.Height = meta.Height
 	c.Title = meta.Title
 	c.Timestamp = meta.Timestamp
-	c.Duration = c.Duration
+	c.Duration = meta.Duration
 	c.Env = meta.Env
 	c.outputStream = json.NewEncoder(stream)
 	c.outputStream.Encode(c)
@@ -20,9 +20,12 @@ import (
 	"errors"
 	"fmt"
 	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/pkg/k8s/informer"
 	"github.com/dnsjia/luban/services"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -58,18 +61,65 @@ func GetRestConf(k8sConf string) (restConf *rest.Config, err error) {
 	return restConf, nil
 }
 
-// ClusterID 公共方法, 获取指定k8s集群的KubeConfig
+// ClusterID 公共方法, 获取指定k8s集群的Client, Client按集群缓存复用, 集群配置变更后重建
 func ClusterID(c *gin.Context) (*kubernetes.Clientset, error) {
 
-	clusterId := c.DefaultQuery("clusterId", "1")
-	clusterIdUint, err := strconv.ParseUint(clusterId, 10, 32)
-	cluster, err := services.GetK8sCluster(uint(clusterIdUint))
+	cluster, err := getCluster(c)
+	if err != nil {
+		return nil, err
+	}
+
+	cc, err := informer.Get(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return cc.Client(), nil
+}
+
+// ClusterRestConfig 公共方法, 获取指定k8s集群的RESTConfig, 用于创建dynamic client等非typed客户端
+func ClusterRestConfig(c *gin.Context) (*rest.Config, error) {
+
+	cluster, err := getCluster(c)
+	if err != nil {
+		return nil, err
+	}
+
+	cc, err := informer.Get(cluster)
 	if err != nil {
-		common.LOG.Error("获取集群失败", zap.Any("err", err))
 		return nil, err
 	}
+	return cc.Config(), nil
+}
 
-	client, _ := GetK8sClient(cluster.KubeConfig)
+// ClusterDynamicClient 公共方法, 获取指定k8s集群的dynamic client, 用于访问CRD等非内置资源
+func ClusterDynamicClient(c *gin.Context) (dynamic.Interface, error) {
 
-	return client, nil
+	config, err := ClusterRestConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(config)
+}
+
+// GetClusterID 从请求参数中解析集群ID, 未指定时默认为1
+func GetClusterID(c *gin.Context) (uint, error) {
+	clusterId := c.DefaultQuery("clusterId", "1")
+	clusterIdUint, err := strconv.ParseUint(clusterId, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("集群ID不合法: %v", clusterId)
+	}
+	return uint(clusterIdUint), nil
+}
+
+func getCluster(c *gin.Context) (models.K8SCluster, error) {
+	clusterId, err := GetClusterID(c)
+	if err != nil {
+		return models.K8SCluster{}, err
+	}
+	cluster, err := services.GetK8sCluster(clusterId)
+	if err != nil {
+		common.LOG.Error("获取集群失败", zap.Any("err", err))
+		return cluster, err
+	}
+	return cluster, nil
 }
@@ -0,0 +1,177 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alert
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMatchEvent(t *testing.T) {
+	rule := models.AlertRule{ClusterID: 1, Namespace: "prod", EventType: "Warning", Reasons: "BackOff, OOMKilling"}
+	record := models.EventRecord{ClusterID: 1, Namespace: "prod", Type: "Warning", Reason: "BackOff", InvolvedKind: "Pod"}
+	if !matchEvent(rule, record) {
+		t.Fatal("expected BackOff event to match")
+	}
+
+	cases := map[string]func(r *models.EventRecord){
+		"cluster":   func(r *models.EventRecord) { r.ClusterID = 2 },
+		"namespace": func(r *models.EventRecord) { r.Namespace = "dev" },
+		"type":      func(r *models.EventRecord) { r.Type = "Normal" },
+		"reason":    func(r *models.EventRecord) { r.Reason = "Pulled" },
+	}
+	for name, mutate := range cases {
+		r := record
+		mutate(&r)
+		if matchEvent(rule, r) {
+			t.Errorf("%s: expected no match", name)
+		}
+	}
+}
+
+func TestEventAlertFingerprint(t *testing.T) {
+	rule := models.AlertRule{Name: "crash"}
+	rule.ID = 1
+	record := models.EventRecord{ClusterID: 1, Namespace: "prod", Reason: "BackOff", InvolvedKind: "Pod", InvolvedName: "web-1", Count: 1}
+	first := eventAlert(rule, "test", record)
+	record.Count = 500
+	if again := eventAlert(rule, "test", record); again.Fingerprint != first.Fingerprint {
+		t.Error("repeated events of the same object should share a fingerprint")
+	}
+	record.InvolvedName = "web-2"
+	if other := eventAlert(rule, "test", record); other.Fingerprint == first.Fingerprint {
+		t.Error("different objects should not share a fingerprint")
+	}
+}
+
+func TestNodeAlerts(t *testing.T) {
+	now := time.Now()
+	node := func(name string, status v1.ConditionStatus, since time.Duration) v1.Node {
+		return v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: v1.NodeStatus{Conditions: []v1.NodeCondition{{
+				Type:               v1.NodeReady,
+				Status:             status,
+				LastTransitionTime: metav1.NewTime(now.Add(-since)),
+			}}},
+		}
+	}
+	nodes := []v1.Node{
+		node("ready", v1.ConditionTrue, time.Hour),
+		node("flapping", v1.ConditionUnknown, 2*time.Minute),
+		node("down", v1.ConditionFalse, 10*time.Minute),
+	}
+	alerts := nodeAlerts(models.AlertRule{NotReadyMinutes: 5}, 1, "test", nodes, now)
+	if len(alerts) != 1 || alerts[0].Object != "down" {
+		t.Fatalf("expected only node down to fire, got %+v", alerts)
+	}
+}
+
+func TestSilenced(t *testing.T) {
+	alert := Alert{RuleID: 1, ClusterID: 1, Namespace: "prod", Object: "web-6d4f-abc"}
+	if silenced([]models.AlertSilence{{ClusterID: 2}}, alert) {
+		t.Error("silence of another cluster should not match")
+	}
+	if !silenced([]models.AlertSilence{{RuleID: 1, Namespace: "prod", Object: "web-"}}, alert) {
+		t.Error("expected object prefix silence to match")
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	defer func(enabled bool) { common.CONFIG.Kubernetes.EventArchive = enabled }(common.CONFIG.Kubernetes.EventArchive)
+	common.CONFIG.Kubernetes.EventArchive = true
+
+	valid := []models.AlertRule{
+		{Type: models.AlertRuleEvent, Reasons: "BackOff", ChannelIDs: "1,2"},
+		{Type: models.AlertRuleEvent, Reasons: "BackOff", Enable: true},
+		{Type: models.AlertRuleNodeNotReady, NotReadyMinutes: 5},
+	}
+	for _, rule := range valid {
+		if err := ValidateRule(rule); err != nil {
+			t.Errorf("%+v: %v", rule, err)
+		}
+	}
+	invalid := []models.AlertRule{
+		{Type: "cpu"},
+		{Type: models.AlertRuleEvent},
+		{Type: models.AlertRuleEvent, EventType: "Error"},
+		{Type: models.AlertRuleEvent, Reasons: "BackOff", ChannelIDs: "a"},
+	}
+	for _, rule := range invalid {
+		if err := ValidateRule(rule); err == nil {
+			t.Errorf("%+v: expected error", rule)
+		}
+	}
+
+	// 未开启事件归档时不允许启用事件规则, 节点规则不受影响
+	common.CONFIG.Kubernetes.EventArchive = false
+	if err := ValidateRule(models.AlertRule{Type: models.AlertRuleEvent, Reasons: "BackOff", Enable: true}); err == nil {
+		t.Errorf("expected error for enabled event rule without event archive")
+	}
+	if err := ValidateRule(models.AlertRule{Type: models.AlertRuleEvent, Reasons: "BackOff"}); err != nil {
+		t.Errorf("disabled event rule: %v", err)
+	}
+	if err := ValidateRule(models.AlertRule{Type: models.AlertRuleNodeNotReady, Enable: true}); err != nil {
+		t.Errorf("node rule: %v", err)
+	}
+}
+
+func TestDingTalkSend(t *testing.T) {
+	var query map[string][]string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer server.Close()
+
+	n, err := NewNotifier(models.AlertChannel{Type: models.AlertChannelDingTalk, URL: server.URL + "?access_token=x", Secret: "SEC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(Alert{Title: "title", Content: "content"}); err != nil {
+		t.Fatal(err)
+	}
+	if query["access_token"][0] != "x" || len(query["sign"]) != 1 || len(query["timestamp"]) != 1 {
+		t.Errorf("unexpected query %v", query)
+	}
+	if body["msgtype"] != "markdown" {
+		t.Errorf("unexpected body %v", body)
+	}
+}
+
+func TestRobotError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errcode":93000,"errmsg":"invalid webhook url"}`))
+	}))
+	defer server.Close()
+
+	n, _ := NewNotifier(models.AlertChannel{Type: models.AlertChannelWeCom, URL: server.URL})
+	if err := n.Send(Alert{Title: "title"}); err == nil || !strings.Contains(err.Error(), "93000") {
+		t.Errorf("expected robot error, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alert
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/pkg/k8s/informer"
+	"github.com/dnsjia/luban/services"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// initialLookback 事件规则第一次评估时回溯的时间, 避免新建规则时推送全部历史事件
+const initialLookback = 5 * time.Minute
+
+type evaluator struct {
+	now      time.Time
+	clusters []models.K8SCluster
+	silences []models.AlertSilence
+}
+
+// Evaluate 评估所有启用的告警规则, 由 asynq worker 周期性调用
+func Evaluate(ctx context.Context) error {
+	rules, err := services.ListEnabledAlertRule()
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	e := &evaluator{now: time.Now()}
+	if e.clusters, err = services.ListAllK8SCluster(); err != nil {
+		return err
+	}
+	if e.silences, err = services.ListActiveAlertSilence(e.now); err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// 每条规则单独判断错误, 避免上一条规则的错误影响后续规则
+		var (
+			alerts []Alert
+			err    error
+		)
+		switch rule.Type {
+		case models.AlertRuleEvent:
+			if !common.CONFIG.Kubernetes.EventArchive {
+				common.LOG.Warn(fmt.Sprintf("告警规则%s依赖事件归档, 未开启 kubernetes.event-archive, 跳过评估", rule.Name))
+				continue
+			}
+			alerts, err = e.eventAlerts(rule)
+		case models.AlertRuleNodeNotReady:
+			alerts = e.nodeAlerts(ctx, rule)
+		}
+		if err != nil {
+			common.LOG.Error(fmt.Sprintf("评估告警规则%s失败", rule.Name), zap.Any("err", err))
+			continue
+		}
+		e.fire(rule, alerts)
+	}
+	return nil
+}
+
+func (e *evaluator) clusterName(id uint) string {
+	for _, cluster := range e.clusters {
+		if cluster.ID == id {
+			return cluster.ClusterName
+		}
+	}
+	return fmt.Sprint(id)
+}
+
+func (e *evaluator) eventAlerts(rule models.AlertRule) ([]Alert, error) {
+	start := rule.EvaluatedAt.Time
+	if start.IsZero() {
+		start = e.now.Add(-initialLookback)
+	}
+	records, err := services.ListEventRecordUpdatedBetween(rule.ClusterID, rule.Namespace, start, e.now)
+	if err != nil {
+		return nil, err
+	}
+	if err := services.UpdateAlertRuleEvaluatedAt(rule.ID, e.now); err != nil {
+		return nil, err
+	}
+
+	var alerts []Alert
+	for _, record := range records {
+		if matchEvent(rule, record) {
+			alerts = append(alerts, eventAlert(rule, e.clusterName(record.ClusterID), record))
+		}
+	}
+	return alerts, nil
+}
+
+func (e *evaluator) nodeAlerts(ctx context.Context, rule models.AlertRule) []Alert {
+	var alerts []Alert
+	for _, cluster := range e.clusters {
+		if rule.ClusterID != 0 && rule.ClusterID != cluster.ID {
+			continue
+		}
+		cc, err := informer.Get(cluster)
+		if err != nil {
+			common.LOG.Warn(fmt.Sprintf("告警规则%s无法连接集群%s", rule.Name, cluster.ClusterName), zap.Any("err", err))
+			continue
+		}
+		listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		nodes, err := cc.Client().CoreV1().Nodes().List(listCtx, metav1.ListOptions{})
+		cancel()
+		if err != nil {
+			common.LOG.Warn(fmt.Sprintf("告警规则%s获取集群%s节点失败", rule.Name, cluster.ClusterName), zap.Any("err", err))
+			continue
+		}
+		alerts = append(alerts, nodeAlerts(rule, cluster.ID, cluster.ClusterName, nodes.Items, e.now)...)
+	}
+	return alerts
+}
+
+// fire 去重窗口内已触发过的告警只累加计数, 被静默的告警只记录不通知
+func (e *evaluator) fire(rule models.AlertRule, alerts []Alert) {
+	if len(alerts) == 0 {
+		return
+	}
+	ids, _ := ParseChannelIDs(rule.ChannelIDs)
+	channels, err := services.GetAlertChannels(ids)
+	if err != nil {
+		common.LOG.Error("获取告警通知渠道失败", zap.Any("err", err))
+		return
+	}
+
+	since := e.now.Add(-dedupWindow(rule))
+	for _, alert := range alerts {
+		recent, err := services.GetRecentAlertRecord(rule.ID, alert.Fingerprint, since)
+		if err != nil {
+			common.LOG.Error("查询告警记录失败", zap.Any("err", err))
+			continue
+		}
+		if recent != nil {
+			if err := services.IncreaseAlertSuppressed(recent.ID); err != nil {
+				common.LOG.Error("更新告警记录失败", zap.Any("err", err))
+			}
+			continue
+		}
+
+		record := models.AlertRecord{
+			RuleID:      rule.ID,
+			RuleName:    rule.Name,
+			ClusterID:   alert.ClusterID,
+			Namespace:   alert.Namespace,
+			Object:      alert.Object,
+			Fingerprint: alert.Fingerprint,
+			Title:       alert.Title,
+			Content:     alert.Content,
+			FiredAt:     models.LocalTime{Time: e.now},
+		}
+		if silenced(e.silences, alert) {
+			record.Status = models.AlertStatusSilenced
+		} else {
+			record.Status, record.Error = send(channels, alert)
+		}
+		if err := services.CreateAlertRecord(&record); err != nil {
+			common.LOG.Error("保存告警记录失败", zap.Any("err", err))
+		}
+	}
+}
+
+// send 发送到所有启用的渠道, 任一渠道失败时记录为失败
+func send(channels []models.AlertChannel, alert Alert) (string, string) {
+	var errs []string
+	for _, channel := range channels {
+		if !channel.Enable {
+			continue
+		}
+		if err := Send(channel, alert); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", channel.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return models.AlertStatusFailed, strings.Join(errs, "; ")
+	}
+	return models.AlertStatusSent, ""
+}
+
+// Send 通过渠道发送告警, 也用于测试渠道配置
+func Send(channel models.AlertChannel, alert Alert) error {
+	notifier, err := NewNotifier(channel)
+	if err != nil {
+		return err
+	}
+	return notifier.Send(alert)
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alert
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnsjia/luban/models"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Notifier sends an alert to one channel.
+type Notifier interface {
+	Send(alert Alert) error
+}
+
+// NewNotifier returns the notifier of the channel type.
+func NewNotifier(channel models.AlertChannel) (Notifier, error) {
+	switch channel.Type {
+	case models.AlertChannelWebhook:
+		return &webhookNotifier{url: channel.URL}, nil
+	case models.AlertChannelDingTalk:
+		return &dingTalkNotifier{url: channel.URL, secret: channel.Secret}, nil
+	case models.AlertChannelWeCom:
+		return &weComNotifier{url: channel.URL}, nil
+	case models.AlertChannelEmail:
+		return &emailNotifier{channel: channel}, nil
+	}
+	return nil, fmt.Errorf("不支持的通知渠道类型: %s", channel.Type)
+}
+
+// ValidateChannel 校验通知渠道配置
+func ValidateChannel(channel models.AlertChannel) error {
+	switch channel.Type {
+	case models.AlertChannelWebhook, models.AlertChannelDingTalk, models.AlertChannelWeCom:
+		u, err := url.Parse(channel.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("通知地址不合法: %s", channel.URL)
+		}
+	case models.AlertChannelEmail:
+		if channel.SmtpHost == "" || channel.SmtpPort <= 0 {
+			return fmt.Errorf("SMTP地址不能为空")
+		}
+		if channel.From == "" || len(splitList(channel.To)) == 0 {
+			return fmt.Errorf("发件人与收件人不能为空")
+		}
+	default:
+		return fmt.Errorf("不支持的通知渠道类型: %s", channel.Type)
+	}
+	return nil
+}
+
+func postJSON(target string, body interface{}) ([]byte, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Post(target, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("通知返回状态码%d: %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+// robotResponse 钉钉与企业微信机器人的返回结构
+type robotResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func checkRobotResponse(body []byte) error {
+	var resp robotResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("解析机器人返回失败: %v", err)
+	}
+	if resp.ErrCode != 0 {
+		return fmt.Errorf("机器人返回错误%d: %s", resp.ErrCode, resp.ErrMsg)
+	}
+	return nil
+}
+
+type webhookNotifier struct {
+	url string
+}
+
+func (n *webhookNotifier) Send(alert Alert) error {
+	_, err := postJSON(n.url, alert)
+	return err
+}
+
+type dingTalkNotifier struct {
+	url    string
+	secret string
+}
+
+// dingTalkSign 钉钉机器人加签: base64(hmac_sha256(timestamp+"\n"+secret))
+func dingTalkSign(timestamp int64, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%d\n%s", timestamp, secret)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (n *dingTalkNotifier) Send(alert Alert) error {
+	target := n.url
+	if n.secret != "" {
+		timestamp := time.Now().UnixNano() / int64(time.Millisecond)
+		params := url.Values{
+			"timestamp": {strconv.FormatInt(timestamp, 10)},
+			"sign":      {dingTalkSign(timestamp, n.secret)},
+		}
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target = target + sep + params.Encode()
+	}
+	body, err := postJSON(target, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": alert.Title,
+			"text":  fmt.Sprintf("### %s\n\n%s", alert.Title, alert.Content),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return checkRobotResponse(body)
+}
+
+type weComNotifier struct {
+	url string
+}
+
+func (n *weComNotifier) Send(alert Alert) error {
+	body, err := postJSON(n.url, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"content": fmt.Sprintf("### %s\n%s", alert.Title, alert.Content),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return checkRobotResponse(body)
+}
+
+type emailNotifier struct {
+	channel models.AlertChannel
+}
+
+func (n *emailNotifier) message(alert Alert, to []string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", n.channel.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", alert.Title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(alert.Content, "\n", "\r\n"))
+	return buf.Bytes()
+}
+
+// Send 465 端口使用 SMTPS, 其他端口在服务端支持时使用 STARTTLS
+func (n *emailNotifier) Send(alert Alert) error {
+	ch := n.channel
+	to := splitList(ch.To)
+	addr := net.JoinHostPort(ch.SmtpHost, strconv.Itoa(ch.SmtpPort))
+	tlsConfig := &tls.Config{ServerName: ch.SmtpHost}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if ch.SmtpPort == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	client, err := smtp.NewClient(conn, ch.SmtpHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok && ch.SmtpPort != 465 {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if ch.SmtpUsername != "" {
+		if err := client.Auth(smtp.PlainAuth("", ch.SmtpUsername, ch.SmtpPassword, ch.SmtpHost)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(ch.From); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(n.message(alert, to)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package alert 按规则评估集群事件与节点状态, 并通过 Webhook、邮件、钉钉、企业微信发送通知
+package alert
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	defaultDedupMinutes    = 30
+	defaultNotReadyMinutes = 5
+)
+
+// Alert is a fired alert, also the payload sent to webhook channels.
+type Alert struct {
+	RuleID      uint      `json:"ruleId"`
+	RuleName    string    `json:"ruleName"`
+	ClusterID   uint      `json:"clusterId"`
+	ClusterName string    `json:"clusterName"`
+	Namespace   string    `json:"namespace"`
+	Object      string    `json:"object"`
+	Fingerprint string    `json:"fingerprint"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	FiredAt     time.Time `json:"firedAt"`
+}
+
+// ValidateRule 校验告警规则
+func ValidateRule(rule models.AlertRule) error {
+	switch rule.Type {
+	case models.AlertRuleEvent:
+		if rule.EventType != "" && rule.EventType != v1.EventTypeNormal && rule.EventType != v1.EventTypeWarning {
+			return fmt.Errorf("事件类型只能为 Normal 或 Warning")
+		}
+		if rule.EventType == "" && rule.Reasons == "" && rule.InvolvedKind == "" {
+			return fmt.Errorf("事件规则至少需要指定事件类型、原因或关联对象类型之一")
+		}
+		// 事件规则只读取归档的事件, 未开启归档时永远不会触发
+		if rule.Enable && !common.CONFIG.Kubernetes.EventArchive {
+			return fmt.Errorf("事件规则依赖事件归档, 请先在配置中开启 kubernetes.event-archive")
+		}
+	case models.AlertRuleNodeNotReady:
+		if rule.NotReadyMinutes < 0 {
+			return fmt.Errorf("NotReady持续时间不能为负数")
+		}
+	default:
+		return fmt.Errorf("不支持的规则类型: %s", rule.Type)
+	}
+	if rule.DedupMinutes < 0 {
+		return fmt.Errorf("去重窗口不能为负数")
+	}
+	if _, err := ParseChannelIDs(rule.ChannelIDs); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseChannelIDs 解析逗号分隔的通知渠道ID
+func ParseChannelIDs(s string) ([]uint, error) {
+	var ids []uint
+	for _, item := range splitList(s) {
+		id, err := strconv.ParseUint(item, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("通知渠道ID不合法: %s", item)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+func dedupWindow(rule models.AlertRule) time.Duration {
+	if rule.DedupMinutes > 0 {
+		return time.Duration(rule.DedupMinutes) * time.Minute
+	}
+	return defaultDedupMinutes * time.Minute
+}
+
+func notReadyThreshold(rule models.AlertRule) time.Duration {
+	if rule.NotReadyMinutes > 0 {
+		return time.Duration(rule.NotReadyMinutes) * time.Minute
+	}
+	return defaultNotReadyMinutes * time.Minute
+}
+
+func fingerprint(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "/")))
+	return hex.EncodeToString(sum[:])
+}
+
+// matchEvent 规则中未设置的条件不参与匹配
+func matchEvent(rule models.AlertRule, record models.EventRecord) bool {
+	if rule.ClusterID != 0 && rule.ClusterID != record.ClusterID {
+		return false
+	}
+	if rule.Namespace != "" && rule.Namespace != record.Namespace {
+		return false
+	}
+	if rule.EventType != "" && rule.EventType != record.Type {
+		return false
+	}
+	if rule.InvolvedKind != "" && rule.InvolvedKind != record.InvolvedKind {
+		return false
+	}
+	if reasons := splitList(rule.Reasons); len(reasons) > 0 {
+		for _, reason := range reasons {
+			if reason == record.Reason {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// eventAlert 同一对象的同一原因使用相同指纹, 崩溃循环的 Pod 在去重窗口内只通知一次
+func eventAlert(rule models.AlertRule, clusterName string, record models.EventRecord) Alert {
+	object := fmt.Sprintf("%s/%s", record.InvolvedKind, record.InvolvedName)
+	return Alert{
+		RuleID:      rule.ID,
+		RuleName:    rule.Name,
+		ClusterID:   record.ClusterID,
+		ClusterName: clusterName,
+		Namespace:   record.Namespace,
+		Object:      record.InvolvedName,
+		Fingerprint: fingerprint(fmt.Sprint(record.ClusterID), record.Namespace, object, record.Reason),
+		Title:       fmt.Sprintf("[%s] %s %s %s", rule.Name, record.Type, record.Reason, object),
+		Content: strings.Join([]string{
+			fmt.Sprintf("- 集群: %s", clusterName),
+			fmt.Sprintf("- 命名空间: %s", record.Namespace),
+			fmt.Sprintf("- 对象: %s", object),
+			fmt.Sprintf("- 原因: %s", record.Reason),
+			fmt.Sprintf("- 次数: %d", record.Count),
+			fmt.Sprintf("- 最近发生: %s", record.LastTimestamp.String()),
+			fmt.Sprintf("- 内容: %s", record.Message),
+		}, "\n"),
+		FiredAt: record.LastTimestamp.Time,
+	}
+}
+
+// nodeNotReadySince 节点 Ready 条件不为 True 时返回状态变化时间
+func nodeNotReadySince(node v1.Node) (time.Time, bool) {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			if condition.Status == v1.ConditionTrue {
+				return time.Time{}, false
+			}
+			return condition.LastTransitionTime.Time, true
+		}
+	}
+	// 没有 Ready 条件的节点从创建时间开始计算
+	return node.CreationTimestamp.Time, true
+}
+
+func nodeAlerts(rule models.AlertRule, clusterID uint, clusterName string, nodes []v1.Node, now time.Time) []Alert {
+	threshold := notReadyThreshold(rule)
+	var alerts []Alert
+	for _, node := range nodes {
+		since, notReady := nodeNotReadySince(node)
+		if !notReady || now.Sub(since) < threshold {
+			continue
+		}
+		alerts = append(alerts, Alert{
+			RuleID:      rule.ID,
+			RuleName:    rule.Name,
+			ClusterID:   clusterID,
+			ClusterName: clusterName,
+			Object:      node.Name,
+			Fingerprint: fingerprint(fmt.Sprint(clusterID), "Node", node.Name, "NotReady"),
+			Title:       fmt.Sprintf("[%s] 节点 %s NotReady", rule.Name, node.Name),
+			Content: strings.Join([]string{
+				fmt.Sprintf("- 集群: %s", clusterName),
+				fmt.Sprintf("- 节点: %s", node.Name),
+				fmt.Sprintf("- NotReady 开始时间: %s", since.Format(models.SecLocalTimeFormat)),
+				fmt.Sprintf("- 持续时间: %s", now.Sub(since).Truncate(time.Second)),
+			}, "\n"),
+			FiredAt: now,
+		})
+	}
+	return alerts
+}
+
+// silenced 静默规则中未设置的条件不参与匹配
+func silenced(silences []models.AlertSilence, alert Alert) bool {
+	for _, s := range silences {
+		if s.RuleID != 0 && s.RuleID != alert.RuleID {
+			continue
+		}
+		if s.ClusterID != 0 && s.ClusterID != alert.ClusterID {
+			continue
+		}
+		if s.Namespace != "" && s.Namespace != alert.Namespace {
+			continue
+		}
+		if s.Object != "" && !strings.HasPrefix(alert.Object, s.Object) {
+			continue
+		}
+		return true
+	}
+	return false
+}
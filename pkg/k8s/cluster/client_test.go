@@ -33,7 +33,7 @@ func TestGetNodeList(t *testing.T) {
 	overrides := &clientcmd.ConfigOverrides{ClusterInfo: clientcmdapi.Cluster{InsecureSkipTLSVerify: true}}
 	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
 	if err != nil {
-		log.Fatalf("Couldn't get Kubernetes default config: %s", err)
+		t.Skipf("Couldn't get Kubernetes default config: %s", err)
 	}
 
 	client, err := kubernetes.NewForConfig(config)
@@ -18,14 +18,13 @@ package cluster
 
 import (
 	"context"
+	"fmt"
 	"github.com/dnsjia/luban/common"
 	"github.com/dnsjia/luban/models"
 	"github.com/dnsjia/luban/tools"
-	"github.com/prometheus/common/expfmt"
 	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"strings"
 )
 
 func GetClusterVersion(c *kubernetes.Clientset) (string, error) {
@@ -60,22 +59,18 @@ func GetClusterNodesRunningStatus(c *kubernetes.Clientset, m *models.ClusterNode
 	nodes, err := c.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		common.LOG.Error("get nodes err", zap.Any("err: ", err))
+		return m
 	}
 
 	var ready int = 0
 	var unready int = 0
 	for _, node := range nodes.Items {
-		listNode, _ := c.CoreV1().Nodes().Get(context.TODO(), node.ObjectMeta.Name, metav1.GetOptions{})
-
-		if len(listNode.Status.Conditions) >= 0 {
-			if string(listNode.Status.Conditions[len(listNode.Status.Conditions)-1].Status) == "True" {
-				ready += 1
-			} else {
-				unready += 1
-			}
+		// 节点刚加入集群时可能还没有任何condition, 视为未就绪
+		if len(node.Status.Conditions) > 0 &&
+			string(node.Status.Conditions[len(node.Status.Conditions)-1].Status) == "True" {
+			ready += 1
 		} else {
-			common.LOG.Error("get nodes ready err")
-			return &models.ClusterNodesStatus{}
+			unready += 1
 		}
 	}
 	m.Ready = ready
@@ -83,79 +78,39 @@ func GetClusterNodesRunningStatus(c *kubernetes.Clientset, m *models.ClusterNode
 	return m
 }
 
-func GetClusterInfo(c *kubernetes.Clientset) *models.ClusterNodesStatus {
-	/*
-		获取集群信息
-	*/
+// GetClusterInfo 获取集群概览, 资源请求量来自集群配置的指标来源, 指标不可用时仅返回节点状态
+func GetClusterInfo(c *kubernetes.Clientset, source models.ClusterMetricsSource) *models.ClusterNodesStatus {
 	var node models.ClusterNodesStatus
-	//nodeNumber, _ := GetClusterNodesNumber(c)
-	//node.Total = nodeNumber
 
 	// eg: node节点不健康
 	_ = GetClusterNodesRunningStatus(c, &node)
+	node.NodeCount = node.Ready + node.UnReady
 
-	//namespace, deployment, pod := GetNodeResource(c)
-	//node.Namespace = namespace
-	//node.Deployment = deployment
-	//node.Pod = pod
-
-	data, _ := c.RESTClient().Get().AbsPath("/api/v1/namespaces/kube-system/services/tke-kube-state-metrics:http-metrics/proxy/metrics").DoRaw(context.TODO())
-
-	var parser expfmt.TextParser
-	mf, err := parser.TextToMetricFamilies(strings.NewReader(string(data)))
+	totals, used, err := getResourceTotals(c, source)
 	if err != nil {
-		common.LOG.Error("解析metrics错误", zap.Any("err:", err))
-		return nil
+		common.LOG.Warn("获取集群资源指标失败", zap.Any("err", err))
+		node.MetricsMessage = fmt.Sprintf("metrics unavailable: %v", err)
+		return &node
 	}
+	node.MetricsAvailable = true
+	node.MetricsSource = used
 
-	var (
-		// cpuUsage
-		kubePodContainerResourceRequestsCpuCores float64 = 0
-		kubeNodeStatusCapacityCpuCores           float64 = 0
-		// memoryUsage
-		kubePodContainerResourceRequestsMemoryBytes float64 = 0
-		kubeNodeStatusAllocatableMemoryBytes        float64 = 0
-	)
-
-	for metricIndex, metricValue := range mf {
-		// sum(kube_pod_container_resource_requests_cpu_cores{node!~"master-.*"})/sum(kube_node_status_capacity_cpu_cores{node!~"master-.*"})*100
-		if metricIndex == "kube_pod_container_resource_requests_cpu_cores" {
-			for _, metric := range metricValue.GetMetric() {
-				kubePodContainerResourceRequestsCpuCores += *metric.Gauge.Value
-			}
-		}
-		if metricIndex == "kube_node_status_capacity_cpu_cores" {
-			for _, metric := range metricValue.GetMetric() {
-				kubeNodeStatusCapacityCpuCores += *metric.Gauge.Value
-			}
-		}
-		// sum(kube_pod_container_resource_requests_memory_bytes{node!~"master-.*"})/sum(kube_node_status_allocatable_memory_bytes{node!~"master-.*"})*100
-		if metricIndex == "kube_pod_container_resource_requests_memory_bytes" {
-			for _, metric := range metricValue.GetMetric() {
-				kubePodContainerResourceRequestsMemoryBytes += *metric.Gauge.Value
-			}
-		}
-		if metricIndex == "kube_node_status_allocatable_memory_bytes" {
-			for _, metric := range metricValue.GetMetric() {
-				kubeNodeStatusAllocatableMemoryBytes += *metric.Gauge.Value
-			}
-		}
+	// sum(kube_pod_container_resource_requests_cpu_cores)/sum(kube_node_status_capacity_cpu_cores)*100
+	node.CpuCore = tools.ParseFloat2F(totals.CpuRequests)
+	node.CpuCapacityCore = tools.ParseFloat2F(totals.CpuCapacity)
+	node.CpuUsage = percentage(totals.CpuRequests, totals.CpuCapacity)
 
-		// 计算Node节点数量
-		if metricIndex == "kube_node_info" {
-			node.NodeCount = len(metricValue.Metric)
-		}
-
-	}
-
-	node.CpuCore = tools.ParseFloat2F(kubePodContainerResourceRequestsCpuCores)
-	node.CpuUsage = tools.ParseFloat2F(kubePodContainerResourceRequestsCpuCores / kubeNodeStatusCapacityCpuCores * 100)
-	node.CpuCapacityCore = tools.ParseFloat2F(kubeNodeStatusCapacityCpuCores)
-
-	node.MemoryUsed = tools.ParseFloat2F(kubePodContainerResourceRequestsMemoryBytes / 1024 / 1024 / 1024)
-	node.MemoryUsage = tools.ParseFloat2F(kubePodContainerResourceRequestsMemoryBytes / kubeNodeStatusAllocatableMemoryBytes * 100)
-	node.MemoryTotal = tools.ParseFloat2F(kubeNodeStatusAllocatableMemoryBytes / 1024 / 1024 / 1024)
+	// sum(kube_pod_container_resource_requests_memory_bytes)/sum(kube_node_status_allocatable_memory_bytes)*100
+	node.MemoryUsed = tools.ParseFloat2F(totals.MemoryRequests / 1024 / 1024 / 1024)
+	node.MemoryTotal = tools.ParseFloat2F(totals.MemoryCapacity / 1024 / 1024 / 1024)
+	node.MemoryUsage = percentage(totals.MemoryRequests, totals.MemoryCapacity)
 
 	return &node
+}
 
+func percentage(used, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return tools.ParseFloat2F(used / total * 100)
 }
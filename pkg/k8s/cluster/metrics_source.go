@@ -0,0 +1,299 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnsjia/luban/models"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"k8s.io/client-go/kubernetes"
+)
+
+// 未配置 kube-state-metrics 服务时依次探测的服务, 兼容社区版与TKE
+var defaultKsmServices = []string{
+	"kube-system/kube-state-metrics:http-metrics",
+	"monitoring/kube-state-metrics:http-metrics",
+	"kube-system/tke-kube-state-metrics:http-metrics",
+}
+
+// ksmServicePattern namespace/name:port
+var ksmServicePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?/[a-z0-9]([-a-z0-9]*[a-z0-9])?(:[a-z0-9]([-a-z0-9]*[a-z0-9])?)?$`)
+
+const metricsRequestTimeout = 10 * time.Second
+
+// resourceTotals 集群资源请求量与容量, CPU单位为核, 内存单位为字节
+type resourceTotals struct {
+	CpuRequests    float64
+	CpuCapacity    float64
+	MemoryRequests float64
+	MemoryCapacity float64
+}
+
+// metricNames 同一组指标在不同 kube-state-metrics 版本中的名称
+// v2 起资源指标合并为 kube_pod_container_resource_requests{resource="cpu"} 这类带 resource 标签的形式
+type metricNames struct {
+	CpuRequests    metricSelector
+	CpuCapacity    metricSelector
+	MemoryRequests metricSelector
+	MemoryCapacity metricSelector
+}
+
+type metricSelector struct {
+	Name     string
+	Resource string
+}
+
+func (s metricSelector) promQL() string {
+	if s.Resource == "" {
+		return fmt.Sprintf("sum(%s)", s.Name)
+	}
+	return fmt.Sprintf(`sum(%s{resource="%s"})`, s.Name, s.Resource)
+}
+
+var (
+	// kube-state-metrics v1.x
+	legacyMetricNames = metricNames{
+		CpuRequests:    metricSelector{Name: "kube_pod_container_resource_requests_cpu_cores"},
+		CpuCapacity:    metricSelector{Name: "kube_node_status_capacity_cpu_cores"},
+		MemoryRequests: metricSelector{Name: "kube_pod_container_resource_requests_memory_bytes"},
+		MemoryCapacity: metricSelector{Name: "kube_node_status_allocatable_memory_bytes"},
+	}
+	// kube-state-metrics v2.x
+	currentMetricNames = metricNames{
+		CpuRequests:    metricSelector{Name: "kube_pod_container_resource_requests", Resource: "cpu"},
+		CpuCapacity:    metricSelector{Name: "kube_node_status_capacity", Resource: "cpu"},
+		MemoryRequests: metricSelector{Name: "kube_pod_container_resource_requests", Resource: "memory"},
+		MemoryCapacity: metricSelector{Name: "kube_node_status_allocatable", Resource: "memory"},
+	}
+)
+
+// ValidateMetricsSource 校验集群指标来源配置
+func ValidateMetricsSource(source models.ClusterMetricsSource) error {
+	switch source.Type {
+	case "", models.MetricsSourceKSM:
+		if source.KsmService != "" && !ksmServicePattern.MatchString(source.KsmService) {
+			return fmt.Errorf("kube-state-metrics服务格式应为 namespace/name:port: %s", source.KsmService)
+		}
+	case models.MetricsSourcePrometheus:
+		u, err := url.Parse(source.PrometheusURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Prometheus地址不合法: %s", source.PrometheusURL)
+		}
+	default:
+		return fmt.Errorf("不支持的指标来源: %s", source.Type)
+	}
+	return nil
+}
+
+// getResourceTotals 根据集群配置的指标来源获取资源请求量与容量, 返回实际使用的来源描述
+func getResourceTotals(c kubernetes.Interface, source models.ClusterMetricsSource) (resourceTotals, string, error) {
+	if source.Type == models.MetricsSourcePrometheus {
+		totals, err := prometheusTotals(source)
+		return totals, source.PrometheusURL, err
+	}
+
+	services := defaultKsmServices
+	if source.KsmService != "" {
+		services = []string{source.KsmService}
+	}
+	var errs []string
+	for _, service := range services {
+		totals, err := ksmTotals(c, service)
+		if err == nil {
+			return totals, service, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", service, err))
+	}
+	return resourceTotals{}, "", errors.New(strings.Join(errs, "; "))
+}
+
+func ksmProxyPath(service string) string {
+	// namespace/name:port -> /api/v1/namespaces/namespace/services/name:port/proxy/metrics
+	parts := strings.SplitN(service, "/", 2)
+	return fmt.Sprintf("/api/v1/namespaces/%s/services/%s/proxy/metrics", parts[0], parts[1])
+}
+
+func ksmTotals(c kubernetes.Interface, service string) (resourceTotals, error) {
+	ctx, cancel := context.WithTimeout(context.TODO(), metricsRequestTimeout)
+	defer cancel()
+
+	data, err := c.CoreV1().RESTClient().Get().AbsPath(ksmProxyPath(service)).DoRaw(ctx)
+	if err != nil {
+		return resourceTotals{}, err
+	}
+	var parser expfmt.TextParser
+	mf, err := parser.TextToMetricFamilies(strings.NewReader(string(data)))
+	if err != nil {
+		return resourceTotals{}, fmt.Errorf("解析metrics错误: %v", err)
+	}
+	return totalsFromMetricFamilies(mf)
+}
+
+// totalsFromMetricFamilies 根据是否存在 v2 指标名自动选择命名方式
+func totalsFromMetricFamilies(mf map[string]*dto.MetricFamily) (resourceTotals, error) {
+	names := legacyMetricNames
+	if _, ok := mf[currentMetricNames.CpuRequests.Name]; ok {
+		names = currentMetricNames
+	} else if _, ok := mf[legacyMetricNames.CpuRequests.Name]; !ok {
+		return resourceTotals{}, errors.New("未找到 kube-state-metrics 资源指标")
+	}
+
+	sum := func(s metricSelector) float64 {
+		var total float64
+		family, ok := mf[s.Name]
+		if !ok {
+			return 0
+		}
+		for _, metric := range family.GetMetric() {
+			if s.Resource != "" && labelValue(metric, "resource") != s.Resource {
+				continue
+			}
+			total += metricValue(metric)
+		}
+		return total
+	}
+	return resourceTotals{
+		CpuRequests:    sum(names.CpuRequests),
+		CpuCapacity:    sum(names.CpuCapacity),
+		MemoryRequests: sum(names.MemoryRequests),
+		MemoryCapacity: sum(names.MemoryCapacity),
+	}, nil
+}
+
+func labelValue(metric *dto.Metric, name string) string {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
+
+func metricValue(metric *dto.Metric) float64 {
+	switch {
+	case metric.Gauge != nil:
+		return metric.Gauge.GetValue()
+	case metric.Untyped != nil:
+		return metric.Untyped.GetValue()
+	case metric.Counter != nil:
+		return metric.Counter.GetValue()
+	}
+	return 0
+}
+
+// promQueryResponse Prometheus /api/v1/query 返回结构
+type promQueryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Value []interface{} `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+var promHTTPClient = &http.Client{Timeout: metricsRequestTimeout}
+
+// promQuery 执行即时查询并对结果求和, 无数据时 found 为false
+func promQuery(baseURL, query string) (value float64, found bool, err error) {
+	endpoint := strings.TrimRight(baseURL, "/") + "/api/v1/query?" + url.Values{"query": {query}}.Encode()
+	resp, err := promHTTPClient.Get(endpoint)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, false, err
+	}
+	var result promQueryResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, false, fmt.Errorf("Prometheus返回异常(%d): %v", resp.StatusCode, err)
+	}
+	if result.Status != "success" {
+		return 0, false, fmt.Errorf("Prometheus查询失败: %s", result.Error)
+	}
+	if result.Data.ResultType != "vector" {
+		return 0, false, fmt.Errorf("PromQL需返回vector类型: %s", query)
+	}
+	for _, sample := range result.Data.Result {
+		if len(sample.Value) != 2 {
+			continue
+		}
+		s, _ := sample.Value[1].(string)
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("解析PromQL结果失败: %v", err)
+		}
+		value += v
+		found = true
+	}
+	return value, found, nil
+}
+
+func prometheusTotals(source models.ClusterMetricsSource) (resourceTotals, error) {
+	// 未自定义CPU请求量查询时, 先用 v2 指标名探测, 无数据再回退到 v1 指标名
+	names := currentMetricNames
+	if source.CpuRequestQuery == "" {
+		_, found, err := promQuery(source.PrometheusURL, currentMetricNames.CpuRequests.promQL())
+		if err != nil {
+			return resourceTotals{}, err
+		}
+		if !found {
+			names = legacyMetricNames
+		}
+	}
+
+	queryOf := func(custom string, s metricSelector) string {
+		if custom != "" {
+			return custom
+		}
+		return s.promQL()
+	}
+	var totals resourceTotals
+	queries := []struct {
+		query  string
+		target *float64
+	}{
+		{queryOf(source.CpuRequestQuery, names.CpuRequests), &totals.CpuRequests},
+		{queryOf(source.CpuCapacityQuery, names.CpuCapacity), &totals.CpuCapacity},
+		{queryOf(source.MemoryRequestQuery, names.MemoryRequests), &totals.MemoryRequests},
+		{queryOf(source.MemoryCapacityQuery, names.MemoryCapacity), &totals.MemoryCapacity},
+	}
+	for _, q := range queries {
+		value, _, err := promQuery(source.PrometheusURL, q.query)
+		if err != nil {
+			return resourceTotals{}, err
+		}
+		*q.target = value
+	}
+	return totals, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dnsjia/luban/models"
+	"github.com/prometheus/common/expfmt"
+)
+
+const ksmV1Metrics = `# TYPE kube_pod_container_resource_requests_cpu_cores gauge
+kube_pod_container_resource_requests_cpu_cores{pod="a",node="n1"} 0.5
+kube_pod_container_resource_requests_cpu_cores{pod="b",node="n1"} 0.25
+# TYPE kube_node_status_capacity_cpu_cores gauge
+kube_node_status_capacity_cpu_cores{node="n1"} 4
+# TYPE kube_pod_container_resource_requests_memory_bytes gauge
+kube_pod_container_resource_requests_memory_bytes{pod="a",node="n1"} 1073741824
+# TYPE kube_node_status_allocatable_memory_bytes gauge
+kube_node_status_allocatable_memory_bytes{node="n1"} 8589934592
+`
+
+const ksmV2Metrics = `# TYPE kube_pod_container_resource_requests gauge
+kube_pod_container_resource_requests{pod="a",node="n1",resource="cpu",unit="core"} 0.5
+kube_pod_container_resource_requests{pod="b",node="n1",resource="cpu",unit="core"} 0.25
+kube_pod_container_resource_requests{pod="a",node="n1",resource="memory",unit="byte"} 1073741824
+# TYPE kube_node_status_capacity gauge
+kube_node_status_capacity{node="n1",resource="cpu",unit="core"} 4
+kube_node_status_capacity{node="n1",resource="memory",unit="byte"} 9663676416
+# TYPE kube_node_status_allocatable gauge
+kube_node_status_allocatable{node="n1",resource="cpu",unit="core"} 3.8
+kube_node_status_allocatable{node="n1",resource="memory",unit="byte"} 8589934592
+`
+
+func TestTotalsFromMetricFamilies(t *testing.T) {
+	want := resourceTotals{CpuRequests: 0.75, CpuCapacity: 4, MemoryRequests: 1073741824, MemoryCapacity: 8589934592}
+	for name, text := range map[string]string{"v1": ksmV1Metrics, "v2": ksmV2Metrics} {
+		var parser expfmt.TextParser
+		mf, err := parser.TextToMetricFamilies(strings.NewReader(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := totalsFromMetricFamilies(mf)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestPrometheusTotalsFallsBackToLegacyNames(t *testing.T) {
+	values := map[string]string{
+		"sum(kube_pod_container_resource_requests_cpu_cores)":    "2",
+		"sum(kube_node_status_capacity_cpu_cores)":               "8",
+		"sum(kube_pod_container_resource_requests_memory_bytes)": "1024",
+		"sum(kube_node_status_allocatable_memory_bytes)":         "4096",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		value, ok := values[r.URL.Query().Get("query")]
+		if !ok {
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1634000000,"` + value + `"]}]}}`))
+	}))
+	defer server.Close()
+
+	got, err := prometheusTotals(models.ClusterMetricsSource{Type: models.MetricsSourcePrometheus, PrometheusURL: server.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := resourceTotals{CpuRequests: 2, CpuCapacity: 8, MemoryRequests: 1024, MemoryCapacity: 4096}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestValidateMetricsSource(t *testing.T) {
+	valid := []models.ClusterMetricsSource{
+		{},
+		{Type: models.MetricsSourceKSM, KsmService: "monitoring/kube-state-metrics:http-metrics"},
+		{Type: models.MetricsSourcePrometheus, PrometheusURL: "http://prometheus.monitoring:9090"},
+	}
+	for _, source := range valid {
+		if err := ValidateMetricsSource(source); err != nil {
+			t.Errorf("%+v: unexpected error %v", source, err)
+		}
+	}
+	invalid := []models.ClusterMetricsSource{
+		{KsmService: "kube-state-metrics"},
+		{Type: models.MetricsSourcePrometheus, PrometheusURL: "prometheus:9090"},
+		{Type: "influxdb"},
+	}
+	for _, source := range invalid {
+		if err := ValidateMetricsSource(source); err == nil {
+			t.Errorf("%+v: expected error", source)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"context"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	"github.com/dnsjia/luban/pkg/k8s/dataselect"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// CustomResourceDefinitionResource is the GVR of apiextensions.k8s.io/v1 CRDs, CRDs are read through the dynamic
+// client as unstructured objects so the apiextensions clientset is not needed.
+var CustomResourceDefinitionResource = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+// CRD scopes
+const (
+	ScopeNamespaced = "Namespaced"
+	ScopeCluster    = "Cluster"
+)
+
+// Names mirrors CustomResourceDefinitionNames.
+type Names struct {
+	Plural     string   `json:"plural"`
+	Singular   string   `json:"singular,omitempty"`
+	ShortNames []string `json:"shortNames,omitempty"`
+	Kind       string   `json:"kind"`
+	ListKind   string   `json:"listKind,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+}
+
+// PrinterColumn mirrors CustomResourceColumnDefinition.
+type PrinterColumn struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Format      string `json:"format,omitempty"`
+	Description string `json:"description,omitempty"`
+	Priority    int32  `json:"priority,omitempty"`
+	JSONPath    string `json:"jsonPath"`
+}
+
+// Version mirrors CustomResourceDefinitionVersion without the schema.
+type Version struct {
+	Name                     string          `json:"name"`
+	Served                   bool            `json:"served"`
+	Storage                  bool            `json:"storage"`
+	Deprecated               bool            `json:"deprecated,omitempty"`
+	AdditionalPrinterColumns []PrinterColumn `json:"additionalPrinterColumns,omitempty"`
+}
+
+// Condition mirrors CustomResourceDefinitionCondition.
+type Condition struct {
+	Type               string      `json:"type"`
+	Status             string      `json:"status"`
+	LastTransitionTime metaV1.Time `json:"lastTransitionTime,omitempty"`
+	Reason             string      `json:"reason,omitempty"`
+	Message            string      `json:"message,omitempty"`
+}
+
+// definition is the subset of apiextensions.k8s.io/v1 CustomResourceDefinition used by the UI.
+type definition struct {
+	metaV1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              struct {
+		Group    string    `json:"group"`
+		Names    Names     `json:"names"`
+		Scope    string    `json:"scope"`
+		Versions []Version `json:"versions"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []Condition `json:"conditions,omitempty"`
+	} `json:"status,omitempty"`
+}
+
+// CustomResourceDefinition is a presentation layer view of a CRD.
+type CustomResourceDefinition struct {
+	ObjectMeta k8s.ObjectMeta `json:"objectMeta"`
+	TypeMeta   k8s.TypeMeta   `json:"typeMeta"`
+
+	Group   string `json:"group"`
+	Kind    string `json:"kind"`
+	Plural  string `json:"plural"`
+	Scope   string `json:"scope"`
+	Version string `json:"version"`
+
+	// Established is true once the API server serves the resource.
+	Established bool `json:"established"`
+}
+
+// CustomResourceDefinitionList contains a list of CRDs in the cluster.
+type CustomResourceDefinitionList struct {
+	ListMeta k8s.ListMeta               `json:"listMeta"`
+	Items    []CustomResourceDefinition `json:"items"`
+}
+
+// CustomResourceDefinitionDetail contains CRD details.
+type CustomResourceDefinitionDetail struct {
+	// Extends list item structure.
+	CustomResourceDefinition `json:",inline"`
+
+	Names      Names       `json:"names"`
+	Versions   []Version   `json:"versions"`
+	Conditions []Condition `json:"conditions"`
+}
+
+// GetCustomResourceDefinitionList returns all CRDs in the cluster.
+func GetCustomResourceDefinitionList(client dynamic.Interface, dsQuery *dataselect.DataSelectQuery) (*CustomResourceDefinitionList, error) {
+	common.LOG.Info("Getting list of custom resource definitions")
+	list, err := client.Resource(CustomResourceDefinitionResource).List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+
+	crdCells, filteredTotal := dataselect.GenericDataSelectWithFilter(toCells(list.Items), dsQuery)
+	result := &CustomResourceDefinitionList{
+		ListMeta: k8s.ListMeta{TotalItems: filteredTotal},
+		Items:    make([]CustomResourceDefinition, 0),
+	}
+	for _, item := range fromCells(crdCells) {
+		crd, err := toDefinition(&item)
+		if err != nil {
+			return nil, err
+		}
+		result.Items = append(result.Items, toCustomResourceDefinition(crd))
+	}
+	return result, nil
+}
+
+// GetCustomResourceDefinitionDetail returns detailed information about a CRD.
+func GetCustomResourceDefinitionDetail(client dynamic.Interface, name string) (*CustomResourceDefinitionDetail, error) {
+	crd, err := getDefinition(client, name)
+	if err != nil {
+		return nil, err
+	}
+	conditions := crd.Status.Conditions
+	if conditions == nil {
+		conditions = make([]Condition, 0)
+	}
+	return &CustomResourceDefinitionDetail{
+		CustomResourceDefinition: toCustomResourceDefinition(crd),
+		Names:                    crd.Spec.Names,
+		Versions:                 crd.Spec.Versions,
+		Conditions:               conditions,
+	}, nil
+}
+
+func getDefinition(client dynamic.Interface, name string) (*definition, error) {
+	raw, err := client.Resource(CustomResourceDefinitionResource).Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return toDefinition(raw)
+}
+
+func toDefinition(obj *unstructured.Unstructured) (*definition, error) {
+	crd := &definition{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, crd); err != nil {
+		return nil, fmt.Errorf("解析CRD %s 失败: %v", obj.GetName(), err)
+	}
+	return crd, nil
+}
+
+func toCustomResourceDefinition(crd *definition) CustomResourceDefinition {
+	result := CustomResourceDefinition{
+		ObjectMeta: k8s.NewObjectMeta(crd.ObjectMeta),
+		TypeMeta:   k8s.NewTypeMeta(k8s.ResourceKindCustomResourceDefinition),
+		Group:      crd.Spec.Group,
+		Kind:       crd.Spec.Names.Kind,
+		Plural:     crd.Spec.Names.Plural,
+		Scope:      crd.Spec.Scope,
+	}
+	if version, err := crd.version(""); err == nil {
+		result.Version = version.Name
+	}
+	for _, condition := range crd.Status.Conditions {
+		if condition.Type == "Established" && condition.Status == string(metaV1.ConditionTrue) {
+			result.Established = true
+		}
+	}
+	return result
+}
+
+// version returns the requested served version, an empty name selects the storage version when it is
+// served and the first served version otherwise.
+func (crd *definition) version(name string) (*Version, error) {
+	var firstServed *Version
+	for i := range crd.Spec.Versions {
+		version := &crd.Spec.Versions[i]
+		if !version.Served {
+			continue
+		}
+		if name != "" && version.Name == name {
+			return version, nil
+		}
+		if name == "" && version.Storage {
+			return version, nil
+		}
+		if firstServed == nil {
+			firstServed = version
+		}
+	}
+	if name == "" && firstServed != nil {
+		return firstServed, nil
+	}
+	return nil, fmt.Errorf("CRD %s 未提供版本 %s", crd.Name, name)
+}
+
+// resource returns the GVR of the custom objects for the given version.
+func (crd *definition) resource(version string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: crd.Spec.Group, Version: version, Resource: crd.Spec.Names.Plural}
+}
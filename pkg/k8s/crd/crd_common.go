@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"github.com/dnsjia/luban/pkg/k8s/dataselect"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// The code below allows to perform complex data section on []unstructured.Unstructured
+
+type UnstructuredCell unstructured.Unstructured
+
+func (self UnstructuredCell) GetProperty(name dataselect.PropertyName) dataselect.ComparableValue {
+	obj := unstructured.Unstructured(self)
+	switch name {
+	case dataselect.NameProperty:
+		return dataselect.StdComparableString(obj.GetName())
+	case dataselect.CreationTimestampProperty:
+		return dataselect.StdComparableTime(obj.GetCreationTimestamp().Time)
+	case dataselect.NamespaceProperty:
+		return dataselect.StdComparableString(obj.GetNamespace())
+	default:
+		// if name is not supported then just return a constant dummy value, sort will have no effect.
+		return nil
+	}
+}
+
+func toCells(std []unstructured.Unstructured) []dataselect.DataCell {
+	cells := make([]dataselect.DataCell, len(std))
+	for i := range std {
+		cells[i] = UnstructuredCell(std[i])
+	}
+	return cells
+}
+
+func fromCells(cells []dataselect.DataCell) []unstructured.Unstructured {
+	std := make([]unstructured.Unstructured, len(cells))
+	for i := range std {
+		std[i] = unstructured.Unstructured(cells[i].(UnstructuredCell))
+	}
+	return std
+}
+
+// objectMetaOf builds the typed ObjectMeta used by the presentation layer from an unstructured object.
+func objectMetaOf(obj *unstructured.Unstructured) metaV1.ObjectMeta {
+	return metaV1.ObjectMeta{
+		Name:              obj.GetName(),
+		Namespace:         obj.GetNamespace(),
+		Labels:            obj.GetLabels(),
+		Annotations:       obj.GetAnnotations(),
+		CreationTimestamp: obj.GetCreationTimestamp(),
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
+	"github.com/dnsjia/luban/pkg/k8s/dataselect"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/jsonpath"
+	"reflect"
+	"strings"
+)
+
+// CustomObject is a presentation layer view of a custom resource object.
+type CustomObject struct {
+	ObjectMeta k8s.ObjectMeta `json:"objectMeta"`
+	TypeMeta   k8s.TypeMeta   `json:"typeMeta"`
+
+	// Columns holds the values of the CRD printer columns in the order of CustomObjectList.Columns.
+	Columns []string `json:"columns"`
+}
+
+// CustomObjectList contains a list of custom objects of a CRD.
+type CustomObjectList struct {
+	ListMeta k8s.ListMeta `json:"listMeta"`
+
+	// Columns are the additionalPrinterColumns of the listed version.
+	Columns []PrinterColumn `json:"columns"`
+	Items   []CustomObject  `json:"items"`
+}
+
+// CustomObjectDetail contains a custom object with its printer column values.
+type CustomObjectDetail struct {
+	CustomObject `json:",inline"`
+
+	PrinterColumns []PrinterColumn        `json:"printerColumns"`
+	Object         map[string]interface{} `json:"object"`
+}
+
+// GetCustomObjectList lists the objects of the CRD named crdName, version may be empty to use the preferred version.
+// Cluster scoped CRDs ignore nsQuery.
+func GetCustomObjectList(client dynamic.Interface, crdName, version string, nsQuery *k8scommon.NamespaceQuery, dsQuery *dataselect.DataSelectQuery) (*CustomObjectList, error) {
+	crd, err := getDefinition(client, crdName)
+	if err != nil {
+		return nil, err
+	}
+	crdVersion, err := crd.version(version)
+	if err != nil {
+		return nil, err
+	}
+	common.LOG.Info(fmt.Sprintf("Getting list of %s in the namespace %s", crdName, nsQuery.ToRequestParam()))
+
+	var list *unstructured.UnstructuredList
+	if crd.Spec.Scope == ScopeNamespaced {
+		list, err = client.Resource(crd.resource(crdVersion.Name)).Namespace(nsQuery.ToRequestParam()).List(context.TODO(), k8s.ListEverything)
+	} else {
+		list, err = client.Resource(crd.resource(crdVersion.Name)).List(context.TODO(), k8s.ListEverything)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var filteredItems []unstructured.Unstructured
+	for _, item := range list.Items {
+		if crd.Spec.Scope != ScopeNamespaced || nsQuery.Matches(item.GetNamespace()) {
+			filteredItems = append(filteredItems, item)
+		}
+	}
+
+	columns, err := parsePrinterColumns(crdVersion.AdditionalPrinterColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	objectCells, filteredTotal := dataselect.GenericDataSelectWithFilter(toCells(filteredItems), dsQuery)
+	result := &CustomObjectList{
+		ListMeta: k8s.ListMeta{TotalItems: filteredTotal},
+		Columns:  printerColumnsOf(crdVersion),
+		Items:    make([]CustomObject, 0),
+	}
+	for _, item := range fromCells(objectCells) {
+		result.Items = append(result.Items, toCustomObject(&item, crd, columns))
+	}
+	return result, nil
+}
+
+// GetCustomObjectDetail returns a single custom object of the CRD named crdName.
+func GetCustomObjectDetail(client dynamic.Interface, crdName, version, namespace, name string) (*CustomObjectDetail, error) {
+	crd, err := getDefinition(client, crdName)
+	if err != nil {
+		return nil, err
+	}
+	crdVersion, err := crd.version(version)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj *unstructured.Unstructured
+	if crd.Spec.Scope == ScopeNamespaced {
+		obj, err = client.Resource(crd.resource(crdVersion.Name)).Namespace(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	} else {
+		obj, err = client.Resource(crd.resource(crdVersion.Name)).Get(context.TODO(), name, metaV1.GetOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := parsePrinterColumns(crdVersion.AdditionalPrinterColumns)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomObjectDetail{
+		CustomObject:   toCustomObject(obj, crd, columns),
+		PrinterColumns: printerColumnsOf(crdVersion),
+		Object:         obj.Object,
+	}, nil
+}
+
+func printerColumnsOf(version *Version) []PrinterColumn {
+	if version.AdditionalPrinterColumns == nil {
+		return make([]PrinterColumn, 0)
+	}
+	return version.AdditionalPrinterColumns
+}
+
+func toCustomObject(obj *unstructured.Unstructured, crd *definition, columns []*jsonpath.JSONPath) CustomObject {
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		values[i] = columnValue(column, obj.Object)
+	}
+	return CustomObject{
+		ObjectMeta: k8s.NewObjectMeta(objectMetaOf(obj)),
+		TypeMeta:   k8s.NewTypeMeta(k8s.ResourceKind(crd.Spec.Names.Kind)),
+		Columns:    values,
+	}
+}
+
+// parsePrinterColumns compiles the printer column JSONPaths the same way kubectl does, e.g. ".spec.replicas".
+func parsePrinterColumns(columns []PrinterColumn) ([]*jsonpath.JSONPath, error) {
+	parsers := make([]*jsonpath.JSONPath, len(columns))
+	for i, column := range columns {
+		parser := jsonpath.New(column.Name).AllowMissingKeys(true)
+		if err := parser.Parse(fmt.Sprintf("{%s}", column.JSONPath)); err != nil {
+			return nil, fmt.Errorf("解析打印列 %s 失败: %v", column.Name, err)
+		}
+		parsers[i] = parser
+	}
+	return parsers, nil
+}
+
+// columnValue evaluates the column on the object, missing values are returned as empty string and
+// non scalar values are rendered as JSON.
+func columnValue(parser *jsonpath.JSONPath, object map[string]interface{}) string {
+	results, err := parser.FindResults(object)
+	if err != nil || len(results) == 0 {
+		return ""
+	}
+
+	values := make([]string, 0, len(results[0]))
+	for _, result := range results[0] {
+		if !result.IsValid() || !result.CanInterface() {
+			continue
+		}
+		value := result.Interface()
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Map, reflect.Slice:
+			var buf bytes.Buffer
+			if err := json.NewEncoder(&buf).Encode(value); err == nil {
+				values = append(values, strings.TrimSpace(buf.String()))
+			}
+		default:
+			if value != nil {
+				values = append(values, fmt.Sprint(value))
+			}
+		}
+	}
+	return strings.Join(values, ",")
+}
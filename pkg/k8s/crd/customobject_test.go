@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"testing"
+)
+
+func TestGetCustomObjectDetail(t *testing.T) {
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": "certificates.cert-manager.io"},
+		"spec": map[string]interface{}{
+			"group": "cert-manager.io",
+			"scope": "Namespaced",
+			"names": map[string]interface{}{"kind": "Certificate", "plural": "certificates"},
+			"versions": []interface{}{
+				map[string]interface{}{"name": "v1alpha2", "served": false, "storage": false},
+				map[string]interface{}{
+					"name": "v1", "served": true, "storage": true,
+					"additionalPrinterColumns": []interface{}{
+						map[string]interface{}{"name": "Ready", "type": "string", "jsonPath": `.status.conditions[?(@.type=="Ready")].status`},
+						map[string]interface{}{"name": "Secret", "type": "string", "jsonPath": ".spec.secretName"},
+						map[string]interface{}{"name": "Issuer", "type": "string", "jsonPath": ".spec.issuerRef.name", "priority": int64(1)},
+					},
+				},
+			},
+		},
+	}}
+	certificate := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "dev"},
+		"spec":       map[string]interface{}{"secretName": "web-tls"},
+		"status": map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+		},
+	}}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		CustomResourceDefinitionResource:                                    "CustomResourceDefinitionList",
+		{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}: "CertificateList",
+	}, crd, certificate)
+
+	detail, err := GetCustomObjectDetail(client, "certificates.cert-manager.io", "", "dev", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(detail.PrinterColumns) != 3 || detail.PrinterColumns[2].Priority != 1 {
+		t.Errorf("unexpected printer columns: %+v", detail.PrinterColumns)
+	}
+	want := []string{"True", "web-tls", ""}
+	for i := range want {
+		if detail.Columns[i] != want[i] {
+			t.Errorf("column %d: got %q, want %q", i, detail.Columns[i], want[i])
+		}
+	}
+	if detail.TypeMeta.Kind != "Certificate" {
+		t.Errorf("got kind %s", detail.TypeMeta.Kind)
+	}
+
+	if _, err := GetCustomObjectDetail(client, "certificates.cert-manager.io", "v1alpha2", "dev", "web"); err == nil {
+		t.Error("expected error for version that is not served")
+	}
+}
@@ -24,6 +24,7 @@ import (
 	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
 	"github.com/dnsjia/luban/pkg/k8s/dataselect"
 	"github.com/dnsjia/luban/pkg/k8s/event"
+	"github.com/dnsjia/luban/pkg/k8s/informer"
 	"go.uber.org/zap"
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
@@ -79,10 +80,10 @@ type StatusInfo struct {
 
 func GetDaemonSetList(client *kubernetes.Clientset, nsQuery *k8scommon.NamespaceQuery, dsQuery *dataselect.DataSelectQuery) (*DaemonSetList, error) {
 	channels := &k8scommon.ResourceChannels{
-		DaemonSetList: k8scommon.GetDaemonSetListChannel(client, nsQuery, 1),
-		ServiceList:   k8scommon.GetServiceListChannel(client, nsQuery, 1),
-		PodList:       k8scommon.GetPodListChannel(client, nsQuery, 1),
-		EventList:     k8scommon.GetEventListChannel(client, nsQuery, 1),
+		DaemonSetList: informer.GetDaemonSetListChannel(client, nsQuery, 1),
+		ServiceList:   informer.GetServiceListChannel(client, nsQuery, 1),
+		PodList:       informer.GetPodListChannel(client, nsQuery, 1),
+		EventList:     informer.GetEventListChannel(client, nsQuery, 1),
 	}
 
 	return GetDaemonSetListFromChannels(channels, dsQuery)
@@ -132,3 +132,6 @@ func NewDataSelectQuery(paginationQuery *PaginationQuery, sortQuery *SortQuery,
 
 // DefaultDataSelect downloads first 10 items from page 1 with no sort and no metrics.
 var DefaultDataSelect = NewDataSelectQuery(DefaultPagination, NoSort, NoFilter)
+
+// NoDataSelect is an option for no data select (same data will be returned).
+var NoDataSelect = NewDataSelectQuery(NoPagination, NoSort, NoFilter)
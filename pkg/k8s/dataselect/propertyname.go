@@ -30,4 +30,6 @@ const (
 	FirstSeenProperty         = "firstSeen"
 	LastSeenProperty          = "lastSeen"
 	ReasonProperty            = "reason"
+	CPUUsageProperty          = "cpuUsage"
+	MemoryUsageProperty       = "memoryUsage"
 )
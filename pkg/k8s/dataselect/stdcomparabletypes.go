@@ -44,6 +44,20 @@ func (self StdComparableTime) Contains(otherV ComparableValue) bool {
 	return self.Compare(otherV) == 0
 }
 
+// StdComparableInt64 is used for numeric properties such as resource usage.
+type StdComparableInt64 int64
+
+func (self StdComparableInt64) Compare(otherV ComparableValue) int {
+	other := otherV.(StdComparableInt64)
+	return ints64Compare(int64(self), int64(other))
+}
+
+// Contains only matches equal numbers, filter values parsed as strings never match.
+func (self StdComparableInt64) Contains(otherV ComparableValue) bool {
+	other, ok := otherV.(StdComparableInt64)
+	return ok && self == other
+}
+
 func ints64Compare(a, b int64) int {
 	if a > b {
 		return 1
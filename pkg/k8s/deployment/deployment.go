@@ -24,6 +24,7 @@ import (
 	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
 	"github.com/dnsjia/luban/pkg/k8s/dataselect"
 	"github.com/dnsjia/luban/pkg/k8s/event"
+	"github.com/dnsjia/luban/pkg/k8s/informer"
 	"github.com/dnsjia/luban/tools"
 	"go.uber.org/zap"
 	apps "k8s.io/api/apps/v1"
@@ -94,10 +95,10 @@ func GetDeploymentList(client *kubernetes.Clientset, nsQuery *k8scommon.Namespac
 	common.LOG.Info("Getting list of all deployments in the cluster")
 
 	channels := &k8scommon.ResourceChannels{
-		DeploymentList: k8scommon.GetDeploymentListChannel(client, nsQuery, 1),
-		PodList:        k8scommon.GetPodListChannel(client, nsQuery, 1),
-		EventList:      k8scommon.GetEventListChannel(client, nsQuery, 1),
-		ReplicaSetList: k8scommon.GetReplicaSetListChannel(client, nsQuery, 1),
+		DeploymentList: informer.GetDeploymentListChannel(client, nsQuery, 1),
+		PodList:        informer.GetPodListChannel(client, nsQuery, 1),
+		EventList:      informer.GetEventListChannel(client, nsQuery, 1),
+		ReplicaSetList: informer.GetReplicaSetListChannel(client, nsQuery, 1),
 	}
 
 	return GetDeploymentListFromChannels(channels, dsQuery)
@@ -22,6 +22,7 @@ import (
 	"github.com/dnsjia/luban/common"
 	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
 	"github.com/dnsjia/luban/pkg/k8s/event"
+	"github.com/dnsjia/luban/pkg/k8s/hpa"
 	"github.com/dnsjia/luban/pkg/k8s/service"
 	"github.com/dnsjia/luban/tools"
 	apps "k8s.io/api/apps/v1"
@@ -90,6 +91,8 @@ type DeploymentDetail struct {
 	PodList *PodList `json:"podList"`
 
 	SvcList *service.ServiceList `json:"svcList"`
+
+	HorizontalPodAutoscalerList *hpa.HorizontalPodAutoscalerList `json:"horizontalPodAutoscalerList"`
 }
 
 // GetDeploymentDetail returns model object of deployment and error, if any.
@@ -145,6 +148,7 @@ func GetDeploymentDetail(client *kubernetes.Clientset, namespace string, deploym
 	}
 	events, _ := event.GetEvents(client, namespace, fmt.Sprintf("involvedObject.name=%v", deploymentName))
 	serviceList, _ := service.GetToService(client, namespace, deploymentName)
+	hpaList, _ := hpa.GetHorizontalPodAutoscalerListForResource(client, namespace, "Deployment", deploymentName)
 
 	return &DeploymentDetail{
 		Deployment:            toDeployment(deployment, rawRs.Items, rawPods.Items, rawEvents.Items),
@@ -159,6 +163,8 @@ func GetDeploymentDetail(client *kubernetes.Clientset, namespace string, deploym
 		PodList:               getDeploymentToPod(client, deployment),
 		SvcList:               serviceList,
 		HistoryVersion:        getDeploymentHistory(namespace, deploymentName, rawRs.Items),
+
+		HorizontalPodAutoscalerList: hpaList,
 	}, nil
 }
 
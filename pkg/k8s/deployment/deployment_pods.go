@@ -22,6 +22,7 @@ import (
 	"github.com/dnsjia/luban/models/k8s"
 	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
 	"github.com/dnsjia/luban/pkg/k8s/event"
+	"github.com/dnsjia/luban/pkg/k8s/metrics"
 	"github.com/dnsjia/luban/pkg/k8s/pods"
 	"go.uber.org/zap"
 	apps "k8s.io/api/apps/v1"
@@ -38,6 +39,11 @@ type PodList struct {
 
 	// Unordered list of Pods.
 	Pods []pods.Pod `json:"pods"`
+
+	// Total live usage of the pods, nil when metrics-server is unavailable.
+	Metrics *metrics.Usage `json:"metrics"`
+
+	MetricsStatus metrics.Status `json:"metricsStatus"`
 }
 
 func getDeploymentToPod(client *kubernetes.Clientset, deployment *apps.Deployment) (po *PodList) {
@@ -52,13 +58,17 @@ func getDeploymentToPod(client *kubernetes.Clientset, deployment *apps.Deploymen
 	if err != nil {
 		common.LOG.Error("Get a pod exception from the deployment", zap.Any("err", err))
 	}
+	podMetrics, metricsStatus := metrics.GetPodMetrics(client, deployment.Namespace, options.LabelSelector)
 	podList := PodList{
-		Pods: make([]pods.Pod, 0),
+		Pods:          make([]pods.Pod, 0),
+		Metrics:       podMetrics.Sum(podData.Items),
+		MetricsStatus: metricsStatus,
 	}
 	podList.ListMeta = k8s.ListMeta{TotalItems: len(podData.Items)}
 	for _, pod := range podData.Items {
 		warnings := event.GetPodsEventWarnings(nil, []v1.Pod{pod})
 		podDetail := pods.ToPod(&pod, warnings)
+		podDetail.Metrics = podMetrics.Get(&pod)
 		podList.Pods = append(podList.Pods, podDetail)
 	}
 	return &podList
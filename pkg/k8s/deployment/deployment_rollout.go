@@ -0,0 +1,263 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/pmezard/go-difflib/difflib"
+	"go.uber.org/zap"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	deploymentutil "k8s.io/kubectl/pkg/util/deployment"
+	"sigs.k8s.io/yaml"
+	"sort"
+)
+
+const (
+	// ChangeCauseAnnotation 记录触发该版本的变更原因, 与 kubectl rollout history 一致
+	ChangeCauseAnnotation = "kubernetes.io/change-cause"
+
+	RolloutProgressing = "progressing"
+	RolloutComplete    = "complete"
+	RolloutStalled     = "stalled"
+	RolloutPaused      = "paused"
+)
+
+// Revision 是 deployment 的一个历史版本, 对应其拥有的一个 ReplicaSet
+type Revision struct {
+	Revision          int64       `json:"revision"`
+	ReplicaSet        string      `json:"replicaSet"`
+	Images            []string    `json:"images"`
+	ChangeCause       string      `json:"changeCause"`
+	CreationTimestamp metav1.Time `json:"creationTimestamp"`
+	Replicas          int32       `json:"replicas"`
+	// Current 表示该版本为当前正在使用的版本
+	Current bool `json:"current"`
+}
+
+// RevisionDiff 两个版本 pod 模板的统一 diff
+type RevisionDiff struct {
+	From int64  `json:"from"`
+	To   int64  `json:"to"`
+	Diff string `json:"diff"`
+}
+
+// RolloutStatus 发布状态, Status 取值 progressing/complete/stalled/paused
+type RolloutStatus struct {
+	Status            string `json:"status"`
+	Message           string `json:"message"`
+	Revision          int64  `json:"revision"`
+	Replicas          int32  `json:"replicas"`
+	UpdatedReplicas   int32  `json:"updatedReplicas"`
+	AvailableReplicas int32  `json:"availableReplicas"`
+}
+
+// GetDeploymentRevisions 列出 deployment 所有的历史版本, 按版本号倒序
+func GetDeploymentRevisions(client kubernetes.Interface, namespace string, deploymentName string) ([]Revision, error) {
+	deployment, err := client.AppsV1().Deployments(namespace).Get(context.TODO(), deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	replicaSets, err := revisionReplicaSets(deployment, client)
+	if err != nil {
+		return nil, err
+	}
+	currentRevision, _ := deploymentutil.Revision(deployment)
+
+	revisions := make([]Revision, 0, len(replicaSets))
+	for revision, rs := range replicaSets {
+		images := make([]string, 0, len(rs.Spec.Template.Spec.Containers))
+		for _, container := range rs.Spec.Template.Spec.Containers {
+			images = append(images, container.Image)
+		}
+		var replicas int32
+		if rs.Spec.Replicas != nil {
+			replicas = *rs.Spec.Replicas
+		}
+		revisions = append(revisions, Revision{
+			Revision:          revision,
+			ReplicaSet:        rs.Name,
+			Images:            images,
+			ChangeCause:       rs.Annotations[ChangeCauseAnnotation],
+			CreationTimestamp: rs.CreationTimestamp,
+			Replicas:          replicas,
+			Current:           revision == currentRevision,
+		})
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+// DiffDeploymentRevisions 对比两个版本的 pod 模板, 返回统一 diff 格式的文本
+func DiffDeploymentRevisions(client kubernetes.Interface, namespace string, deploymentName string, from, to int64) (*RevisionDiff, error) {
+	deployment, err := client.AppsV1().Deployments(namespace).Get(context.TODO(), deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	replicaSets, err := revisionReplicaSets(deployment, client)
+	if err != nil {
+		return nil, err
+	}
+	fromRS, ok := replicaSets[from]
+	if !ok {
+		return nil, revisionNotFoundErr(from)
+	}
+	toRS, ok := replicaSets[to]
+	if !ok {
+		return nil, revisionNotFoundErr(to)
+	}
+
+	fromYAML, err := podTemplateYAML(fromRS.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+	toYAML, err := podTemplateYAML(toRS.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(fromYAML),
+		B:        difflib.SplitLines(toYAML),
+		FromFile: fmt.Sprintf("revision-%d", from),
+		ToFile:   fmt.Sprintf("revision-%d", to),
+		Context:  3,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &RevisionDiff{From: from, To: to, Diff: diff}, nil
+}
+
+// PauseDeployment 暂停 deployment 的发布
+func PauseDeployment(client kubernetes.Interface, deploymentName string, namespace string) error {
+	common.LOG.Info(fmt.Sprintf("暂停应用发布, 名称空间：%v, 无状态应用：%v", namespace, deploymentName))
+	return setDeploymentPaused(client, deploymentName, namespace, true)
+}
+
+// ResumeDeployment 恢复 deployment 的发布
+func ResumeDeployment(client kubernetes.Interface, deploymentName string, namespace string) error {
+	common.LOG.Info(fmt.Sprintf("恢复应用发布, 名称空间：%v, 无状态应用：%v", namespace, deploymentName))
+	return setDeploymentPaused(client, deploymentName, namespace, false)
+}
+
+func setDeploymentPaused(client kubernetes.Interface, deploymentName string, namespace string, paused bool) error {
+	data := fmt.Sprintf(`{"spec":{"paused":%t}}`, paused)
+	_, err := client.AppsV1().Deployments(namespace).Patch(
+		context.TODO(),
+		deploymentName,
+		types.StrategicMergePatchType,
+		[]byte(data),
+		metav1.PatchOptions{
+			FieldManager: "kubectl-rollout",
+		})
+	if err != nil {
+		common.LOG.Error("修改应用发布状态失败", zap.Any("err: ", err))
+		return err
+	}
+	return nil
+}
+
+// GetRolloutStatus 返回 deployment 的发布状态, 判断逻辑与 kubectl rollout status 一致
+func GetRolloutStatus(client kubernetes.Interface, namespace string, deploymentName string) (*RolloutStatus, error) {
+	deployment, err := client.AppsV1().Deployments(namespace).Get(context.TODO(), deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return toRolloutStatus(deployment), nil
+}
+
+func toRolloutStatus(deployment *apps.Deployment) *RolloutStatus {
+	revision, _ := deploymentutil.Revision(deployment)
+	status := &RolloutStatus{
+		Revision:          revision,
+		Replicas:          deployment.Status.Replicas,
+		UpdatedReplicas:   deployment.Status.UpdatedReplicas,
+		AvailableReplicas: deployment.Status.AvailableReplicas,
+	}
+
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		status.Status = RolloutProgressing
+		status.Message = "Waiting for deployment spec update to be observed"
+		return status
+	}
+	if deployment.Spec.Paused {
+		status.Status = RolloutPaused
+		status.Message = fmt.Sprintf("deployment %q is paused", deployment.Name)
+		return status
+	}
+	cond := deploymentutil.GetDeploymentCondition(deployment.Status, apps.DeploymentProgressing)
+	if cond != nil && cond.Reason == deploymentutil.TimedOutReason {
+		status.Status = RolloutStalled
+		status.Message = fmt.Sprintf("deployment %q exceeded its progress deadline", deployment.Name)
+		return status
+	}
+
+	status.Status = RolloutProgressing
+	switch {
+	case deployment.Spec.Replicas != nil && deployment.Status.UpdatedReplicas < *deployment.Spec.Replicas:
+		status.Message = fmt.Sprintf("Waiting for rollout to finish: %d out of %d new replicas have been updated",
+			deployment.Status.UpdatedReplicas, *deployment.Spec.Replicas)
+	case deployment.Status.Replicas > deployment.Status.UpdatedReplicas:
+		status.Message = fmt.Sprintf("Waiting for rollout to finish: %d old replicas are pending termination",
+			deployment.Status.Replicas-deployment.Status.UpdatedReplicas)
+	case deployment.Status.AvailableReplicas < deployment.Status.UpdatedReplicas:
+		status.Message = fmt.Sprintf("Waiting for rollout to finish: %d of %d updated replicas are available",
+			deployment.Status.AvailableReplicas, deployment.Status.UpdatedReplicas)
+	default:
+		status.Status = RolloutComplete
+		status.Message = fmt.Sprintf("deployment %q successfully rolled out", deployment.Name)
+	}
+	return status
+}
+
+// revisionReplicaSets 返回 deployment 拥有的 ReplicaSet, 以版本号为 key
+func revisionReplicaSets(deployment *apps.Deployment, c kubernetes.Interface) (map[int64]*apps.ReplicaSet, error) {
+	_, allOldRSs, newRS, err := deploymentutil.GetAllReplicaSets(deployment, c.AppsV1())
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve replica sets from deployment %s: %v", deployment.Name, err)
+	}
+	allRSs := allOldRSs
+	if newRS != nil {
+		allRSs = append(allRSs, newRS)
+	}
+
+	replicaSets := make(map[int64]*apps.ReplicaSet, len(allRSs))
+	for _, rs := range allRSs {
+		if v, err := deploymentutil.Revision(rs); err == nil {
+			replicaSets[v] = rs
+		}
+	}
+	return replicaSets, nil
+}
+
+// podTemplateYAML 将 pod 模板序列化为 yaml, 去掉每个版本都不同的 pod-template-hash 标签
+func podTemplateYAML(template v1.PodTemplateSpec) (string, error) {
+	template = *template.DeepCopy()
+	delete(template.Labels, apps.DefaultDeploymentUniqueLabelKey)
+	data, err := yaml.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
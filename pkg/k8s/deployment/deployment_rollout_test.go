@@ -0,0 +1,285 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dnsjia/luban/common"
+	"go.uber.org/zap"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	deploymentutil "k8s.io/kubectl/pkg/util/deployment"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func podTemplate(image string) v1.PodTemplateSpec {
+	return v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "web", Image: image}}},
+	}
+}
+
+func newRolloutDeployment() *apps.Deployment {
+	return &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "web",
+			UID:         types.UID("deploy-web"),
+			Annotations: map[string]string{deploymentutil.RevisionAnnotation: "3"},
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas: int32Ptr(2),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: podTemplate("nginx:1.21"),
+		},
+	}
+}
+
+// newRevisionReplicaSet revision 为空时不设置版本注解
+func newRevisionReplicaSet(owner *apps.Deployment, name, revision, image string) *apps.ReplicaSet {
+	controller := true
+	template := podTemplate(image)
+	template.Labels[apps.DefaultDeploymentUniqueLabelKey] = name
+	rs := &apps.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "default",
+			Name:            name,
+			UID:             types.UID("rs-" + name),
+			Labels:          template.Labels,
+			Annotations:     map[string]string{ChangeCauseAnnotation: "set image " + image},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: owner.Name, UID: owner.UID, Controller: &controller}},
+		},
+		Spec: apps.ReplicaSetSpec{
+			Replicas: int32Ptr(1),
+			Selector: owner.Spec.Selector,
+			Template: template,
+		},
+	}
+	if revision != "" {
+		rs.Annotations[deploymentutil.RevisionAnnotation] = revision
+	}
+	return rs
+}
+
+func newRevisionClient() *fake.Clientset {
+	deployment := newRolloutDeployment()
+	other := newRolloutDeployment()
+	other.Name, other.UID = "web-canary", types.UID("deploy-web-canary")
+	return fake.NewSimpleClientset(
+		deployment,
+		newRevisionReplicaSet(deployment, "web-2", "2", "nginx:1.20"),
+		newRevisionReplicaSet(deployment, "web-3", "3", "nginx:1.21"),
+		newRevisionReplicaSet(deployment, "web-1", "1", "nginx:1.19"),
+		newRevisionReplicaSet(deployment, "web-bad", "abc", "nginx:bad"),
+		newRevisionReplicaSet(deployment, "web-none", "", "nginx:none"),
+		newRevisionReplicaSet(other, "web-canary-9", "9", "nginx:canary"),
+	)
+}
+
+func TestGetDeploymentRevisions(t *testing.T) {
+	revisions, err := GetDeploymentRevisions(newRevisionClient(), "default", "web")
+	if err != nil {
+		t.Fatalf("GetDeploymentRevisions returned error: %v", err)
+	}
+
+	// 版本注解不合法的 ReplicaSet 被忽略, 缺少注解的视为版本0, 其他 deployment 的 ReplicaSet 不参与
+	expected := []struct {
+		revision   int64
+		replicaSet string
+		image      string
+		current    bool
+	}{
+		{revision: 3, replicaSet: "web-3", image: "nginx:1.21", current: true},
+		{revision: 2, replicaSet: "web-2", image: "nginx:1.20"},
+		{revision: 1, replicaSet: "web-1", image: "nginx:1.19"},
+		{revision: 0, replicaSet: "web-none", image: "nginx:none"},
+	}
+	if len(revisions) != len(expected) {
+		t.Fatalf("GetDeploymentRevisions returned %d revisions: %+v", len(revisions), revisions)
+	}
+	for i, e := range expected {
+		r := revisions[i]
+		if r.Revision != e.revision || r.ReplicaSet != e.replicaSet || r.Current != e.current ||
+			len(r.Images) != 1 || r.Images[0] != e.image || r.ChangeCause != "set image "+e.image {
+			t.Errorf("revision %d == %+v, expected %+v", i, r, e)
+		}
+	}
+
+	if _, err := GetDeploymentRevisions(newRevisionClient(), "default", "missing"); err == nil {
+		t.Errorf("expected error for missing deployment")
+	}
+}
+
+func TestDiffDeploymentRevisions(t *testing.T) {
+	common.LOG = zap.NewNop()
+	cases := []struct {
+		name     string
+		from, to int64
+		contains []string
+		wantErr  bool
+	}{
+		{name: "image change", from: 1, to: 3, contains: []string{"--- revision-1", "+++ revision-3", "-  - image: nginx:1.19", "+  - image: nginx:1.21"}},
+		{name: "same revision", from: 2, to: 2},
+		{name: "unknown from", from: 7, to: 3, wantErr: true},
+		{name: "unknown to", from: 1, to: 9, wantErr: true},
+	}
+	for _, c := range cases {
+		diff, err := DiffDeploymentRevisions(newRevisionClient(), "default", "web", c.from, c.to)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: DiffDeploymentRevisions() error = %v, wantErr %v", c.name, err, c.wantErr)
+			continue
+		}
+		if c.wantErr {
+			continue
+		}
+		if strings.Contains(diff.Diff, apps.DefaultDeploymentUniqueLabelKey) {
+			t.Errorf("%s: diff should not include the pod-template-hash label:\n%s", c.name, diff.Diff)
+		}
+		if len(c.contains) == 0 && diff.Diff != "" {
+			t.Errorf("%s: expected empty diff, got:\n%s", c.name, diff.Diff)
+		}
+		for _, s := range c.contains {
+			if !strings.Contains(diff.Diff, s) {
+				t.Errorf("%s: diff does not contain %q:\n%s", c.name, s, diff.Diff)
+			}
+		}
+	}
+}
+
+func TestPodTemplateYAML(t *testing.T) {
+	template := podTemplate("nginx:1.21")
+	template.Labels[apps.DefaultDeploymentUniqueLabelKey] = "abc"
+
+	data, err := podTemplateYAML(template)
+	if err != nil {
+		t.Fatalf("podTemplateYAML returned error: %v", err)
+	}
+	if strings.Contains(data, apps.DefaultDeploymentUniqueLabelKey) || !strings.Contains(data, "app: web") {
+		t.Errorf("unexpected yaml:\n%s", data)
+	}
+	if template.Labels[apps.DefaultDeploymentUniqueLabelKey] != "abc" {
+		t.Errorf("podTemplateYAML modified the input template")
+	}
+}
+
+func TestSetDeploymentPaused(t *testing.T) {
+	common.LOG = zap.NewNop()
+	client := fake.NewSimpleClientset(newRolloutDeployment())
+
+	cases := []struct {
+		name   string
+		action func() error
+		paused bool
+	}{
+		{name: "pause", action: func() error { return PauseDeployment(client, "web", "default") }, paused: true},
+		{name: "pause again", action: func() error { return PauseDeployment(client, "web", "default") }, paused: true},
+		{name: "resume", action: func() error { return ResumeDeployment(client, "web", "default") }, paused: false},
+	}
+	for _, c := range cases {
+		if err := c.action(); err != nil {
+			t.Fatalf("%s: returned error: %v", c.name, err)
+		}
+		deployment, err := client.AppsV1().Deployments("default").Get(context.TODO(), "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("%s: get deployment: %v", c.name, err)
+		}
+		if deployment.Spec.Paused != c.paused {
+			t.Errorf("%s: paused == %v, expected %v", c.name, deployment.Spec.Paused, c.paused)
+		}
+	}
+
+	if err := PauseDeployment(client, "missing", "default"); err == nil {
+		t.Errorf("expected error for missing deployment")
+	}
+}
+
+func TestToRolloutStatus(t *testing.T) {
+	cases := []struct {
+		name     string
+		modify   func(d *apps.Deployment)
+		expected string
+		message  string
+	}{
+		{
+			name:     "spec not observed",
+			modify:   func(d *apps.Deployment) { d.Generation = 2; d.Status.ObservedGeneration = 1 },
+			expected: RolloutProgressing,
+			message:  "Waiting for deployment spec update to be observed",
+		},
+		{
+			name:     "paused",
+			modify:   func(d *apps.Deployment) { d.Spec.Paused = true },
+			expected: RolloutPaused,
+			message:  "is paused",
+		},
+		{
+			name: "progress deadline exceeded",
+			modify: func(d *apps.Deployment) {
+				d.Status.Conditions = []apps.DeploymentCondition{{Type: apps.DeploymentProgressing, Reason: deploymentutil.TimedOutReason}}
+			},
+			expected: RolloutStalled,
+			message:  "exceeded its progress deadline",
+		},
+		{
+			name:     "new replicas not updated",
+			modify:   func(d *apps.Deployment) { d.Status.UpdatedReplicas = 1 },
+			expected: RolloutProgressing,
+			message:  "1 out of 2 new replicas have been updated",
+		},
+		{
+			name:     "old replicas pending termination",
+			modify:   func(d *apps.Deployment) { d.Status.Replicas = 3 },
+			expected: RolloutProgressing,
+			message:  "1 old replicas are pending termination",
+		},
+		{
+			name:     "updated replicas not available",
+			modify:   func(d *apps.Deployment) { d.Status.AvailableReplicas = 1 },
+			expected: RolloutProgressing,
+			message:  "1 of 2 updated replicas are available",
+		},
+		{
+			name:     "complete",
+			modify:   func(d *apps.Deployment) {},
+			expected: RolloutComplete,
+			message:  "successfully rolled out",
+		},
+	}
+	for _, c := range cases {
+		deployment := newRolloutDeployment()
+		deployment.Generation, deployment.Status.ObservedGeneration = 1, 1
+		deployment.Status.Replicas, deployment.Status.UpdatedReplicas, deployment.Status.AvailableReplicas = 2, 2, 2
+		c.modify(deployment)
+
+		status := toRolloutStatus(deployment)
+		if status.Status != c.expected || !strings.Contains(status.Message, c.message) {
+			t.Errorf("%s: status == %s %q, expected %s containing %q", c.name, status.Status, status.Message, c.expected, c.message)
+		}
+		if status.Revision != 3 {
+			t.Errorf("%s: revision == %d, expected 3", c.name, status.Revision)
+		}
+	}
+}
@@ -43,6 +43,9 @@ type Endpoint struct {
 
 	// Array of endpoint ports
 	Ports []v1.EndpointPort `json:"ports"`
+
+	// Reference to object providing the endpoint, usually a pod
+	TargetRef *v1.ObjectReference `json:"targetRef,omitempty"`
 }
 
 // GetServiceEndpoints gets list of endpoints targeted by given label selector in given namespace.
@@ -91,10 +94,11 @@ func GetEndpoints(client k8sClient.Interface, namespace, name string) ([]v1.Endp
 func toEndpoint(address v1.EndpointAddress, ports []v1.EndpointPort, ready bool) *Endpoint {
 
 	return &Endpoint{
-		TypeMeta: k8s.NewTypeMeta(k8s.ResourceKindEndpoint),
-		Host:     address.IP,
-		Ports:    ports,
-		Ready:    ready,
-		NodeName: address.NodeName,
+		TypeMeta:  k8s.NewTypeMeta(k8s.ResourceKindEndpoint),
+		Host:      address.IP,
+		Ports:     ports,
+		Ready:     ready,
+		NodeName:  address.NodeName,
+		TargetRef: address.TargetRef,
 	}
 }
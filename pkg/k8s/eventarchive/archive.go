@@ -0,0 +1,229 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package eventarchive 将各集群的事件持续写入数据库, 保留超过 apiserver 默认1小时TTL的事件历史
+package eventarchive
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/pkg/k8s/informer"
+	"github.com/dnsjia/luban/services"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	defaultRetentionDays = 30
+	// reconcileInterval 同步集群列表的间隔, 新增、修改、删除集群后在该间隔内生效
+	reconcileInterval = time.Minute
+	cleanupInterval   = 6 * time.Hour
+)
+
+// RetentionDays 事件保留天数
+func RetentionDays() int {
+	if common.CONFIG.Kubernetes.EventRetentionDays > 0 {
+		return common.CONFIG.Kubernetes.EventRetentionDays
+	}
+	return defaultRetentionDays
+}
+
+// watcher 单个集群的事件处理函数, 注册在 informer 缓存的事件 informer 上, 不单独 watch apiserver
+type watcher struct {
+	clusterID uint
+	cache     *informer.ClusterCache
+	stopCh    chan struct{}
+	// seen 已写入的事件次数, 避免 resync 与重复的 Update 反复查询数据库
+	seen map[string]int32
+}
+
+type archiver struct {
+	mu       sync.Mutex
+	watchers map[uint]*watcher
+}
+
+var defaultArchiver = &archiver{watchers: make(map[uint]*watcher)}
+
+// Start 未开启事件归档时直接返回, 否则在后台为每个集群启动事件 watcher 并定期清理过期事件
+func Start() {
+	if !common.CONFIG.Kubernetes.EventArchive {
+		return
+	}
+	common.LOG.Info(fmt.Sprintf("开启集群事件归档, 保留%d天", RetentionDays()))
+	go func() {
+		for {
+			defaultArchiver.reconcile()
+			time.Sleep(reconcileInterval)
+		}
+	}()
+	go func() {
+		for {
+			cleanup()
+			time.Sleep(cleanupInterval)
+		}
+	}()
+}
+
+// reconcile 为新增集群启动 watcher, 停止已删除集群的 watcher, 集群配置变更后 client 会重建, watcher 随之重启
+func (a *archiver) reconcile() {
+	clusters, err := services.ListAllK8SCluster()
+	if err != nil {
+		common.LOG.Error("事件归档获取集群失败", zap.Any("err", err))
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	active := make(map[uint]bool, len(clusters))
+	for _, cluster := range clusters {
+		active[cluster.ID] = true
+		cc, err := informer.Get(cluster)
+		if err != nil {
+			common.LOG.Warn(fmt.Sprintf("事件归档无法连接集群%d", cluster.ID), zap.Any("err", err))
+			continue
+		}
+		if w, ok := a.watchers[cluster.ID]; ok {
+			if w.cache == cc {
+				continue
+			}
+			close(w.stopCh)
+			delete(a.watchers, cluster.ID)
+		}
+		w := &watcher{
+			clusterID: cluster.ID,
+			cache:     cc,
+			stopCh:    make(chan struct{}),
+			seen:      make(map[string]int32),
+		}
+		if err := w.run(); err != nil {
+			common.LOG.Warn(fmt.Sprintf("事件归档无法监听集群%d的事件", cluster.ID), zap.Any("err", err))
+			continue
+		}
+		a.watchers[cluster.ID] = w
+	}
+
+	for id, w := range a.watchers {
+		if !active[id] {
+			close(w.stopCh)
+			delete(a.watchers, id)
+		}
+	}
+}
+
+func (w *watcher) run() error {
+	return w.cache.AddEventHandler(informer.ResourceEvents, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			w.handle(obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			w.handle(obj)
+		},
+	})
+}
+
+// handle 由 informer 在单个 goroutine 中依次调用, seen 无需加锁. 处理函数无法从 informer 移除, watcher 停止后直接忽略
+func (w *watcher) handle(obj interface{}) {
+	select {
+	case <-w.stopCh:
+		return
+	default:
+	}
+	event, ok := obj.(*v1.Event)
+	if !ok {
+		return
+	}
+	record := ToEventRecord(w.clusterID, event)
+	if count, ok := w.seen[record.UID]; ok && record.Count <= count {
+		return
+	}
+	if _, err := services.SaveEventRecord(&record); err != nil {
+		common.LOG.Error("保存集群事件失败", zap.Any("err", err))
+		return
+	}
+	w.seen[record.UID] = record.Count
+	// 事件在 apiserver 中约1小时后过期, seen 不需要长期保留
+	if len(w.seen) > 100000 {
+		w.seen = make(map[string]int32)
+	}
+}
+
+// ToEventRecord 将事件转换为归档记录, 兼容 events.k8s.io 新版事件的 series 与 eventTime
+func ToEventRecord(clusterID uint, event *v1.Event) models.EventRecord {
+	count := event.Count
+	first := event.FirstTimestamp.Time
+	last := event.LastTimestamp.Time
+	if event.Series != nil {
+		count = event.Series.Count
+		last = event.Series.LastObservedTime.Time
+	}
+	if first.IsZero() {
+		first = event.EventTime.Time
+	}
+	if last.IsZero() {
+		last = first
+	}
+	if last.IsZero() {
+		last = event.CreationTimestamp.Time
+		first = last
+	}
+	if count < 1 {
+		count = 1
+	}
+
+	source := event.Source.Component
+	if source == "" {
+		source = event.ReportingController
+	}
+	if event.Source.Host != "" && source != "" {
+		source = fmt.Sprintf("%s, %s", source, event.Source.Host)
+	} else if event.Source.Host != "" {
+		source = event.Source.Host
+	}
+
+	return models.EventRecord{
+		ClusterID:      clusterID,
+		UID:            string(event.UID),
+		Namespace:      event.Namespace,
+		InvolvedKind:   event.InvolvedObject.Kind,
+		InvolvedName:   event.InvolvedObject.Name,
+		InvolvedUID:    string(event.InvolvedObject.UID),
+		Reason:         event.Reason,
+		Type:           event.Type,
+		Message:        event.Message,
+		Source:         source,
+		Count:          count,
+		FirstTimestamp: models.LocalTime{Time: first},
+		LastTimestamp:  models.LocalTime{Time: last},
+	}
+}
+
+func cleanup() {
+	before := time.Now().AddDate(0, 0, -RetentionDays())
+	deleted, err := services.DeleteEventRecordBefore(before)
+	if err != nil {
+		common.LOG.Error("清理过期事件失败", zap.Any("err", err))
+		return
+	}
+	if deleted > 0 {
+		common.LOG.Info(fmt.Sprintf("已清理%d条%s之前的归档事件", deleted, before.Format(models.SecLocalTimeFormat)))
+	}
+}
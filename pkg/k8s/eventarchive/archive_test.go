@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventarchive
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestToEventRecord(t *testing.T) {
+	first := time.Date(2021, 10, 1, 8, 0, 0, 0, time.Local)
+	last := first.Add(10 * time.Minute)
+
+	legacy := &v1.Event{
+		ObjectMeta:     metav1.ObjectMeta{UID: "e1", Namespace: "default"},
+		InvolvedObject: v1.ObjectReference{Kind: "Pod", Name: "web-0", UID: "p1"},
+		Reason:         "BackOff",
+		Type:           v1.EventTypeWarning,
+		Source:         v1.EventSource{Component: "kubelet", Host: "node-1"},
+		Count:          5,
+		FirstTimestamp: metav1.NewTime(first),
+		LastTimestamp:  metav1.NewTime(last),
+	}
+	record := ToEventRecord(3, legacy)
+	if record.ClusterID != 3 || record.UID != "e1" || record.InvolvedKind != "Pod" || record.InvolvedName != "web-0" {
+		t.Fatalf("unexpected record %+v", record)
+	}
+	if record.Count != 5 || !record.FirstTimestamp.Equal(first) || !record.LastTimestamp.Equal(last) {
+		t.Fatalf("unexpected count or timestamps %+v", record)
+	}
+	if record.Source != "kubelet, node-1" {
+		t.Fatalf("unexpected source %q", record.Source)
+	}
+
+	// events.k8s.io 事件只有 eventTime 与 series
+	series := &v1.Event{
+		ObjectMeta:          metav1.ObjectMeta{UID: "e2", Namespace: "default"},
+		ReportingController: "default-scheduler",
+		EventTime:           metav1.NewMicroTime(first),
+		Series:              &v1.EventSeries{Count: 7, LastObservedTime: metav1.NewMicroTime(last)},
+	}
+	record = ToEventRecord(3, series)
+	if record.Count != 7 || !record.FirstTimestamp.Equal(first) || !record.LastTimestamp.Equal(last) {
+		t.Fatalf("unexpected series record %+v", record)
+	}
+	if record.Source != "default-scheduler" {
+		t.Fatalf("unexpected source %q", record.Source)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventarchive
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// generatedSuffix 控制器生成名称使用的字符集, 与 pod-template-hash 及 generateName 的随机后缀一致, 不含元音.
+// 早期版本的 pod-template-hash 为纯数字, 因此包含全部数字.
+const generatedSuffix = "[0-9bcdfghjklmnpqrstvwxz]"
+
+// WorkloadObjects 工作负载名下的 ReplicaSet 与 Pod. 名称来自集群中现存对象的 ownerReferences,
+// 已删除对象的事件依靠名称格式匹配
+type WorkloadObjects struct {
+	ReplicaSets       []string
+	Pods              []string
+	ReplicaSetPattern string
+	PodPattern        string
+}
+
+// ResolveWorkloadObjects 通过 ownerReferences 查找工作负载当前拥有的 ReplicaSet 与 Pod,
+// 不会按名称前缀匹配, 避免 api 与 api-gateway 这类同前缀工作负载的事件互相混入
+func ResolveWorkloadObjects(client kubernetes.Interface, namespace, kind, name string) (WorkloadObjects, error) {
+	objects := workloadNamePatterns(kind, name)
+
+	owners := map[string]bool{kind + "/" + name: true}
+	if kind == "Deployment" {
+		replicaSets, err := client.AppsV1().ReplicaSets(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return objects, err
+		}
+		for i := range replicaSets.Items {
+			if controlledBy(&replicaSets.Items[i], kind, name) {
+				objects.ReplicaSets = append(objects.ReplicaSets, replicaSets.Items[i].Name)
+				owners["ReplicaSet/"+replicaSets.Items[i].Name] = true
+			}
+		}
+	}
+
+	pods, err := client.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return objects, err
+	}
+	for i := range pods.Items {
+		if ref := metav1.GetControllerOf(&pods.Items[i]); ref != nil && owners[ref.Kind+"/"+ref.Name] {
+			objects.Pods = append(objects.Pods, pods.Items[i].Name)
+		}
+	}
+	return objects, nil
+}
+
+func controlledBy(obj metav1.Object, kind, name string) bool {
+	ref := metav1.GetControllerOf(obj)
+	return ref != nil && ref.Kind == kind && ref.Name == name
+}
+
+// workloadNamePatterns 各类控制器生成的子对象名称格式:
+// Deployment 的 ReplicaSet 为 <name>-<hash>, Pod 为 <name>-<hash>-<suffix>;
+// StatefulSet 的 Pod 为 <name>-<序号>; DaemonSet、ReplicaSet、Job 的 Pod 为 <name>-<suffix>
+func workloadNamePatterns(kind, name string) WorkloadObjects {
+	quoted := regexp.QuoteMeta(name)
+	var objects WorkloadObjects
+	switch kind {
+	case "Deployment":
+		objects.ReplicaSetPattern = fmt.Sprintf("^%s-%s{1,10}$", quoted, generatedSuffix)
+		objects.PodPattern = fmt.Sprintf("^%s-%s{1,10}-%s{5}$", quoted, generatedSuffix, generatedSuffix)
+	case "StatefulSet":
+		objects.PodPattern = fmt.Sprintf("^%s-[0-9]+$", quoted)
+	case "DaemonSet", "ReplicaSet", "Job":
+		objects.PodPattern = fmt.Sprintf("^%s-%s{5}$", quoted, generatedSuffix)
+	}
+	return objects
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventarchive
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func controllerRef(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func TestResolveWorkloadObjects(t *testing.T) {
+	meta := func(name string, owners []metav1.OwnerReference) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Namespace: "default", Name: name, OwnerReferences: owners}
+	}
+	client := fake.NewSimpleClientset(
+		&appsv1.ReplicaSet{ObjectMeta: meta("api-7d4b9c8f6d", controllerRef("Deployment", "api"))},
+		&appsv1.ReplicaSet{ObjectMeta: meta("api-gateway-5c9d7b8f4", controllerRef("Deployment", "api-gateway"))},
+		&v1.Pod{ObjectMeta: meta("api-7d4b9c8f6d-x2v9k", controllerRef("ReplicaSet", "api-7d4b9c8f6d"))},
+		&v1.Pod{ObjectMeta: meta("api-gateway-5c9d7b8f4-q8w2z", controllerRef("ReplicaSet", "api-gateway-5c9d7b8f4"))},
+		&v1.Pod{ObjectMeta: meta("api-debug", nil)},
+		&v1.Pod{ObjectMeta: meta("web-0", controllerRef("StatefulSet", "web"))},
+		&v1.Pod{ObjectMeta: meta("web-cache-0", controllerRef("StatefulSet", "web-cache"))},
+	)
+
+	cases := []struct {
+		kind        string
+		name        string
+		replicaSets []string
+		pods        []string
+	}{
+		{kind: "Deployment", name: "api", replicaSets: []string{"api-7d4b9c8f6d"}, pods: []string{"api-7d4b9c8f6d-x2v9k"}},
+		{kind: "Deployment", name: "api-gateway", replicaSets: []string{"api-gateway-5c9d7b8f4"}, pods: []string{"api-gateway-5c9d7b8f4-q8w2z"}},
+		{kind: "StatefulSet", name: "web", pods: []string{"web-0"}},
+		{kind: "DaemonSet", name: "api"},
+	}
+	for _, c := range cases {
+		objects, err := ResolveWorkloadObjects(client, "default", c.kind, c.name)
+		if err != nil {
+			t.Fatalf("%s/%s: ResolveWorkloadObjects returned error: %v", c.kind, c.name, err)
+		}
+		if !reflect.DeepEqual(objects.ReplicaSets, c.replicaSets) || !reflect.DeepEqual(objects.Pods, c.pods) {
+			t.Errorf("%s/%s: resolved %v %v, expected %v %v", c.kind, c.name, objects.ReplicaSets, objects.Pods, c.replicaSets, c.pods)
+		}
+	}
+}
+
+func TestWorkloadNamePatterns(t *testing.T) {
+	cases := []struct {
+		kind     string
+		name     string
+		resource string
+		object   string
+		matched  bool
+	}{
+		{kind: "Deployment", name: "api", resource: "ReplicaSet", object: "api-7d4b9c8f6d", matched: true},
+		{kind: "Deployment", name: "api", resource: "ReplicaSet", object: "api-1234567890", matched: true},
+		{kind: "Deployment", name: "api", resource: "ReplicaSet", object: "api-gateway", matched: false},
+		{kind: "Deployment", name: "api", resource: "ReplicaSet", object: "api-gateway-5c9d7b8f4", matched: false},
+		{kind: "Deployment", name: "api", resource: "Pod", object: "api-7d4b9c8f6d-x2v9k", matched: true},
+		{kind: "Deployment", name: "api", resource: "Pod", object: "api-gateway-5c9d7b8f4-q8w2z", matched: false},
+		{kind: "Deployment", name: "api", resource: "Pod", object: "api-7d4b9c8f6d", matched: false},
+		{kind: "Deployment", name: "a.b", resource: "Pod", object: "axb-7d4b9c8f6d-x2v9k", matched: false},
+		{kind: "StatefulSet", name: "web", resource: "Pod", object: "web-12", matched: true},
+		{kind: "StatefulSet", name: "web", resource: "Pod", object: "web-cache-0", matched: false},
+		{kind: "DaemonSet", name: "agent", resource: "Pod", object: "agent-x2v9k", matched: true},
+		{kind: "DaemonSet", name: "agent", resource: "Pod", object: "agent-proxy-x2v9k", matched: false},
+		{kind: "StatefulSet", name: "web", resource: "ReplicaSet", object: "web-7d4b9c8f6d", matched: false},
+	}
+	for _, c := range cases {
+		objects := workloadNamePatterns(c.kind, c.name)
+		pattern := objects.PodPattern
+		if c.resource == "ReplicaSet" {
+			pattern = objects.ReplicaSetPattern
+		}
+		matched := pattern != "" && regexp.MustCompile(pattern).MatchString(c.object)
+		if matched != c.matched {
+			t.Errorf("%s/%s: %s %s matched == %v, expected %v (pattern %q)", c.kind, c.name, c.resource, c.object, matched, c.matched, pattern)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filecopy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"path"
+	"strings"
+	"time"
+)
+
+// 默认大小限制, 可通过配置文件 kubernetes.max-upload-size / max-download-size 修改, 单位MB
+const (
+	defaultMaxUploadSize   = 100
+	defaultMaxDownloadSize = 500
+)
+
+// 下载打包格式
+const (
+	FormatTar = "tar"
+	FormatZip = "zip"
+)
+
+// ErrSizeLimitExceeded 传输内容超过大小限制
+var ErrSizeLimitExceeded = errors.New("文件大小超过限制")
+
+// Options 目标容器及审计信息
+type Options struct {
+	ClusterID uint
+	Namespace string
+	PodName   string
+	Container string
+	UserName  string
+	ClientIP  string
+}
+
+// MaxUploadSize 上传大小限制, 单位字节
+func MaxUploadSize() int64 {
+	if common.CONFIG.Kubernetes.MaxUploadSize > 0 {
+		return common.CONFIG.Kubernetes.MaxUploadSize << 20
+	}
+	return defaultMaxUploadSize << 20
+}
+
+// MaxDownloadSize 下载大小限制, 按容器内tar流的字节数计算, 单位字节
+func MaxDownloadSize() int64 {
+	if common.CONFIG.Kubernetes.MaxDownloadSize > 0 {
+		return common.CONFIG.Kubernetes.MaxDownloadSize << 20
+	}
+	return defaultMaxDownloadSize << 20
+}
+
+// Upload 将文件上传到容器的 destDir 目录, 与 kubectl cp 相同, 在容器内执行 tar 解包, 容器内需要有 tar 命令.
+// extract 为 true 时 src 为 tar 或 tar.gz 包, 在 destDir 下解开; 否则 src 作为单个文件以 fileName 保存.
+func Upload(client kubernetes.Interface, cfg *rest.Config, options Options, destDir, fileName string, src io.Reader, size int64, extract bool) (err error) {
+	record := newRecord(options, models.FileTransferUpload, destDir)
+	record.FileName = fileName
+	record.Size = size
+	defer func() { saveRecord(record, err) }()
+
+	if size > MaxUploadSize() {
+		return ErrSizeLimitExceeded
+	}
+	destDir, err = cleanPath(destDir)
+	if err != nil {
+		return err
+	}
+	fileName = path.Base(path.Clean("/" + fileName))
+	if fileName == "/" || fileName == "." {
+		return errors.New("文件名不能为空")
+	}
+
+	cmd := []string{"tar", "xmf", "-", "-C", destDir}
+	var stdin io.Reader
+	if extract {
+		switch {
+		case strings.HasSuffix(fileName, ".tar.gz") || strings.HasSuffix(fileName, ".tgz"):
+			cmd = []string{"tar", "xzmf", "-", "-C", destDir}
+		case strings.HasSuffix(fileName, ".tar"):
+		default:
+			return errors.New("解压上传只支持 .tar、.tar.gz、.tgz 文件")
+		}
+		record.Format = FormatTar
+		stdin = &limitedReader{r: src, remaining: MaxUploadSize()}
+	} else {
+		reader, writer := io.Pipe()
+		go func() {
+			writer.CloseWithError(writeSingleFileTar(writer, fileName, src, size))
+		}()
+		defer reader.Close()
+		stdin = reader
+	}
+
+	return execute(client, cfg, options, cmd, stdin, ioutil.Discard)
+}
+
+// writeSingleFileTar 将单个文件打包为tar流
+func writeSingleFileTar(w io.Writer, fileName string, src io.Reader, size int64) error {
+	tw := tar.NewWriter(w)
+	err := tw.WriteHeader(&tar.Header{
+		Name:    fileName,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(tw, src, size); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// Download 将容器内的文件或目录以 tar 或 zip 格式写入 w, 返回从容器读取的字节数.
+// zip 格式由服务端将tar流转换, 会忽略符号链接等非普通文件.
+func Download(client kubernetes.Interface, cfg *rest.Config, options Options, srcPath, format string, w io.Writer) (size int64, err error) {
+	record := newRecord(options, models.FileTransferDownload, srcPath)
+	record.Format = format
+	defer func() {
+		record.Size = size
+		saveRecord(record, err)
+	}()
+
+	if format != FormatTar && format != FormatZip {
+		return 0, fmt.Errorf("不支持的格式: %s", format)
+	}
+	srcPath, err = cleanPath(srcPath)
+	if err != nil {
+		return 0, err
+	}
+	if srcPath == "/" {
+		return 0, errors.New("不允许下载根目录")
+	}
+
+	reader, writer := io.Pipe()
+	limited := &limitedReader{r: reader, remaining: MaxDownloadSize()}
+	execErr := make(chan error, 1)
+	go func() {
+		cmd := []string{"tar", "cf", "-", "-C", path.Dir(srcPath), path.Base(srcPath)}
+		err := execute(client, cfg, options, cmd, nil, writer)
+		writer.CloseWithError(err)
+		execErr <- err
+	}()
+
+	if format == FormatTar {
+		_, err = io.Copy(w, limited)
+	} else {
+		err = tarToZip(limited, w)
+	}
+	// 提前结束时关闭读端, 使容器内的 tar 写入失败并退出
+	reader.CloseWithError(err)
+	if streamErr := <-execErr; err == nil {
+		err = streamErr
+	}
+	return limited.read, err
+}
+
+// tarToZip 将tar流转换为zip, 路径中的 .. 和开头的 / 会被去除
+func tarToZip(r io.Reader, w io.Writer) error {
+	tr := tar.NewReader(r)
+	zw := zip.NewWriter(w)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		if name == "" {
+			continue
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if _, err := zw.Create(name + "/"); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: header.ModTime})
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(fw, tr); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+// execute 在容器内执行命令, 失败时返回命令的标准错误输出
+func execute(client kubernetes.Interface, cfg *rest.Config, options Options, cmd []string, stdin io.Reader, stdout io.Writer) error {
+	req := client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(options.PodName).
+		Namespace(options.Namespace).
+		SubResource("exec")
+
+	req.VersionedParams(&v1.PodExecOptions{
+		Container: options.Container,
+		Command:   cmd,
+		Stdin:     stdin != nil,
+		Stdout:    true,
+		Stderr:    true,
+		TTY:       false,
+	}, scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(cfg, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+
+	stderr := new(bytes.Buffer)
+	err = exec.Stream(remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%v: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// cleanPath 容器内路径必须为绝对路径
+func cleanPath(p string) (string, error) {
+	if !path.IsAbs(p) {
+		return "", fmt.Errorf("容器内路径必须为绝对路径: %s", p)
+	}
+	return path.Clean(p), nil
+}
+
+// limitedReader 读取超过 remaining 字节时返回 ErrSizeLimitExceeded
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	read      int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// 再读一个字节确认是否超限
+		var b [1]byte
+		n, err := l.r.Read(b[:])
+		if n > 0 {
+			return 0, ErrSizeLimitExceeded
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	l.read += int64(n)
+	return n, err
+}
+
+func newRecord(options Options, direction, p string) *models.FileTransferRecord {
+	return &models.FileTransferRecord{
+		ClusterID: options.ClusterID,
+		Namespace: options.Namespace,
+		PodName:   options.PodName,
+		Container: options.Container,
+		UserName:  options.UserName,
+		ClientIP:  options.ClientIP,
+		Direction: direction,
+		Path:      p,
+		StartTime: models.LocalTime{Time: time.Now()},
+	}
+}
+
+func saveRecord(record *models.FileTransferRecord, err error) {
+	record.EndTime = models.LocalTime{Time: time.Now()}
+	record.Status = models.FileTransferSuccess
+	if err != nil {
+		record.Status = models.FileTransferFailed
+		record.Message = err.Error()
+		if len(record.Message) > 1024 {
+			record.Message = record.Message[:1024]
+		}
+	}
+	if dbErr := common.DB.Create(record).Error; dbErr != nil {
+		common.LOG.Error("保存文件传输记录失败", zap.Any("err", dbErr))
+	}
+	common.LOG.Info(fmt.Sprintf("用户：%v, %s 容器文件 %s/%s:%s, 大小: %d, 结果: %s",
+		record.UserName, record.Direction, record.Namespace, record.PodName, record.Path, record.Size, record.Status))
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filecopy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestTarToZip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	entries := []struct {
+		header  tar.Header
+		content string
+	}{
+		{tar.Header{Name: "app/", Typeflag: tar.TypeDir, Mode: 0755}, ""},
+		{tar.Header{Name: "app/config.yaml", Typeflag: tar.TypeReg, Mode: 0644}, "port: 80"},
+		{tar.Header{Name: "../../etc/passwd", Typeflag: tar.TypeReg, Mode: 0644}, "root"},
+		{tar.Header{Name: "app/link", Typeflag: tar.TypeSymlink, Linkname: "/etc/shadow"}, ""},
+	}
+	for _, entry := range entries {
+		entry.header.Size = int64(len(entry.content))
+		if err := tw.WriteHeader(&entry.header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+
+	out := new(bytes.Buffer)
+	if err := tarToZip(buf, out); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "app/,app/config.yaml,etc/passwd" {
+		t.Errorf("unexpected zip entries: %v", names)
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	data, err := ioutil.ReadAll(&limitedReader{r: strings.NewReader("12345"), remaining: 5})
+	if err != nil || string(data) != "12345" {
+		t.Errorf("got %q, %v", data, err)
+	}
+	if _, err := ioutil.ReadAll(&limitedReader{r: strings.NewReader("123456"), remaining: 5}); err != ErrSizeLimitExceeded {
+		t.Errorf("expected ErrSizeLimitExceeded, got %v", err)
+	}
+}
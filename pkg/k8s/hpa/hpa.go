@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hpa
+
+import (
+	"context"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
+	"github.com/dnsjia/luban/pkg/k8s/dataselect"
+	autoscaling "k8s.io/api/autoscaling/v2beta2"
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HorizontalPodAutoscalerList contains a list of Horizontal Pod Autoscalers in the cluster.
+type HorizontalPodAutoscalerList struct {
+	ListMeta k8s.ListMeta `json:"listMeta"`
+
+	// Unordered list of Horizontal Pod Autoscalers.
+	HorizontalPodAutoscalers []HorizontalPodAutoscaler `json:"horizontalpodautoscalers"`
+}
+
+// ScaleTargetRef identifies the resource scaled by a Horizontal Pod Autoscaler.
+type ScaleTargetRef struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	APIVersion string `json:"apiVersion"`
+}
+
+// HorizontalPodAutoscaler (aka. Horizontal Pod Autoscaler)
+type HorizontalPodAutoscaler struct {
+	ObjectMeta k8s.ObjectMeta `json:"objectMeta"`
+	TypeMeta   k8s.TypeMeta   `json:"typeMeta"`
+
+	ScaleTargetRef ScaleTargetRef `json:"scaleTargetRef"`
+
+	MinReplicas *int32 `json:"minReplicas"`
+	MaxReplicas int32  `json:"maxReplicas"`
+
+	CurrentReplicas int32 `json:"currentReplicas"`
+	DesiredReplicas int32 `json:"desiredReplicas"`
+
+	// CPU utilization percentages, only set when the autoscaler targets average CPU utilization.
+	CurrentCPUUtilizationPercentage *int32 `json:"currentCPUUtilizationPercentage"`
+	TargetCPUUtilizationPercentage  *int32 `json:"targetCPUUtilizationPercentage"`
+}
+
+// GetHorizontalPodAutoscalerList returns a list of all Horizontal Pod Autoscalers in the cluster.
+func GetHorizontalPodAutoscalerList(client kubernetes.Interface, nsQuery *k8scommon.NamespaceQuery, dsQuery *dataselect.DataSelectQuery) (*HorizontalPodAutoscalerList, error) {
+	common.LOG.Info(fmt.Sprintf("Getting list of horizontal pod autoscalers in the namespace %s", nsQuery.ToRequestParam()))
+	hpaList, err := client.AutoscalingV2beta2().HorizontalPodAutoscalers(nsQuery.ToRequestParam()).List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+
+	var filteredItems []autoscaling.HorizontalPodAutoscaler
+	for _, item := range hpaList.Items {
+		if nsQuery.Matches(item.ObjectMeta.Namespace) {
+			filteredItems = append(filteredItems, item)
+		}
+	}
+	return toHorizontalPodAutoscalerList(filteredItems, dsQuery), nil
+}
+
+// GetHorizontalPodAutoscalerListForResource returns Horizontal Pod Autoscalers targeting the given resource,
+// kind is the scale target kind such as Deployment or StatefulSet.
+func GetHorizontalPodAutoscalerListForResource(client kubernetes.Interface, namespace, kind, name string) (*HorizontalPodAutoscalerList, error) {
+	hpaList, err := client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+
+	var filteredItems []autoscaling.HorizontalPodAutoscaler
+	for _, item := range hpaList.Items {
+		if item.Spec.ScaleTargetRef.Kind == kind && item.Spec.ScaleTargetRef.Name == name {
+			filteredItems = append(filteredItems, item)
+		}
+	}
+	return toHorizontalPodAutoscalerList(filteredItems, dataselect.NoDataSelect), nil
+}
+
+func toHorizontalPodAutoscalerList(hpas []autoscaling.HorizontalPodAutoscaler, dsQuery *dataselect.DataSelectQuery) *HorizontalPodAutoscalerList {
+	result := &HorizontalPodAutoscalerList{
+		HorizontalPodAutoscalers: make([]HorizontalPodAutoscaler, 0),
+		ListMeta:                 k8s.ListMeta{TotalItems: len(hpas)},
+	}
+
+	hpaCells, filteredTotal := dataselect.GenericDataSelectWithFilter(toCells(hpas), dsQuery)
+	hpas = fromCells(hpaCells)
+	result.ListMeta = k8s.ListMeta{TotalItems: filteredTotal}
+
+	for _, item := range hpas {
+		result.HorizontalPodAutoscalers = append(result.HorizontalPodAutoscalers, toHorizontalPodAutoscaler(&item))
+	}
+	return result
+}
+
+func toHorizontalPodAutoscaler(hpa *autoscaling.HorizontalPodAutoscaler) HorizontalPodAutoscaler {
+	result := HorizontalPodAutoscaler{
+		ObjectMeta: k8s.NewObjectMeta(hpa.ObjectMeta),
+		TypeMeta:   k8s.NewTypeMeta(k8s.ResourceKindHorizontalPodAutoscaler),
+		ScaleTargetRef: ScaleTargetRef{
+			Kind:       hpa.Spec.ScaleTargetRef.Kind,
+			Name:       hpa.Spec.ScaleTargetRef.Name,
+			APIVersion: hpa.Spec.ScaleTargetRef.APIVersion,
+		},
+		MinReplicas:     hpa.Spec.MinReplicas,
+		MaxReplicas:     hpa.Spec.MaxReplicas,
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		DesiredReplicas: hpa.Status.DesiredReplicas,
+	}
+
+	for _, metric := range hpa.Spec.Metrics {
+		if metric.Type == autoscaling.ResourceMetricSourceType && metric.Resource != nil &&
+			metric.Resource.Name == v1.ResourceCPU && metric.Resource.Target.AverageUtilization != nil {
+			result.TargetCPUUtilizationPercentage = metric.Resource.Target.AverageUtilization
+		}
+	}
+	for _, metric := range hpa.Status.CurrentMetrics {
+		if metric.Type == autoscaling.ResourceMetricSourceType && metric.Resource != nil &&
+			metric.Resource.Name == v1.ResourceCPU && metric.Resource.Current.AverageUtilization != nil {
+			result.CurrentCPUUtilizationPercentage = metric.Resource.Current.AverageUtilization
+		}
+	}
+	return result
+}
+
+// DeleteHorizontalPodAutoscaler 删除HPA
+func DeleteHorizontalPodAutoscaler(client kubernetes.Interface, namespace, name string) error {
+	common.LOG.Info(fmt.Sprintf("请求删除HPA: %v, namespace: %v", name, namespace))
+	return client.AutoscalingV1().HorizontalPodAutoscalers(namespace).Delete(context.TODO(), name, metaV1.DeleteOptions{})
+}
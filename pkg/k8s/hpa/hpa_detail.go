@@ -0,0 +1,184 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hpa
+
+import (
+	"context"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
+	autoscaling "k8s.io/api/autoscaling/v2beta2"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HorizontalPodAutoscalerDetail provides the presentation layer view of Kubernetes Horizontal Pod Autoscaler resource.
+type HorizontalPodAutoscalerDetail struct {
+	// Extends list item structure.
+	HorizontalPodAutoscaler `json:",inline"`
+
+	// Current versus target value of every metric the autoscaler is driven by.
+	Metrics []MetricValue `json:"metrics"`
+
+	// Conditions explain why the autoscaler can or can not scale, e.g. ScalingLimited when stuck at max replicas.
+	Conditions []k8scommon.Condition `json:"conditions"`
+
+	LastScaleTime *metaV1.Time `json:"lastScaleTime"`
+
+	Behavior *autoscaling.HorizontalPodAutoscalerBehavior `json:"behavior"`
+}
+
+// MetricValue is a human readable pair of the target and current value of a metric.
+type MetricValue struct {
+	Type    autoscaling.MetricSourceType `json:"type"`
+	Name    string                       `json:"name"`
+	Target  string                       `json:"target"`
+	Current string                       `json:"current"`
+}
+
+// GetHorizontalPodAutoscalerDetail returns detailed information about a horizontal pod autoscaler
+func GetHorizontalPodAutoscalerDetail(client kubernetes.Interface, namespace string, name string) (*HorizontalPodAutoscalerDetail, error) {
+	common.LOG.Info(fmt.Sprintf("Getting details of %s horizontal pod autoscaler in %s namespace", name, namespace))
+
+	rawHpa, err := client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return getHorizontalPodAutoscalerDetail(rawHpa), nil
+}
+
+func getHorizontalPodAutoscalerDetail(hpa *autoscaling.HorizontalPodAutoscaler) *HorizontalPodAutoscalerDetail {
+	conditions := make([]k8scommon.Condition, 0, len(hpa.Status.Conditions))
+	for _, condition := range hpa.Status.Conditions {
+		conditions = append(conditions, k8scommon.Condition{
+			Type:               string(condition.Type),
+			Status:             metaV1.ConditionStatus(condition.Status),
+			LastTransitionTime: condition.LastTransitionTime,
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+		})
+	}
+
+	return &HorizontalPodAutoscalerDetail{
+		HorizontalPodAutoscaler: toHorizontalPodAutoscaler(hpa),
+		Metrics:                 getMetricValues(hpa.Spec.Metrics, hpa.Status.CurrentMetrics),
+		Conditions:              conditions,
+		LastScaleTime:           hpa.Status.LastScaleTime,
+		Behavior:                hpa.Spec.Behavior,
+	}
+}
+
+// getMetricValues pairs spec metrics with status metrics. The controller reports current metrics in the
+// same order as the spec, the same assumption kubectl describe makes.
+func getMetricValues(specs []autoscaling.MetricSpec, statuses []autoscaling.MetricStatus) []MetricValue {
+	values := make([]MetricValue, 0, len(specs))
+	for i, spec := range specs {
+		value := MetricValue{Type: spec.Type, Current: "<unknown>"}
+		var status *autoscaling.MetricStatus
+		if i < len(statuses) && statuses[i].Type == spec.Type {
+			status = &statuses[i]
+		}
+
+		switch spec.Type {
+		case autoscaling.ResourceMetricSourceType:
+			if spec.Resource == nil {
+				continue
+			}
+			value.Name = string(spec.Resource.Name)
+			value.Target = formatTarget(spec.Resource.Target)
+			if status != nil && status.Resource != nil {
+				value.Current = formatCurrent(status.Resource.Current, spec.Resource.Target.Type)
+			}
+		case autoscaling.ContainerResourceMetricSourceType:
+			if spec.ContainerResource == nil {
+				continue
+			}
+			value.Name = fmt.Sprintf("%s/%s", spec.ContainerResource.Container, spec.ContainerResource.Name)
+			value.Target = formatTarget(spec.ContainerResource.Target)
+			if status != nil && status.ContainerResource != nil {
+				value.Current = formatCurrent(status.ContainerResource.Current, spec.ContainerResource.Target.Type)
+			}
+		case autoscaling.PodsMetricSourceType:
+			if spec.Pods == nil {
+				continue
+			}
+			value.Name = spec.Pods.Metric.Name
+			value.Target = formatTarget(spec.Pods.Target)
+			if status != nil && status.Pods != nil {
+				value.Current = formatCurrent(status.Pods.Current, spec.Pods.Target.Type)
+			}
+		case autoscaling.ObjectMetricSourceType:
+			if spec.Object == nil {
+				continue
+			}
+			value.Name = fmt.Sprintf("%s (on %s/%s)", spec.Object.Metric.Name, spec.Object.DescribedObject.Kind, spec.Object.DescribedObject.Name)
+			value.Target = formatTarget(spec.Object.Target)
+			if status != nil && status.Object != nil {
+				value.Current = formatCurrent(status.Object.Current, spec.Object.Target.Type)
+			}
+		case autoscaling.ExternalMetricSourceType:
+			if spec.External == nil {
+				continue
+			}
+			value.Name = spec.External.Metric.Name
+			value.Target = formatTarget(spec.External.Target)
+			if status != nil && status.External != nil {
+				value.Current = formatCurrent(status.External.Current, spec.External.Target.Type)
+			}
+		default:
+			continue
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+func formatTarget(target autoscaling.MetricTarget) string {
+	switch target.Type {
+	case autoscaling.UtilizationMetricType:
+		if target.AverageUtilization != nil {
+			return fmt.Sprintf("%d%%", *target.AverageUtilization)
+		}
+	case autoscaling.AverageValueMetricType:
+		if target.AverageValue != nil {
+			return target.AverageValue.String() + " (avg)"
+		}
+	case autoscaling.ValueMetricType:
+		if target.Value != nil {
+			return target.Value.String()
+		}
+	}
+	return "<unset>"
+}
+
+func formatCurrent(current autoscaling.MetricValueStatus, targetType autoscaling.MetricTargetType) string {
+	switch targetType {
+	case autoscaling.UtilizationMetricType:
+		if current.AverageUtilization != nil {
+			return fmt.Sprintf("%d%%", *current.AverageUtilization)
+		}
+	case autoscaling.AverageValueMetricType:
+		if current.AverageValue != nil {
+			return current.AverageValue.String() + " (avg)"
+		}
+	case autoscaling.ValueMetricType:
+		if current.Value != nil {
+			return current.Value.String()
+		}
+	}
+	return "<unknown>"
+}
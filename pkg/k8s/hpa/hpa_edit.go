@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hpa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscaling "k8s.io/api/autoscaling/v2beta2"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// CreateHorizontalPodAutoscaler 按 data.APIVersion 创建 autoscaling/v1 或 autoscaling/v2beta2 的HPA
+func CreateHorizontalPodAutoscaler(client kubernetes.Interface, data k8s.HorizontalPodAutoscalerData) error {
+	if err := validateHorizontalPodAutoscaler(data); err != nil {
+		return err
+	}
+	common.LOG.Info(fmt.Sprintf("创建HPA: %v, namespace: %v, 版本: %v", data.Name, data.Namespace, data.APIVersion))
+	meta := metaV1.ObjectMeta{Name: data.Name, Namespace: data.Namespace}
+
+	if data.APIVersion == k8s.AutoscalingV1 {
+		hpa := &autoscalingv1.HorizontalPodAutoscaler{ObjectMeta: meta}
+		applyV1Spec(hpa, data)
+		_, err := client.AutoscalingV1().HorizontalPodAutoscalers(data.Namespace).Create(context.TODO(), hpa, metaV1.CreateOptions{})
+		return err
+	}
+	hpa := &autoscaling.HorizontalPodAutoscaler{ObjectMeta: meta}
+	applyV2beta2Spec(hpa, data)
+	_, err := client.AutoscalingV2beta2().HorizontalPodAutoscalers(data.Namespace).Create(context.TODO(), hpa, metaV1.CreateOptions{})
+	return err
+}
+
+// UpdateHorizontalPodAutoscaler 修改已有HPA的 spec, 保留 metadata 中的其他字段
+func UpdateHorizontalPodAutoscaler(client kubernetes.Interface, data k8s.HorizontalPodAutoscalerData) error {
+	if err := validateHorizontalPodAutoscaler(data); err != nil {
+		return err
+	}
+	common.LOG.Info(fmt.Sprintf("修改HPA: %v, namespace: %v, 版本: %v", data.Name, data.Namespace, data.APIVersion))
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if data.APIVersion == k8s.AutoscalingV1 {
+			hpa, err := client.AutoscalingV1().HorizontalPodAutoscalers(data.Namespace).Get(context.TODO(), data.Name, metaV1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			applyV1Spec(hpa, data)
+			_, err = client.AutoscalingV1().HorizontalPodAutoscalers(data.Namespace).Update(context.TODO(), hpa, metaV1.UpdateOptions{})
+			return err
+		}
+		hpa, err := client.AutoscalingV2beta2().HorizontalPodAutoscalers(data.Namespace).Get(context.TODO(), data.Name, metaV1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		applyV2beta2Spec(hpa, data)
+		_, err = client.AutoscalingV2beta2().HorizontalPodAutoscalers(data.Namespace).Update(context.TODO(), hpa, metaV1.UpdateOptions{})
+		return err
+	})
+}
+
+func applyV1Spec(hpa *autoscalingv1.HorizontalPodAutoscaler, data k8s.HorizontalPodAutoscalerData) {
+	hpa.Spec = autoscalingv1.HorizontalPodAutoscalerSpec{
+		ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{
+			Kind:       data.ScaleTargetKind,
+			Name:       data.ScaleTargetName,
+			APIVersion: scaleTargetAPIVersion(data),
+		},
+		MinReplicas:                    data.MinReplicas,
+		MaxReplicas:                    data.MaxReplicas,
+		TargetCPUUtilizationPercentage: data.TargetCPUUtilizationPercentage,
+	}
+}
+
+func applyV2beta2Spec(hpa *autoscaling.HorizontalPodAutoscaler, data k8s.HorizontalPodAutoscalerData) {
+	hpa.Spec = autoscaling.HorizontalPodAutoscalerSpec{
+		ScaleTargetRef: autoscaling.CrossVersionObjectReference{
+			Kind:       data.ScaleTargetKind,
+			Name:       data.ScaleTargetName,
+			APIVersion: scaleTargetAPIVersion(data),
+		},
+		MinReplicas: data.MinReplicas,
+		MaxReplicas: data.MaxReplicas,
+		Metrics:     data.Metrics,
+		Behavior:    data.Behavior,
+	}
+}
+
+func scaleTargetAPIVersion(data k8s.HorizontalPodAutoscalerData) string {
+	if data.ScaleTargetAPIVersion == "" {
+		return "apps/v1"
+	}
+	return data.ScaleTargetAPIVersion
+}
+
+func validateHorizontalPodAutoscaler(data k8s.HorizontalPodAutoscalerData) error {
+	switch data.APIVersion {
+	case "", k8s.AutoscalingV2beta2, k8s.AutoscalingV1:
+	default:
+		return fmt.Errorf("不支持的HPA版本: %v", data.APIVersion)
+	}
+	if data.MaxReplicas < 1 {
+		return errors.New("最大副本数必须大于0")
+	}
+	if data.MinReplicas != nil && (*data.MinReplicas < 1 || *data.MinReplicas > data.MaxReplicas) {
+		return errors.New("最小副本数必须大于0且不大于最大副本数")
+	}
+	if data.APIVersion == k8s.AutoscalingV1 && len(data.Metrics) > 0 {
+		return errors.New("autoscaling/v1 只支持 targetCPUUtilizationPercentage, 请使用 autoscaling/v2beta2")
+	}
+	return nil
+}
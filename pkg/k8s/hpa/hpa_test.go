@@ -0,0 +1,209 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hpa
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	"go.uber.org/zap"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscaling "k8s.io/api/autoscaling/v2beta2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func cpuMetric(utilization int32) []autoscaling.MetricSpec {
+	return []autoscaling.MetricSpec{{
+		Type: autoscaling.ResourceMetricSourceType,
+		Resource: &autoscaling.ResourceMetricSource{
+			Name:   v1.ResourceCPU,
+			Target: autoscaling.MetricTarget{Type: autoscaling.UtilizationMetricType, AverageUtilization: &utilization},
+		},
+	}}
+}
+
+func TestValidateHorizontalPodAutoscaler(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    k8s.HorizontalPodAutoscalerData
+		wantErr bool
+	}{
+		{name: "default version", data: k8s.HorizontalPodAutoscalerData{MaxReplicas: 3}},
+		{name: "v2beta2 with metrics", data: k8s.HorizontalPodAutoscalerData{APIVersion: k8s.AutoscalingV2beta2, MaxReplicas: 3, Metrics: cpuMetric(80)}},
+		{name: "v1 with cpu target", data: k8s.HorizontalPodAutoscalerData{APIVersion: k8s.AutoscalingV1, MaxReplicas: 3, TargetCPUUtilizationPercentage: int32Ptr(80)}},
+		{name: "v1 with metrics", data: k8s.HorizontalPodAutoscalerData{APIVersion: k8s.AutoscalingV1, MaxReplicas: 3, Metrics: cpuMetric(80)}, wantErr: true},
+		{name: "unknown version", data: k8s.HorizontalPodAutoscalerData{APIVersion: "autoscaling/v3", MaxReplicas: 3}, wantErr: true},
+		{name: "zero max replicas", data: k8s.HorizontalPodAutoscalerData{MaxReplicas: 0}, wantErr: true},
+		{name: "min greater than max", data: k8s.HorizontalPodAutoscalerData{MaxReplicas: 3, MinReplicas: int32Ptr(4)}, wantErr: true},
+		{name: "zero min replicas", data: k8s.HorizontalPodAutoscalerData{MaxReplicas: 3, MinReplicas: int32Ptr(0)}, wantErr: true},
+	}
+	for _, c := range cases {
+		if err := validateHorizontalPodAutoscaler(c.data); (err != nil) != c.wantErr {
+			t.Errorf("%s: validateHorizontalPodAutoscaler() error = %v, wantErr %v", c.name, err, c.wantErr)
+		}
+	}
+}
+
+// TestCreateHorizontalPodAutoscalerVersion 按 APIVersion 写入对应版本的HPA, 未指定时使用 v2beta2
+func TestCreateHorizontalPodAutoscalerVersion(t *testing.T) {
+	common.LOG = zap.NewNop()
+	behavior := &autoscaling.HorizontalPodAutoscalerBehavior{
+		ScaleDown: &autoscaling.HPAScalingRules{StabilizationWindowSeconds: int32Ptr(60)},
+	}
+	cases := []struct {
+		name       string
+		apiVersion string
+		wantV1     bool
+	}{
+		{name: "default", apiVersion: ""},
+		{name: "v2beta2", apiVersion: k8s.AutoscalingV2beta2},
+		{name: "v1", apiVersion: k8s.AutoscalingV1, wantV1: true},
+	}
+
+	for _, c := range cases {
+		client := fake.NewSimpleClientset()
+		data := k8s.HorizontalPodAutoscalerData{
+			APIVersion:      c.apiVersion,
+			Namespace:       "default",
+			Name:            "api",
+			ScaleTargetKind: "Deployment",
+			ScaleTargetName: "api",
+			MinReplicas:     int32Ptr(2),
+			MaxReplicas:     5,
+		}
+		if c.wantV1 {
+			data.TargetCPUUtilizationPercentage = int32Ptr(70)
+		} else {
+			data.Metrics = cpuMetric(70)
+			data.Behavior = behavior
+		}
+		if err := CreateHorizontalPodAutoscaler(client, data); err != nil {
+			t.Fatalf("%s: CreateHorizontalPodAutoscaler returned error: %v", c.name, err)
+		}
+
+		v1List, _ := client.AutoscalingV1().HorizontalPodAutoscalers("default").List(context.TODO(), metaV1.ListOptions{})
+		v2List, _ := client.AutoscalingV2beta2().HorizontalPodAutoscalers("default").List(context.TODO(), metaV1.ListOptions{})
+		if c.wantV1 {
+			if len(v1List.Items) != 1 || len(v2List.Items) != 0 {
+				t.Fatalf("%s: created %d v1 and %d v2beta2 objects", c.name, len(v1List.Items), len(v2List.Items))
+			}
+			spec := v1List.Items[0].Spec
+			if spec.ScaleTargetRef.APIVersion != "apps/v1" || *spec.MinReplicas != 2 || spec.MaxReplicas != 5 ||
+				spec.TargetCPUUtilizationPercentage == nil || *spec.TargetCPUUtilizationPercentage != 70 {
+				t.Errorf("%s: unexpected v1 spec %+v", c.name, spec)
+			}
+			continue
+		}
+		if len(v1List.Items) != 0 || len(v2List.Items) != 1 {
+			t.Fatalf("%s: created %d v1 and %d v2beta2 objects", c.name, len(v1List.Items), len(v2List.Items))
+		}
+		spec := v2List.Items[0].Spec
+		if spec.ScaleTargetRef.APIVersion != "apps/v1" || *spec.MinReplicas != 2 || spec.MaxReplicas != 5 ||
+			len(spec.Metrics) != 1 || *spec.Metrics[0].Resource.Target.AverageUtilization != 70 ||
+			spec.Behavior == nil || *spec.Behavior.ScaleDown.StabilizationWindowSeconds != 60 {
+			t.Errorf("%s: unexpected v2beta2 spec %+v", c.name, spec)
+		}
+	}
+}
+
+// TestUpdateHorizontalPodAutoscaler 修改时替换 spec 并保留 metadata
+func TestUpdateHorizontalPodAutoscaler(t *testing.T) {
+	common.LOG = zap.NewNop()
+	meta := metaV1.ObjectMeta{Namespace: "default", Name: "api", Labels: map[string]string{"app": "api"}}
+	client := fake.NewSimpleClientset(
+		&autoscalingv1.HorizontalPodAutoscaler{ObjectMeta: meta, Spec: autoscalingv1.HorizontalPodAutoscalerSpec{MaxReplicas: 3}},
+	)
+	v2Client := fake.NewSimpleClientset(
+		&autoscaling.HorizontalPodAutoscaler{ObjectMeta: meta, Spec: autoscaling.HorizontalPodAutoscalerSpec{MaxReplicas: 3, Metrics: cpuMetric(50)}},
+	)
+
+	data := k8s.HorizontalPodAutoscalerData{
+		APIVersion:                     k8s.AutoscalingV1,
+		Namespace:                      "default",
+		Name:                           "api",
+		ScaleTargetKind:                "StatefulSet",
+		ScaleTargetName:                "db",
+		ScaleTargetAPIVersion:          "apps/v1beta2",
+		MaxReplicas:                    10,
+		TargetCPUUtilizationPercentage: int32Ptr(90),
+	}
+	if err := UpdateHorizontalPodAutoscaler(client, data); err != nil {
+		t.Fatalf("UpdateHorizontalPodAutoscaler(v1) returned error: %v", err)
+	}
+	hpaV1, _ := client.AutoscalingV1().HorizontalPodAutoscalers("default").Get(context.TODO(), "api", metaV1.GetOptions{})
+	if hpaV1.Labels["app"] != "api" || hpaV1.Spec.MaxReplicas != 10 || hpaV1.Spec.ScaleTargetRef.Kind != "StatefulSet" ||
+		hpaV1.Spec.ScaleTargetRef.APIVersion != "apps/v1beta2" || *hpaV1.Spec.TargetCPUUtilizationPercentage != 90 {
+		t.Errorf("unexpected v1 object %+v", hpaV1)
+	}
+
+	data.APIVersion = k8s.AutoscalingV2beta2
+	data.TargetCPUUtilizationPercentage = nil
+	data.Metrics = cpuMetric(60)
+	if err := UpdateHorizontalPodAutoscaler(v2Client, data); err != nil {
+		t.Fatalf("UpdateHorizontalPodAutoscaler(v2beta2) returned error: %v", err)
+	}
+	hpaV2, _ := v2Client.AutoscalingV2beta2().HorizontalPodAutoscalers("default").Get(context.TODO(), "api", metaV1.GetOptions{})
+	if hpaV2.Labels["app"] != "api" || hpaV2.Spec.MaxReplicas != 10 || *hpaV2.Spec.Metrics[0].Resource.Target.AverageUtilization != 60 {
+		t.Errorf("unexpected v2beta2 object %+v", hpaV2)
+	}
+
+	data.Name = "missing"
+	if err := UpdateHorizontalPodAutoscaler(v2Client, data); err == nil {
+		t.Errorf("UpdateHorizontalPodAutoscaler of a missing HPA returned nil")
+	}
+}
+
+func TestGetMetricValues(t *testing.T) {
+	quantity := resource.MustParse("100m")
+	specs := append(cpuMetric(80), autoscaling.MetricSpec{
+		Type: autoscaling.PodsMetricSourceType,
+		Pods: &autoscaling.PodsMetricSource{
+			Metric: autoscaling.MetricIdentifier{Name: "qps"},
+			Target: autoscaling.MetricTarget{Type: autoscaling.AverageValueMetricType, AverageValue: &quantity},
+		},
+	}, autoscaling.MetricSpec{Type: autoscaling.ExternalMetricSourceType})
+	statuses := []autoscaling.MetricStatus{{
+		Type: autoscaling.ResourceMetricSourceType,
+		Resource: &autoscaling.ResourceMetricStatus{
+			Name:    v1.ResourceCPU,
+			Current: autoscaling.MetricValueStatus{AverageUtilization: int32Ptr(35)},
+		},
+	}}
+
+	values := getMetricValues(specs, statuses)
+	expected := []MetricValue{
+		{Type: autoscaling.ResourceMetricSourceType, Name: "cpu", Target: "80%", Current: "35%"},
+		{Type: autoscaling.PodsMetricSourceType, Name: "qps", Target: "100m (avg)", Current: "<unknown>"},
+	}
+	if len(values) != len(expected) {
+		t.Fatalf("getMetricValues returned %+v", values)
+	}
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Errorf("metric %d == %+v, expected %+v", i, values[i], expected[i])
+		}
+	}
+}
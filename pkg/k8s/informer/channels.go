@@ -0,0 +1,164 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	"reflect"
+
+	"github.com/dnsjia/luban/pkg/k8s/common"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// 以下函数与 common 包中同名函数签名一致, client 由 Get 创建且对应 informer 已同步时从缓存读取,
+// 否则回退到直接请求 apiserver. 缓存中的对象与 informer 共享, 调用方只能读取不能修改.
+
+// GetPodListChannel returns a pair of channels to a Pod list and errors that both must be read numReads times.
+func GetPodListChannel(client kubernetes.Interface, nsQuery *common.NamespaceQuery, numReads int) common.PodListChannel {
+	var channel common.PodListChannel
+	if !listChannelFromCache(client, ResourcePods, nsQuery, numReads, &channel) {
+		return common.GetPodListChannel(client, nsQuery, numReads)
+	}
+	return channel
+}
+
+// GetEventListChannel returns a pair of channels to an Event list and errors that both must be read numReads times.
+func GetEventListChannel(client *kubernetes.Clientset, nsQuery *common.NamespaceQuery, numReads int) common.EventListChannel {
+	var channel common.EventListChannel
+	if !listChannelFromCache(client, ResourceEvents, nsQuery, numReads, &channel) {
+		return common.GetEventListChannel(client, nsQuery, numReads)
+	}
+	return channel
+}
+
+// GetServiceListChannel returns a pair of channels to a Service list and errors that both must be read numReads times.
+func GetServiceListChannel(client kubernetes.Interface, nsQuery *common.NamespaceQuery, numReads int) common.ServiceListChannel {
+	var channel common.ServiceListChannel
+	if !listChannelFromCache(client, ResourceServices, nsQuery, numReads, &channel) {
+		return common.GetServiceListChannel(client, nsQuery, numReads)
+	}
+	return channel
+}
+
+// GetReplicaSetListChannel returns a pair of channels to a ReplicaSet list and errors that both must be read numReads times.
+func GetReplicaSetListChannel(client kubernetes.Interface, nsQuery *common.NamespaceQuery, numReads int) common.ReplicaSetListChannel {
+	var channel common.ReplicaSetListChannel
+	if !listChannelFromCache(client, ResourceReplicaSets, nsQuery, numReads, &channel) {
+		return common.GetReplicaSetListChannel(client, nsQuery, numReads)
+	}
+	return channel
+}
+
+// GetDeploymentListChannel returns a pair of channels to a Deployment list and errors that both must be read numReads times.
+func GetDeploymentListChannel(client kubernetes.Interface, nsQuery *common.NamespaceQuery, numReads int) common.DeploymentListChannel {
+	var channel common.DeploymentListChannel
+	if !listChannelFromCache(client, ResourceDeployments, nsQuery, numReads, &channel) {
+		return common.GetDeploymentListChannel(client, nsQuery, numReads)
+	}
+	return channel
+}
+
+// GetStatefulSetListChannel returns a pair of channels to a StatefulSet list and errors that both must be read numReads times.
+func GetStatefulSetListChannel(client kubernetes.Interface, nsQuery *common.NamespaceQuery, numReads int) common.StatefulSetListChannel {
+	var channel common.StatefulSetListChannel
+	if !listChannelFromCache(client, ResourceStatefulSets, nsQuery, numReads, &channel) {
+		return common.GetStatefulSetListChannel(client, nsQuery, numReads)
+	}
+	return channel
+}
+
+// GetDaemonSetListChannel returns a pair of channels to a DaemonSet list and errors that both must be read numReads times.
+func GetDaemonSetListChannel(client kubernetes.Interface, nsQuery *common.NamespaceQuery, numReads int) common.DaemonSetListChannel {
+	var channel common.DaemonSetListChannel
+	if !listChannelFromCache(client, ResourceDaemonSets, nsQuery, numReads, &channel) {
+		return common.GetDaemonSetListChannel(client, nsQuery, numReads)
+	}
+	return channel
+}
+
+// listChannelFromCache 从缓存读取资源列表并填充 channel, 缓存不可用时返回 false.
+// channel 为 common 包中 *XxxListChannel 的指针, 其 List 字段为 chan *XxxList, Error 字段为 chan error,
+// 列表类型由 List 字段推导, 缓存中的 *Xxx 对象按值放入 XxxList.Items.
+func listChannelFromCache(client kubernetes.Interface, resource string, nsQuery *common.NamespaceQuery, numReads int, channel interface{}) bool {
+	objects, ok := listFromCache(client, resource, nsQuery)
+	if !ok {
+		return false
+	}
+
+	value := reflect.ValueOf(channel).Elem()
+	listChan := value.FieldByName("List")
+	errChan := value.FieldByName("Error")
+	listChan.Set(reflect.MakeChan(listChan.Type(), numReads))
+	errChan.Set(reflect.MakeChan(errChan.Type(), numReads))
+
+	list := buildList(listChan.Type().Elem().Elem(), objects)
+	for i := 0; i < numReads; i++ {
+		listChan.Send(list)
+		errChan.Send(reflect.Zero(errChan.Type().Elem()))
+	}
+	return true
+}
+
+// buildList 创建 listType 类型的列表并将 objects 放入 Items, 返回列表的指针
+func buildList(listType reflect.Type, objects []interface{}) reflect.Value {
+	list := reflect.New(listType)
+	items := list.Elem().FieldByName("Items")
+	values := reflect.MakeSlice(items.Type(), 0, len(objects))
+	for _, obj := range objects {
+		values = reflect.Append(values, reflect.ValueOf(obj).Elem())
+	}
+	items.Set(values)
+	return list
+}
+
+// listFromCache 从集群缓存中读取匹配 nsQuery 的对象, 缓存不存在或尚未同步时返回 false
+func listFromCache(client kubernetes.Interface, resource string, nsQuery *common.NamespaceQuery) ([]interface{}, bool) {
+	cc := ForClient(client)
+	if cc == nil || !cc.HasSynced(resource) {
+		return nil, false
+	}
+	return cc.list(resource, nsQuery)
+}
+
+// list 读取缓存中匹配 nsQuery 的对象, 调用方需确保 informer 已同步
+func (cc *ClusterCache) list(resource string, nsQuery *common.NamespaceQuery) ([]interface{}, bool) {
+	cc.mu.RLock()
+	informer, ok := cc.informers[resource]
+	cc.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	indexer := informer.GetIndexer()
+
+	if len(nsQuery.Namespaces) == 1 {
+		objects, err := indexer.ByIndex(cache.NamespaceIndex, nsQuery.Namespaces[0])
+		return objects, err == nil
+	}
+
+	objects := make([]interface{}, 0)
+	for _, obj := range indexer.List() {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			continue
+		}
+		if nsQuery.Matches(accessor.GetNamespace()) {
+			objects = append(objects, obj)
+		}
+	}
+	return objects, true
+}
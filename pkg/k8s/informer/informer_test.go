@@ -0,0 +1,225 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	lubanCommon "github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/pkg/k8s/common"
+	"go.uber.org/zap"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+func testKubeConfig(server string) string {
+	return `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: ` + server + `
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: test
+`
+}
+
+// TestGetFingerprint 只有 kubeconfig 变化时才重建缓存
+func TestGetFingerprint(t *testing.T) {
+	lubanCommon.LOG = zap.NewNop()
+	cluster := models.K8SCluster{KubeConfig: testKubeConfig("https://127.0.0.1:6443")}
+	cluster.ID = 1001
+	defer Invalidate(cluster.ID)
+
+	cc, err := Get(cluster)
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+
+	// 修改名称、监控数据源等字段会更新 UpdatedAt, 不应重建
+	cluster.ClusterName = "renamed"
+	cluster.MetricsSource.Type = models.MetricsSourceKSM
+	cluster.UpdatedAt = models.LocalTime{Time: time.Now().Add(time.Hour)}
+	same, err := Get(cluster)
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if same != cc {
+		t.Errorf("Get rebuilt the cache after an unrelated cluster edit")
+	}
+
+	cluster.KubeConfig = testKubeConfig("https://127.0.0.2:6443")
+	rebuilt, err := Get(cluster)
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if rebuilt == cc || !cc.stopped {
+		t.Errorf("Get did not rebuild the cache after the kubeconfig changed")
+	}
+	if ForClient(cc.Client()) != nil || ForClient(rebuilt.Client()) != rebuilt {
+		t.Errorf("clientset index was not updated")
+	}
+}
+
+// newSyncedCache 返回以 objects 为内容且已同步的集群缓存, 通过返回的 clientset 可以找到该缓存
+func newSyncedCache(t *testing.T, objects ...runtime.Object) *kubernetes.Clientset {
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: "https://127.0.0.1:6443"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopCh := make(chan struct{})
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(objects...), 0)
+	cc := &ClusterCache{
+		ClusterID: 1002,
+		client:    clientset,
+		factory:   factory,
+		informers: make(map[string]cache.SharedIndexInformer),
+		stopCh:    stopCh,
+	}
+	for resource, gvr := range resourceGVRs {
+		informer, err := factory.ForResource(gvr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cc.informers[resource] = informer.Informer()
+	}
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	manager.Lock()
+	manager.clusters[cc.ClusterID] = cc
+	manager.clients[clientset] = cc
+	manager.Unlock()
+	t.Cleanup(func() { Invalidate(cc.ClusterID) })
+	return clientset
+}
+
+func TestListChannelFromCache(t *testing.T) {
+	pod := func(namespace, name string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metaV1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+	clientset := newSyncedCache(t,
+		pod("default", "a"), pod("default", "b"), pod("kube-system", "c"), pod("dev", "d"),
+		&apps.Deployment{ObjectMeta: metaV1.ObjectMeta{Namespace: "default", Name: "api"}},
+	)
+
+	cases := []struct {
+		nsQuery  *common.NamespaceQuery
+		expected []string
+	}{
+		{nsQuery: common.NewSameNamespaceQuery("default"), expected: []string{"a", "b"}},
+		{nsQuery: common.NewNamespaceQuery([]string{"default", "dev"}), expected: []string{"a", "b", "d"}},
+		{nsQuery: common.NewNamespaceQuery(nil), expected: []string{"a", "b", "c", "d"}},
+		{nsQuery: common.NewSameNamespaceQuery("missing"), expected: []string{}},
+	}
+	for _, c := range cases {
+		channel := GetPodListChannel(clientset, c.nsQuery, 2)
+		for i := 0; i < 2; i++ {
+			list := <-channel.List
+			if err := <-channel.Error; err != nil {
+				t.Fatalf("read %d returned error: %v", i, err)
+			}
+			names := make([]string, 0, len(list.Items))
+			for _, item := range list.Items {
+				names = append(names, item.Name)
+			}
+			sort.Strings(names)
+			if len(names) != len(c.expected) {
+				t.Errorf("%v: read %d returned %v, expected %v", c.nsQuery.Namespaces, i, names, c.expected)
+				continue
+			}
+			for j := range names {
+				if names[j] != c.expected[j] {
+					t.Errorf("%v: read %d returned %v, expected %v", c.nsQuery.Namespaces, i, names, c.expected)
+					break
+				}
+			}
+		}
+	}
+
+	deployments := <-GetDeploymentListChannel(clientset, common.NewSameNamespaceQuery("default"), 1).List
+	if len(deployments.Items) != 1 || deployments.Items[0].Name != "api" {
+		t.Errorf("GetDeploymentListChannel returned %v", deployments.Items)
+	}
+	services := <-GetServiceListChannel(clientset, common.NewNamespaceQuery(nil), 1).List
+	if len(services.Items) != 0 {
+		t.Errorf("GetServiceListChannel returned %v", services.Items)
+	}
+}
+
+// TestListChannelFallback 不是由 Get 创建的客户端直接请求 apiserver
+func TestListChannelFallback(t *testing.T) {
+	client := fake.NewSimpleClientset(&apps.StatefulSet{ObjectMeta: metaV1.ObjectMeta{Namespace: "default", Name: "db"}})
+	channel := GetStatefulSetListChannel(client, common.NewSameNamespaceQuery("default"), 1)
+	list := <-channel.List
+	if err := <-channel.Error; err != nil {
+		t.Fatalf("GetStatefulSetListChannel returned error: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "db" {
+		t.Errorf("GetStatefulSetListChannel returned %v", list.Items)
+	}
+}
+
+func TestAddEventHandler(t *testing.T) {
+	lubanCommon.LOG = zap.NewNop()
+	clientset := newSyncedCache(t, &v1.Event{ObjectMeta: metaV1.ObjectMeta{Namespace: "default", Name: "api.1"}})
+	cc := ForClient(clientset)
+
+	added := make(chan string, 1)
+	err := cc.AddEventHandler(ResourceEvents, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			added <- obj.(*v1.Event).Name
+		},
+	})
+	if err != nil {
+		t.Fatalf("AddEventHandler returned error: %v", err)
+	}
+	select {
+	case name := <-added:
+		if name != "api.1" {
+			t.Errorf("handler received %s, expected api.1", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("handler did not receive the cached event")
+	}
+
+	if err := cc.AddEventHandler("configmaps", cache.ResourceEventHandlerFuncs{}); err == nil {
+		t.Errorf("expected error for an uncached resource")
+	}
+	cc.stop()
+	if err := cc.AddEventHandler(ResourceEvents, cache.ResourceEventHandlerFuncs{}); err == nil {
+		t.Errorf("expected error after the cache is stopped")
+	}
+}
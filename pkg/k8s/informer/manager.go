@@ -0,0 +1,277 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package informer 为每个集群维护一份 shared informer 缓存, 列表请求优先从缓存读取, 避免每次都全量 LIST apiserver
+package informer
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models"
+	"go.uber.org/zap"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"sync"
+	"time"
+)
+
+// 缓存的资源类型
+const (
+	ResourcePods         = "pods"
+	ResourceEvents       = "events"
+	ResourceReplicaSets  = "replicasets"
+	ResourceDeployments  = "deployments"
+	ResourceStatefulSets = "statefulsets"
+	ResourceDaemonSets   = "daemonsets"
+	ResourceServices     = "services"
+)
+
+// resourceGVRs 缓存的资源, informer 通过 SharedInformerFactory.ForResource 创建
+var resourceGVRs = map[string]schema.GroupVersionResource{
+	ResourcePods:         coreV1.SchemeGroupVersion.WithResource(ResourcePods),
+	ResourceEvents:       coreV1.SchemeGroupVersion.WithResource(ResourceEvents),
+	ResourceServices:     coreV1.SchemeGroupVersion.WithResource(ResourceServices),
+	ResourceReplicaSets:  appsV1.SchemeGroupVersion.WithResource(ResourceReplicaSets),
+	ResourceDeployments:  appsV1.SchemeGroupVersion.WithResource(ResourceDeployments),
+	ResourceStatefulSets: appsV1.SchemeGroupVersion.WithResource(ResourceStatefulSets),
+	ResourceDaemonSets:   appsV1.SchemeGroupVersion.WithResource(ResourceDaemonSets),
+}
+
+// ClusterCache 单个集群的客户端与 informer 缓存. 客户端在创建时就绪, informer 在第一次读取列表时才启动
+type ClusterCache struct {
+	ClusterID uint
+
+	config      *rest.Config
+	client      *kubernetes.Clientset
+	fingerprint string
+
+	mu        sync.RWMutex
+	stopped   bool
+	factory   informers.SharedInformerFactory
+	informers map[string]cache.SharedIndexInformer
+	stopCh    chan struct{}
+	startedAt time.Time
+}
+
+// SyncStatus 集群缓存的同步状态
+type SyncStatus struct {
+	ClusterID uint            `json:"clusterId"`
+	Started   bool            `json:"started"`
+	StartedAt *time.Time      `json:"startedAt"`
+	Synced    bool            `json:"synced"`
+	Resources map[string]bool `json:"resources"`
+}
+
+// Manager 管理所有集群的缓存, 以集群ID为 key
+type Manager struct {
+	sync.RWMutex
+	clusters map[uint]*ClusterCache
+	// clients 通过 clientset 反查缓存, 使现有以 clientset 为参数的列表函数无需修改签名
+	clients map[*kubernetes.Clientset]*ClusterCache
+}
+
+var manager = &Manager{
+	clusters: make(map[uint]*ClusterCache),
+	clients:  make(map[*kubernetes.Clientset]*ClusterCache),
+}
+
+// Get 返回集群的缓存. 集群的 kubeconfig 发生变化时, 旧缓存会被停止并重建
+func Get(cluster models.K8SCluster) (*ClusterCache, error) {
+	fingerprint := clusterFingerprint(cluster)
+
+	manager.RLock()
+	cc, ok := manager.clusters[cluster.ID]
+	manager.RUnlock()
+	if ok && cc.fingerprint == fingerprint {
+		return cc, nil
+	}
+
+	manager.Lock()
+	defer manager.Unlock()
+	if cc, ok := manager.clusters[cluster.ID]; ok {
+		if cc.fingerprint == fingerprint {
+			return cc, nil
+		}
+		common.LOG.Info(fmt.Sprintf("集群%d配置已变更, 重建informer缓存", cluster.ID))
+		manager.remove(cc)
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig([]byte(cluster.KubeConfig))
+	if err != nil {
+		common.LOG.Error("KubeConfig内容错误", zap.Any("err", err))
+		return nil, fmt.Errorf("KubeConfig内容错误")
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		common.LOG.Error("创建Client失败", zap.Any("err", err))
+		return nil, fmt.Errorf("创建Client失败！")
+	}
+
+	cc = &ClusterCache{
+		ClusterID:   cluster.ID,
+		config:      config,
+		client:      client,
+		fingerprint: fingerprint,
+	}
+	manager.clusters[cluster.ID] = cc
+	manager.clients[client] = cc
+	return cc, nil
+}
+
+// Invalidate 停止并移除集群的缓存, 集群被删除或修改时调用
+func Invalidate(clusterID uint) {
+	manager.Lock()
+	defer manager.Unlock()
+	if cc, ok := manager.clusters[clusterID]; ok {
+		manager.remove(cc)
+	}
+}
+
+// Status 返回集群缓存的同步状态, 缓存不存在时返回未启动
+func Status(clusterID uint) SyncStatus {
+	manager.RLock()
+	cc, ok := manager.clusters[clusterID]
+	manager.RUnlock()
+	if !ok {
+		return SyncStatus{ClusterID: clusterID, Resources: map[string]bool{}}
+	}
+	return cc.Status()
+}
+
+// ForClient 返回由 Get 创建该 clientset 的集群缓存, 不存在时返回 nil
+func ForClient(client kubernetes.Interface) *ClusterCache {
+	clientset, ok := client.(*kubernetes.Clientset)
+	if !ok {
+		return nil
+	}
+	manager.RLock()
+	defer manager.RUnlock()
+	return manager.clients[clientset]
+}
+
+func (m *Manager) remove(cc *ClusterCache) {
+	delete(m.clusters, cc.ClusterID)
+	delete(m.clients, cc.client)
+	cc.stop()
+}
+
+// Client 返回集群的 clientset
+func (cc *ClusterCache) Client() *kubernetes.Clientset {
+	return cc.client
+}
+
+// Config 返回集群 RESTConfig 的副本
+func (cc *ClusterCache) Config() *rest.Config {
+	return rest.CopyConfig(cc.config)
+}
+
+// Start 启动集群的 informer, 重复调用只会启动一次, 不等待同步完成
+func (cc *ClusterCache) Start() {
+	cc.mu.RLock()
+	started := cc.informers != nil || cc.stopped
+	cc.mu.RUnlock()
+	if started {
+		return
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.informers != nil || cc.stopped {
+		return
+	}
+	common.LOG.Info(fmt.Sprintf("启动集群%d的informer缓存", cc.ClusterID))
+	cc.stopCh = make(chan struct{})
+	cc.factory = informers.NewSharedInformerFactory(cc.client, 0)
+	cc.informers = make(map[string]cache.SharedIndexInformer, len(resourceGVRs))
+	for resource, gvr := range resourceGVRs {
+		informer, err := cc.factory.ForResource(gvr)
+		if err != nil {
+			common.LOG.Error(fmt.Sprintf("创建%s informer失败", resource), zap.Any("err", err))
+			continue
+		}
+		cc.informers[resource] = informer.Informer()
+	}
+	cc.startedAt = time.Now()
+	cc.factory.Start(cc.stopCh)
+}
+
+// AddEventHandler 为资源的 informer 注册处理函数, 缓存未启动时会先启动. 当前 client-go 版本不支持移除处理函数,
+// 缓存停止后处理函数不会再被调用
+func (cc *ClusterCache) AddEventHandler(resource string, handler cache.ResourceEventHandler) error {
+	cc.Start()
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	informer, ok := cc.informers[resource]
+	if !ok || cc.stopped {
+		return fmt.Errorf("集群%d的%s缓存不可用", cc.ClusterID, resource)
+	}
+	informer.AddEventHandler(handler)
+	return nil
+}
+
+// HasSynced 返回资源的 informer 是否已完成首次同步, 缓存未启动时会先启动
+func (cc *ClusterCache) HasSynced(resource string) bool {
+	cc.Start()
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	informer, ok := cc.informers[resource]
+	return ok && !cc.stopped && informer.HasSynced()
+}
+
+// Status 返回缓存的同步状态
+func (cc *ClusterCache) Status() SyncStatus {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	status := SyncStatus{ClusterID: cc.ClusterID, Resources: make(map[string]bool)}
+	if cc.informers == nil || cc.stopped {
+		return status
+	}
+	startedAt := cc.startedAt
+	status.Started = true
+	status.StartedAt = &startedAt
+	status.Synced = true
+
+	for resource, informer := range cc.informers {
+		synced := informer.HasSynced()
+		status.Resources[resource] = synced
+		status.Synced = status.Synced && synced
+	}
+	return status
+}
+
+func (cc *ClusterCache) stop() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.stopped {
+		return
+	}
+	cc.stopped = true
+	if cc.stopCh != nil {
+		close(cc.stopCh)
+	}
+}
+
+// clusterFingerprint 集群连接信息的指纹, 只取 kubeconfig, 修改集群名称、监控数据源等不会重建缓存
+func clusterFingerprint(cluster models.K8SCluster) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(cluster.KubeConfig)))
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package limitrange
+
+import (
+	"context"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// limitRanges provides set of limit ranges by limit types and resource names
+type limitRanges map[v1.LimitType]rangeMap
+
+// rangeMap provides limit ranges by resource name
+type rangeMap map[v1.ResourceName]*LimitRangeItem
+
+func (rMap rangeMap) getRange(resource v1.ResourceName) *LimitRangeItem {
+	r, ok := rMap[resource]
+	if !ok {
+		rMap[resource] = &LimitRangeItem{}
+		return rMap[resource]
+	}
+	return r
+}
+
+// LimitRangeItem provides resource limit range values
+type LimitRangeItem struct {
+	// ResourceName usage constraints on this kind by resource name
+	ResourceName string `json:"resourceName,omitempty"`
+	// ResourceType of resource that this limit applies to
+	ResourceType string `json:"resourceType,omitempty"`
+	// Min usage constraints on this kind by resource name
+	Min string `json:"min,omitempty"`
+	// Max usage constraints on this kind by resource name
+	Max string `json:"max,omitempty"`
+	// Default resource requirement limit value by resource name.
+	Default string `json:"default,omitempty"`
+	// DefaultRequest resource requirement request value by resource name.
+	DefaultRequest string `json:"defaultRequest,omitempty"`
+	// MaxLimitRequestRatio represents the max burst value for the named resource
+	MaxLimitRequestRatio string `json:"maxLimitRequestRatio,omitempty"`
+}
+
+// LimitRange is a presentation layer view of Kubernetes LimitRange resource.
+type LimitRange struct {
+	ObjectMeta k8s.ObjectMeta `json:"objectMeta"`
+	TypeMeta   k8s.TypeMeta   `json:"typeMeta"`
+
+	// Limits flattened by limit type and resource name.
+	Limits []LimitRangeItem `json:"limits"`
+}
+
+// LimitRangeList contains a list of LimitRanges in the namespace.
+type LimitRangeList struct {
+	ListMeta k8s.ListMeta `json:"listMeta"`
+	Items    []LimitRange `json:"items"`
+}
+
+// GetLimitRangeList returns all limit ranges of the namespace.
+func GetLimitRangeList(client kubernetes.Interface, namespace string) (*LimitRangeList, error) {
+	list, err := client.CoreV1().LimitRanges(namespace).List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LimitRangeList{
+		Items:    make([]LimitRange, 0),
+		ListMeta: k8s.ListMeta{TotalItems: len(list.Items)},
+	}
+	for _, item := range list.Items {
+		result.Items = append(result.Items, toLimitRange(&item))
+	}
+	return result, nil
+}
+
+// GetLimitRangeDetail returns detailed information about a limit range.
+func GetLimitRangeDetail(client kubernetes.Interface, namespace, name string) (*LimitRange, error) {
+	rawLimitRange, err := client.CoreV1().LimitRanges(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	limitRange := toLimitRange(rawLimitRange)
+	return &limitRange, nil
+}
+
+func toLimitRange(rawLimitRange *v1.LimitRange) LimitRange {
+	return LimitRange{
+		ObjectMeta: k8s.NewObjectMeta(rawLimitRange.ObjectMeta),
+		TypeMeta:   k8s.NewTypeMeta(k8s.ResourceKindLimitRange),
+		Limits:     ToLimitRanges(rawLimitRange),
+	}
+}
+
+// ToLimitRanges flattens the limit range items by limit type and resource name.
+func ToLimitRanges(rawLimitRange *v1.LimitRange) []LimitRangeItem {
+	rawLimitRanges := rawLimitRange.Spec.Limits
+
+	limitRangeMap := make(limitRanges)
+	for _, rawLimitRangeItem := range rawLimitRanges {
+		rangeMap := make(rangeMap)
+
+		for resource, min := range rawLimitRangeItem.Min {
+			rangeMap.getRange(resource).Min = min.String()
+		}
+		for resource, max := range rawLimitRangeItem.Max {
+			rangeMap.getRange(resource).Max = max.String()
+		}
+		for resource, df := range rawLimitRangeItem.Default {
+			rangeMap.getRange(resource).Default = df.String()
+		}
+		for resource, dfR := range rawLimitRangeItem.DefaultRequest {
+			rangeMap.getRange(resource).DefaultRequest = dfR.String()
+		}
+		for resource, mLR := range rawLimitRangeItem.MaxLimitRequestRatio {
+			rangeMap.getRange(resource).MaxLimitRequestRatio = mLR.String()
+		}
+
+		limitRangeMap[rawLimitRangeItem.Type] = rangeMap
+	}
+
+	limitRangeList := make([]LimitRangeItem, 0)
+	for limitType, rangeMap := range limitRangeMap {
+		for resourceName, limit := range rangeMap {
+			limit.ResourceName = resourceName.String()
+			limit.ResourceType = string(limitType)
+			limitRangeList = append(limitRangeList, *limit)
+		}
+	}
+	return limitRangeList
+}
+
+// CreateLimitRange 创建LimitRange
+func CreateLimitRange(client kubernetes.Interface, data k8s.LimitRangeData) error {
+	common.LOG.Info(fmt.Sprintf("创建LimitRange: %v, namespace: %v", data.Name, data.Namespace))
+	limitRange := &v1.LimitRange{
+		ObjectMeta: metaV1.ObjectMeta{Name: data.Name, Namespace: data.Namespace, Labels: data.Labels},
+		Spec:       data.Spec,
+	}
+	_, err := client.CoreV1().LimitRanges(data.Namespace).Create(context.TODO(), limitRange, metaV1.CreateOptions{})
+	return err
+}
+
+// UpdateLimitRange 修改LimitRange的 spec
+func UpdateLimitRange(client kubernetes.Interface, data k8s.LimitRangeData) error {
+	common.LOG.Info(fmt.Sprintf("修改LimitRange: %v, namespace: %v", data.Name, data.Namespace))
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		limitRange, err := client.CoreV1().LimitRanges(data.Namespace).Get(context.TODO(), data.Name, metaV1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		limitRange.Spec = data.Spec
+		if data.Labels != nil {
+			limitRange.Labels = data.Labels
+		}
+		_, err = client.CoreV1().LimitRanges(data.Namespace).Update(context.TODO(), limitRange, metaV1.UpdateOptions{})
+		return err
+	})
+}
+
+// DeleteLimitRange 删除LimitRange
+func DeleteLimitRange(client kubernetes.Interface, namespace, name string) error {
+	common.LOG.Info(fmt.Sprintf("请求删除LimitRange: %v, namespace: %v", name, namespace))
+	return client.CoreV1().LimitRanges(namespace).Delete(context.TODO(), name, metaV1.DeleteOptions{})
+}
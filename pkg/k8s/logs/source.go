@@ -30,6 +30,9 @@ func GetLogSources(k8sClient kubernetes.Interface, ns string, resourceName strin
 	if resourceType == "pod" {
 		return getLogSourcesFromPod(k8sClient, ns, resourceName)
 	}
+	if resourceType == k8s.ResourceKindDeployment {
+		return getLogSourcesFromDeployment(k8sClient, ns, resourceName)
+	}
 	return getLogSourcesFromController(k8sClient, ns, resourceName, resourceType)
 }
 
@@ -59,3 +62,30 @@ func getLogSourcesFromController(k8sClient kubernetes.Interface, ns, resourceNam
 	}
 	return rc.GetLogSources(allPods.Items), nil
 }
+
+// getLogSourcesFromDeployment returns all pods and containers for a deployment. Pods are owned by the
+// deployment's ReplicaSets rather than the deployment itself, so they are matched by the label selector.
+func getLogSourcesFromDeployment(k8sClient kubernetes.Interface, ns, resourceName string) (controller.LogSources, error) {
+	deployment, err := k8sClient.AppsV1().Deployments(ns).Get(context.TODO(), resourceName, meta.GetOptions{})
+	if err != nil {
+		return controller.LogSources{}, err
+	}
+	selector, err := meta.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return controller.LogSources{}, err
+	}
+	pods, err := k8sClient.CoreV1().Pods(ns).List(context.TODO(), meta.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return controller.LogSources{}, err
+	}
+
+	podNames := make([]string, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		podNames = append(podNames, pod.Name)
+	}
+	return controller.LogSources{
+		ContainerNames:     common.GetContainerNames(&deployment.Spec.Template.Spec),
+		InitContainerNames: common.GetInitContainerNames(&deployment.Spec.Template.Spec),
+		PodNames:           podNames,
+	}, nil
+}
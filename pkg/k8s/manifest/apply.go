@@ -0,0 +1,253 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"io"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"strings"
+)
+
+// FieldManager server-side apply 时使用的字段管理者名称
+const FieldManager = "luban"
+
+// apply 结果
+const (
+	ResultCreated    = "created"
+	ResultConfigured = "configured"
+	ResultUnchanged  = "unchanged"
+	// ResultConflict 字段由其他管理者(例如 HPA 控制器)持有, 需确认后使用 force 重新 apply
+	ResultConflict = "conflict"
+	ResultError    = "error"
+)
+
+// ApplyResult 单个对象的apply结果
+type ApplyResult struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	Result     string `json:"result"`
+	Message    string `json:"message,omitempty"`
+}
+
+// ApplyManifest 解析多文档YAML/JSON, 逐个对象执行server-side apply.
+// namespace 为未声明命名空间对象的默认命名空间, dryRun 为 true 时仅在服务端校验, 不会持久化.
+// force 为 false 时字段被其他管理者持有的对象返回 ResultConflict, 为 true 时强制接管这些字段.
+func ApplyManifest(config *rest.Config, content string, namespace string, dryRun bool, force bool) ([]ApplyResult, error) {
+	objects, err := ParseManifest(content)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := NewRESTMapper(config)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ApplyResult, 0, len(objects))
+	for _, obj := range objects {
+		results = append(results, applyObject(client, mapper, obj, namespace, dryRun, force))
+	}
+	return results, nil
+}
+
+// ParseManifest 将多文档YAML或JSON解析为unstructured对象, List类型会展开为其中的items
+func ParseManifest(content string) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(content), 4096)
+	objects := make([]*unstructured.Unstructured, 0)
+	for index := 0; ; index++ {
+		var raw map[string]interface{}
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("第%d个资源解析失败: %v", index+1, err)
+		}
+		// 跳过空文档, 例如只包含注释或连续的 ---
+		if len(raw) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: raw}
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" {
+			return nil, fmt.Errorf("第%d个资源缺少apiVersion或kind", index+1)
+		}
+
+		if obj.IsList() {
+			err := obj.EachListItem(func(item runtime.Object) error {
+				objects = append(objects, item.(*unstructured.Unstructured))
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("第%d个资源解析失败: %v", index+1, err)
+			}
+			continue
+		}
+		objects = append(objects, obj)
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("未解析到任何资源")
+	}
+	return objects, nil
+}
+
+// NewRESTMapper 通过集群的discovery信息构建 GroupVersionKind 到 GroupVersionResource 的映射
+func NewRESTMapper(config *rest.Config) (meta.RESTMapper, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	groupResources, err := restmapper.GetAPIGroupResources(discoveryClient)
+	if err != nil {
+		return nil, err
+	}
+	return restmapper.NewDiscoveryRESTMapper(groupResources), nil
+}
+
+// ResourceInterface 根据对象的GVK获取对应的dynamic客户端, 集群级别资源会忽略namespace
+func ResourceInterface(client dynamic.Interface, mapper meta.RESTMapper, obj *unstructured.Unstructured, namespace string) (dynamic.ResourceInterface, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		obj.SetNamespace("")
+		return client.Resource(mapping.Resource), nil
+	}
+
+	if obj.GetNamespace() == "" {
+		if namespace == "" {
+			namespace = metaV1.NamespaceDefault
+		}
+		obj.SetNamespace(namespace)
+	}
+	return client.Resource(mapping.Resource).Namespace(obj.GetNamespace()), nil
+}
+
+func applyObject(client dynamic.Interface, mapper meta.RESTMapper, obj *unstructured.Unstructured, namespace string, dryRun bool, force bool) ApplyResult {
+	result := ApplyResult{
+		Kind:       obj.GetKind(),
+		APIVersion: obj.GetAPIVersion(),
+		Name:       obj.GetName(),
+	}
+	fail := func(err error) ApplyResult {
+		result.Result = ResultError
+		result.Message = err.Error()
+		return result
+	}
+
+	if obj.GetName() == "" {
+		return fail(fmt.Errorf("metadata.name 不能为空"))
+	}
+
+	resource, err := ResourceInterface(client, mapper, obj, namespace)
+	if err != nil {
+		return fail(err)
+	}
+	result.Namespace = obj.GetNamespace()
+
+	existing, err := resource.Get(context.TODO(), obj.GetName(), metaV1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fail(err)
+		}
+		existing = nil
+	}
+
+	// server-side apply 不允许请求体中携带 managedFields
+	obj.SetManagedFields(nil)
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return fail(err)
+	}
+
+	applied, err := resource.Patch(context.TODO(), obj.GetName(), types.ApplyPatchType, data, applyOptions(dryRun, force))
+	if err != nil {
+		if errors.IsConflict(err) {
+			result.Result = ResultConflict
+			result.Message = err.Error()
+			return result
+		}
+		return fail(err)
+	}
+
+	switch {
+	case existing == nil:
+		result.Result = ResultCreated
+	case isChanged(existing, applied):
+		result.Result = ResultConfigured
+	default:
+		result.Result = ResultUnchanged
+	}
+	common.LOG.Info(fmt.Sprintf("apply %s %s/%s: %s, dryRun: %v", result.Kind, result.Namespace, result.Name, result.Result, dryRun))
+	return result
+}
+
+func applyOptions(dryRun bool, force bool) metaV1.PatchOptions {
+	options := metaV1.PatchOptions{FieldManager: FieldManager, Force: &force}
+	if dryRun {
+		options.DryRun = []string{metaV1.DryRunAll}
+	}
+	return options
+}
+
+// isChanged 比较apply前后的对象, 忽略由服务端维护的元数据字段.
+// dry-run 模式下 resourceVersion 不会变化, 因此需要比较对象内容.
+func isChanged(before, after *unstructured.Unstructured) bool {
+	return !equality.Semantic.DeepEqual(stripServerFields(before), stripServerFields(after))
+}
+
+func stripServerFields(obj *unstructured.Unstructured) map[string]interface{} {
+	copied := obj.DeepCopy()
+	copied.SetResourceVersion("")
+	copied.SetManagedFields(nil)
+	copied.SetGeneration(0)
+	unstructured.RemoveNestedField(copied.Object, "status")
+	return copied.Object
+}
+
+// HasConflict 是否存在字段冲突的对象
+func HasConflict(results []ApplyResult) bool {
+	for _, result := range results {
+		if result.Result == ResultConflict {
+			return true
+		}
+	}
+	return false
+}
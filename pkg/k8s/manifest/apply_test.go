@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	cases := []struct {
+		content       string
+		expectedKinds []string
+		expectedError bool
+	}{
+		{
+			content: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+# only comments
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: b
+`,
+			expectedKinds: []string{"ConfigMap", "Deployment"},
+		},
+		{
+			content:       `{"apiVersion": "v1", "kind": "List", "items": [{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "c"}}]}`,
+			expectedKinds: []string{"Service"},
+		},
+		{
+			content:       "metadata:\n  name: d\n",
+			expectedError: true,
+		},
+		{
+			content:       "---\n",
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		objects, err := ParseManifest(c.content)
+		if c.expectedError {
+			if err == nil {
+				t.Errorf("ParseManifest(%q) expected error, got nil", c.content)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ParseManifest(%q) returned error: %v", c.content, err)
+		}
+		if len(objects) != len(c.expectedKinds) {
+			t.Fatalf("ParseManifest(%q) returned %d objects, expected %d", c.content, len(objects), len(c.expectedKinds))
+		}
+		for i, obj := range objects {
+			if obj.GetKind() != c.expectedKinds[i] {
+				t.Errorf("object %d kind == %s, expected %s", i, obj.GetKind(), c.expectedKinds[i])
+			}
+		}
+	}
+}
+
+func TestApplyOptions(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		for _, force := range []bool{false, true} {
+			options := applyOptions(dryRun, force)
+			if options.FieldManager != FieldManager || options.Force == nil || *options.Force != force {
+				t.Errorf("applyOptions(%v, %v) == %+v", dryRun, force, options)
+			}
+			if (len(options.DryRun) > 0) != dryRun {
+				t.Errorf("applyOptions(%v, %v) DryRun == %v", dryRun, force, options.DryRun)
+			}
+		}
+	}
+}
+
+// TestApplyObjectConflict 字段冲突时返回 conflict 结果, 由调用方确认后再强制 apply
+func TestApplyObjectConflict(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gvk.GroupVersion()})
+	mapper.Add(gvk, meta.RESTScopeNamespace)
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{gvr: "DeploymentList"})
+	client.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewConflict(gvr.GroupResource(), "api", nil)
+	})
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName("api")
+	result := applyObject(client, mapper, obj, "", false, false)
+	if result.Result != ResultConflict || result.Namespace != "default" || result.Message == "" {
+		t.Errorf("result == %+v, expected conflict in default", result)
+	}
+	if !HasConflict([]ApplyResult{{Result: ResultCreated}, result}) {
+		t.Errorf("HasConflict == false, expected true")
+	}
+	if HasConflict([]ApplyResult{{Result: ResultCreated}, {Result: ResultError}}) {
+		t.Errorf("HasConflict == true, expected false")
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// ErrResourceConflict 提交的 resourceVersion 与集群中的对象不一致, 说明对象已被其他人修改
+var ErrResourceConflict = errors.New("资源已被他人修改, 请刷新后重新编辑")
+
+// ResourceYAML 资源对象的原始YAML
+type ResourceYAML struct {
+	Kind            string `json:"kind"`
+	APIVersion      string `json:"apiVersion"`
+	Namespace       string `json:"namespace"`
+	Name            string `json:"name"`
+	ResourceVersion string `json:"resourceVersion"`
+	Content         string `json:"content"`
+}
+
+// GetResourceYAML 获取任意资源对象的YAML, kind 支持单数或复数形式, 例如 deployment, deployments.
+// group 为空时按集群中的首选版本匹配.
+func GetResourceYAML(config *rest.Config, group, kind, namespace, name string) (*ResourceYAML, error) {
+	client, mapper, err := newDynamicClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return getResourceYAML(client, mapper, group, kind, namespace, name)
+}
+
+func getResourceYAML(client dynamic.Interface, mapper meta.RESTMapper, group, kind, namespace, name string) (*ResourceYAML, error) {
+	resource, err := namedResourceInterface(client, mapper, group, kind, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := resource.Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return toResourceYAML(obj)
+}
+
+// UpdateResourceYAML 使用YAML内容更新资源对象. 内容中必须携带 metadata.resourceVersion,
+// 若对象在读取后已被修改, 返回 ErrResourceConflict 而不会覆盖他人的修改.
+func UpdateResourceYAML(config *rest.Config, group, kind, namespace, name, content string) (*ResourceYAML, error) {
+	client, mapper, err := newDynamicClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return updateResourceYAML(client, mapper, group, kind, namespace, name, content)
+}
+
+func updateResourceYAML(client dynamic.Interface, mapper meta.RESTMapper, group, kind, namespace, name, content string) (*ResourceYAML, error) {
+	objects, err := ParseManifest(content)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) != 1 {
+		return nil, fmt.Errorf("一次只能编辑一个资源, 实际提交了%d个", len(objects))
+	}
+	obj := objects[0]
+
+	if obj.GetName() != name {
+		return nil, fmt.Errorf("不允许修改资源名称: %s", obj.GetName())
+	}
+	if obj.GetResourceVersion() == "" {
+		return nil, fmt.Errorf("metadata.resourceVersion 不能为空")
+	}
+
+	mapping, err := restMappingFor(mapper, group, kind)
+	if err != nil {
+		return nil, err
+	}
+	if obj.GroupVersionKind().GroupKind() != mapping.GroupVersionKind.GroupKind() {
+		return nil, fmt.Errorf("资源类型不匹配: %s", obj.GroupVersionKind().String())
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && obj.GetNamespace() != "" && obj.GetNamespace() != namespace {
+		return nil, fmt.Errorf("不允许修改资源命名空间: %s", obj.GetNamespace())
+	}
+
+	resource, err := ResourceInterface(client, mapper, obj, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := resource.Update(context.TODO(), obj, metaV1.UpdateOptions{FieldManager: FieldManager})
+	if err != nil {
+		if k8serrors.IsConflict(err) {
+			common.LOG.Warn(fmt.Sprintf("更新资源冲突 %s %s/%s, resourceVersion: %s", kind, namespace, name, obj.GetResourceVersion()))
+			return nil, ErrResourceConflict
+		}
+		return nil, err
+	}
+	common.LOG.Info(fmt.Sprintf("更新资源 %s %s/%s, resourceVersion: %s", kind, namespace, name, updated.GetResourceVersion()))
+	return toResourceYAML(updated)
+}
+
+func newDynamicClient(config *rest.Config) (dynamic.Interface, meta.RESTMapper, error) {
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	mapper, err := NewRESTMapper(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, mapper, nil
+}
+
+func namedResourceInterface(client dynamic.Interface, mapper meta.RESTMapper, group, kind, namespace string) (dynamic.ResourceInterface, error) {
+	mapping, err := restMappingFor(mapper, group, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return client.Resource(mapping.Resource), nil
+	}
+	if namespace == "" {
+		namespace = metaV1.NamespaceDefault
+	}
+	return client.Resource(mapping.Resource).Namespace(namespace), nil
+}
+
+func restMappingFor(mapper meta.RESTMapper, group, kind string) (*meta.RESTMapping, error) {
+	gvr, err := mapper.ResourceFor(schema.GroupVersionResource{Group: group, Resource: strings.ToLower(kind)})
+	if err != nil {
+		return nil, err
+	}
+	gvk, err := mapper.KindFor(gvr)
+	if err != nil {
+		return nil, err
+	}
+	return mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
+
+func toResourceYAML(obj *unstructured.Unstructured) (*ResourceYAML, error) {
+	copied := obj.DeepCopy()
+	// managedFields 对编辑无意义且内容冗长, 不返回给前端
+	copied.SetManagedFields(nil)
+	content, err := yaml.Marshal(copied.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResourceYAML{
+		Kind:            obj.GetKind(),
+		APIVersion:      obj.GetAPIVersion(),
+		Namespace:       obj.GetNamespace(),
+		Name:            obj.GetName(),
+		ResourceVersion: obj.GetResourceVersion(),
+		Content:         string(content),
+	}, nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dnsjia/luban/common"
+	"go.uber.org/zap"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var deploymentGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+// newYAMLTestClient 返回包含 default/api 的 fake 客户端, 更新时 resourceVersion 不等于 "2" 返回409
+func newYAMLTestClient() (*dynamicfake.FakeDynamicClient, meta.RESTMapper) {
+	common.LOG = zap.NewNop()
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gvk.GroupVersion()})
+	mapper.Add(gvk, meta.RESTScopeNamespace)
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace("default")
+	obj.SetName("api")
+	obj.SetResourceVersion("2")
+	obj.SetManagedFields([]metaV1.ManagedFieldsEntry{{Manager: "kubectl"}})
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{deploymentGVR: "DeploymentList"}, obj)
+	client.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		updated := action.(k8stesting.UpdateAction).GetObject().(*unstructured.Unstructured)
+		if updated.GetResourceVersion() != "2" {
+			return true, nil, k8serrors.NewConflict(deploymentGVR.GroupResource(), updated.GetName(), errors.New("the object has been modified"))
+		}
+		updated = updated.DeepCopy()
+		updated.SetResourceVersion("3")
+		return true, updated, nil
+	})
+	return client, mapper
+}
+
+func TestGetResourceYAML(t *testing.T) {
+	client, mapper := newYAMLTestClient()
+	for _, kind := range []string{"deployment", "Deployments"} {
+		data, err := getResourceYAML(client, mapper, "", kind, "default", "api")
+		if err != nil {
+			t.Fatalf("getResourceYAML(%s) returned error: %v", kind, err)
+		}
+		if data.Kind != "Deployment" || data.Name != "api" || data.ResourceVersion != "2" {
+			t.Errorf("getResourceYAML(%s) == %+v", kind, data)
+		}
+		if !strings.Contains(data.Content, "resourceVersion: \"2\"") || strings.Contains(data.Content, "managedFields") {
+			t.Errorf("getResourceYAML(%s) content:\n%s", kind, data.Content)
+		}
+	}
+
+	if _, err := getResourceYAML(client, mapper, "", "deployment", "default", "missing"); !k8serrors.IsNotFound(err) {
+		t.Errorf("getResourceYAML(missing) error == %v, expected not found", err)
+	}
+}
+
+func TestUpdateResourceYAML(t *testing.T) {
+	content := func(name, namespace, resourceVersion string) string {
+		return "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: " + name +
+			"\n  namespace: " + namespace + "\n  resourceVersion: \"" + resourceVersion + "\"\n  labels:\n    app: api\n"
+	}
+	cases := []struct {
+		name        string
+		kind        string
+		content     string
+		conflict    bool
+		expectedErr bool
+	}{
+		{name: "ok", kind: "deployment", content: content("api", "default", "2")},
+		{name: "stale resourceVersion", kind: "deployment", content: content("api", "default", "1"), conflict: true},
+		{name: "rename", kind: "deployment", content: content("web", "default", "2"), expectedErr: true},
+		{name: "move namespace", kind: "deployment", content: content("api", "kube-system", "2"), expectedErr: true},
+		{name: "no resourceVersion", kind: "deployment", content: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\n", expectedErr: true},
+		{name: "kind mismatch", kind: "deployment", content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: api\n  resourceVersion: \"2\"\n", expectedErr: true},
+		{name: "multiple documents", kind: "deployment", content: content("api", "default", "2") + "---\n" + content("api", "default", "2"), expectedErr: true},
+	}
+
+	for _, c := range cases {
+		client, mapper := newYAMLTestClient()
+		data, err := updateResourceYAML(client, mapper, "", c.kind, "default", "api", c.content)
+		switch {
+		case c.conflict:
+			if !errors.Is(err, ErrResourceConflict) {
+				t.Errorf("%s: error == %v, expected ErrResourceConflict", c.name, err)
+			}
+		case c.expectedErr:
+			if err == nil || errors.Is(err, ErrResourceConflict) {
+				t.Errorf("%s: error == %v, expected validation error", c.name, err)
+			}
+		default:
+			if err != nil {
+				t.Fatalf("%s: returned error: %v", c.name, err)
+			}
+			if data.ResourceVersion != "3" || !strings.Contains(data.Content, "app: api") {
+				t.Errorf("%s: returned %+v", c.name, data)
+			}
+		}
+	}
+}
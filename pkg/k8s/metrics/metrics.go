@@ -0,0 +1,216 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// metrics-server 通过聚合层提供的 metrics.k8s.io API
+const metricsAPIPath = "/apis/metrics.k8s.io/v1beta1"
+
+// UnavailableMessage 集群未安装 metrics-server 或其不可用时返回的提示
+const UnavailableMessage = "metrics unavailable"
+
+// requestTimeout 避免 metrics-server 异常时拖慢列表接口
+const requestTimeout = 5 * time.Second
+
+// Usage is the live resource usage reported by metrics-server.
+type Usage struct {
+	// CPU usage in millicores.
+	CPU int64 `json:"cpu"`
+
+	// Memory usage (working set) in bytes.
+	Memory int64 `json:"memory"`
+}
+
+// Add accumulates other into u.
+func (u *Usage) Add(other Usage) {
+	u.CPU += other.CPU
+	u.Memory += other.Memory
+}
+
+// Status tells whether the metrics API could be queried. When it is not available the
+// resource lists are still returned, just without usage data.
+type Status struct {
+	Available bool   `json:"available"`
+	Message   string `json:"message,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func available() Status {
+	return Status{Available: true}
+}
+
+func unavailable(err error) Status {
+	return Status{Message: UnavailableMessage, Reason: err.Error()}
+}
+
+// NodeMetrics maps node name to its usage.
+type NodeMetrics map[string]Usage
+
+// Get returns the usage of the given node or nil when unknown.
+func (m NodeMetrics) Get(name string) *Usage {
+	usage, ok := m[name]
+	if !ok {
+		return nil
+	}
+	return &usage
+}
+
+// PodMetrics maps "namespace/name" to the summed usage of the pod containers.
+type PodMetrics map[string]Usage
+
+func podKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// Get returns the usage of the given pod or nil when unknown.
+func (m PodMetrics) Get(pod *v1.Pod) *Usage {
+	usage, ok := m[podKey(pod.Namespace, pod.Name)]
+	if !ok {
+		return nil
+	}
+	return &usage
+}
+
+// Sum returns the total usage of the given pods, nil if none of them has metrics.
+func (m PodMetrics) Sum(pods []v1.Pod) *Usage {
+	var total *Usage
+	for i := range pods {
+		usage := m.Get(&pods[i])
+		if usage == nil {
+			continue
+		}
+		if total == nil {
+			total = &Usage{}
+		}
+		total.Add(*usage)
+	}
+	return total
+}
+
+// metrics.k8s.io 返回结构, k8s.io/metrics 未引入, 仅解析需要的字段
+type metricsMeta struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type nodeMetricsList struct {
+	Items []struct {
+		Metadata metricsMeta     `json:"metadata"`
+		Usage    v1.ResourceList `json:"usage"`
+	} `json:"items"`
+}
+
+type podMetricsList struct {
+	Items []struct {
+		Metadata   metricsMeta `json:"metadata"`
+		Containers []struct {
+			Name  string          `json:"name"`
+			Usage v1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+func toUsage(list v1.ResourceList) Usage {
+	var usage Usage
+	if cpu, ok := list[v1.ResourceCPU]; ok {
+		usage.CPU = cpu.MilliValue()
+	}
+	if memory, ok := list[v1.ResourceMemory]; ok {
+		usage.Memory = memory.Value()
+	}
+	return usage
+}
+
+func parseNodeMetrics(data []byte) (NodeMetrics, error) {
+	var list nodeMetricsList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode node metrics failed: %v", err)
+	}
+	result := make(NodeMetrics, len(list.Items))
+	for _, item := range list.Items {
+		result[item.Metadata.Name] = toUsage(item.Usage)
+	}
+	return result, nil
+}
+
+func parsePodMetrics(data []byte) (PodMetrics, error) {
+	var list podMetricsList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode pod metrics failed: %v", err)
+	}
+	result := make(PodMetrics, len(list.Items))
+	for _, item := range list.Items {
+		var usage Usage
+		for _, container := range item.Containers {
+			usage.Add(toUsage(container.Usage))
+		}
+		result[podKey(item.Metadata.Namespace, item.Metadata.Name)] = usage
+	}
+	return result, nil
+}
+
+func getRaw(client kubernetes.Interface, path, labelSelector string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.TODO(), requestTimeout)
+	defer cancel()
+
+	req := client.CoreV1().RESTClient().Get().AbsPath(metricsAPIPath, path)
+	if labelSelector != "" {
+		req = req.Param("labelSelector", labelSelector)
+	}
+	return req.DoRaw(ctx)
+}
+
+// GetNodeMetrics returns the usage of all nodes. It never fails: when metrics-server
+// is not installed the returned status is unavailable and the map is empty.
+func GetNodeMetrics(client kubernetes.Interface) (NodeMetrics, Status) {
+	data, err := getRaw(client, "nodes", "")
+	if err != nil {
+		return NodeMetrics{}, unavailable(err)
+	}
+	result, err := parseNodeMetrics(data)
+	if err != nil {
+		return NodeMetrics{}, unavailable(err)
+	}
+	return result, available()
+}
+
+// GetPodMetrics returns the usage of the pods in namespace (all namespaces when empty)
+// matching labelSelector. Like GetNodeMetrics it degrades to an unavailable status.
+func GetPodMetrics(client kubernetes.Interface, namespace, labelSelector string) (PodMetrics, Status) {
+	path := "pods"
+	if namespace != "" {
+		path = "namespaces/" + namespace + "/pods"
+	}
+	data, err := getRaw(client, path, labelSelector)
+	if err != nil {
+		return PodMetrics{}, unavailable(err)
+	}
+	result, err := parsePodMetrics(data)
+	if err != nil {
+		return PodMetrics{}, unavailable(err)
+	}
+	return result, available()
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const podMetricsBody = `{
+  "kind": "PodMetricsList",
+  "apiVersion": "metrics.k8s.io/v1beta1",
+  "items": [
+    {
+      "metadata": {"name": "web-0", "namespace": "default"},
+      "containers": [
+        {"name": "app", "usage": {"cpu": "250m", "memory": "64Mi"}},
+        {"name": "sidecar", "usage": {"cpu": "2000000n", "memory": "1Mi"}}
+      ]
+    }
+  ]
+}`
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) kubernetes.Interface {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestGetPodMetrics(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/metrics.k8s.io/v1beta1/namespaces/default/pods" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("labelSelector"); got != "app=web" {
+			t.Errorf("unexpected labelSelector %q", got)
+		}
+		_, _ = w.Write([]byte(podMetricsBody))
+	})
+
+	podMetrics, status := GetPodMetrics(client, "default", "app=web")
+	if !status.Available {
+		t.Fatalf("expected metrics to be available, got %+v", status)
+	}
+
+	pods := []v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"}},
+	}
+	usage := podMetrics.Get(&pods[0])
+	if usage == nil || usage.CPU != 252 || usage.Memory != 65*1024*1024 {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+	if podMetrics.Get(&pods[1]) != nil {
+		t.Fatalf("expected no usage for a pod missing from metrics-server")
+	}
+	if total := podMetrics.Sum(pods); total == nil || *total != *usage {
+		t.Fatalf("unexpected total %+v", total)
+	}
+}
+
+func TestGetNodeMetricsUnavailable(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	nodeMetrics, status := GetNodeMetrics(client)
+	if status.Available || status.Message != UnavailableMessage {
+		t.Fatalf("expected unavailable status, got %+v", status)
+	}
+	if nodeMetrics.Get("node-1") != nil {
+		t.Fatalf("expected no node usage")
+	}
+}
@@ -18,11 +18,46 @@ package namespace
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	"github.com/dnsjia/luban/pkg/k8s/limitrange"
+	"github.com/dnsjia/luban/pkg/k8s/resourcequota"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"strings"
 )
 
+// metadataNameLabel 由 apiserver 自动维护, 修改标签时需要保留
+const metadataNameLabel = "kubernetes.io/metadata.name"
+
+// protectedNamespaces 系统名称空间, 禁止通过平台删除
+var protectedNamespaces = map[string]bool{
+	"default":         true,
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+}
+
+// NamespaceDetail 名称空间详情, 包含配额使用情况及LimitRange
+type NamespaceDetail struct {
+	ObjectMeta k8s.ObjectMeta `json:"objectMeta"`
+	TypeMeta   k8s.TypeMeta   `json:"typeMeta"`
+
+	// Phase is the current lifecycle phase of the namespace.
+	Phase v1.NamespacePhase `json:"phase"`
+
+	// ResourceQuotaList is list of resource quotas associated to the namespace
+	ResourceQuotaList *resourcequota.ResourceQuotaDetailList `json:"resourceQuotaList"`
+
+	// LimitRangeList is list of limit ranges associated to the namespace
+	LimitRangeList *limitrange.LimitRangeList `json:"limitRangeList"`
+}
+
 func GetNamespaceList(client *kubernetes.Clientset) (*v1.NamespaceList, error) {
 
 	namespace, err := client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
@@ -31,3 +66,92 @@ func GetNamespaceList(client *kubernetes.Clientset) (*v1.NamespaceList, error) {
 	}
 	return namespace, nil
 }
+
+// GetNamespaceDetail 获取名称空间详情
+func GetNamespaceDetail(client kubernetes.Interface, name string) (*NamespaceDetail, error) {
+	namespace, err := client.CoreV1().Namespaces().Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	quotaList, err := resourcequota.GetResourceQuotaList(client, name)
+	if err != nil {
+		return nil, err
+	}
+	limitRangeList, err := limitrange.GetLimitRangeList(client, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NamespaceDetail{
+		ObjectMeta:        k8s.NewObjectMeta(namespace.ObjectMeta),
+		TypeMeta:          k8s.NewTypeMeta(k8s.ResourceKindNamespace),
+		Phase:             namespace.Status.Phase,
+		ResourceQuotaList: quotaList,
+		LimitRangeList:    limitRangeList,
+	}, nil
+}
+
+// CreateNamespace 创建名称空间
+func CreateNamespace(client kubernetes.Interface, data k8s.CreateNamespaceData) error {
+	common.LOG.Info(fmt.Sprintf("创建名称空间: %v", data.Name))
+	if errs := validation.IsDNS1123Label(data.Name); len(errs) > 0 {
+		return fmt.Errorf("名称空间 %s 不合法: %s", data.Name, strings.Join(errs, "; "))
+	}
+	if err := validateLabels(data.Labels); err != nil {
+		return err
+	}
+	namespace := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: data.Name, Labels: data.Labels},
+	}
+	_, err := client.CoreV1().Namespaces().Create(context.TODO(), namespace, metav1.CreateOptions{})
+	return err
+}
+
+// DeleteNamespace 删除名称空间, 需要再次输入名称空间名称确认, 系统名称空间禁止删除
+func DeleteNamespace(client kubernetes.Interface, data k8s.RemoveNamespaceData) error {
+	common.LOG.Info(fmt.Sprintf("请求删除名称空间: %v", data.Name))
+	if data.Confirm != data.Name {
+		return errors.New("确认名称与名称空间不一致, 拒绝删除")
+	}
+	if protectedNamespaces[data.Name] {
+		return fmt.Errorf("系统名称空间 %s 禁止删除", data.Name)
+	}
+	return client.CoreV1().Namespaces().Delete(context.TODO(), data.Name, metav1.DeleteOptions{})
+}
+
+// UpdateNamespaceLabels 使用提交的标签整体替换名称空间标签
+func UpdateNamespaceLabels(client kubernetes.Interface, data k8s.NamespaceLabelsData) error {
+	common.LOG.Info(fmt.Sprintf("修改名称空间标签: %v", data.Name))
+	if err := validateLabels(data.Labels); err != nil {
+		return err
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		namespace, err := client.CoreV1().Namespaces().Get(context.TODO(), data.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		labels := make(map[string]string, len(data.Labels)+1)
+		for k, v := range data.Labels {
+			labels[k] = v
+		}
+		if value, ok := namespace.Labels[metadataNameLabel]; ok {
+			labels[metadataNameLabel] = value
+		}
+		namespace.Labels = labels
+		_, err = client.CoreV1().Namespaces().Update(context.TODO(), namespace, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func validateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("标签 %s 不合法: %s", k, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			return fmt.Errorf("标签 %s 的值 %s 不合法: %s", k, v, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networkpolicy
+
+import (
+	"context"
+	"fmt"
+	"github.com/dnsjia/luban/models/k8s"
+	v1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"net"
+	"strings"
+)
+
+// Verdict explains the result of one traffic direction.
+type Verdict struct {
+	Allowed bool `json:"allowed"`
+	// Isolated is true when at least one policy selects the pod for this direction, otherwise all traffic is allowed.
+	Isolated bool `json:"isolated"`
+	// SelectingPolicies are the policies isolating the pod, they block the traffic unless one of them allows it.
+	SelectingPolicies []string `json:"selectingPolicies"`
+	// AllowedBy are the policies with a rule allowing the traffic.
+	AllowedBy []string `json:"allowedBy"`
+	Reason    string   `json:"reason"`
+}
+
+// TrafficAnalysis is the result of evaluating all policies for traffic from source to destination pod.
+type TrafficAnalysis struct {
+	Allowed bool `json:"allowed"`
+	// Egress is evaluated against the policies of the source pod namespace.
+	Egress Verdict `json:"egress"`
+	// Ingress is evaluated against the policies of the destination pod namespace.
+	Ingress Verdict `json:"ingress"`
+}
+
+// AnalysisInput contains every object needed to evaluate the traffic, Port 0 means any port.
+type AnalysisInput struct {
+	Source      *v1.Pod
+	Destination *v1.Pod
+	Port        int32
+	Protocol    v1.Protocol
+	Policies    []networking.NetworkPolicy
+	Namespaces  []v1.Namespace
+}
+
+// AnalyzeTraffic loads the pods, namespaces and policies from the cluster and evaluates the traffic.
+func AnalyzeTraffic(client kubernetes.Interface, data k8s.TrafficAnalysisData) (*TrafficAnalysis, error) {
+	source, err := client.CoreV1().Pods(data.SourceNamespace).Get(context.TODO(), data.SourcePod, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	destination, err := client.CoreV1().Pods(data.DestinationNamespace).Get(context.TODO(), data.DestinationPod, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	namespaces, err := client.CoreV1().Namespaces().List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+
+	input := AnalysisInput{
+		Source:      source,
+		Destination: destination,
+		Port:        data.Port,
+		Protocol:    v1.Protocol(strings.ToUpper(data.Protocol)),
+		Namespaces:  namespaces.Items,
+	}
+	for _, namespace := range uniqueNamespaces(data.SourceNamespace, data.DestinationNamespace) {
+		policies, err := client.NetworkingV1().NetworkPolicies(namespace).List(context.TODO(), k8s.ListEverything)
+		if err != nil {
+			return nil, err
+		}
+		input.Policies = append(input.Policies, policies.Items...)
+	}
+	return Analyze(input), nil
+}
+
+func uniqueNamespaces(namespaces ...string) []string {
+	result := make([]string, 0, len(namespaces))
+	seen := make(map[string]bool)
+	for _, namespace := range namespaces {
+		if !seen[namespace] {
+			seen[namespace] = true
+			result = append(result, namespace)
+		}
+	}
+	return result
+}
+
+// Analyze evaluates the policies offline. Traffic is allowed when the egress of the source pod and the ingress of
+// the destination pod are both allowed.
+func Analyze(input AnalysisInput) *TrafficAnalysis {
+	if input.Protocol == "" {
+		input.Protocol = v1.ProtocolTCP
+	}
+	namespaceLabels := make(map[string]labels.Set, len(input.Namespaces))
+	for _, namespace := range input.Namespaces {
+		namespaceLabels[namespace.Name] = namespace.Labels
+	}
+
+	analyzer := &analyzer{input: input, namespaceLabels: namespaceLabels}
+	result := &TrafficAnalysis{
+		Egress:  analyzer.verdict(networking.PolicyTypeEgress),
+		Ingress: analyzer.verdict(networking.PolicyTypeIngress),
+	}
+	result.Allowed = result.Egress.Allowed && result.Ingress.Allowed
+	return result
+}
+
+type analyzer struct {
+	input           AnalysisInput
+	namespaceLabels map[string]labels.Set
+}
+
+// verdict evaluates one direction, egress policies select the source pod and match the destination as peer,
+// ingress policies select the destination pod and match the source as peer.
+func (a *analyzer) verdict(direction networking.PolicyType) Verdict {
+	target, peer := a.input.Destination, a.input.Source
+	if direction == networking.PolicyTypeEgress {
+		target, peer = a.input.Source, a.input.Destination
+	}
+
+	verdict := Verdict{SelectingPolicies: make([]string, 0), AllowedBy: make([]string, 0)}
+	for i := range a.input.Policies {
+		policy := &a.input.Policies[i]
+		if policy.Namespace != target.Namespace || !hasPolicyType(policy, direction) || !selectorMatches(&policy.Spec.PodSelector, target.Labels) {
+			continue
+		}
+		verdict.SelectingPolicies = append(verdict.SelectingPolicies, policy.Name)
+		if a.policyAllows(policy, direction, peer) {
+			verdict.AllowedBy = append(verdict.AllowedBy, policy.Name)
+		}
+	}
+
+	verdict.Isolated = len(verdict.SelectingPolicies) > 0
+	verdict.Allowed = !verdict.Isolated || len(verdict.AllowedBy) > 0
+	switch {
+	case !verdict.Isolated:
+		verdict.Reason = fmt.Sprintf("没有%s策略选中 Pod %s/%s, 默认允许", direction, target.Namespace, target.Name)
+	case verdict.Allowed:
+		verdict.Reason = fmt.Sprintf("策略 %s 允许该流量", strings.Join(verdict.AllowedBy, ", "))
+	default:
+		verdict.Reason = fmt.Sprintf("Pod %s/%s 被策略 %s 隔离, 没有规则允许该流量", target.Namespace, target.Name, strings.Join(verdict.SelectingPolicies, ", "))
+	}
+	return verdict
+}
+
+func (a *analyzer) policyAllows(policy *networking.NetworkPolicy, direction networking.PolicyType, peer *v1.Pod) bool {
+	if direction == networking.PolicyTypeIngress {
+		for _, rule := range policy.Spec.Ingress {
+			if a.peersMatch(policy.Namespace, rule.From, peer) && a.portsMatch(rule.Ports) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, rule := range policy.Spec.Egress {
+		if a.peersMatch(policy.Namespace, rule.To, peer) && a.portsMatch(rule.Ports) {
+			return true
+		}
+	}
+	return false
+}
+
+// peersMatch reports whether pod is one of the peers, an empty peer list matches everything.
+func (a *analyzer) peersMatch(policyNamespace string, peers []networking.NetworkPolicyPeer, pod *v1.Pod) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, peer := range peers {
+		if peer.IPBlock != nil {
+			if ipBlockMatches(peer.IPBlock, pod.Status.PodIP) {
+				return true
+			}
+			continue
+		}
+		if peer.NamespaceSelector == nil {
+			// podSelector alone selects pods in the policy namespace.
+			if pod.Namespace == policyNamespace && selectorMatches(peer.PodSelector, pod.Labels) {
+				return true
+			}
+			continue
+		}
+		if !selectorMatches(peer.NamespaceSelector, a.namespaceLabels[pod.Namespace]) {
+			continue
+		}
+		if peer.PodSelector == nil || selectorMatches(peer.PodSelector, pod.Labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// portsMatch reports whether the queried port is allowed, ports always refer to the destination pod. When no
+// port was queried any rule matches since some port is reachable.
+func (a *analyzer) portsMatch(ports []networking.NetworkPolicyPort) bool {
+	if len(ports) == 0 || a.input.Port == 0 {
+		return true
+	}
+	for _, port := range ports {
+		protocol := v1.ProtocolTCP
+		if port.Protocol != nil {
+			protocol = *port.Protocol
+		}
+		if protocol != a.input.Protocol {
+			continue
+		}
+		if port.Port == nil {
+			return true
+		}
+		number := port.Port.IntVal
+		if port.Port.StrVal != "" {
+			number = namedPort(a.input.Destination, port.Port.StrVal, protocol)
+			if number == 0 {
+				continue
+			}
+		}
+		end := number
+		if port.EndPort != nil {
+			end = *port.EndPort
+		}
+		if a.input.Port >= number && a.input.Port <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// namedPort resolves a named container port of the pod, 0 when the pod has no such port.
+func namedPort(pod *v1.Pod, name string, protocol v1.Protocol) int32 {
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			portProtocol := port.Protocol
+			if portProtocol == "" {
+				portProtocol = v1.ProtocolTCP
+			}
+			if port.Name == name && portProtocol == protocol {
+				return port.ContainerPort
+			}
+		}
+	}
+	return 0
+}
+
+func ipBlockMatches(block *networking.IPBlock, podIP string) bool {
+	ip := net.ParseIP(podIP)
+	if ip == nil {
+		return false
+	}
+	_, cidr, err := net.ParseCIDR(block.CIDR)
+	if err != nil || !cidr.Contains(ip) {
+		return false
+	}
+	for _, except := range block.Except {
+		if _, exceptCIDR, err := net.ParseCIDR(except); err == nil && exceptCIDR.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectorMatches reports whether the label set matches the selector, an empty selector matches everything.
+func selectorMatches(selector *metaV1.LabelSelector, set labels.Set) bool {
+	if selector == nil {
+		return false
+	}
+	s, err := metaV1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return s.Matches(set)
+}
+
+func hasPolicyType(policy *networking.NetworkPolicy, policyType networking.PolicyType) bool {
+	for _, t := range policyTypes(policy) {
+		if t == policyType {
+			return true
+		}
+	}
+	return false
+}
+
+// policyTypes returns the effective policy types, when unset Ingress always applies and Egress applies
+// only if the policy has egress rules.
+func policyTypes(policy *networking.NetworkPolicy) []networking.PolicyType {
+	if len(policy.Spec.PolicyTypes) > 0 {
+		return policy.Spec.PolicyTypes
+	}
+	types := []networking.PolicyType{networking.PolicyTypeIngress}
+	if len(policy.Spec.Egress) > 0 {
+		types = append(types, networking.PolicyTypeEgress)
+	}
+	return types
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networkpolicy
+
+import (
+	"github.com/dnsjia/luban/models/k8s"
+	v1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func newPod(namespace, name, ip string, labels map[string]string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: v1.PodSpec{Containers: []v1.Container{{
+			Name:  "app",
+			Ports: []v1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+		}}},
+		Status: v1.PodStatus{PodIP: ip},
+	}
+}
+
+func newAnalysisClient() *fake.Clientset {
+	httpPort := intstr.FromString("http")
+	return fake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "web", Labels: map[string]string{"team": "web"}}},
+		&v1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "db", Labels: map[string]string{"team": "db"}}},
+		&v1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "batch"}},
+		newPod("web", "frontend", "10.0.0.10", map[string]string{"app": "frontend"}),
+		newPod("web", "backend", "10.0.0.11", map[string]string{"app": "backend"}),
+		newPod("db", "mysql", "10.0.1.10", map[string]string{"app": "mysql"}),
+		newPod("batch", "job", "10.0.2.10", map[string]string{"app": "job"}),
+		// Isolate backend, only frontend may reach it on the named http port.
+		&networking.NetworkPolicy{
+			ObjectMeta: metaV1.ObjectMeta{Name: "backend-from-frontend", Namespace: "web"},
+			Spec: networking.NetworkPolicySpec{
+				PodSelector: metaV1.LabelSelector{MatchLabels: map[string]string{"app": "backend"}},
+				Ingress: []networking.NetworkPolicyIngressRule{{
+					From:  []networking.NetworkPolicyPeer{{PodSelector: &metaV1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}}}},
+					Ports: []networking.NetworkPolicyPort{{Port: &httpPort}},
+				}},
+			},
+		},
+		// Deny all ingress to db except from the web namespace.
+		&networking.NetworkPolicy{
+			ObjectMeta: metaV1.ObjectMeta{Name: "db-from-web", Namespace: "db"},
+			Spec: networking.NetworkPolicySpec{
+				PodSelector: metaV1.LabelSelector{},
+				Ingress: []networking.NetworkPolicyIngressRule{{
+					From: []networking.NetworkPolicyPeer{{NamespaceSelector: &metaV1.LabelSelector{MatchLabels: map[string]string{"team": "web"}}}},
+				}},
+			},
+		},
+		// Batch pods may only talk to 10.0.1.0/24.
+		&networking.NetworkPolicy{
+			ObjectMeta: metaV1.ObjectMeta{Name: "batch-egress", Namespace: "batch"},
+			Spec: networking.NetworkPolicySpec{
+				PodSelector: metaV1.LabelSelector{},
+				PolicyTypes: []networking.PolicyType{networking.PolicyTypeEgress},
+				Egress: []networking.NetworkPolicyEgressRule{{
+					To: []networking.NetworkPolicyPeer{{IPBlock: &networking.IPBlock{CIDR: "10.0.1.0/24"}}},
+				}},
+			},
+		},
+	)
+}
+
+func TestAnalyzeTraffic(t *testing.T) {
+	client := newAnalysisClient()
+
+	cases := []struct {
+		name      string
+		data      k8s.TrafficAnalysisData
+		allowed   bool
+		allowedBy string
+		blockedBy string
+	}{
+		{"named port allowed", k8s.TrafficAnalysisData{SourceNamespace: "web", SourcePod: "frontend", DestinationNamespace: "web", DestinationPod: "backend", Port: 8080}, true, "backend-from-frontend", ""},
+		{"other port blocked", k8s.TrafficAnalysisData{SourceNamespace: "web", SourcePod: "frontend", DestinationNamespace: "web", DestinationPod: "backend", Port: 9090}, false, "", "backend-from-frontend"},
+		{"udp blocked", k8s.TrafficAnalysisData{SourceNamespace: "web", SourcePod: "frontend", DestinationNamespace: "web", DestinationPod: "backend", Port: 8080, Protocol: "udp"}, false, "", "backend-from-frontend"},
+		{"unselected pod allowed", k8s.TrafficAnalysisData{SourceNamespace: "web", SourcePod: "backend", DestinationNamespace: "web", DestinationPod: "frontend"}, true, "", ""},
+		{"namespace selector allowed", k8s.TrafficAnalysisData{SourceNamespace: "web", SourcePod: "backend", DestinationNamespace: "db", DestinationPod: "mysql", Port: 3306}, true, "db-from-web", ""},
+		{"namespace selector blocked", k8s.TrafficAnalysisData{SourceNamespace: "batch", SourcePod: "job", DestinationNamespace: "db", DestinationPod: "mysql", Port: 3306}, false, "", "db-from-web"},
+		{"egress ip block blocked", k8s.TrafficAnalysisData{SourceNamespace: "batch", SourcePod: "job", DestinationNamespace: "web", DestinationPod: "frontend"}, false, "", "batch-egress"},
+	}
+	for _, c := range cases {
+		result, err := AnalyzeTraffic(client, c.data)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if result.Allowed != c.allowed {
+			t.Errorf("%s: got allowed=%v, want %v (%+v)", c.name, result.Allowed, c.allowed, result)
+			continue
+		}
+		if c.allowedBy != "" && !contains(result.Ingress.AllowedBy, c.allowedBy) {
+			t.Errorf("%s: %s not in %v", c.name, c.allowedBy, result.Ingress.AllowedBy)
+		}
+		if c.blockedBy != "" && !contains(result.Ingress.SelectingPolicies, c.blockedBy) && !contains(result.Egress.SelectingPolicies, c.blockedBy) {
+			t.Errorf("%s: %s is not reported as selecting policy: %+v", c.name, c.blockedBy, result)
+		}
+	}
+}
+
+func TestIPBlockMatches(t *testing.T) {
+	block := &networking.IPBlock{CIDR: "10.0.0.0/16", Except: []string{"10.0.5.0/24"}}
+	if !ipBlockMatches(block, "10.0.1.1") {
+		t.Error("10.0.1.1 should match")
+	}
+	if ipBlockMatches(block, "10.0.5.1") {
+		t.Error("10.0.5.1 is excepted")
+	}
+	if ipBlockMatches(block, "") {
+		t.Error("pod without ip should not match")
+	}
+}
+
+func contains(items []string, item string) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
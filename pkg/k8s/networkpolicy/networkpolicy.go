@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networkpolicy
+
+import (
+	"context"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
+	"github.com/dnsjia/luban/pkg/k8s/dataselect"
+	networking "k8s.io/api/networking/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NetworkPolicy is a presentation layer view of Kubernetes NetworkPolicy resource.
+type NetworkPolicy struct {
+	ObjectMeta k8s.ObjectMeta `json:"objectMeta"`
+	TypeMeta   k8s.TypeMeta   `json:"typeMeta"`
+
+	PodSelector metaV1.LabelSelector    `json:"podSelector"`
+	PolicyTypes []networking.PolicyType `json:"policyTypes"`
+}
+
+// NetworkPolicyList contains a list of NetworkPolicies in the cluster.
+type NetworkPolicyList struct {
+	ListMeta k8s.ListMeta    `json:"listMeta"`
+	Items    []NetworkPolicy `json:"items"`
+}
+
+// NetworkPolicyDetail contains NetworkPolicy details.
+type NetworkPolicyDetail struct {
+	// Extends list item structure.
+	NetworkPolicy `json:",inline"`
+
+	Ingress []networking.NetworkPolicyIngressRule `json:"ingress"`
+	Egress  []networking.NetworkPolicyEgressRule  `json:"egress"`
+
+	// Pods are the names of the pods selected by the policy.
+	Pods []string `json:"pods"`
+}
+
+// GetNetworkPolicyList returns a list of all NetworkPolicies in the namespaces selected by nsQuery.
+func GetNetworkPolicyList(client kubernetes.Interface, nsQuery *k8scommon.NamespaceQuery, dsQuery *dataselect.DataSelectQuery) (*NetworkPolicyList, error) {
+	common.LOG.Info(fmt.Sprintf("Getting list of network policies in the namespace %s", nsQuery.ToRequestParam()))
+	list, err := client.NetworkingV1().NetworkPolicies(nsQuery.ToRequestParam()).List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+
+	var filteredItems []networking.NetworkPolicy
+	for _, item := range list.Items {
+		if nsQuery.Matches(item.ObjectMeta.Namespace) {
+			filteredItems = append(filteredItems, item)
+		}
+	}
+
+	policyCells, filteredTotal := dataselect.GenericDataSelectWithFilter(toCells(filteredItems), dsQuery)
+	result := &NetworkPolicyList{
+		ListMeta: k8s.ListMeta{TotalItems: filteredTotal},
+		Items:    make([]NetworkPolicy, 0),
+	}
+	for _, item := range fromCells(policyCells) {
+		result.Items = append(result.Items, toNetworkPolicy(&item))
+	}
+	return result, nil
+}
+
+// GetNetworkPolicyDetail returns detailed information about a NetworkPolicy.
+func GetNetworkPolicyDetail(client kubernetes.Interface, namespace, name string) (*NetworkPolicyDetail, error) {
+	policy, err := client.NetworkingV1().NetworkPolicies(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metaV1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := client.CoreV1().Pods(namespace).List(context.TODO(), metaV1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &NetworkPolicyDetail{
+		NetworkPolicy: toNetworkPolicy(policy),
+		Ingress:       policy.Spec.Ingress,
+		Egress:        policy.Spec.Egress,
+		Pods:          make([]string, 0, len(pods.Items)),
+	}
+	for _, pod := range pods.Items {
+		detail.Pods = append(detail.Pods, pod.Name)
+	}
+	return detail, nil
+}
+
+func toNetworkPolicy(policy *networking.NetworkPolicy) NetworkPolicy {
+	return NetworkPolicy{
+		ObjectMeta:  k8s.NewObjectMeta(policy.ObjectMeta),
+		TypeMeta:    k8s.NewTypeMeta(k8s.ResourceKindNetworkPolicy),
+		PodSelector: policy.Spec.PodSelector,
+		PolicyTypes: policyTypes(policy),
+	}
+}
+
+// CreateNetworkPolicy 创建NetworkPolicy
+func CreateNetworkPolicy(client kubernetes.Interface, data k8s.NetworkPolicyData) error {
+	common.LOG.Info(fmt.Sprintf("创建NetworkPolicy: %v, namespace: %v", data.Name, data.Namespace))
+	policy := &networking.NetworkPolicy{
+		ObjectMeta: metaV1.ObjectMeta{Name: data.Name, Namespace: data.Namespace, Labels: data.Labels},
+		Spec:       data.Spec,
+	}
+	_, err := client.NetworkingV1().NetworkPolicies(data.Namespace).Create(context.TODO(), policy, metaV1.CreateOptions{})
+	return err
+}
+
+// DeleteNetworkPolicy 删除NetworkPolicy
+func DeleteNetworkPolicy(client kubernetes.Interface, namespace, name string) error {
+	common.LOG.Info(fmt.Sprintf("请求删除NetworkPolicy: %v, namespace: %v", name, namespace))
+	return client.NetworkingV1().NetworkPolicies(namespace).Delete(context.TODO(), name, metaV1.DeleteOptions{})
+}
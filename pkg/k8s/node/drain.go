@@ -0,0 +1,550 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/pkg/k8s/evict"
+	"github.com/dnsjia/luban/services"
+	uuid "github.com/satori/go.uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 排空任务状态
+const (
+	DrainJobRunning   = "running"
+	DrainJobSucceeded = "succeeded"
+	DrainJobFailed    = "failed"
+)
+
+// 排空任务中单个 pod 的状态
+const (
+	PodDrainPending  = "pending"
+	PodDrainEvicting = "evicting"
+	PodDrainEvicted  = "evicted"
+	PodDrainSkipped  = "skipped"
+	PodDrainFailed   = "failed"
+)
+
+const (
+	// mirrorPodAnnotation 静态 pod 在 apiserver 中的镜像 pod 带有该注解
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+	// defaultDrainTimeout 未指定超时时间时整个排空任务的超时时间
+	defaultDrainTimeout = 10 * time.Minute
+	// drainJobPersistInterval 执行中的任务写入数据库的间隔, 同时作为执行实例的心跳
+	drainJobPersistInterval = 10 * time.Second
+	// drainJobStaleAfter 运行中的任务超过该时间未写入进度, 视为执行实例已停止
+	drainJobStaleAfter = 3 * drainJobPersistInterval
+	// drainJobListLimit 任务列表最多返回的条数
+	drainJobListLimit = 100
+)
+
+var (
+	// evictRetryInterval 驱逐被 PodDisruptionBudget 拒绝(429)后的初始重试间隔, 之后按倍数退避
+	evictRetryInterval    = 2 * time.Second
+	evictMaxRetryInterval = 30 * time.Second
+	// podDeletePollInterval 等待 pod 删除完成的轮询间隔
+	podDeletePollInterval = 2 * time.Second
+	// drainJobRetention 结束时写入数据库失败的任务在内存中保留的时长
+	drainJobRetention = 24 * time.Hour
+)
+
+// DrainOptions 排空节点的选项, 与 kubectl drain 的参数对应
+type DrainOptions struct {
+	// GracePeriodSeconds pod 优雅退出时间, 为空时使用 pod 自身的配置
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds"`
+	// TimeoutSeconds 整个排空任务的超时时间, 0 表示使用默认值
+	TimeoutSeconds int64 `json:"timeoutSeconds"`
+	// DeleteEmptyDirData 是否允许驱逐使用 emptyDir 的 pod, emptyDir 中的数据会随 pod 删除
+	DeleteEmptyDirData bool `json:"deleteEmptyDirData"`
+	// Force 是否驱逐不受任何控制器管理的 pod, 这些 pod 被驱逐后不会重建
+	Force bool `json:"force"`
+	// DeleteNode 排空成功后是否从集群中移除节点
+	DeleteNode bool `json:"deleteNode"`
+}
+
+// PodDrainStatus 排空任务中单个 pod 的进度
+type PodDrainStatus struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason"`
+	Attempts  int    `json:"attempts"`
+}
+
+// DrainJob 后台排空任务
+type DrainJob struct {
+	sync.RWMutex `json:"-"`
+
+	ID         string            `json:"id"`
+	ClusterID  uint              `json:"clusterId"`
+	NodeName   string            `json:"nodeName"`
+	Options    DrainOptions      `json:"options"`
+	Status     string            `json:"status"`
+	Message    string            `json:"message"`
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt *time.Time        `json:"finishedAt"`
+	Pods       []*PodDrainStatus `json:"pods"`
+
+	// recordID 数据库中对应记录的主键
+	recordID uint
+}
+
+// drainJobs 本实例正在执行的排空任务, 进度以内存中的为准; 其他实例的任务从数据库读取
+var drainJobs = struct {
+	sync.RWMutex
+	jobs map[string]*DrainJob
+}{jobs: make(map[string]*DrainJob)}
+
+// StartDrainJob 创建并在后台执行排空任务, 返回任务的快照
+func StartDrainJob(client kubernetes.Interface, clusterID uint, nodeName string, options DrainOptions) (*DrainJob, error) {
+	if nodeName == "" {
+		return nil, errors.New("节点名称不能为空")
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	job := &DrainJob{
+		ID:        id.String(),
+		ClusterID: clusterID,
+		NodeName:  nodeName,
+		Options:   options,
+		Status:    DrainJobRunning,
+		StartedAt: time.Now(),
+		Pods:      make([]*PodDrainStatus, 0),
+	}
+	record, err := job.record()
+	if err != nil {
+		return nil, err
+	}
+	if err := services.CreateNodeDrainJob(record); err != nil {
+		common.LOG.Error("保存排空任务失败", zap.Any("err", err))
+		return nil, err
+	}
+	job.recordID = record.ID
+
+	drainJobs.Lock()
+	for jobID, j := range drainJobs.jobs {
+		j.RLock()
+		expired := j.FinishedAt != nil && time.Since(*j.FinishedAt) > drainJobRetention
+		j.RUnlock()
+		if expired {
+			delete(drainJobs.jobs, jobID)
+		}
+	}
+	drainJobs.jobs[job.ID] = job
+	drainJobs.Unlock()
+
+	common.LOG.Info(fmt.Sprintf("排空节点:%v, 异步任务:%v 已开始", nodeName, job.ID))
+	go job.run(client)
+	return job.Snapshot(), nil
+}
+
+// GetDrainJob 返回排空任务的快照, 本实例执行的任务直接读取内存, 否则从数据库读取
+func GetDrainJob(clusterID uint, id string) (*DrainJob, error) {
+	drainJobs.RLock()
+	job, ok := drainJobs.jobs[id]
+	drainJobs.RUnlock()
+	if ok && job.ClusterID == clusterID {
+		return job.Snapshot(), nil
+	}
+
+	record, err := services.GetNodeDrainJob(clusterID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("排空任务 %v 不存在", id)
+		}
+		return nil, err
+	}
+	return drainJobFromRecord(record)
+}
+
+// ListDrainJobs 返回集群最近的排空任务, 按开始时间倒序
+func ListDrainJobs(clusterID uint) []*DrainJob {
+	jobs := make([]*DrainJob, 0)
+	local := make(map[string]bool)
+	drainJobs.RLock()
+	for _, job := range drainJobs.jobs {
+		if job.ClusterID == clusterID {
+			jobs = append(jobs, job.Snapshot())
+			local[job.ID] = true
+		}
+	}
+	drainJobs.RUnlock()
+
+	records := make([]models.NodeDrainJob, 0)
+	if err := services.ListNodeDrainJob(clusterID, drainJobListLimit, &records); err != nil {
+		common.LOG.Error("查询排空任务失败", zap.Any("err", err))
+	}
+	for _, record := range records {
+		if local[record.JobID] {
+			continue
+		}
+		job, err := drainJobFromRecord(record)
+		if err != nil {
+			common.LOG.Error(fmt.Sprintf("解析排空任务%v失败", record.JobID), zap.Any("err", err))
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].StartedAt.After(jobs[j].StartedAt)
+	})
+	return jobs
+}
+
+// Snapshot 返回任务当前状态的副本, 用于序列化
+func (job *DrainJob) Snapshot() *DrainJob {
+	job.RLock()
+	defer job.RUnlock()
+	snapshot := &DrainJob{
+		ID:         job.ID,
+		ClusterID:  job.ClusterID,
+		NodeName:   job.NodeName,
+		Options:    job.Options,
+		Status:     job.Status,
+		Message:    job.Message,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+		Pods:       make([]*PodDrainStatus, 0, len(job.Pods)),
+	}
+	for _, pod := range job.Pods {
+		p := *pod
+		snapshot.Pods = append(snapshot.Pods, &p)
+	}
+	return snapshot
+}
+
+func (job *DrainJob) run(client kubernetes.Interface) {
+	timeout := defaultDrainTimeout
+	if job.Options.TimeoutSeconds > 0 {
+		timeout = time.Duration(job.Options.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go job.heartbeat(stop, stopped)
+
+	err := job.drain(ctx, client)
+	if err == nil && job.Options.DeleteNode {
+		err = client.CoreV1().Nodes().Delete(ctx, job.NodeName, metav1.DeleteOptions{})
+	}
+	// 等待心跳退出, 避免旧的进度覆盖最终状态
+	close(stop)
+	<-stopped
+	job.finish(err)
+
+	// 最终状态写入数据库后不再保留在内存中, 写入失败时保留 drainJobRetention 供本实例查询
+	if job.persist() == nil {
+		drainJobs.Lock()
+		delete(drainJobs.jobs, job.ID)
+		drainJobs.Unlock()
+	}
+}
+
+func (job *DrainJob) finish(err error) {
+	job.Lock()
+	defer job.Unlock()
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = DrainJobFailed
+		job.Message = err.Error()
+		common.LOG.Error(fmt.Sprintf("排空节点:%v 失败, 任务:%v, err: %v", job.NodeName, job.ID, err))
+		return
+	}
+	job.Status = DrainJobSucceeded
+	if job.Options.DeleteNode {
+		job.Message = "节点已排空并从集群中移除"
+	} else {
+		job.Message = "节点已排空"
+	}
+	common.LOG.Info(fmt.Sprintf("排空节点:%v 完成, 任务:%v, 耗时：%v", job.NodeName, job.ID, now.Sub(job.StartedAt)))
+}
+
+// heartbeat 定期写入任务进度, 直到 stop 被关闭
+func (job *DrainJob) heartbeat(stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+	ticker := time.NewTicker(drainJobPersistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			_ = job.persist()
+		}
+	}
+}
+
+func (job *DrainJob) persist() error {
+	job.RLock()
+	record, err := job.record()
+	job.RUnlock()
+	if err == nil {
+		err = services.SaveNodeDrainJob(record)
+	}
+	if err != nil {
+		common.LOG.Error(fmt.Sprintf("保存排空任务%v失败", job.ID), zap.Any("err", err))
+	}
+	return err
+}
+
+// record 转换为数据库记录, 调用方需持有读锁
+func (job *DrainJob) record() (*models.NodeDrainJob, error) {
+	options, err := json.Marshal(job.Options)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := json.Marshal(job.Pods)
+	if err != nil {
+		return nil, err
+	}
+	record := &models.NodeDrainJob{
+		JobID:      job.ID,
+		ClusterID:  job.ClusterID,
+		NodeName:   job.NodeName,
+		Status:     job.Status,
+		Message:    job.Message,
+		Options:    string(options),
+		Pods:       string(pods),
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	record.ID = job.recordID
+	return record, nil
+}
+
+// drainJobFromRecord 运行中的任务长时间未更新时, 说明执行任务的实例已重启或退出, 任务不会再继续
+func drainJobFromRecord(record models.NodeDrainJob) (*DrainJob, error) {
+	job := &DrainJob{
+		ID:         record.JobID,
+		ClusterID:  record.ClusterID,
+		NodeName:   record.NodeName,
+		Status:     record.Status,
+		Message:    record.Message,
+		StartedAt:  record.StartedAt,
+		FinishedAt: record.FinishedAt,
+		Pods:       make([]*PodDrainStatus, 0),
+		recordID:   record.ID,
+	}
+	if record.Options != "" {
+		if err := json.Unmarshal([]byte(record.Options), &job.Options); err != nil {
+			return nil, err
+		}
+	}
+	if record.Pods != "" {
+		if err := json.Unmarshal([]byte(record.Pods), &job.Pods); err != nil {
+			return nil, err
+		}
+	}
+	if job.Status == DrainJobRunning && time.Since(record.UpdatedAt.Time) > drainJobStaleAfter {
+		job.Status = DrainJobFailed
+		job.Message = "执行任务的实例已停止上报进度, 任务可能已中断"
+	}
+	return job, nil
+}
+
+// drain 设置节点不可调度, 然后并发驱逐节点上的 pod 并等待其删除完成
+func (job *DrainJob) drain(ctx context.Context, client kubernetes.Interface) error {
+	if err := cordon(ctx, client, job.NodeName); err != nil {
+		return err
+	}
+
+	pods, err := client.CoreV1().Pods(v1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: "spec.nodeName=" + job.NodeName,
+	})
+	if err != nil {
+		return err
+	}
+
+	var (
+		evictable []v1.Pod
+		statuses  []*PodDrainStatus
+		blocked   int
+	)
+	for _, pod := range pods.Items {
+		status := &PodDrainStatus{Namespace: pod.Namespace, Name: pod.Name, Status: PodDrainPending}
+		statuses = append(statuses, status)
+		skip, reason, ok := filterDrainPod(pod, job.Options)
+		switch {
+		case !ok:
+			status.Status = PodDrainFailed
+			status.Reason = reason
+			blocked++
+		case skip:
+			status.Status = PodDrainSkipped
+			status.Reason = reason
+		default:
+			evictable = append(evictable, pod)
+		}
+	}
+	job.Lock()
+	job.Pods = statuses
+	job.Unlock()
+	// 与 kubectl drain 一致, 存在无法安全驱逐的 pod 时不驱逐任何 pod
+	if blocked > 0 {
+		return fmt.Errorf("%d 个pod无法安全驱逐, 请检查 deleteEmptyDirData/force 选项", blocked)
+	}
+
+	var wg sync.WaitGroup
+	for i := range evictable {
+		wg.Add(1)
+		go func(pod v1.Pod) {
+			defer wg.Done()
+			job.evictPod(ctx, client, pod)
+		}(evictable[i])
+	}
+	wg.Wait()
+
+	var failed int
+	job.RLock()
+	for _, status := range job.Pods {
+		if status.Status == PodDrainFailed {
+			failed++
+		}
+	}
+	job.RUnlock()
+	if failed > 0 {
+		return fmt.Errorf("%d 个pod驱逐失败", failed)
+	}
+	return nil
+}
+
+// evictPod 驱逐单个 pod, 被 PodDisruptionBudget 拒绝时退避重试, 直到 pod 被删除或任务超时
+func (job *DrainJob) evictPod(ctx context.Context, client kubernetes.Interface, pod v1.Pod) {
+	interval := evictRetryInterval
+	for {
+		job.updatePod(pod, func(status *PodDrainStatus) {
+			status.Status = PodDrainEvicting
+			status.Attempts++
+		})
+		err := evict.EvictsPodWithGracePeriod(ctx, client, pod.Name, pod.Namespace, job.Options.GracePeriodSeconds)
+		if err == nil || apierrors.IsNotFound(err) {
+			break
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			job.failPod(pod, err.Error())
+			return
+		}
+		job.updatePod(pod, func(status *PodDrainStatus) {
+			status.Reason = fmt.Sprintf("受 PodDisruptionBudget 限制, %v 后重试", interval)
+		})
+		select {
+		case <-ctx.Done():
+			job.failPod(pod, "驱逐超时: "+err.Error())
+			return
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > evictMaxRetryInterval {
+			interval = evictMaxRetryInterval
+		}
+	}
+
+	if err := waitForPodDeleted(ctx, client, pod); err != nil {
+		job.failPod(pod, "等待pod删除超时")
+		return
+	}
+	job.updatePod(pod, func(status *PodDrainStatus) {
+		status.Status = PodDrainEvicted
+		status.Reason = ""
+	})
+}
+
+func (job *DrainJob) failPod(pod v1.Pod, reason string) {
+	common.LOG.Error(fmt.Sprintf("驱逐Pod：%v/%v失败: %v", pod.Namespace, pod.Name, reason))
+	job.updatePod(pod, func(status *PodDrainStatus) {
+		status.Status = PodDrainFailed
+		status.Reason = reason
+	})
+}
+
+func (job *DrainJob) updatePod(pod v1.Pod, update func(status *PodDrainStatus)) {
+	job.Lock()
+	defer job.Unlock()
+	for _, status := range job.Pods {
+		if status.Namespace == pod.Namespace && status.Name == pod.Name {
+			update(status)
+			return
+		}
+	}
+}
+
+// filterDrainPod 判断 pod 是否需要驱逐. skip 为 true 表示无需驱逐, ok 为 false 表示当前选项下不能安全驱逐
+func filterDrainPod(pod v1.Pod, options DrainOptions) (skip bool, reason string, ok bool) {
+	if _, isMirror := pod.Annotations[mirrorPodAnnotation]; isMirror {
+		return true, "静态pod", true
+	}
+	controllerRef := metav1.GetControllerOf(&pod)
+	if controllerRef != nil && controllerRef.Kind == "DaemonSet" {
+		return true, "DaemonSet管理的pod", true
+	}
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return false, "", true
+	}
+	if controllerRef == nil && !options.Force {
+		return false, "pod不受控制器管理, 需要开启force", false
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil && !options.DeleteEmptyDirData {
+			return false, "pod使用了emptyDir, 需要开启deleteEmptyDirData", false
+		}
+	}
+	return false, "", true
+}
+
+// waitForPodDeleted 等待 pod 被删除, 同名但 UID 不同的 pod 视为已删除
+func waitForPodDeleted(ctx context.Context, client kubernetes.Interface, pod v1.Pod) error {
+	for {
+		current, err := client.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(podDeletePollInterval):
+		}
+	}
+}
+
+func cordon(ctx context.Context, client kubernetes.Interface, nodeName string) error {
+	node, err := client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if node.Spec.Unschedulable {
+		return nil
+	}
+	node.Spec.Unschedulable = true
+	_, err = client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dnsjia/luban/common"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestFilterDrainPod(t *testing.T) {
+	controller := true
+	ownedBy := func(kind string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: "owner", Controller: &controller}}
+	}
+	emptyDir := []v1.Volume{{Name: "cache", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}}
+
+	cases := []struct {
+		name     string
+		pod      v1.Pod
+		options  DrainOptions
+		wantSkip bool
+		wantOk   bool
+	}{
+		{
+			name:     "mirror pod",
+			pod:      v1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{mirrorPodAnnotation: "x"}}},
+			wantSkip: true,
+			wantOk:   true,
+		},
+		{
+			name:     "daemonset pod",
+			pod:      v1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: ownedBy("DaemonSet")}},
+			wantSkip: true,
+			wantOk:   true,
+		},
+		{
+			name:   "replicaset pod",
+			pod:    v1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: ownedBy("ReplicaSet")}},
+			wantOk: true,
+		},
+		{
+			name:   "unmanaged pod without force",
+			pod:    v1.Pod{},
+			wantOk: false,
+		},
+		{
+			name:    "unmanaged pod with force",
+			pod:     v1.Pod{},
+			options: DrainOptions{Force: true},
+			wantOk:  true,
+		},
+		{
+			name: "emptyDir pod",
+			pod: v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{OwnerReferences: ownedBy("ReplicaSet")},
+				Spec:       v1.PodSpec{Volumes: emptyDir},
+			},
+			wantOk: false,
+		},
+		{
+			name: "emptyDir pod with deleteEmptyDirData",
+			pod: v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{OwnerReferences: ownedBy("ReplicaSet")},
+				Spec:       v1.PodSpec{Volumes: emptyDir},
+			},
+			options: DrainOptions{DeleteEmptyDirData: true},
+			wantOk:  true,
+		},
+		{
+			name:   "finished unmanaged pod",
+			pod:    v1.Pod{Status: v1.PodStatus{Phase: v1.PodSucceeded}},
+			wantOk: true,
+		},
+	}
+
+	for _, c := range cases {
+		skip, _, ok := filterDrainPod(c.pod, c.options)
+		if skip != c.wantSkip || ok != c.wantOk {
+			t.Errorf("%s: got skip=%v ok=%v, want skip=%v ok=%v", c.name, skip, ok, c.wantSkip, c.wantOk)
+		}
+	}
+}
+
+// evictionReactor 依次返回 results 中的错误, 用完后重复最后一个. 驱逐成功或 pod 不存在时从 tracker 中删除 pod,
+// keepPod 为 true 时模拟驱逐被接受但 pod 一直未删除
+type evictionReactor struct {
+	mu       sync.Mutex
+	results  []error
+	keepPod  bool
+	attempts int
+}
+
+func (r *evictionReactor) install(client *fake.Clientset) {
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		r.mu.Lock()
+		err := r.results[len(r.results)-1]
+		if r.attempts < len(r.results) {
+			err = r.results[r.attempts]
+		}
+		r.attempts++
+		r.mu.Unlock()
+		if (err == nil && !r.keepPod) || apierrors.IsNotFound(err) {
+			gvr := v1.SchemeGroupVersion.WithResource("pods")
+			_ = client.Tracker().Delete(gvr, action.GetNamespace(), action.(k8stesting.CreateAction).GetObject().(metav1.Object).GetName())
+		}
+		return true, nil, err
+	})
+}
+
+func TestEvictPod(t *testing.T) {
+	common.LOG = zap.NewNop()
+	defer func(retry, maxRetry, poll time.Duration) {
+		evictRetryInterval, evictMaxRetryInterval, podDeletePollInterval = retry, maxRetry, poll
+	}(evictRetryInterval, evictMaxRetryInterval, podDeletePollInterval)
+	evictRetryInterval, evictMaxRetryInterval, podDeletePollInterval = 10*time.Millisecond, 20*time.Millisecond, 10*time.Millisecond
+
+	pdbRejected := apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "web-0", nil)
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, "web-0")
+
+	cases := []struct {
+		name         string
+		results      []error
+		keepPod      bool
+		timeout      time.Duration
+		wantStatus   string
+		wantAttempts int
+		wantReason   string
+	}{
+		{name: "evicted", results: []error{nil}, timeout: time.Second, wantStatus: PodDrainEvicted, wantAttempts: 1},
+		{name: "retry after pdb rejection", results: []error{pdbRejected, pdbRejected, nil}, timeout: time.Second, wantStatus: PodDrainEvicted, wantAttempts: 3},
+		{name: "pdb rejection until timeout", results: []error{pdbRejected}, timeout: 100 * time.Millisecond, wantStatus: PodDrainFailed, wantReason: "驱逐超时"},
+		{name: "other error fails immediately", results: []error{forbidden}, timeout: time.Second, wantStatus: PodDrainFailed, wantAttempts: 1, wantReason: "forbidden"},
+		{name: "pod already gone", results: []error{notFound}, timeout: time.Second, wantStatus: PodDrainEvicted, wantAttempts: 1},
+		{name: "pod not deleted", results: []error{nil}, keepPod: true, timeout: 100 * time.Millisecond, wantStatus: PodDrainFailed, wantAttempts: 1, wantReason: "等待pod删除超时"},
+	}
+	for _, c := range cases {
+		pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-0", UID: "p1"}}
+		client := fake.NewSimpleClientset(&pod)
+		reactor := &evictionReactor{results: c.results, keepPod: c.keepPod}
+		reactor.install(client)
+
+		job := &DrainJob{Pods: []*PodDrainStatus{{Namespace: "default", Name: "web-0", Status: PodDrainPending}}}
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		job.evictPod(ctx, client, pod)
+		cancel()
+
+		status := job.Pods[0]
+		if status.Status != c.wantStatus {
+			t.Errorf("%s: status == %s (%s), expected %s", c.name, status.Status, status.Reason, c.wantStatus)
+		}
+		if c.wantAttempts > 0 && status.Attempts != c.wantAttempts {
+			t.Errorf("%s: attempts == %d, expected %d", c.name, status.Attempts, c.wantAttempts)
+		}
+		if c.wantAttempts == 0 && status.Attempts < 2 {
+			t.Errorf("%s: expected retries, got %d attempts", c.name, status.Attempts)
+		}
+		if !strings.Contains(status.Reason, c.wantReason) {
+			t.Errorf("%s: reason == %q, expected to contain %q", c.name, status.Reason, c.wantReason)
+		}
+	}
+}
+
+func TestDrainJobFromRecord(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name       string
+		status     string
+		updatedAt  time.Time
+		wantStatus string
+	}{
+		{name: "running with heartbeat", status: DrainJobRunning, updatedAt: now, wantStatus: DrainJobRunning},
+		{name: "running without heartbeat", status: DrainJobRunning, updatedAt: now.Add(-2 * drainJobStaleAfter), wantStatus: DrainJobFailed},
+		{name: "finished long ago", status: DrainJobSucceeded, updatedAt: now.Add(-time.Hour), wantStatus: DrainJobSucceeded},
+	}
+	for _, c := range cases {
+		job := &DrainJob{
+			ID:        "job-1",
+			ClusterID: 1,
+			NodeName:  "node-1",
+			Options:   DrainOptions{Force: true, TimeoutSeconds: 60},
+			Status:    c.status,
+			StartedAt: now.Add(-time.Hour),
+			Pods:      []*PodDrainStatus{{Namespace: "default", Name: "web-0", Status: PodDrainEvicting, Attempts: 2}},
+			recordID:  7,
+		}
+		record, err := job.record()
+		if err != nil {
+			t.Fatalf("%s: record returned error: %v", c.name, err)
+		}
+		record.UpdatedAt.Time = c.updatedAt
+
+		restored, err := drainJobFromRecord(*record)
+		if err != nil {
+			t.Fatalf("%s: drainJobFromRecord returned error: %v", c.name, err)
+		}
+		if restored.Status != c.wantStatus {
+			t.Errorf("%s: status == %s, expected %s", c.name, restored.Status, c.wantStatus)
+		}
+		if restored.ID != "job-1" || restored.recordID != 7 || !restored.Options.Force || restored.Options.TimeoutSeconds != 60 ||
+			len(restored.Pods) != 1 || restored.Pods[0].Attempts != 2 {
+			t.Errorf("%s: unexpected job %+v", c.name, restored)
+		}
+	}
+}
@@ -23,19 +23,21 @@ import (
 	"github.com/dnsjia/luban/common"
 	"github.com/dnsjia/luban/models/k8s"
 	"github.com/dnsjia/luban/pkg/k8s/dataselect"
-	"github.com/dnsjia/luban/pkg/k8s/evict"
+	"github.com/dnsjia/luban/pkg/k8s/metrics"
 	"github.com/dnsjia/luban/pkg/k8s/parser"
 	"github.com/gin-gonic/gin"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"time"
 )
 
 // NodeList 包含集群中的节点列表.
 type NodeList struct {
 	ListMeta k8s.ListMeta `json:"listMeta"`
 	Nodes    []Node       `json:"nodes"`
+
+	// metrics-server 是否可用, 不可用时节点的 metrics 为空
+	MetricsStatus metrics.Status `json:"metricsStatus"`
 }
 
 // Node is a presentation layer view of Kubernetes nodes. This means it is node plus additional
@@ -48,6 +50,7 @@ type Node struct {
 	NodeIP             k8s.NodeIP                 `json:"nodeIP"`
 	AllocatedResources k8s.NodeAllocatedResources `json:"allocatedResources"`
 	NodeInfo           v1.NodeSystemInfo          `json:"nodeInfo"`
+	Metrics            *metrics.Usage             `json:"metrics"`
 	//RuntimeType        string                     `json:"runtimeType"`
 }
 
@@ -78,6 +81,9 @@ func toNodeList(client *kubernetes.Clientset, nodes []v1.Node, dsQuery *gin.Cont
 	nodes = fromCells(nodeCells)
 	// 更新node数量, filteredTotal过滤后的数量
 	nodeList.ListMeta = k8s.ListMeta{TotalItems: filteredTotal}
+	// 从 metrics-server 获取节点实时使用量, 未安装时不影响列表返回
+	nodeMetrics, metricsStatus := metrics.GetNodeMetrics(client)
+	nodeList.MetricsStatus = metricsStatus
 
 	for _, node := range nodes {
 		// 根据Node名称去获取节点上面的pod，过滤时排除pod为 Succeeded, Failed 返回pods
@@ -87,7 +93,9 @@ func toNodeList(client *kubernetes.Clientset, nodes []v1.Node, dsQuery *gin.Cont
 		}
 
 		// 调用toNode方法获取 node节点的计算资源
-		nodeList.Nodes = append(nodeList.Nodes, toNode(node, pods, getNodeRole(node)))
+		item := toNode(node, pods, getNodeRole(node))
+		item.Metrics = nodeMetrics.Get(node.Name)
+		nodeList.Nodes = append(nodeList.Nodes, item)
 	}
 
 	return nodeList
@@ -185,44 +193,22 @@ func NodeUnschdulable(client *kubernetes.Clientset, nodeName string, unschdulabl
 	return true, nil
 }
 
-func CordonNode(client *kubernetes.Clientset, nodeName string) (bool, error) {
+// CordonNode 排空节点, 设置节点不可调度并在后台驱逐节点上的pod, 返回排空任务
+func CordonNode(client *kubernetes.Clientset, clusterID uint, nodeName string, options DrainOptions) (*DrainJob, error) {
 	/*
 		排空节点
 		选择排空节点（同时设置为不可调度），在后续进行应用部署时，则Pod不会再调度到该节点，并且该节点上由DaemonSet控制的Pod不会被排空。
 		kubectl drain cn-beijing.i-2ze19qyi8votgjz12345 --grace-period=120 --ignore-daemonsets=true
 	*/
-	_, err := NodeUnschdulable(client, nodeName, true)
-	if err != nil {
-		return false, err
-	}
-	err = evict.EvictsNodePods(client, nodeName)
-	if err != nil {
-		common.LOG.Error(fmt.Sprintf("排空节点出现异常: %v", err.Error()))
-		return false, err
-	}
-	return true, nil
-
+	options.DeleteNode = false
+	return StartDrainJob(client, clusterID, nodeName, options)
 }
 
-func RemoveNode(client *kubernetes.Clientset, nodeName string) (bool, error) {
-	startTime := time.Now()
-	common.LOG.Info(fmt.Sprintf("移除Node节点:%v, 异步任务已开始", nodeName))
-	_, err := NodeUnschdulable(client, nodeName, true)
-	if err != nil {
-		return false, err
-	}
-	err = evict.EvictsNodePods(client, nodeName)
-	if err != nil {
-		common.LOG.Error(fmt.Sprintf("排空节点出现异常: %v", err.Error()))
-		return false, err
-	}
-	err2 := client.CoreV1().Nodes().Delete(context.TODO(), nodeName, metav1.DeleteOptions{})
-	if err2 != nil {
-		return false, err2
-	}
-	common.LOG.Info(fmt.Sprintf("已将节点：%v从集群中移除, 异步任务已完成,任务耗时：%v", nodeName, time.Since(startTime)))
-
-	return true, nil
+// RemoveNode 排空节点后将节点从集群中移除, 返回排空任务
+func RemoveNode(client *kubernetes.Clientset, clusterID uint, nodeName string, options DrainOptions) (*DrainJob, error) {
+	common.LOG.Info(fmt.Sprintf("移除Node节点:%v", nodeName))
+	options.DeleteNode = true
+	return StartDrainJob(client, clusterID, nodeName, options)
 }
 
 func CollectionNodeUnschedule(client *kubernetes.Clientset, nodeName []string) error {
@@ -253,35 +239,23 @@ func CollectionNodeUnschedule(client *kubernetes.Clientset, nodeName []string) e
 	return nil
 }
 
-func CollectionCordonNode(client *kubernetes.Clientset, nodeName []string) error {
+func CollectionCordonNode(client *kubernetes.Clientset, clusterID uint, nodeName []string, options DrainOptions) ([]*DrainJob, error) {
 	/*
 		批量排空Node节点， 不允许调度
 		{"node_name": ["k8s-master", "k8s-node"]}
 	*/
 
 	if len(nodeName) <= 0 {
-		return errors.New("节点名称不能为空")
+		return nil, errors.New("节点名称不能为空")
 	}
 	common.LOG.Info(fmt.Sprintf("开始排空节点, 设置Node节点:%v  不可调度：true", nodeName))
+	jobs := make([]*DrainJob, 0, len(nodeName))
 	for _, v := range nodeName {
-		node, err := client.CoreV1().Nodes().Get(context.TODO(), v, metav1.GetOptions{})
+		job, err := CordonNode(client, clusterID, v, options)
 		if err != nil {
-			common.LOG.Error(fmt.Sprintf("get node err: %v", err.Error()))
-			return err
-		}
-		node.Spec.Unschedulable = true
-
-		_, err2 := client.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{})
-
-		if err2 != nil {
-			common.LOG.Error(fmt.Sprintf("设置节点调度失败：%v", err2.Error()))
-			return err
-		}
-		_, cordonErr := CordonNode(client, v)
-		if cordonErr != nil {
-			return cordonErr
+			return jobs, err
 		}
+		jobs = append(jobs, job)
 	}
-	common.LOG.Info(fmt.Sprintf("已将所有Node节点:%v  设置为不可调度", nodeName))
-	return nil
+	return jobs, nil
 }
@@ -0,0 +1,306 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"strings"
+)
+
+// MetadataChange 对节点标签或注解的修改, Set 中的键会被新增或覆盖, Remove 中的键会被删除
+type MetadataChange struct {
+	Set    map[string]string `json:"set"`
+	Remove []string          `json:"remove"`
+}
+
+// TaintChange 对节点污点的修改, Set 中 key+effect 相同的污点会被覆盖, Remove 按 key+effect 删除, effect 为空时删除该 key 的所有污点
+type TaintChange struct {
+	Set    []v1.Taint `json:"set"`
+	Remove []v1.Taint `json:"remove"`
+}
+
+// TaintAffectedPod 添加 NoExecute 污点后会被驱逐的 pod
+type TaintAffectedPod struct {
+	NodeName  string   `json:"nodeName"`
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Taint     v1.Taint `json:"taint"`
+	// TolerationSeconds 不为空时表示 pod 容忍该污点, 但会在指定秒数后被驱逐
+	TolerationSeconds *int64 `json:"tolerationSeconds"`
+}
+
+// UpdateNodeLabels 修改节点标签
+func UpdateNodeLabels(client *kubernetes.Clientset, nodeName string, change MetadataChange) error {
+	if err := validateLabels(change); err != nil {
+		return err
+	}
+	common.LOG.Info(fmt.Sprintf("修改Node节点:%v 标签, 设置: %v, 删除: %v", nodeName, change.Set, change.Remove))
+	return patchNodeMetadata(client, nodeName, "labels", change)
+}
+
+// CollectionUpdateNodeLabels 批量修改节点标签
+func CollectionUpdateNodeLabels(client *kubernetes.Clientset, nodeName []string, change MetadataChange) error {
+	if len(nodeName) <= 0 {
+		return errors.New("节点名称不能为空")
+	}
+	for _, v := range nodeName {
+		if err := UpdateNodeLabels(client, v, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateNodeAnnotations 修改节点注解
+func UpdateNodeAnnotations(client *kubernetes.Clientset, nodeName string, change MetadataChange) error {
+	if err := validateAnnotations(change); err != nil {
+		return err
+	}
+	common.LOG.Info(fmt.Sprintf("修改Node节点:%v 注解, 设置: %v, 删除: %v", nodeName, change.Set, change.Remove))
+	return patchNodeMetadata(client, nodeName, "annotations", change)
+}
+
+// CollectionUpdateNodeAnnotations 批量修改节点注解
+func CollectionUpdateNodeAnnotations(client *kubernetes.Clientset, nodeName []string, change MetadataChange) error {
+	if len(nodeName) <= 0 {
+		return errors.New("节点名称不能为空")
+	}
+	for _, v := range nodeName {
+		if err := UpdateNodeAnnotations(client, v, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateNodeTaints 修改节点污点, 发生冲突时重新读取节点后重试
+func UpdateNodeTaints(client *kubernetes.Clientset, nodeName string, change TaintChange) error {
+	if err := validateTaints(change.Set); err != nil {
+		return err
+	}
+	common.LOG.Info(fmt.Sprintf("修改Node节点:%v 污点, 设置: %v, 删除: %v", nodeName, change.Set, change.Remove))
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := client.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		node.Spec.Taints = applyTaintChange(node.Spec.Taints, change)
+		_, err = client.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// CollectionUpdateNodeTaints 批量修改节点污点
+func CollectionUpdateNodeTaints(client *kubernetes.Clientset, nodeName []string, change TaintChange) error {
+	if len(nodeName) <= 0 {
+		return errors.New("节点名称不能为空")
+	}
+	for _, v := range nodeName {
+		if err := UpdateNodeTaints(client, v, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PreviewNoExecuteTaints 预览在节点上添加污点后会被驱逐的 pod, 只有 NoExecute 污点会驱逐已运行的 pod
+func PreviewNoExecuteTaints(client *kubernetes.Clientset, nodeName []string, taints []v1.Taint) ([]TaintAffectedPod, error) {
+	if len(nodeName) <= 0 {
+		return nil, errors.New("节点名称不能为空")
+	}
+	if err := validateTaints(taints); err != nil {
+		return nil, err
+	}
+	affected := make([]TaintAffectedPod, 0)
+	for _, v := range nodeName {
+		pods, err := client.CoreV1().Pods(v1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
+			FieldSelector: "spec.nodeName=" + v,
+		})
+		if err != nil {
+			return nil, err
+		}
+		affected = append(affected, noExecuteAffectedPods(v, pods.Items, taints)...)
+	}
+	return affected, nil
+}
+
+func noExecuteAffectedPods(nodeName string, pods []v1.Pod, taints []v1.Taint) []TaintAffectedPod {
+	affected := make([]TaintAffectedPod, 0)
+	for _, pod := range pods {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		for i := range taints {
+			taint := taints[i]
+			if taint.Effect != v1.TaintEffectNoExecute {
+				continue
+			}
+			toleration, tolerated := findToleration(pod.Spec.Tolerations, &taint)
+			if tolerated && toleration.TolerationSeconds == nil {
+				continue
+			}
+			item := TaintAffectedPod{NodeName: nodeName, Namespace: pod.Namespace, Name: pod.Name, Taint: taint}
+			if tolerated {
+				item.TolerationSeconds = toleration.TolerationSeconds
+			}
+			affected = append(affected, item)
+		}
+	}
+	return affected
+}
+
+// findToleration 查找容忍该污点的 toleration, 优先返回永久容忍的
+func findToleration(tolerations []v1.Toleration, taint *v1.Taint) (v1.Toleration, bool) {
+	var (
+		found  v1.Toleration
+		exists bool
+	)
+	for _, toleration := range tolerations {
+		if !toleration.ToleratesTaint(taint) {
+			continue
+		}
+		if toleration.TolerationSeconds == nil {
+			return toleration, true
+		}
+		if !exists || *toleration.TolerationSeconds > *found.TolerationSeconds {
+			found, exists = toleration, true
+		}
+	}
+	return found, exists
+}
+
+func applyTaintChange(taints []v1.Taint, change TaintChange) []v1.Taint {
+	change.Set = dedupTaints(change.Set)
+	result := make([]v1.Taint, 0, len(taints)+len(change.Set))
+	for _, taint := range taints {
+		removed := false
+		for _, remove := range change.Remove {
+			if taint.Key == remove.Key && (remove.Effect == "" || taint.Effect == remove.Effect) {
+				removed = true
+				break
+			}
+		}
+		for _, set := range change.Set {
+			if taint.MatchTaint(&set) {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			result = append(result, taint)
+		}
+	}
+	for _, set := range change.Set {
+		if set.Effect == v1.TaintEffectNoExecute && set.TimeAdded == nil {
+			now := metav1.Now()
+			set.TimeAdded = &now
+		}
+		result = append(result, set)
+	}
+	return result
+}
+
+// dedupTaints 按 key 与 effect 去重, 重复时保留最后一个的值
+func dedupTaints(taints []v1.Taint) []v1.Taint {
+	result := make([]v1.Taint, 0, len(taints))
+	index := make(map[string]int, len(taints))
+	for _, taint := range taints {
+		key := taint.Key + ":" + string(taint.Effect)
+		if i, ok := index[key]; ok {
+			result[i] = taint
+			continue
+		}
+		index[key] = len(result)
+		result = append(result, taint)
+	}
+	return result
+}
+
+// patchNodeMetadata 使用 merge patch 修改节点的 labels 或 annotations, 值为 null 的键会被删除
+func patchNodeMetadata(client *kubernetes.Clientset, nodeName string, field string, change MetadataChange) error {
+	values := make(map[string]interface{}, len(change.Set)+len(change.Remove))
+	for _, key := range change.Remove {
+		values[key] = nil
+	}
+	for key, value := range change.Set {
+		values[key] = value
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{field: values},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.CoreV1().Nodes().Patch(context.TODO(), nodeName, types.MergePatchType, data, metav1.PatchOptions{})
+	if err != nil {
+		common.LOG.Error(fmt.Sprintf("修改节点%v失败：%v", field, err.Error()))
+	}
+	return err
+}
+
+func validateLabels(change MetadataChange) error {
+	for key, value := range change.Set {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("标签 %q 不合法: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("标签 %q 的值 %q 不合法: %s", key, value, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+func validateAnnotations(change MetadataChange) error {
+	for key := range change.Set {
+		if errs := validation.IsQualifiedName(strings.ToLower(key)); len(errs) > 0 {
+			return fmt.Errorf("注解 %q 不合法: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+func validateTaints(taints []v1.Taint) error {
+	for _, taint := range taints {
+		if errs := validation.IsQualifiedName(taint.Key); len(errs) > 0 {
+			return fmt.Errorf("污点 %q 不合法: %s", taint.Key, strings.Join(errs, "; "))
+		}
+		if taint.Value != "" {
+			if errs := validation.IsValidLabelValue(taint.Value); len(errs) > 0 {
+				return fmt.Errorf("污点 %q 的值 %q 不合法: %s", taint.Key, taint.Value, strings.Join(errs, "; "))
+			}
+		}
+		switch taint.Effect {
+		case v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+		default:
+			return fmt.Errorf("污点 %q 的 effect %q 不合法", taint.Key, taint.Effect)
+		}
+	}
+	return nil
+}
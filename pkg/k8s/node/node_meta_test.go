@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestNoExecuteAffectedPods(t *testing.T) {
+	var tolerationSeconds int64 = 300
+	taints := []v1.Taint{
+		{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoExecute},
+		{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule},
+	}
+	pod := func(name string, tolerations ...v1.Toleration) v1.Pod {
+		p := v1.Pod{Spec: v1.PodSpec{Tolerations: tolerations}}
+		p.Name = name
+		return p
+	}
+	pods := []v1.Pod{
+		pod("plain"),
+		pod("tolerates", v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "gpu"}),
+		pod("tolerates-for-a-while", v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpExists,
+			Effect: v1.TaintEffectNoExecute, TolerationSeconds: &tolerationSeconds}),
+		pod("tolerates-everything", v1.Toleration{Operator: v1.TolerationOpExists}),
+	}
+
+	affected := noExecuteAffectedPods("node-1", pods, taints)
+	if len(affected) != 2 {
+		t.Fatalf("expected 2 affected pods, got %d: %+v", len(affected), affected)
+	}
+	if affected[0].Name != "plain" || affected[0].TolerationSeconds != nil {
+		t.Errorf("unexpected affected pod %+v", affected[0])
+	}
+	if affected[1].Name != "tolerates-for-a-while" || affected[1].TolerationSeconds == nil || *affected[1].TolerationSeconds != 300 {
+		t.Errorf("unexpected affected pod %+v", affected[1])
+	}
+}
+
+func TestApplyTaintChange(t *testing.T) {
+	taints := []v1.Taint{
+		{Key: "a", Value: "1", Effect: v1.TaintEffectNoSchedule},
+		{Key: "a", Value: "1", Effect: v1.TaintEffectNoExecute},
+		{Key: "b", Value: "1", Effect: v1.TaintEffectNoSchedule},
+	}
+	result := applyTaintChange(taints, TaintChange{
+		Set:    []v1.Taint{{Key: "b", Value: "2", Effect: v1.TaintEffectNoSchedule}},
+		Remove: []v1.Taint{{Key: "a"}},
+	})
+	if len(result) != 1 || result[0].Key != "b" || result[0].Value != "2" {
+		t.Errorf("unexpected taints %+v", result)
+	}
+}
+
+func TestApplyTaintChangeDuplicateSet(t *testing.T) {
+	taints := []v1.Taint{{Key: "a", Value: "1", Effect: v1.TaintEffectNoSchedule}}
+	result := applyTaintChange(taints, TaintChange{
+		Set: []v1.Taint{
+			{Key: "a", Value: "2", Effect: v1.TaintEffectNoSchedule},
+			{Key: "b", Value: "1", Effect: v1.TaintEffectNoSchedule},
+			{Key: "a", Value: "3", Effect: v1.TaintEffectNoSchedule},
+			{Key: "a", Value: "1", Effect: v1.TaintEffectPreferNoSchedule},
+		},
+	})
+	expected := []v1.Taint{
+		{Key: "a", Value: "3", Effect: v1.TaintEffectNoSchedule},
+		{Key: "b", Value: "1", Effect: v1.TaintEffectNoSchedule},
+		{Key: "a", Value: "1", Effect: v1.TaintEffectPreferNoSchedule},
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("unexpected taints %+v", result)
+	}
+	for i := range expected {
+		if result[i].Key != expected[i].Key || result[i].Value != expected[i].Value || result[i].Effect != expected[i].Effect {
+			t.Errorf("taint %d == %+v, expected %+v", i, result[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pods
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"time"
+)
+
+// debugContainerTimeout 等待调试容器启动的超时时间, 包含拉取镜像的时间
+const debugContainerTimeout = 2 * time.Minute
+
+// EphemeralContainer is an ephemeral debug container added to a running pod.
+type EphemeralContainer struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+
+	// TargetContainerName is the container whose process namespace is shared with the debug container.
+	TargetContainerName string `json:"targetContainerName"`
+
+	Commands []string `json:"commands"`
+	Args     []string `json:"args"`
+
+	// Status of the ephemeral container
+	Status *v1.ContainerStatus `json:"status"`
+}
+
+func extractEphemeralContainerInfo(pod *v1.Pod) []EphemeralContainer {
+	containers := make([]EphemeralContainer, 0, len(pod.Spec.EphemeralContainers))
+	for _, container := range pod.Spec.EphemeralContainers {
+		item := EphemeralContainer{
+			Name:                container.Name,
+			Image:               container.Image,
+			TargetContainerName: container.TargetContainerName,
+			Commands:            container.Command,
+			Args:                container.Args,
+		}
+		for i := range pod.Status.EphemeralContainerStatuses {
+			if pod.Status.EphemeralContainerStatuses[i].Name == container.Name {
+				item.Status = &pod.Status.EphemeralContainerStatuses[i]
+				break
+			}
+		}
+		containers = append(containers, item)
+	}
+	return containers
+}
+
+// DebugImage 校验调试镜像是否在配置的允许列表中, image 为空时返回默认镜像
+func DebugImage(image string) (string, error) {
+	allowed := common.CONFIG.Kubernetes.DebugImages
+	if image == "" {
+		if len(allowed) == 0 {
+			return "", errors.New("未指定调试镜像")
+		}
+		return allowed[0], nil
+	}
+	if len(allowed) == 0 {
+		return image, nil
+	}
+	for _, v := range allowed {
+		if v == image {
+			return image, nil
+		}
+	}
+	return "", fmt.Errorf("不允许使用调试镜像 %s", image)
+}
+
+// CreateDebugContainer 为运行中的Pod添加临时调试容器, 与目标容器共享进程命名空间, 返回调试容器名称
+func CreateDebugContainer(client kubernetes.Interface, data k8s.DebugContainerData) (string, error) {
+	pod, err := client.CoreV1().Pods(data.Namespace).Get(context.TODO(), data.PodName, metaV1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if pod.Status.Phase != v1.PodRunning {
+		return "", fmt.Errorf("Pod %s/%s 未处于运行状态: %s", data.Namespace, data.PodName, pod.Status.Phase)
+	}
+
+	target := data.TargetContainer
+	if target == "" && len(pod.Spec.Containers) > 0 {
+		target = pod.Spec.Containers[0].Name
+	}
+	if !hasContainer(pod, target) {
+		return "", fmt.Errorf("Pod %s/%s 不存在容器 %s", data.Namespace, data.PodName, target)
+	}
+
+	name := debugContainerName(pod)
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, v1.EphemeralContainer{
+		EphemeralContainerCommon: v1.EphemeralContainerCommon{
+			Name:                     name,
+			Image:                    data.Image,
+			Command:                  data.Command,
+			ImagePullPolicy:          v1.PullIfNotPresent,
+			Stdin:                    true,
+			TTY:                      true,
+			TerminationMessagePolicy: v1.TerminationMessageReadFile,
+		},
+		TargetContainerName: target,
+	})
+
+	common.LOG.Info(fmt.Sprintf("为Pod %s/%s 添加调试容器 %s, 镜像: %s, 目标容器: %s", data.Namespace, data.PodName, name, data.Image, target))
+	_, err = client.CoreV1().Pods(data.Namespace).UpdateEphemeralContainers(context.TODO(), data.PodName, pod, metaV1.UpdateOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return "", errors.New("集群不支持临时容器, 需要 Kubernetes 1.22+ 并开启 EphemeralContainers 特性")
+		}
+		return "", err
+	}
+	return name, nil
+}
+
+// WaitForDebugContainer 等待调试容器进入运行状态, 拉取镜像失败或容器退出时立即返回错误
+func WaitForDebugContainer(client kubernetes.Interface, namespace, podName, name string) error {
+	return wait.PollImmediate(time.Second, debugContainerTimeout, func() (bool, error) {
+		pod, err := client.CoreV1().Pods(namespace).Get(context.TODO(), podName, metaV1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name != name {
+				continue
+			}
+			switch {
+			case status.State.Running != nil:
+				return true, nil
+			case status.State.Terminated != nil:
+				return false, fmt.Errorf("调试容器已退出: %s", status.State.Terminated.Reason)
+			case status.State.Waiting != nil:
+				switch status.State.Waiting.Reason {
+				case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CreateContainerError":
+					return false, fmt.Errorf("调试容器启动失败: %s %s", status.State.Waiting.Reason, status.State.Waiting.Message)
+				}
+			}
+		}
+		return false, nil
+	})
+}
+
+func hasContainer(pod *v1.Pod, name string) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// debugContainerName 生成与已有容器不重复的调试容器名称
+func debugContainerName(pod *v1.Pod) string {
+	existing := make(map[string]bool)
+	for _, container := range pod.Spec.Containers {
+		existing[container.Name] = true
+	}
+	for _, container := range pod.Spec.InitContainers {
+		existing[container.Name] = true
+	}
+	for _, container := range pod.Spec.EphemeralContainers {
+		existing[container.Name] = true
+	}
+	for {
+		name := fmt.Sprintf("debugger-%s", utilrand.String(5))
+		if !existing[name] {
+			return name
+		}
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pods
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDebugImage(t *testing.T) {
+	defer func(images []string) { common.CONFIG.Kubernetes.DebugImages = images }(common.CONFIG.Kubernetes.DebugImages)
+
+	cases := []struct {
+		name     string
+		allowed  []string
+		image    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "default image", allowed: []string{"busybox:1.34", "nicolaka/netshoot"}, expected: "busybox:1.34"},
+		{name: "allowed image", allowed: []string{"busybox:1.34", "nicolaka/netshoot"}, image: "nicolaka/netshoot", expected: "nicolaka/netshoot"},
+		{name: "image not allowed", allowed: []string{"busybox:1.34"}, image: "alpine", wantErr: true},
+		{name: "no allow-list", image: "alpine", expected: "alpine"},
+		{name: "no allow-list and no image", wantErr: true},
+	}
+	for _, c := range cases {
+		common.CONFIG.Kubernetes.DebugImages = c.allowed
+		image, err := DebugImage(c.image)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: DebugImage() error = %v, wantErr %v", c.name, err, c.wantErr)
+			continue
+		}
+		if image != c.expected {
+			t.Errorf("%s: DebugImage() == %q, expected %q", c.name, image, c.expected)
+		}
+	}
+}
+
+// TestDebugContainerName 固定随机种子, 使第一个候选名称与已有容器重名
+func TestDebugContainerName(t *testing.T) {
+	utilrand.Seed(1)
+	taken := debugContainerName(&v1.Pod{})
+
+	cases := []struct {
+		name string
+		pod  *v1.Pod
+	}{
+		{name: "container", pod: &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: taken}}}}},
+		{name: "init container", pod: &v1.Pod{Spec: v1.PodSpec{InitContainers: []v1.Container{{Name: taken}}}}},
+		{name: "ephemeral container", pod: &v1.Pod{Spec: v1.PodSpec{EphemeralContainers: []v1.EphemeralContainer{
+			{EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: taken}},
+		}}}},
+	}
+	for _, c := range cases {
+		utilrand.Seed(1)
+		name := debugContainerName(c.pod)
+		if name == taken || !strings.HasPrefix(name, "debugger-") {
+			t.Errorf("%s: debugContainerName() == %q, existing %q", c.name, name, taken)
+		}
+	}
+}
+
+func TestExtractEphemeralContainerInfo(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{EphemeralContainers: []v1.EphemeralContainer{
+			{
+				EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debugger-a", Image: "busybox", Command: []string{"sh"}},
+				TargetContainerName:      "app",
+			},
+			{EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debugger-b", Image: "alpine", Args: []string{"-c"}}},
+		}},
+		Status: v1.PodStatus{EphemeralContainerStatuses: []v1.ContainerStatus{
+			{Name: "debugger-b", Ready: true},
+		}},
+	}
+
+	containers := extractEphemeralContainerInfo(pod)
+	if len(containers) != 2 {
+		t.Fatalf("extractEphemeralContainerInfo returned %d containers", len(containers))
+	}
+	first, second := containers[0], containers[1]
+	if first.Name != "debugger-a" || first.Image != "busybox" || first.TargetContainerName != "app" ||
+		len(first.Commands) != 1 || first.Status != nil {
+		t.Errorf("unexpected first container %+v", first)
+	}
+	if second.Name != "debugger-b" || len(second.Args) != 1 || second.Status == nil || !second.Status.Ready {
+		t.Errorf("unexpected second container %+v", second)
+	}
+	if len(extractEphemeralContainerInfo(&v1.Pod{})) != 0 {
+		t.Errorf("expected no containers for a pod without ephemeral containers")
+	}
+}
+
+func TestCreateDebugContainer(t *testing.T) {
+	common.LOG = zap.NewNop()
+	newPod := func(name string, phase v1.PodPhase) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "default", Name: name},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app"}, {Name: "sidecar"}}},
+			Status:     v1.PodStatus{Phase: phase},
+		}
+	}
+
+	cases := []struct {
+		name           string
+		data           k8s.DebugContainerData
+		expectedTarget string
+		wantErr        bool
+	}{
+		{name: "default target", data: k8s.DebugContainerData{PodName: "running"}, expectedTarget: "app"},
+		{name: "explicit target", data: k8s.DebugContainerData{PodName: "running", TargetContainer: "sidecar"}, expectedTarget: "sidecar"},
+		{name: "unknown target", data: k8s.DebugContainerData{PodName: "running", TargetContainer: "db"}, wantErr: true},
+		{name: "pod not running", data: k8s.DebugContainerData{PodName: "pending"}, wantErr: true},
+		{name: "pod not found", data: k8s.DebugContainerData{PodName: "missing"}, wantErr: true},
+	}
+	for _, c := range cases {
+		client := fake.NewSimpleClientset(newPod("running", v1.PodRunning), newPod("pending", v1.PodPending))
+		c.data.Namespace = "default"
+		c.data.Image = "busybox"
+		c.data.Command = []string{"sh"}
+
+		name, err := CreateDebugContainer(client, c.data)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: CreateDebugContainer() error = %v, wantErr %v", c.name, err, c.wantErr)
+			continue
+		}
+		if c.wantErr {
+			continue
+		}
+
+		pod, err := client.CoreV1().Pods("default").Get(context.TODO(), c.data.PodName, metaV1.GetOptions{})
+		if err != nil {
+			t.Fatalf("%s: get pod: %v", c.name, err)
+		}
+		containers := extractEphemeralContainerInfo(pod)
+		if len(containers) != 1 {
+			t.Fatalf("%s: pod has %d ephemeral containers", c.name, len(containers))
+		}
+		if containers[0].Name != name || containers[0].Image != "busybox" || containers[0].TargetContainerName != c.expectedTarget {
+			t.Errorf("%s: unexpected ephemeral container %+v", c.name, containers[0])
+		}
+	}
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pods
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/pkg/k8s/logs"
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	// followFlushInterval 聚合缓冲窗口, 窗口内的日志按时间戳排序后再推送
+	followFlushInterval = time.Second
+	// followRefreshInterval 重新获取日志来源的间隔, 用于滚动更新时挂载新的 pod
+	followRefreshInterval = 5 * time.Second
+)
+
+// followMaxLineSize 单行日志的最大长度
+const followMaxLineSize = 1024 * 1024
+
+// defaultFollowTailLines 首次挂载某个容器时回放的行数
+var defaultFollowTailLines int64 = 100
+
+// openLogStream 打开容器的日志流
+var openLogStream = func(ctx context.Context, client kubernetes.Interface, namespace, pod string, options *v1.PodLogOptions) (io.ReadCloser, error) {
+	return client.CoreV1().Pods(namespace).GetLogs(pod, options).Stream(ctx)
+}
+
+// FollowLogLine 实时日志中的一行, Line 为带有 [pod/container] 前缀的内容
+type FollowLogLine struct {
+	PodName       string            `json:"podName"`
+	ContainerName string            `json:"containerName"`
+	Timestamp     logs.LogTimestamp `json:"timestamp"`
+	Content       string            `json:"content"`
+	Line          string            `json:"line"`
+
+	time time.Time
+}
+
+// FollowOptions 实时日志的选项, Container 为空时跟踪所有容器
+type FollowOptions struct {
+	Container    string
+	TailLines    *int64
+	SinceSeconds *int64
+}
+
+// followStream 单个 pod/container 的跟踪状态, finished 表示 pod 已运行结束, 不再重新挂载
+type followStream struct {
+	active   bool
+	finished bool
+	lastSeen time.Time
+}
+
+// logFollower 跟踪一个资源下所有 pod/container 的日志流
+type logFollower struct {
+	ctx          context.Context
+	client       kubernetes.Interface
+	namespace    string
+	resourceName string
+	resourceType string
+	opts         FollowOptions
+	lines        chan FollowLogLine
+
+	mu      sync.Mutex
+	streams map[string]*followStream
+	wg      sync.WaitGroup
+}
+
+// FollowLogs 跟踪资源下所有 pod/container 的日志, 按时间戳合并后批量交给 send, 直到 ctx 结束或 send 返回错误.
+// 每隔 followRefreshInterval 重新解析日志来源, 新出现的 pod 会被自动挂载, 断开的流会从最后一行的时间点继续,
+// 已删除的 pod 会被移除, 已运行结束的 pod 不会重复挂载.
+func FollowLogs(ctx context.Context, client kubernetes.Interface, namespace, resourceName, resourceType string,
+	opts FollowOptions, send func([]FollowLogLine) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	f := &logFollower{
+		ctx:          ctx,
+		client:       client,
+		namespace:    namespace,
+		resourceName: resourceName,
+		resourceType: resourceType,
+		opts:         opts,
+		lines:        make(chan FollowLogLine, 1024),
+		streams:      make(map[string]*followStream),
+	}
+	// 返回前等待所有日志流退出
+	defer func() {
+		cancel()
+		f.wg.Wait()
+	}()
+	if err := f.attach(); err != nil {
+		return err
+	}
+
+	flush := time.NewTicker(followFlushInterval)
+	defer flush.Stop()
+	refresh := time.NewTicker(followRefreshInterval)
+	defer refresh.Stop()
+
+	var buffer []FollowLogLine
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line := <-f.lines:
+			buffer = append(buffer, line)
+		case <-flush.C:
+			if len(buffer) == 0 {
+				continue
+			}
+			sortFollowLines(buffer)
+			if err := send(buffer); err != nil {
+				return err
+			}
+			buffer = nil
+		case <-refresh.C:
+			if err := f.attach(); err != nil {
+				common.LOG.Debug(fmt.Sprintf("refresh log sources of %s/%s: %v", namespace, resourceName, err))
+			}
+		}
+	}
+}
+
+// sortFollowLines 按时间戳排序, 时间相同时保持到达顺序
+func sortFollowLines(lines []FollowLogLine) {
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].time.Before(lines[j].time)
+	})
+}
+
+// attach 挂载新出现的 pod/container, 并移除已不在日志来源中的跟踪状态
+func (f *logFollower) attach() error {
+	sources, err := logs.GetLogSources(f.client, f.namespace, f.resourceName, f.resourceType)
+	if err != nil {
+		return err
+	}
+	containers := sources.ContainerNames
+	if f.opts.Container != "" {
+		containers = []string{f.opts.Container}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	current := make(map[string]bool)
+	for _, pod := range sources.PodNames {
+		for _, container := range containers {
+			key := pod + "/" + container
+			current[key] = true
+			stream, ok := f.streams[key]
+			if !ok {
+				stream = &followStream{}
+				f.streams[key] = stream
+			}
+			if stream.active || stream.finished {
+				continue
+			}
+			stream.active = true
+			f.wg.Add(1)
+			go f.follow(pod, container, stream, stream.lastSeen)
+		}
+	}
+	for key, stream := range f.streams {
+		if !current[key] && !stream.active {
+			delete(f.streams, key)
+		}
+	}
+	return nil
+}
+
+// follow 跟踪单个容器直到日志流结束, 日志流正常结束且 pod 已运行结束时标记为 finished
+func (f *logFollower) follow(pod, container string, stream *followStream, since time.Time) {
+	defer f.wg.Done()
+	err := followContainer(f.ctx, f.client, f.namespace, pod, container, f.opts, since, func(line FollowLogLine) {
+		f.mu.Lock()
+		stream.lastSeen = line.time
+		f.mu.Unlock()
+		select {
+		case f.lines <- line:
+		case <-f.ctx.Done():
+		}
+	})
+	if err != nil && f.ctx.Err() == nil {
+		common.LOG.Debug(fmt.Sprintf("follow logs of %s/%s/%s: %v", f.namespace, pod, container, err))
+	}
+	finished := err == nil && f.ctx.Err() == nil && f.podCompleted(pod)
+
+	f.mu.Lock()
+	stream.active = false
+	stream.finished = finished
+	f.mu.Unlock()
+}
+
+func (f *logFollower) podCompleted(name string) bool {
+	pod, err := f.client.CoreV1().Pods(f.namespace).Get(f.ctx, name, metaV1.GetOptions{})
+	if err != nil {
+		return false
+	}
+	return pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed
+}
+
+// followContainer 打开单个容器的 Follow 日志流, 逐行解析后交给 emit. since 非零时只读取之后的日志, 用于断线续传
+func followContainer(ctx context.Context, client kubernetes.Interface, namespace, pod, container string,
+	opts FollowOptions, since time.Time, emit func(FollowLogLine)) error {
+	logOptions := &v1.PodLogOptions{
+		Container:  container,
+		Follow:     true,
+		Timestamps: true,
+	}
+	if since.IsZero() {
+		logOptions.TailLines = opts.TailLines
+		if logOptions.TailLines == nil {
+			logOptions.TailLines = &defaultFollowTailLines
+		}
+		logOptions.SinceSeconds = opts.SinceSeconds
+	} else {
+		sinceTime := metaV1.NewTime(since)
+		logOptions.SinceTime = &sinceTime
+	}
+
+	readCloser, err := openLogStream(ctx, client, namespace, pod, logOptions)
+	if err != nil {
+		return err
+	}
+	defer readCloser.Close()
+
+	scanner := bufio.NewScanner(readCloser)
+	scanner.Buffer(make([]byte, 0, 64*1024), followMaxLineSize)
+	for scanner.Scan() {
+		logLines := logs.ToLogLines(scanner.Text())
+		if len(logLines) == 0 {
+			continue
+		}
+		logLine := logLines[0]
+		t, err := time.Parse(time.RFC3339Nano, string(logLine.Timestamp))
+		if err != nil {
+			t = time.Now()
+		}
+		// SinceTime 只精确到秒, 续传时丢弃已经推送过的行
+		if !since.IsZero() && !t.After(since) {
+			continue
+		}
+		emit(FollowLogLine{
+			PodName:       pod,
+			ContainerName: container,
+			Timestamp:     logLine.Timestamp,
+			Content:       logLine.Content,
+			Line:          fmt.Sprintf("[%s/%s] %s", pod, container, logLine.Content),
+			time:          t,
+		})
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pods
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dnsjia/luban/common"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeLogStreams 替换 openLogStream, 每个 pod 返回固定的日志内容并记录打开次数
+type fakeLogStreams struct {
+	mu      sync.Mutex
+	content map[string]string
+	opened  map[string]int
+}
+
+func (f *fakeLogStreams) open(ctx context.Context, client kubernetes.Interface, namespace, pod string, options *v1.PodLogOptions) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.opened[pod]++
+	return ioutil.NopCloser(strings.NewReader(f.content[pod])), nil
+}
+
+func (f *fakeLogStreams) count(pod string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.opened[pod]
+}
+
+func setupFollowTest(t *testing.T, content map[string]string, objects ...runtime.Object) (*fake.Clientset, *fakeLogStreams) {
+	common.LOG = zap.NewNop()
+	streams := &fakeLogStreams{content: content, opened: make(map[string]int)}
+	savedOpen, savedFlush, savedRefresh := openLogStream, followFlushInterval, followRefreshInterval
+	openLogStream = streams.open
+	followFlushInterval, followRefreshInterval = 200*time.Millisecond, 20*time.Millisecond
+	t.Cleanup(func() {
+		openLogStream, followFlushInterval, followRefreshInterval = savedOpen, savedFlush, savedRefresh
+	})
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metaV1.ObjectMeta{Name: "api", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metaV1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}}},
+		},
+	}
+	return fake.NewSimpleClientset(append(objects, deployment)...), streams
+}
+
+func followTestPod(name, app string, phase v1.PodPhase) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": app}},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app"}}},
+		Status:     v1.PodStatus{Phase: phase},
+	}
+}
+
+func TestSortFollowLines(t *testing.T) {
+	base := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	lines := []FollowLogLine{
+		{Content: "c", time: base.Add(2 * time.Second)},
+		{Content: "a", time: base},
+		{Content: "b1", time: base.Add(time.Second)},
+		{Content: "b2", time: base.Add(time.Second)},
+	}
+	sortFollowLines(lines)
+	var got []string
+	for _, line := range lines {
+		got = append(got, line.Content)
+	}
+	if strings.Join(got, ",") != "a,b1,b2,c" {
+		t.Errorf("sortFollowLines == %v, expected a,b1,b2,c", got)
+	}
+}
+
+// TestFollowLogsAggregation 多个 pod 的日志按时间戳合并, 重新挂载时不会重复推送
+func TestFollowLogsAggregation(t *testing.T) {
+	client, streams := setupFollowTest(t, map[string]string{
+		"api-1":         "2021-01-01T00:00:02Z b\n2021-01-01T00:00:04Z d\n",
+		"api-2":         "2021-01-01T00:00:01Z a\n2021-01-01T00:00:03Z c\n",
+		"api-gateway-1": "2021-01-01T00:00:00Z other\n",
+	},
+		followTestPod("api-1", "api", v1.PodRunning),
+		followTestPod("api-2", "api", v1.PodRunning),
+		followTestPod("api-gateway-1", "api-gateway", v1.PodRunning),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var got []FollowLogLine
+	err := FollowLogs(ctx, client, "default", "api", "deployment", FollowOptions{}, func(lines []FollowLogLine) error {
+		got = append(got, lines...)
+		// 等待若干次重新挂载后结束, 用于验证续传不会重复推送
+		if streams.count("api-1") > 2 {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("FollowLogs returned error: %v", err)
+	}
+
+	var lines []string
+	for _, line := range got {
+		lines = append(lines, line.Line)
+	}
+	expected := "[api-2/app] a,[api-1/app] b,[api-2/app] c,[api-1/app] d"
+	if strings.Join(lines, ",") != expected {
+		t.Errorf("FollowLogs sent %v, expected %s", lines, expected)
+	}
+	if streams.count("api-gateway-1") != 0 {
+		t.Errorf("followed pod of another deployment")
+	}
+}
+
+// TestLogFollowerAttach 已运行结束的 pod 只挂载一次, 已删除的 pod 会被移除
+func TestLogFollowerAttach(t *testing.T) {
+	client, streams := setupFollowTest(t, map[string]string{},
+		followTestPod("api-1", "api", v1.PodSucceeded),
+		followTestPod("api-2", "api", v1.PodRunning),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &logFollower{
+		ctx:          ctx,
+		client:       client,
+		namespace:    "default",
+		resourceName: "api",
+		resourceType: "deployment",
+		lines:        make(chan FollowLogLine, 16),
+		streams:      make(map[string]*followStream),
+	}
+	defer func() {
+		cancel()
+		f.wg.Wait()
+	}()
+	waitInactive := func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			f.mu.Lock()
+			active := false
+			for _, stream := range f.streams {
+				active = active || stream.active
+			}
+			f.mu.Unlock()
+			if !active {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("streams still active")
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := f.attach(); err != nil {
+			t.Fatalf("attach returned error: %v", err)
+		}
+		waitInactive()
+	}
+	if streams.count("api-1") != 1 || streams.count("api-2") != 3 {
+		t.Errorf("opened api-1 %d times and api-2 %d times, expected 1 and 3", streams.count("api-1"), streams.count("api-2"))
+	}
+
+	if err := client.CoreV1().Pods("default").Delete(ctx, "api-2", metaV1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.attach(); err != nil {
+		t.Fatalf("attach returned error: %v", err)
+	}
+	if _, ok := f.streams["api-2/app"]; ok {
+		t.Errorf("stream of deleted pod was not pruned")
+	}
+	if _, ok := f.streams["api-1/app"]; !ok {
+		t.Errorf("stream of completed pod was pruned")
+	}
+}
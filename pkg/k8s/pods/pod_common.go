@@ -21,6 +21,7 @@ import (
 	"github.com/dnsjia/luban/pkg/k8s/common"
 	"github.com/dnsjia/luban/pkg/k8s/dataselect"
 	"github.com/dnsjia/luban/pkg/k8s/event"
+	"github.com/dnsjia/luban/pkg/k8s/metrics"
 	v1 "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -68,18 +69,32 @@ func getPodStatusPhase(pod v1.Pod, warnings []common.Event) v1.PodPhase {
 	return v1.PodPending
 }
 
-type PodCell v1.Pod
+// PodCell wraps a pod with its live usage so lists can be sorted by cpuUsage / memoryUsage.
+type PodCell struct {
+	v1.Pod
+	Usage *metrics.Usage
+}
 
 func (self PodCell) GetProperty(name dataselect.PropertyName) dataselect.ComparableValue {
 	switch name {
 	case dataselect.NameProperty:
 		return dataselect.StdComparableString(self.ObjectMeta.Name)
 	case dataselect.StatusProperty:
-		return dataselect.StdComparableString(getPodStatus(v1.Pod(self)))
+		return dataselect.StdComparableString(getPodStatus(self.Pod))
 	case dataselect.CreationTimestampProperty:
 		return dataselect.StdComparableTime(self.ObjectMeta.CreationTimestamp.Time)
 	case dataselect.NamespaceProperty:
 		return dataselect.StdComparableString(self.ObjectMeta.Namespace)
+	case dataselect.CPUUsageProperty:
+		if self.Usage == nil {
+			return dataselect.StdComparableInt64(0)
+		}
+		return dataselect.StdComparableInt64(self.Usage.CPU)
+	case dataselect.MemoryUsageProperty:
+		if self.Usage == nil {
+			return dataselect.StdComparableInt64(0)
+		}
+		return dataselect.StdComparableInt64(self.Usage.Memory)
 	default:
 		// if name is not supported then just return a constant dummy value, sort will have no effect.
 		return nil
@@ -180,10 +195,10 @@ func getRestartCount(pod v1.Pod) int32 {
 	return restartCount
 }
 
-func toCells(std []v1.Pod) []dataselect.DataCell {
+func toCells(std []v1.Pod, podMetrics metrics.PodMetrics) []dataselect.DataCell {
 	cells := make([]dataselect.DataCell, len(std))
 	for i := range std {
-		cells[i] = PodCell(std[i])
+		cells[i] = PodCell{Pod: std[i], Usage: podMetrics.Get(&std[i])}
 	}
 	return cells
 }
@@ -191,7 +206,7 @@ func toCells(std []v1.Pod) []dataselect.DataCell {
 func fromCells(cells []dataselect.DataCell) []v1.Pod {
 	std := make([]v1.Pod, len(cells))
 	for i := range std {
-		std[i] = v1.Pod(cells[i].(PodCell))
+		std[i] = cells[i].(PodCell).Pod
 	}
 	return std
 }
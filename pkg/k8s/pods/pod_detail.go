@@ -49,6 +49,7 @@ type PodDetail struct {
 	Controller                *controller.ResourceOwner     `json:"controller,omitempty"`
 	Containers                []Container                   `json:"containers"`
 	InitContainers            []Container                   `json:"initContainers"`
+	EphemeralContainers       []EphemeralContainer          `json:"ephemeralContainers"`
 	Conditions                []k8scommon.Condition         `json:"conditions"`
 	ImagePullSecrets          []v1.LocalObjectReference     `json:"imagePullSecrets,omitempty"`
 	EventList                 k8scommon.EventList           `json:"eventList"`
@@ -216,6 +217,7 @@ func toPodDetail(pod *v1.Pod, configMaps *v1.ConfigMapList, secrets *v1.SecretLi
 		Controller:                controller,
 		Containers:                extractContainerInfo(pod.Spec.Containers, pod, configMaps, secrets),
 		InitContainers:            extractContainerInfo(pod.Spec.InitContainers, pod, configMaps, secrets),
+		EphemeralContainers:       extractEphemeralContainerInfo(pod),
 		Conditions:                getPodConditions(*pod),
 		ImagePullSecrets:          pod.Spec.ImagePullSecrets,
 		EventList:                 *events,
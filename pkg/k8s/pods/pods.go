@@ -24,6 +24,8 @@ import (
 	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
 	"github.com/dnsjia/luban/pkg/k8s/dataselect"
 	"github.com/dnsjia/luban/pkg/k8s/event"
+	"github.com/dnsjia/luban/pkg/k8s/informer"
+	"github.com/dnsjia/luban/pkg/k8s/metrics"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -38,6 +40,9 @@ type PodList struct {
 
 	// Unordered list of Pods.
 	Pods []Pod `json:"pods"`
+
+	// Whether live usage from metrics-server is available.
+	MetricsStatus metrics.Status `json:"metricsStatus"`
 }
 
 type PodStatus struct {
@@ -69,6 +74,9 @@ type Pod struct {
 
 	// Pod ip address
 	PodIP string `json:"podIP"`
+
+	// Live CPU / memory usage from metrics-server, nil when unavailable.
+	Metrics *metrics.Usage `json:"metrics"`
 }
 
 var EmptyPodList = &PodList{
@@ -81,17 +89,22 @@ var EmptyPodList = &PodList{
 func GetPodsList(client *kubernetes.Clientset, nsQuery *k8scommon.NamespaceQuery, dsQuery *dataselect.DataSelectQuery) (*PodList, error) {
 	common.LOG.Info("Getting list of all pods in the cluster")
 	channels := &k8scommon.ResourceChannels{
-		PodList:   k8scommon.GetPodListChannelWithOptions(client, nsQuery, metav1.ListOptions{}, 1),
-		EventList: k8scommon.GetEventListChannel(client, nsQuery, 1),
+		PodList:   informer.GetPodListChannel(client, nsQuery, 1),
+		EventList: informer.GetEventListChannel(client, nsQuery, 1),
 	}
 
-	return GetPodListFromChannels(channels, dsQuery)
-
+	podMetrics, metricsStatus := metrics.GetPodMetrics(client, nsQuery.ToRequestParam(), "")
+	podList, err := GetPodListFromChannels(channels, podMetrics, dsQuery)
+	if err != nil {
+		return nil, err
+	}
+	podList.MetricsStatus = metricsStatus
+	return podList, nil
 }
 
 // GetPodListFromChannels returns a list of all Pods in the cluster
 // reading required resource list once from the channels.
-func GetPodListFromChannels(channels *k8scommon.ResourceChannels, dsQuery *dataselect.DataSelectQuery) (*PodList, error) {
+func GetPodListFromChannels(channels *k8scommon.ResourceChannels, podMetrics metrics.PodMetrics, dsQuery *dataselect.DataSelectQuery) (*PodList, error) {
 
 	pods := <-channels.PodList.List
 	err := <-channels.PodList.Error
@@ -105,22 +118,25 @@ func GetPodListFromChannels(channels *k8scommon.ResourceChannels, dsQuery *datas
 		return nil, err
 	}
 
-	podList := ToPodList(pods.Items, eventList.Items, dsQuery)
+	podList := ToPodList(pods.Items, eventList.Items, podMetrics, dsQuery)
 	podList.Status = getStatus(pods, eventList.Items)
 	return &podList, nil
 }
-func ToPodList(pods []v1.Pod, events []v1.Event, dsQuery *dataselect.DataSelectQuery) PodList {
+
+// ToPodList converts pods for presentation, podMetrics may be nil when metrics-server is unavailable.
+func ToPodList(pods []v1.Pod, events []v1.Event, podMetrics metrics.PodMetrics, dsQuery *dataselect.DataSelectQuery) PodList {
 	podList := PodList{
 		Pods: make([]Pod, 0),
 	}
 
-	podCells, filteredTotal := dataselect.GenericDataSelectWithFilter(toCells(pods), dsQuery)
+	podCells, filteredTotal := dataselect.GenericDataSelectWithFilter(toCells(pods, podMetrics), dsQuery)
 	pods = fromCells(podCells)
 	podList.ListMeta = k8s.ListMeta{TotalItems: filteredTotal}
 
 	for _, pod := range pods {
 		warnings := event.GetPodsEventWarnings(events, []v1.Pod{pod})
 		podDetail := ToPod(&pod, warnings)
+		podDetail.Metrics = podMetrics.Get(&pod)
 		podList.Pods = append(podList.Pods, podDetail)
 	}
 
@@ -0,0 +1,271 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// 空闲超时, 隧道两端在超时时间内没有数据传输时断开
+const (
+	DefaultIdleTimeout = 10 * time.Minute
+	MaxIdleTimeout     = time.Hour
+)
+
+const bufferSize = 32 * 1024
+
+// Options 端口转发会话的审计信息及超时设置
+type Options struct {
+	ClusterID   uint
+	UserName    string
+	ClientIP    string
+	IdleTimeout time.Duration
+}
+
+// tunnel 一条WebSocket连接对应一条到Pod端口的TCP流
+type tunnel struct {
+	sessionId string
+	target    *Target
+	options   Options
+	ws        *websocket.Conn
+	// writeLock 保证WebSocket写操作串行
+	writeLock    sync.Mutex
+	bytesIn      int64
+	bytesOut     int64
+	lastActivity int64
+}
+
+// Forward 通过SPDY建立到Pod端口的转发, 并与WebSocket双向拷贝数据, 直到任意一端关闭、空闲超时或ctx取消.
+// 每个会话结束后写入一条审计记录.
+func Forward(ctx context.Context, client kubernetes.Interface, config *rest.Config, target *Target, ws *websocket.Conn, options Options) error {
+	sessionId, err := genSessionId()
+	if err != nil {
+		return err
+	}
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = DefaultIdleTimeout
+	}
+	if options.IdleTimeout > MaxIdleTimeout {
+		options.IdleTimeout = MaxIdleTimeout
+	}
+
+	t := &tunnel{sessionId: sessionId, target: target, options: options, ws: ws}
+	t.touch()
+	connectTime := time.Now()
+	common.LOG.Info(fmt.Sprintf("用户：%v, 创建端口转发会话 %s, pod: %s/%s:%d", options.UserName, sessionId, target.Namespace, target.PodName, target.Port))
+
+	reason := t.run(ctx, client, config)
+	t.audit(connectTime, reason)
+	return nil
+}
+
+// run 返回会话结束的原因
+func (t *tunnel) run(ctx context.Context, client kubernetes.Interface, config *rest.Config) string {
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return t.fail(err)
+	}
+	req := client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(t.target.Namespace).
+		Name(t.target.PodName).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL())
+	conn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return t.fail(fmt.Errorf("连接Pod失败: %v", err))
+	}
+	defer conn.Close()
+
+	headers := http.Header{}
+	headers.Set(v1.StreamType, v1.StreamTypeError)
+	headers.Set(v1.PortHeader, strconv.Itoa(int(t.target.Port)))
+	headers.Set(v1.PortForwardRequestIDHeader, "0")
+	errorStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return t.fail(fmt.Errorf("创建错误流失败: %v", err))
+	}
+	// we're not writing to this stream
+	errorStream.Close()
+
+	headers.Set(v1.StreamType, v1.StreamTypeData)
+	dataStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return t.fail(fmt.Errorf("创建数据流失败: %v", err))
+	}
+
+	done := make(chan string, 4)
+	go func() {
+		message, err := ioutil.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			done <- fmt.Sprintf("读取错误流失败: %v", err)
+		case len(message) > 0:
+			done <- fmt.Sprintf("Pod端口转发出错: %s", string(message))
+		}
+	}()
+	go func() {
+		done <- t.copyToPod(dataStream)
+	}()
+	go func() {
+		done <- t.copyFromPod(dataStream)
+	}()
+
+	ticker := time.NewTicker(time.Second * 10)
+	defer ticker.Stop()
+	for {
+		select {
+		case reason := <-done:
+			t.close(websocket.CloseNormalClosure, reason)
+			return reason
+		case <-ctx.Done():
+			t.close(websocket.CloseGoingAway, "服务端关闭")
+			return "服务端关闭"
+		case <-ticker.C:
+			if time.Since(time.Unix(0, atomic.LoadInt64(&t.lastActivity))) > t.options.IdleTimeout {
+				reason := fmt.Sprintf("空闲超过 %v", t.options.IdleTimeout)
+				t.close(websocket.CloseNormalClosure, reason)
+				return reason
+			}
+		}
+	}
+}
+
+// copyToPod WebSocket -> Pod, 客户端关闭后半关闭数据流
+func (t *tunnel) copyToPod(dataStream io.WriteCloser) string {
+	defer dataStream.Close()
+	for {
+		messageType, data, err := t.ws.ReadMessage()
+		if err != nil {
+			return "客户端断开连接"
+		}
+		if messageType != websocket.BinaryMessage && messageType != websocket.TextMessage {
+			continue
+		}
+		if _, err := dataStream.Write(data); err != nil {
+			return fmt.Sprintf("写入Pod失败: %v", err)
+		}
+		atomic.AddInt64(&t.bytesIn, int64(len(data)))
+		t.touch()
+	}
+}
+
+// copyFromPod Pod -> WebSocket
+func (t *tunnel) copyFromPod(dataStream io.Reader) string {
+	buf := make([]byte, bufferSize)
+	for {
+		n, err := dataStream.Read(buf)
+		if n > 0 {
+			t.writeLock.Lock()
+			writeErr := t.ws.WriteMessage(websocket.BinaryMessage, buf[:n])
+			t.writeLock.Unlock()
+			if writeErr != nil {
+				return "客户端断开连接"
+			}
+			atomic.AddInt64(&t.bytesOut, int64(n))
+			t.touch()
+		}
+		if err == io.EOF {
+			return "Pod端关闭连接"
+		}
+		if err != nil {
+			return fmt.Sprintf("读取Pod失败: %v", err)
+		}
+	}
+}
+
+func (t *tunnel) touch() {
+	atomic.StoreInt64(&t.lastActivity, time.Now().UnixNano())
+}
+
+func (t *tunnel) fail(err error) string {
+	t.close(websocket.CloseInternalServerErr, err.Error())
+	return err.Error()
+}
+
+// close 发送关闭帧, 原因超出控制帧长度时截断
+func (t *tunnel) close(code int, reason string) {
+	reason = truncateUTF8(reason, 120)
+	t.writeLock.Lock()
+	defer t.writeLock.Unlock()
+	_ = t.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	_ = t.ws.Close()
+}
+
+func (t *tunnel) audit(connectTime time.Time, reason string) {
+	reason = truncateUTF8(reason, 512)
+	record := models.PortForwardRecord{
+		SessionID:   t.sessionId,
+		ClusterID:   t.options.ClusterID,
+		Namespace:   t.target.Namespace,
+		PodName:     t.target.PodName,
+		ServiceName: t.target.ServiceName,
+		Port:        t.target.Port,
+		UserName:    t.options.UserName,
+		ClientIP:    t.options.ClientIP,
+		ConnectTime: models.LocalTime{Time: connectTime},
+		CloseTime:   models.LocalTime{Time: time.Now()},
+		BytesIn:     atomic.LoadInt64(&t.bytesIn),
+		BytesOut:    atomic.LoadInt64(&t.bytesOut),
+		CloseReason: reason,
+	}
+	if err := common.DB.Create(&record).Error; err != nil {
+		common.LOG.Error("保存端口转发记录失败", zap.Any("err", err))
+	}
+	common.LOG.Info(fmt.Sprintf("用户：%v, 端口转发会话 %s 结束: %s", t.options.UserName, t.sessionId, reason))
+}
+
+func genSessionId() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	id := make([]byte, hex.EncodedLen(len(bytes)))
+	hex.Encode(id, bytes)
+	return string(id), nil
+}
+
+// truncateUTF8 按字节数截断字符串, 不会截断多字节字符, 关闭帧中的原因必须是合法的 UTF-8
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
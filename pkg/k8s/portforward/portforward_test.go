@@ -0,0 +1,50 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateUTF8(t *testing.T) {
+	cases := []struct {
+		name     string
+		s        string
+		n        int
+		expected string
+	}{
+		{name: "short", s: "Pod端关闭连接", n: 120, expected: "Pod端关闭连接"},
+		{name: "ascii", s: "connection reset", n: 10, expected: "connection"},
+		// "端" 占3个字节, 在其中间截断时回退到字符开始处
+		{name: "inside multibyte rune", s: "Pod端关闭", n: 5, expected: "Pod"},
+		{name: "rune boundary", s: "Pod端关闭", n: 6, expected: "Pod端"},
+		{name: "zero", s: "端口", n: 0, expected: ""},
+	}
+	for _, c := range cases {
+		if actual := truncateUTF8(c.s, c.n); actual != c.expected {
+			t.Errorf("%s: truncateUTF8(%q, %d) == %q, expected %q", c.name, c.s, c.n, actual, c.expected)
+		}
+	}
+
+	long := "读取Pod失败: " + strings.Repeat("连接被重置", 30)
+	truncated := truncateUTF8(long, 120)
+	if len(truncated) > 120 || !utf8.ValidString(truncated) {
+		t.Errorf("truncateUTF8 returned %d bytes, valid utf8: %v", len(truncated), utf8.ValidString(truncated))
+	}
+}
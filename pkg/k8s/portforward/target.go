@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dnsjia/luban/pkg/k8s/endpoint"
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Target is the pod and container port traffic is forwarded to.
+type Target struct {
+	Namespace string `json:"namespace"`
+	PodName   string `json:"pod_name"`
+	Port      int32  `json:"port"`
+	// ServiceName is set when the target was resolved from a service.
+	ServiceName string `json:"service_name,omitempty"`
+}
+
+// ResolvePodTarget checks the pod is running and exposes the port, named container ports are resolved to numbers.
+func ResolvePodTarget(client kubernetes.Interface, namespace, podName, port string) (*Target, error) {
+	pod, err := client.CoreV1().Pods(namespace).Get(context.TODO(), podName, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if pod.Status.Phase != v1.PodRunning {
+		return nil, fmt.Errorf("Pod %s/%s 未处于运行状态: %s", namespace, podName, pod.Status.Phase)
+	}
+
+	portNumber, err := containerPort(pod, intstr.Parse(port))
+	if err != nil {
+		return nil, err
+	}
+	return &Target{Namespace: namespace, PodName: podName, Port: portNumber}, nil
+}
+
+// ResolveServiceTarget resolves a service port, given by number or name, to a ready endpoint pod and its target port.
+func ResolveServiceTarget(client kubernetes.Interface, namespace, serviceName, port string) (*Target, error) {
+	service, err := client.CoreV1().Services(namespace).Get(context.TODO(), serviceName, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var servicePort *v1.ServicePort
+	requested := intstr.Parse(port)
+	for i := range service.Spec.Ports {
+		p := &service.Spec.Ports[i]
+		if (requested.Type == intstr.Int && p.Port == requested.IntVal) ||
+			(requested.Type == intstr.String && p.Name == requested.StrVal) {
+			servicePort = p
+			break
+		}
+	}
+	if servicePort == nil {
+		return nil, fmt.Errorf("Service %s/%s 不存在端口 %s", namespace, serviceName, port)
+	}
+	if servicePort.Protocol != "" && servicePort.Protocol != v1.ProtocolTCP {
+		return nil, fmt.Errorf("端口转发只支持TCP协议, 端口 %s 协议为 %s", port, servicePort.Protocol)
+	}
+
+	endpoints, err := endpoint.GetServiceEndpoints(client, namespace, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	for _, ep := range endpoints.Endpoints {
+		if !ep.Ready || ep.TargetRef == nil || ep.TargetRef.Kind != "Pod" {
+			continue
+		}
+		// Endpoint ports carry the resolved container port under the service port name.
+		for _, epPort := range ep.Ports {
+			if epPort.Name == servicePort.Name {
+				return &Target{
+					Namespace:   namespace,
+					PodName:     ep.TargetRef.Name,
+					Port:        epPort.Port,
+					ServiceName: serviceName,
+				}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("Service %s/%s 没有就绪的Pod", namespace, serviceName)
+}
+
+func containerPort(pod *v1.Pod, port intstr.IntOrString) (int32, error) {
+	if port.Type == intstr.Int {
+		if port.IntVal <= 0 || port.IntVal > 65535 {
+			return 0, fmt.Errorf("端口 %d 不合法", port.IntVal)
+		}
+		return port.IntVal, nil
+	}
+	if port.StrVal == "" {
+		return 0, errors.New("端口不能为空")
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, p := range container.Ports {
+			if p.Name == port.StrVal {
+				return p.ContainerPort, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("Pod %s/%s 不存在名为 %s 的端口", pod.Namespace, pod.Name, port.StrVal)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestResolvePodTarget(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&v1.Pod{
+			ObjectMeta: metaV1.ObjectMeta{Name: "api", Namespace: "dev"},
+			Spec: v1.PodSpec{Containers: []v1.Container{{
+				Name:  "api",
+				Ports: []v1.ContainerPort{{Name: "debug", ContainerPort: 5005}},
+			}}},
+			Status: v1.PodStatus{Phase: v1.PodRunning},
+		},
+		&v1.Pod{
+			ObjectMeta: metaV1.ObjectMeta{Name: "pending", Namespace: "dev"},
+			Status:     v1.PodStatus{Phase: v1.PodPending},
+		},
+	)
+
+	cases := []struct {
+		pod, port string
+		want      int32
+		wantErr   bool
+	}{
+		{"api", "8080", 8080, false},
+		{"api", "debug", 5005, false},
+		{"api", "metrics", 0, true},
+		{"api", "70000", 0, true},
+		{"pending", "8080", 0, true},
+	}
+	for _, c := range cases {
+		target, err := ResolvePodTarget(client, "dev", c.pod, c.port)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s:%s expected error", c.pod, c.port)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s:%s unexpected error: %v", c.pod, c.port, err)
+			continue
+		}
+		if target.Port != c.want {
+			t.Errorf("%s:%s got port %d, want %d", c.pod, c.port, target.Port, c.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbac
+
+import (
+	"github.com/dnsjia/luban/pkg/k8s/dataselect"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The code below allows to perform complex data section on RBAC objects. All RBAC kinds are selected by their
+// metadata only, so a single cell type wraps the object together with its ObjectMeta.
+
+type ObjectCell struct {
+	ObjectMeta metaV1.ObjectMeta
+	Object     interface{}
+}
+
+func (self ObjectCell) GetProperty(name dataselect.PropertyName) dataselect.ComparableValue {
+	switch name {
+	case dataselect.NameProperty:
+		return dataselect.StdComparableString(self.ObjectMeta.Name)
+	case dataselect.CreationTimestampProperty:
+		return dataselect.StdComparableTime(self.ObjectMeta.CreationTimestamp.Time)
+	case dataselect.NamespaceProperty:
+		return dataselect.StdComparableString(self.ObjectMeta.Namespace)
+	default:
+		// if name is not supported then just return a constant dummy value, sort will have no effect.
+		return nil
+	}
+}
+
+// selectObjects applies dsQuery on cells and returns the selected objects with the filtered total.
+func selectObjects(cells []dataselect.DataCell, dsQuery *dataselect.DataSelectQuery) ([]interface{}, int) {
+	selected, filteredTotal := dataselect.GenericDataSelectWithFilter(cells, dsQuery)
+	objects := make([]interface{}, len(selected))
+	for i := range selected {
+		objects[i] = selected[i].(ObjectCell).Object
+	}
+	return objects, filteredTotal
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
+	"github.com/dnsjia/luban/pkg/k8s/dataselect"
+	rbac "k8s.io/api/rbac/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Role is a presentation layer view of Kubernetes Role and ClusterRole resource.
+type Role struct {
+	ObjectMeta k8s.ObjectMeta `json:"objectMeta"`
+	TypeMeta   k8s.TypeMeta   `json:"typeMeta"`
+}
+
+// RoleList contains a list of Roles or ClusterRoles in the cluster.
+type RoleList struct {
+	ListMeta k8s.ListMeta `json:"listMeta"`
+	Items    []Role       `json:"items"`
+}
+
+// RoleDetail contains Role or ClusterRole details.
+type RoleDetail struct {
+	// Extends list item structure.
+	Role `json:",inline"`
+
+	Rules []rbac.PolicyRule `json:"rules"`
+
+	// AggregationRule is only set on aggregated ClusterRoles.
+	AggregationRule *rbac.AggregationRule `json:"aggregationRule,omitempty"`
+}
+
+// GetRoleList returns a list of all Roles in the namespaces selected by nsQuery.
+func GetRoleList(client kubernetes.Interface, nsQuery *k8scommon.NamespaceQuery, dsQuery *dataselect.DataSelectQuery) (*RoleList, error) {
+	common.LOG.Info(fmt.Sprintf("Getting list of roles in the namespace %s", nsQuery.ToRequestParam()))
+	roles, err := client.RbacV1().Roles(nsQuery.ToRequestParam()).List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+
+	cells := make([]dataselect.DataCell, 0)
+	for i := range roles.Items {
+		if nsQuery.Matches(roles.Items[i].Namespace) {
+			cells = append(cells, ObjectCell{ObjectMeta: roles.Items[i].ObjectMeta, Object: &roles.Items[i]})
+		}
+	}
+
+	objects, filteredTotal := selectObjects(cells, dsQuery)
+	result := &RoleList{ListMeta: k8s.ListMeta{TotalItems: filteredTotal}, Items: make([]Role, 0)}
+	for _, object := range objects {
+		result.Items = append(result.Items, toRole(object.(*rbac.Role).ObjectMeta, k8s.ResourceKindRole))
+	}
+	return result, nil
+}
+
+// GetRoleDetail returns detailed information about a Role.
+func GetRoleDetail(client kubernetes.Interface, namespace, name string) (*RoleDetail, error) {
+	role, err := client.RbacV1().Roles(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &RoleDetail{
+		Role:  toRole(role.ObjectMeta, k8s.ResourceKindRole),
+		Rules: role.Rules,
+	}, nil
+}
+
+// GetClusterRoleList returns a list of all ClusterRoles in the cluster.
+func GetClusterRoleList(client kubernetes.Interface, dsQuery *dataselect.DataSelectQuery) (*RoleList, error) {
+	common.LOG.Info("Getting list of all cluster roles in the cluster")
+	clusterRoles, err := client.RbacV1().ClusterRoles().List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+
+	cells := make([]dataselect.DataCell, len(clusterRoles.Items))
+	for i := range clusterRoles.Items {
+		cells[i] = ObjectCell{ObjectMeta: clusterRoles.Items[i].ObjectMeta, Object: &clusterRoles.Items[i]}
+	}
+
+	objects, filteredTotal := selectObjects(cells, dsQuery)
+	result := &RoleList{ListMeta: k8s.ListMeta{TotalItems: filteredTotal}, Items: make([]Role, 0)}
+	for _, object := range objects {
+		result.Items = append(result.Items, toRole(object.(*rbac.ClusterRole).ObjectMeta, k8s.ResourceKindClusterRole))
+	}
+	return result, nil
+}
+
+// GetClusterRoleDetail returns detailed information about a ClusterRole.
+func GetClusterRoleDetail(client kubernetes.Interface, name string) (*RoleDetail, error) {
+	clusterRole, err := client.RbacV1().ClusterRoles().Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &RoleDetail{
+		Role:            toRole(clusterRole.ObjectMeta, k8s.ResourceKindClusterRole),
+		Rules:           clusterRole.Rules,
+		AggregationRule: clusterRole.AggregationRule,
+	}, nil
+}
+
+func toRole(meta metaV1.ObjectMeta, kind k8s.ResourceKind) Role {
+	return Role{
+		ObjectMeta: k8s.NewObjectMeta(meta),
+		TypeMeta:   k8s.NewTypeMeta(kind),
+	}
+}
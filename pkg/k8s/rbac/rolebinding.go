@@ -0,0 +1,122 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
+	"github.com/dnsjia/luban/pkg/k8s/dataselect"
+	rbac "k8s.io/api/rbac/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RoleBinding is a presentation layer view of Kubernetes RoleBinding and ClusterRoleBinding resource.
+type RoleBinding struct {
+	ObjectMeta k8s.ObjectMeta `json:"objectMeta"`
+	TypeMeta   k8s.TypeMeta   `json:"typeMeta"`
+
+	RoleRef  rbac.RoleRef   `json:"roleRef"`
+	Subjects []rbac.Subject `json:"subjects"`
+}
+
+// RoleBindingList contains a list of RoleBindings or ClusterRoleBindings in the cluster.
+type RoleBindingList struct {
+	ListMeta k8s.ListMeta  `json:"listMeta"`
+	Items    []RoleBinding `json:"items"`
+}
+
+// GetRoleBindingList returns a list of all RoleBindings in the namespaces selected by nsQuery.
+func GetRoleBindingList(client kubernetes.Interface, nsQuery *k8scommon.NamespaceQuery, dsQuery *dataselect.DataSelectQuery) (*RoleBindingList, error) {
+	common.LOG.Info(fmt.Sprintf("Getting list of role bindings in the namespace %s", nsQuery.ToRequestParam()))
+	bindings, err := client.RbacV1().RoleBindings(nsQuery.ToRequestParam()).List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+
+	cells := make([]dataselect.DataCell, 0)
+	for i := range bindings.Items {
+		if nsQuery.Matches(bindings.Items[i].Namespace) {
+			cells = append(cells, ObjectCell{ObjectMeta: bindings.Items[i].ObjectMeta, Object: &bindings.Items[i]})
+		}
+	}
+
+	objects, filteredTotal := selectObjects(cells, dsQuery)
+	result := &RoleBindingList{ListMeta: k8s.ListMeta{TotalItems: filteredTotal}, Items: make([]RoleBinding, 0)}
+	for _, object := range objects {
+		binding := object.(*rbac.RoleBinding)
+		result.Items = append(result.Items, toRoleBinding(binding.ObjectMeta, k8s.ResourceKindRoleBinding, binding.RoleRef, binding.Subjects))
+	}
+	return result, nil
+}
+
+// GetRoleBindingDetail returns detailed information about a RoleBinding.
+func GetRoleBindingDetail(client kubernetes.Interface, namespace, name string) (*RoleBinding, error) {
+	binding, err := client.RbacV1().RoleBindings(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	result := toRoleBinding(binding.ObjectMeta, k8s.ResourceKindRoleBinding, binding.RoleRef, binding.Subjects)
+	return &result, nil
+}
+
+// GetClusterRoleBindingList returns a list of all ClusterRoleBindings in the cluster.
+func GetClusterRoleBindingList(client kubernetes.Interface, dsQuery *dataselect.DataSelectQuery) (*RoleBindingList, error) {
+	common.LOG.Info("Getting list of all cluster role bindings in the cluster")
+	bindings, err := client.RbacV1().ClusterRoleBindings().List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+
+	cells := make([]dataselect.DataCell, len(bindings.Items))
+	for i := range bindings.Items {
+		cells[i] = ObjectCell{ObjectMeta: bindings.Items[i].ObjectMeta, Object: &bindings.Items[i]}
+	}
+
+	objects, filteredTotal := selectObjects(cells, dsQuery)
+	result := &RoleBindingList{ListMeta: k8s.ListMeta{TotalItems: filteredTotal}, Items: make([]RoleBinding, 0)}
+	for _, object := range objects {
+		binding := object.(*rbac.ClusterRoleBinding)
+		result.Items = append(result.Items, toRoleBinding(binding.ObjectMeta, k8s.ResourceKindClusterRoleBinding, binding.RoleRef, binding.Subjects))
+	}
+	return result, nil
+}
+
+// GetClusterRoleBindingDetail returns detailed information about a ClusterRoleBinding.
+func GetClusterRoleBindingDetail(client kubernetes.Interface, name string) (*RoleBinding, error) {
+	binding, err := client.RbacV1().ClusterRoleBindings().Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	result := toRoleBinding(binding.ObjectMeta, k8s.ResourceKindClusterRoleBinding, binding.RoleRef, binding.Subjects)
+	return &result, nil
+}
+
+func toRoleBinding(meta metaV1.ObjectMeta, kind k8s.ResourceKind, roleRef rbac.RoleRef, subjects []rbac.Subject) RoleBinding {
+	if subjects == nil {
+		subjects = make([]rbac.Subject, 0)
+	}
+	return RoleBinding{
+		ObjectMeta: k8s.NewObjectMeta(meta),
+		TypeMeta:   k8s.NewTypeMeta(kind),
+		RoleRef:    roleRef,
+		Subjects:   subjects,
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
+	"github.com/dnsjia/luban/pkg/k8s/dataselect"
+	v1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ServiceAccount is a presentation layer view of Kubernetes ServiceAccount resource.
+type ServiceAccount struct {
+	ObjectMeta k8s.ObjectMeta `json:"objectMeta"`
+	TypeMeta   k8s.TypeMeta   `json:"typeMeta"`
+}
+
+// ServiceAccountList contains a list of ServiceAccounts in the cluster.
+type ServiceAccountList struct {
+	ListMeta k8s.ListMeta     `json:"listMeta"`
+	Items    []ServiceAccount `json:"items"`
+}
+
+// ServiceAccountDetail contains ServiceAccount details.
+type ServiceAccountDetail struct {
+	// Extends list item structure.
+	ServiceAccount `json:",inline"`
+
+	Secrets          []v1.ObjectReference      `json:"secrets"`
+	ImagePullSecrets []v1.LocalObjectReference `json:"imagePullSecrets"`
+}
+
+// Permission is a set of rules granted to a ServiceAccount by a single binding.
+type Permission struct {
+	BindingKind string `json:"bindingKind"`
+	BindingName string `json:"bindingName"`
+	// Namespace where the rules apply, empty means cluster wide.
+	Namespace string `json:"namespace"`
+	RoleKind  string `json:"roleKind"`
+	RoleName  string `json:"roleName"`
+	// Subject of the binding that matched the ServiceAccount, either the account itself or one of its groups.
+	Subject rbac.Subject      `json:"subject"`
+	Rules   []rbac.PolicyRule `json:"rules"`
+}
+
+// ServiceAccountPermissions contains the effective permissions of a ServiceAccount.
+type ServiceAccountPermissions struct {
+	ServiceAccount ServiceAccount `json:"serviceAccount"`
+	Permissions    []Permission   `json:"permissions"`
+}
+
+// GetServiceAccountList returns a list of all ServiceAccounts in the namespaces selected by nsQuery.
+func GetServiceAccountList(client kubernetes.Interface, nsQuery *k8scommon.NamespaceQuery, dsQuery *dataselect.DataSelectQuery) (*ServiceAccountList, error) {
+	common.LOG.Info(fmt.Sprintf("Getting list of service accounts in the namespace %s", nsQuery.ToRequestParam()))
+	accounts, err := client.CoreV1().ServiceAccounts(nsQuery.ToRequestParam()).List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+
+	cells := make([]dataselect.DataCell, 0)
+	for i := range accounts.Items {
+		if nsQuery.Matches(accounts.Items[i].Namespace) {
+			cells = append(cells, ObjectCell{ObjectMeta: accounts.Items[i].ObjectMeta, Object: &accounts.Items[i]})
+		}
+	}
+
+	objects, filteredTotal := selectObjects(cells, dsQuery)
+	result := &ServiceAccountList{ListMeta: k8s.ListMeta{TotalItems: filteredTotal}, Items: make([]ServiceAccount, 0)}
+	for _, object := range objects {
+		result.Items = append(result.Items, toServiceAccount(object.(*v1.ServiceAccount)))
+	}
+	return result, nil
+}
+
+// GetServiceAccountDetail returns detailed information about a ServiceAccount.
+func GetServiceAccountDetail(client kubernetes.Interface, namespace, name string) (*ServiceAccountDetail, error) {
+	account, err := client.CoreV1().ServiceAccounts(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &ServiceAccountDetail{
+		ServiceAccount:   toServiceAccount(account),
+		Secrets:          account.Secrets,
+		ImagePullSecrets: account.ImagePullSecrets,
+	}, nil
+}
+
+// GetServiceAccountPermissions returns every binding granting rules to the ServiceAccount, either directly
+// or through the groups every ServiceAccount belongs to.
+func GetServiceAccountPermissions(client kubernetes.Interface, namespace, name string) (*ServiceAccountPermissions, error) {
+	account, err := client.CoreV1().ServiceAccounts(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// RoleBindings in any namespace may reference the ServiceAccount.
+	snapshot, err := loadPolicySnapshot(client, v1.NamespaceAll)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]Permission, 0)
+	for _, b := range snapshot.bindings() {
+		rules, ok := snapshot.rulesFor(b)
+		if !ok {
+			continue
+		}
+		for _, subject := range b.subjects {
+			if !serviceAccountMatches(subject, b.namespace, namespace, name) {
+				continue
+			}
+			permissions = append(permissions, Permission{
+				BindingKind: b.kind,
+				BindingName: b.name,
+				Namespace:   b.namespace,
+				RoleKind:    b.roleRef.Kind,
+				RoleName:    b.roleRef.Name,
+				Subject:     subject,
+				Rules:       rules,
+			})
+			break
+		}
+	}
+
+	return &ServiceAccountPermissions{
+		ServiceAccount: toServiceAccount(account),
+		Permissions:    permissions,
+	}, nil
+}
+
+// serviceAccountMatches reports whether the binding subject refers to the ServiceAccount namespace/name.
+func serviceAccountMatches(subject rbac.Subject, bindingNamespace, namespace, name string) bool {
+	switch subject.Kind {
+	case rbac.ServiceAccountKind:
+		// A ServiceAccount subject without namespace in a RoleBinding defaults to the binding namespace.
+		subjectNamespace := subject.Namespace
+		if subjectNamespace == "" {
+			subjectNamespace = bindingNamespace
+		}
+		return subject.Name == name && subjectNamespace == namespace
+	case rbac.GroupKind:
+		return subject.Name == "system:serviceaccounts" ||
+			subject.Name == "system:serviceaccounts:"+namespace ||
+			subject.Name == "system:authenticated"
+	case rbac.UserKind:
+		return subject.Name == fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name)
+	}
+	return false
+}
+
+func toServiceAccount(account *v1.ServiceAccount) ServiceAccount {
+	return ServiceAccount{
+		ObjectMeta: k8s.NewObjectMeta(account.ObjectMeta),
+		TypeMeta:   k8s.NewTypeMeta(k8s.ResourceKindServiceAccount),
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbac
+
+import (
+	"context"
+	"errors"
+	"github.com/dnsjia/luban/models/k8s"
+	rbac "k8s.io/api/rbac/v1"
+	"k8s.io/client-go/kubernetes"
+	"sort"
+	"strings"
+)
+
+// WhoCanQuery describes the action to look up, e.g. verb=list resource=pods or resource=deployments/scale apiGroup=apps.
+type WhoCanQuery struct {
+	Verb     string
+	Resource string
+	APIGroup string
+	// Namespace is empty for cluster scoped questions, only ClusterRoleBindings are considered then.
+	Namespace    string
+	ResourceName string
+}
+
+// WhoCanSubject is a subject allowed to perform the queried action together with the binding granting it.
+type WhoCanSubject struct {
+	rbac.Subject `json:",inline"`
+
+	BindingKind      string `json:"bindingKind"`
+	BindingName      string `json:"bindingName"`
+	BindingNamespace string `json:"bindingNamespace,omitempty"`
+	RoleKind         string `json:"roleKind"`
+	RoleName         string `json:"roleName"`
+}
+
+// WhoCanResult contains all subjects allowed to perform the queried action.
+type WhoCanResult struct {
+	ListMeta k8s.ListMeta    `json:"listMeta"`
+	Subjects []WhoCanSubject `json:"subjects"`
+}
+
+// policySnapshot holds all RBAC objects needed to evaluate bindings.
+type policySnapshot struct {
+	roles               map[string][]rbac.PolicyRule // namespace/name
+	clusterRoles        map[string][]rbac.PolicyRule
+	roleBindings        []rbac.RoleBinding
+	clusterRoleBindings []rbac.ClusterRoleBinding
+}
+
+// binding is the common view of RoleBinding and ClusterRoleBinding.
+type binding struct {
+	kind      string
+	name      string
+	namespace string
+	roleRef   rbac.RoleRef
+	subjects  []rbac.Subject
+}
+
+// loadPolicySnapshot lists roles and bindings, namespace limits RoleBindings and Roles to that namespace, empty lists all.
+func loadPolicySnapshot(client kubernetes.Interface, namespace string) (*policySnapshot, error) {
+	snapshot := &policySnapshot{
+		roles:        make(map[string][]rbac.PolicyRule),
+		clusterRoles: make(map[string][]rbac.PolicyRule),
+	}
+
+	clusterRoles, err := client.RbacV1().ClusterRoles().List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range clusterRoles.Items {
+		snapshot.clusterRoles[item.Name] = item.Rules
+	}
+
+	clusterRoleBindings, err := client.RbacV1().ClusterRoleBindings().List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.clusterRoleBindings = clusterRoleBindings.Items
+
+	roles, err := client.RbacV1().Roles(namespace).List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range roles.Items {
+		snapshot.roles[item.Namespace+"/"+item.Name] = item.Rules
+	}
+
+	roleBindings, err := client.RbacV1().RoleBindings(namespace).List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.roleBindings = roleBindings.Items
+	return snapshot, nil
+}
+
+// bindings returns ClusterRoleBindings first and then RoleBindings.
+func (s *policySnapshot) bindings() []binding {
+	result := make([]binding, 0, len(s.clusterRoleBindings)+len(s.roleBindings))
+	for _, item := range s.clusterRoleBindings {
+		result = append(result, binding{kind: "ClusterRoleBinding", name: item.Name, roleRef: item.RoleRef, subjects: item.Subjects})
+	}
+	for _, item := range s.roleBindings {
+		result = append(result, binding{kind: "RoleBinding", name: item.Name, namespace: item.Namespace, roleRef: item.RoleRef, subjects: item.Subjects})
+	}
+	return result
+}
+
+// rulesFor resolves the role referenced by the binding, ok is false when the role does not exist.
+func (s *policySnapshot) rulesFor(b binding) ([]rbac.PolicyRule, bool) {
+	switch b.roleRef.Kind {
+	case "ClusterRole":
+		rules, ok := s.clusterRoles[b.roleRef.Name]
+		return rules, ok
+	case "Role":
+		rules, ok := s.roles[b.namespace+"/"+b.roleRef.Name]
+		return rules, ok
+	}
+	return nil, false
+}
+
+// WhoCan returns the subjects allowed to perform the queried action.
+func WhoCan(client kubernetes.Interface, query WhoCanQuery) (*WhoCanResult, error) {
+	if query.Verb == "" || query.Resource == "" {
+		return nil, errors.New("verb 和 resource 不能为空")
+	}
+	// Roles and RoleBindings outside the queried namespace never apply, cluster scoped questions
+	// only consider ClusterRoleBindings.
+	namespace := query.Namespace
+	snapshot, err := loadPolicySnapshot(client, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	resource, subresource := query.Resource, ""
+	if parts := strings.SplitN(query.Resource, "/", 2); len(parts) == 2 {
+		resource, subresource = parts[0], parts[1]
+	}
+
+	subjects := make([]WhoCanSubject, 0)
+	for _, b := range snapshot.bindings() {
+		if b.kind == "RoleBinding" && (namespace == "" || b.namespace != namespace) {
+			continue
+		}
+		rules, ok := snapshot.rulesFor(b)
+		if !ok || !rulesAllow(rules, query.Verb, query.APIGroup, resource, subresource, query.ResourceName) {
+			continue
+		}
+		for _, subject := range b.subjects {
+			subjects = append(subjects, WhoCanSubject{
+				Subject:          subject,
+				BindingKind:      b.kind,
+				BindingName:      b.name,
+				BindingNamespace: b.namespace,
+				RoleKind:         b.roleRef.Kind,
+				RoleName:         b.roleRef.Name,
+			})
+		}
+	}
+
+	sort.SliceStable(subjects, func(i, j int) bool {
+		if subjects[i].Kind != subjects[j].Kind {
+			return subjects[i].Kind < subjects[j].Kind
+		}
+		if subjects[i].Namespace != subjects[j].Namespace {
+			return subjects[i].Namespace < subjects[j].Namespace
+		}
+		return subjects[i].Name < subjects[j].Name
+	})
+	return &WhoCanResult{ListMeta: k8s.ListMeta{TotalItems: len(subjects)}, Subjects: subjects}, nil
+}
+
+// rulesAllow follows the matching rules of the kube-apiserver RBAC authorizer.
+func rulesAllow(rules []rbac.PolicyRule, verb, apiGroup, resource, subresource, resourceName string) bool {
+	for _, rule := range rules {
+		if ruleAllows(rule, verb, apiGroup, resource, subresource, resourceName) {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleAllows(rule rbac.PolicyRule, verb, apiGroup, resource, subresource, resourceName string) bool {
+	if !hasItem(rule.Verbs, verb) || !hasItem(rule.APIGroups, apiGroup) {
+		return false
+	}
+	if !resourceMatches(rule.Resources, resource, subresource) {
+		return false
+	}
+	if len(rule.ResourceNames) == 0 {
+		return true
+	}
+	// Rules restricted to resource names never grant access to the whole collection.
+	for _, name := range rule.ResourceNames {
+		if name == resourceName && resourceName != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasItem(items []string, item string) bool {
+	for _, v := range items {
+		if v == rbac.VerbAll || v == item {
+			return true
+		}
+	}
+	return false
+}
+
+func resourceMatches(ruleResources []string, resource, subresource string) bool {
+	combined := resource
+	if subresource != "" {
+		combined = resource + "/" + subresource
+	}
+	for _, ruleResource := range ruleResources {
+		if ruleResource == rbac.ResourceAll || ruleResource == combined {
+			return true
+		}
+		// "*/subresource" matches the subresource of every resource.
+		if subresource != "" && ruleResource == "*/"+subresource {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbac
+
+import (
+	v1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func newRBACClient() *fake.Clientset {
+	return fake.NewSimpleClientset(
+		&v1.ServiceAccount{ObjectMeta: metaV1.ObjectMeta{Name: "builder", Namespace: "dev"}},
+		&rbac.ClusterRole{
+			ObjectMeta: metaV1.ObjectMeta{Name: "pod-reader"},
+			Rules:      []rbac.PolicyRule{{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"pods", "pods/log"}}},
+		},
+		&rbac.Role{
+			ObjectMeta: metaV1.ObjectMeta{Name: "deployer", Namespace: "dev"},
+			Rules:      []rbac.PolicyRule{{Verbs: []string{"*"}, APIGroups: []string{"apps"}, Resources: []string{"deployments"}}},
+		},
+		&rbac.ClusterRoleBinding{
+			ObjectMeta: metaV1.ObjectMeta{Name: "ops-read-pods"},
+			RoleRef:    rbac.RoleRef{Kind: "ClusterRole", Name: "pod-reader"},
+			Subjects:   []rbac.Subject{{Kind: rbac.GroupKind, Name: "ops"}},
+		},
+		&rbac.RoleBinding{
+			ObjectMeta: metaV1.ObjectMeta{Name: "builder-deploy", Namespace: "dev"},
+			RoleRef:    rbac.RoleRef{Kind: "Role", Name: "deployer"},
+			Subjects:   []rbac.Subject{{Kind: rbac.ServiceAccountKind, Name: "builder"}},
+		},
+	)
+}
+
+func TestWhoCan(t *testing.T) {
+	client := newRBACClient()
+
+	cases := []struct {
+		query WhoCanQuery
+		want  []string
+	}{
+		{WhoCanQuery{Verb: "list", Resource: "pods", Namespace: "dev"}, []string{"ops"}},
+		{WhoCanQuery{Verb: "get", Resource: "pods/log", Namespace: "prod"}, []string{"ops"}},
+		{WhoCanQuery{Verb: "delete", Resource: "pods", Namespace: "dev"}, nil},
+		{WhoCanQuery{Verb: "patch", Resource: "deployments", APIGroup: "apps", Namespace: "dev"}, []string{"builder"}},
+		{WhoCanQuery{Verb: "patch", Resource: "deployments", APIGroup: "apps", Namespace: "prod"}, nil},
+		{WhoCanQuery{Verb: "patch", Resource: "deployments", APIGroup: "apps"}, nil},
+	}
+	for _, c := range cases {
+		result, err := WhoCan(client, c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Subjects) != len(c.want) {
+			t.Errorf("%+v: got %+v, want %v", c.query, result.Subjects, c.want)
+			continue
+		}
+		for i, subject := range result.Subjects {
+			if subject.Name != c.want[i] {
+				t.Errorf("%+v: got subject %s, want %s", c.query, subject.Name, c.want[i])
+			}
+		}
+	}
+}
+
+func TestGetServiceAccountPermissions(t *testing.T) {
+	result, err := GetServiceAccountPermissions(newRBACClient(), "dev", "builder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Permissions) != 1 {
+		t.Fatalf("got %d permissions, want 1", len(result.Permissions))
+	}
+	permission := result.Permissions[0]
+	if permission.BindingName != "builder-deploy" || permission.Namespace != "dev" || len(permission.Rules) != 1 {
+		t.Errorf("unexpected permission: %+v", permission)
+	}
+}
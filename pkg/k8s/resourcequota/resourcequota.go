@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcequota
+
+import (
+	"context"
+	"fmt"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models/k8s"
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// ResourceStatus provides the status of the resource defined by a resource quota.
+type ResourceStatus struct {
+	Used string `json:"used,omitempty"`
+	Hard string `json:"hard,omitempty"`
+	// UsedPercentage is used/hard in percent, -1 when it can not be computed.
+	UsedPercentage float64 `json:"usedPercentage"`
+}
+
+// ResourceQuotaDetail provides the presentation layer view of Kubernetes Resource Quotas resource.
+type ResourceQuotaDetail struct {
+	ObjectMeta k8s.ObjectMeta `json:"objectMeta"`
+	TypeMeta   k8s.TypeMeta   `json:"typeMeta"`
+
+	// Scopes defines quota scopes
+	Scopes []v1.ResourceQuotaScope `json:"scopes,omitempty"`
+
+	// StatusList is a set of (resource name, Used, Hard) tuple.
+	StatusList map[v1.ResourceName]ResourceStatus `json:"statusList,omitempty"`
+}
+
+// ResourceQuotaDetailList
+type ResourceQuotaDetailList struct {
+	ListMeta k8s.ListMeta          `json:"listMeta"`
+	Items    []ResourceQuotaDetail `json:"items"`
+}
+
+// GetResourceQuotaList returns all resource quotas of the namespace.
+func GetResourceQuotaList(client kubernetes.Interface, namespace string) (*ResourceQuotaDetailList, error) {
+	list, err := client.CoreV1().ResourceQuotas(namespace).List(context.TODO(), k8s.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ResourceQuotaDetailList{
+		Items:    make([]ResourceQuotaDetail, 0),
+		ListMeta: k8s.ListMeta{TotalItems: len(list.Items)},
+	}
+	for _, item := range list.Items {
+		result.Items = append(result.Items, *ToResourceQuotaDetail(&item))
+	}
+	return result, nil
+}
+
+// GetResourceQuotaDetail returns detailed information about a resource quota.
+func GetResourceQuotaDetail(client kubernetes.Interface, namespace, name string) (*ResourceQuotaDetail, error) {
+	rawResourceQuota, err := client.CoreV1().ResourceQuotas(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return ToResourceQuotaDetail(rawResourceQuota), nil
+}
+
+func ToResourceQuotaDetail(rawResourceQuota *v1.ResourceQuota) *ResourceQuotaDetail {
+	statusList := make(map[v1.ResourceName]ResourceStatus)
+
+	for key, hardQuantity := range rawResourceQuota.Status.Hard {
+		usedQuantity := rawResourceQuota.Status.Used[key]
+		percentage := float64(-1)
+		if hardQuantity.MilliValue() > 0 {
+			percentage = float64(usedQuantity.MilliValue()) / float64(hardQuantity.MilliValue()) * 100
+		}
+		statusList[key] = ResourceStatus{
+			Used:           usedQuantity.String(),
+			Hard:           hardQuantity.String(),
+			UsedPercentage: percentage,
+		}
+	}
+	return &ResourceQuotaDetail{
+		ObjectMeta: k8s.NewObjectMeta(rawResourceQuota.ObjectMeta),
+		TypeMeta:   k8s.NewTypeMeta(k8s.ResourceKindResourceQuota),
+		Scopes:     rawResourceQuota.Spec.Scopes,
+		StatusList: statusList,
+	}
+}
+
+// CreateResourceQuota 创建ResourceQuota
+func CreateResourceQuota(client kubernetes.Interface, data k8s.ResourceQuotaData) error {
+	common.LOG.Info(fmt.Sprintf("创建ResourceQuota: %v, namespace: %v", data.Name, data.Namespace))
+	quota := &v1.ResourceQuota{
+		ObjectMeta: metaV1.ObjectMeta{Name: data.Name, Namespace: data.Namespace, Labels: data.Labels},
+		Spec:       data.Spec,
+	}
+	_, err := client.CoreV1().ResourceQuotas(data.Namespace).Create(context.TODO(), quota, metaV1.CreateOptions{})
+	return err
+}
+
+// UpdateResourceQuota 修改ResourceQuota的 spec
+func UpdateResourceQuota(client kubernetes.Interface, data k8s.ResourceQuotaData) error {
+	common.LOG.Info(fmt.Sprintf("修改ResourceQuota: %v, namespace: %v", data.Name, data.Namespace))
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		quota, err := client.CoreV1().ResourceQuotas(data.Namespace).Get(context.TODO(), data.Name, metaV1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		quota.Spec = data.Spec
+		if data.Labels != nil {
+			quota.Labels = data.Labels
+		}
+		_, err = client.CoreV1().ResourceQuotas(data.Namespace).Update(context.TODO(), quota, metaV1.UpdateOptions{})
+		return err
+	})
+}
+
+// DeleteResourceQuota 删除ResourceQuota
+func DeleteResourceQuota(client kubernetes.Interface, namespace, name string) error {
+	common.LOG.Info(fmt.Sprintf("请求删除ResourceQuota: %v, namespace: %v", name, namespace))
+	return client.CoreV1().ResourceQuotas(namespace).Delete(context.TODO(), name, metaV1.DeleteOptions{})
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcequota
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"testing"
+)
+
+func TestToResourceQuotaDetail(t *testing.T) {
+	quota := &v1.ResourceQuota{
+		Status: v1.ResourceQuotaStatus{
+			Hard: v1.ResourceList{
+				v1.ResourceRequestsCPU: resource.MustParse("2"),
+				v1.ResourcePods:        resource.MustParse("0"),
+			},
+			Used: v1.ResourceList{
+				v1.ResourceRequestsCPU: resource.MustParse("500m"),
+			},
+		},
+	}
+
+	detail := ToResourceQuotaDetail(quota)
+
+	cpu := detail.StatusList[v1.ResourceRequestsCPU]
+	if cpu.Hard != "2" || cpu.Used != "500m" || cpu.UsedPercentage != 25 {
+		t.Errorf("unexpected cpu status: %+v", cpu)
+	}
+	pods := detail.StatusList[v1.ResourcePods]
+	if pods.Used != "0" || pods.UsedPercentage != -1 {
+		t.Errorf("unexpected pods status: %+v", pods)
+	}
+}
@@ -0,0 +1,417 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package search 在所有已注册集群中并发搜索 Pod、Service、Node
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/models/k8s"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// 搜索方式
+const (
+	ByName  = "name"
+	ByLabel = "label"
+	ByImage = "image"
+	ByIP    = "ip"
+)
+
+// 可搜索的资源类型
+const (
+	KindPod     = "pod"
+	KindService = "service"
+	KindNode    = "node"
+)
+
+const (
+	DefaultTimeout = 5 * time.Second
+	MaxTimeout     = 30 * time.Second
+	// maxMatchesPerCluster 单个集群返回的最大结果数, 避免关键字过短时返回整个集群
+	maxMatchesPerCluster = 200
+)
+
+var allKinds = []string{KindPod, KindService, KindNode}
+
+// ClientFunc returns the client of a registered cluster.
+type ClientFunc func(cluster models.K8SCluster) (kubernetes.Interface, error)
+
+// Match is a resource matching the search.
+type Match struct {
+	Kind      string            `json:"kind"`
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	IPs       []string          `json:"ips,omitempty"`
+	Images    []string          `json:"images,omitempty"`
+	// NodeName of the pod.
+	NodeName string `json:"nodeName,omitempty"`
+}
+
+// ClusterResult groups the matches of one cluster. Error is set when the cluster could not
+// be searched completely, the matches found before the failure are still returned.
+type ClusterResult struct {
+	ClusterID   uint    `json:"clusterId"`
+	ClusterName string  `json:"clusterName"`
+	Matches     []Match `json:"matches"`
+	Truncated   bool    `json:"truncated"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// Result of a search across clusters.
+type Result struct {
+	Clusters []ClusterResult `json:"clusters"`
+	Total    int             `json:"total"`
+	// Partial is true when at least one cluster failed or timed out.
+	Partial bool `json:"partial"`
+}
+
+// query is the validated form of k8s.SearchQuery.
+type query struct {
+	by       string
+	keyword  string
+	selector labels.Selector
+	kinds    []string
+	timeout  time.Duration
+}
+
+func parseQuery(q k8s.SearchQuery) (*query, error) {
+	parsed := &query{
+		by:      q.By,
+		keyword: strings.TrimSpace(q.Keyword),
+		timeout: DefaultTimeout,
+	}
+	if parsed.keyword == "" {
+		return nil, fmt.Errorf("搜索关键字不能为空")
+	}
+	switch parsed.by {
+	case "":
+		parsed.by = ByName
+	case ByName, ByImage, ByIP:
+	case ByLabel:
+		selector, err := labels.Parse(parsed.keyword)
+		if err != nil {
+			return nil, fmt.Errorf("标签选择器不合法: %v", err)
+		}
+		parsed.selector = selector
+	default:
+		return nil, fmt.Errorf("不支持的搜索方式: %s", q.By)
+	}
+
+	if q.Kinds == "" {
+		parsed.kinds = allKinds
+	} else {
+		for _, kind := range strings.Split(q.Kinds, ",") {
+			kind = strings.TrimSpace(kind)
+			switch kind {
+			case KindPod, KindService, KindNode:
+				parsed.kinds = append(parsed.kinds, kind)
+			default:
+				return nil, fmt.Errorf("不支持的资源类型: %s", kind)
+			}
+		}
+	}
+
+	if q.Timeout > 0 {
+		parsed.timeout = time.Duration(q.Timeout) * time.Second
+		if parsed.timeout > MaxTimeout {
+			parsed.timeout = MaxTimeout
+		}
+	}
+	return parsed, nil
+}
+
+// Search fans out to all clusters concurrently, each cluster bounded by the query timeout.
+// Unreachable clusters are reported in their ClusterResult and do not fail the search.
+func Search(clusters []models.K8SCluster, clientFor ClientFunc, q k8s.SearchQuery) (*Result, error) {
+	parsed, err := parseQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ClusterResult, len(clusters))
+	var wg sync.WaitGroup
+	for i := range clusters {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = searchCluster(clusters[i], clientFor, parsed)
+		}(i)
+	}
+	wg.Wait()
+
+	result := &Result{Clusters: results}
+	for _, r := range results {
+		result.Total += len(r.Matches)
+		if r.Error != "" {
+			result.Partial = true
+		}
+	}
+	return result, nil
+}
+
+func searchCluster(cluster models.K8SCluster, clientFor ClientFunc, q *query) ClusterResult {
+	result := ClusterResult{
+		ClusterID:   cluster.ID,
+		ClusterName: cluster.ClusterName,
+		Matches:     make([]Match, 0),
+	}
+	client, err := clientFor(cluster)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), q.timeout)
+	defer cancel()
+
+	var errs []string
+	for _, kind := range q.kinds {
+		var matches []Match
+		switch kind {
+		case KindPod:
+			matches, err = searchPods(ctx, client, q)
+		case KindService:
+			matches, err = searchServices(ctx, client, q)
+		case KindNode:
+			matches, err = searchNodes(ctx, client, q)
+		}
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				err = fmt.Errorf("timed out after %v", q.timeout)
+			}
+			errs = append(errs, fmt.Sprintf("%s: %v", kind, err))
+		}
+		result.Matches = append(result.Matches, matches...)
+	}
+	if len(errs) > 0 {
+		result.Error = strings.Join(errs, "; ")
+	}
+
+	sort.SliceStable(result.Matches, func(i, j int) bool {
+		a, b := result.Matches[i], result.Matches[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	if len(result.Matches) > maxMatchesPerCluster {
+		result.Matches = result.Matches[:maxMatchesPerCluster]
+		result.Truncated = true
+	}
+	return result
+}
+
+// listOptions 标签搜索交给 apiserver 过滤, 其他方式在本地过滤
+func (q *query) listOptions() metav1.ListOptions {
+	if q.selector != nil {
+		return metav1.ListOptions{LabelSelector: q.selector.String()}
+	}
+	return metav1.ListOptions{}
+}
+
+func (q *query) matchName(name string) bool {
+	return strings.Contains(strings.ToLower(name), strings.ToLower(q.keyword))
+}
+
+// matchIP IP 按前缀匹配, 便于按网段查找
+func (q *query) matchIP(ips []string) bool {
+	for _, ip := range ips {
+		if ip != "" && strings.HasPrefix(ip, q.keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *query) matchImage(images []string) bool {
+	for _, image := range images {
+		if strings.Contains(image, q.keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func searchPods(ctx context.Context, client kubernetes.Interface, q *query) ([]Match, error) {
+	list, err := client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, q.listOptions())
+	if err != nil {
+		return nil, err
+	}
+	var matches []Match
+	for _, pod := range list.Items {
+		ips := podIPs(pod)
+		images := podImages(pod)
+		var ok bool
+		switch q.by {
+		case ByName:
+			ok = q.matchName(pod.Name)
+		case ByLabel:
+			ok = true
+		case ByImage:
+			ok = q.matchImage(images)
+		case ByIP:
+			ok = q.matchIP(ips) || q.matchIP([]string{pod.Status.HostIP})
+		}
+		if ok {
+			matches = append(matches, Match{
+				Kind:      KindPod,
+				Namespace: pod.Namespace,
+				Name:      pod.Name,
+				Labels:    pod.Labels,
+				IPs:       ips,
+				Images:    images,
+				NodeName:  pod.Spec.NodeName,
+			})
+		}
+	}
+	return matches, nil
+}
+
+func podIPs(pod v1.Pod) []string {
+	var ips []string
+	for _, ip := range pod.Status.PodIPs {
+		ips = append(ips, ip.IP)
+	}
+	if len(ips) == 0 && pod.Status.PodIP != "" {
+		ips = append(ips, pod.Status.PodIP)
+	}
+	return ips
+}
+
+func podImages(pod v1.Pod) []string {
+	var images []string
+	for _, container := range pod.Spec.InitContainers {
+		images = append(images, container.Image)
+	}
+	for _, container := range pod.Spec.Containers {
+		images = append(images, container.Image)
+	}
+	return images
+}
+
+func searchServices(ctx context.Context, client kubernetes.Interface, q *query) ([]Match, error) {
+	// Service 没有镜像
+	if q.by == ByImage {
+		return nil, nil
+	}
+	list, err := client.CoreV1().Services(metav1.NamespaceAll).List(ctx, q.listOptions())
+	if err != nil {
+		return nil, err
+	}
+	var matches []Match
+	for _, svc := range list.Items {
+		ips := serviceIPs(svc)
+		var ok bool
+		switch q.by {
+		case ByName:
+			ok = q.matchName(svc.Name)
+		case ByLabel:
+			ok = true
+		case ByIP:
+			ok = q.matchIP(ips)
+		}
+		if ok {
+			matches = append(matches, Match{
+				Kind:      KindService,
+				Namespace: svc.Namespace,
+				Name:      svc.Name,
+				Labels:    svc.Labels,
+				IPs:       ips,
+			})
+		}
+	}
+	return matches, nil
+}
+
+func serviceIPs(svc v1.Service) []string {
+	var ips []string
+	clusterIPs := svc.Spec.ClusterIPs
+	if len(clusterIPs) == 0 {
+		clusterIPs = []string{svc.Spec.ClusterIP}
+	}
+	for _, ip := range clusterIPs {
+		// headless service
+		if ip != "" && ip != v1.ClusterIPNone {
+			ips = append(ips, ip)
+		}
+	}
+	ips = append(ips, svc.Spec.ExternalIPs...)
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			ips = append(ips, ingress.IP)
+		}
+	}
+	return ips
+}
+
+func searchNodes(ctx context.Context, client kubernetes.Interface, q *query) ([]Match, error) {
+	list, err := client.CoreV1().Nodes().List(ctx, q.listOptions())
+	if err != nil {
+		return nil, err
+	}
+	var matches []Match
+	for _, node := range list.Items {
+		var ips []string
+		for _, addr := range node.Status.Addresses {
+			if addr.Type == v1.NodeInternalIP || addr.Type == v1.NodeExternalIP {
+				ips = append(ips, addr.Address)
+			}
+		}
+		var images []string
+		var ok bool
+		switch q.by {
+		case ByName:
+			ok = q.matchName(node.Name)
+		case ByLabel:
+			ok = true
+		case ByImage:
+			// 返回已拉取该镜像的节点
+			for _, image := range node.Status.Images {
+				if q.matchImage(image.Names) {
+					images = append(images, image.Names...)
+				}
+			}
+			ok = len(images) > 0
+		case ByIP:
+			ok = q.matchIP(ips)
+		}
+		if ok {
+			matches = append(matches, Match{
+				Kind:   KindNode,
+				Name:   node.Name,
+				Labels: node.Labels,
+				IPs:    ips,
+				Images: images,
+			})
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package search
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/models/k8s"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testClusters() ([]models.K8SCluster, ClientFunc) {
+	prod := fake.NewSimpleClientset(
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-7d9c", Namespace: "default", Labels: map[string]string{"app": "foo"}},
+			Spec:       v1.PodSpec{NodeName: "node-1", Containers: []v1.Container{{Name: "app", Image: "registry.local/foo:1.2"}}},
+			Status:     v1.PodStatus{PodIP: "10.0.1.5", HostIP: "192.168.0.11"},
+		},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Labels: map[string]string{"app": "foo"}},
+			Spec:       v1.ServiceSpec{ClusterIP: "172.16.0.10"},
+		},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"},
+			Spec:       v1.ServiceSpec{ClusterIP: v1.ClusterIPNone},
+		},
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status: v1.NodeStatus{
+				Addresses: []v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "192.168.0.11"}},
+				Images:    []v1.ContainerImage{{Names: []string{"registry.local/foo:1.2"}}},
+			},
+		},
+	)
+	staging := fake.NewSimpleClientset(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-canary", Namespace: "staging"},
+	})
+
+	clusters := []models.K8SCluster{
+		{GModel: models.GModel{ID: 1}, ClusterName: "prod"},
+		{GModel: models.GModel{ID: 2}, ClusterName: "staging"},
+		{GModel: models.GModel{ID: 3}, ClusterName: "offline"},
+	}
+	clients := map[uint]kubernetes.Interface{1: prod, 2: staging}
+	clientFor := func(cluster models.K8SCluster) (kubernetes.Interface, error) {
+		client, ok := clients[cluster.ID]
+		if !ok {
+			return nil, errors.New("connection refused")
+		}
+		return client, nil
+	}
+	return clusters, clientFor
+}
+
+func matchNames(r ClusterResult) []string {
+	var names []string
+	for _, m := range r.Matches {
+		names = append(names, m.Kind+"/"+m.Name)
+	}
+	return names
+}
+
+func TestSearchByNameAcrossClusters(t *testing.T) {
+	clusters, clientFor := testClusters()
+	result, err := Search(clusters, clientFor, k8s.SearchQuery{Keyword: "FOO", Kinds: "service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Partial || result.Clusters[2].Error == "" {
+		t.Fatalf("expected the offline cluster to be reported, got %+v", result.Clusters[2])
+	}
+	if result.Total != 2 {
+		t.Fatalf("expected 2 matches, got %d", result.Total)
+	}
+	if got := matchNames(result.Clusters[0]); len(got) != 1 || got[0] != "service/foo" {
+		t.Errorf("unexpected prod matches %v", got)
+	}
+	if got := matchNames(result.Clusters[1]); len(got) != 1 || got[0] != "service/foo-canary" {
+		t.Errorf("unexpected staging matches %v", got)
+	}
+}
+
+func TestSearchByImageAndIP(t *testing.T) {
+	clusters, clientFor := testClusters()
+
+	result, err := Search(clusters[:1], clientFor, k8s.SearchQuery{By: ByImage, Keyword: "registry.local/foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := matchNames(result.Clusters[0]); len(got) != 2 || got[0] != "node/node-1" || got[1] != "pod/foo-7d9c" {
+		t.Errorf("unexpected image matches %v", got)
+	}
+
+	result, err = Search(clusters[:1], clientFor, k8s.SearchQuery{By: ByIP, Keyword: "192.168.0.11"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := matchNames(result.Clusters[0]); len(got) != 2 || got[0] != "node/node-1" || got[1] != "pod/foo-7d9c" {
+		t.Errorf("unexpected ip matches %v", got)
+	}
+}
+
+func TestSearchByLabel(t *testing.T) {
+	clusters, clientFor := testClusters()
+	result, err := Search(clusters[:1], clientFor, k8s.SearchQuery{By: ByLabel, Keyword: "app=foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := matchNames(result.Clusters[0]); len(got) != 2 || got[0] != "pod/foo-7d9c" || got[1] != "service/foo" {
+		t.Errorf("unexpected label matches %v", got)
+	}
+}
+
+func TestSearchInvalidQuery(t *testing.T) {
+	clusters, clientFor := testClusters()
+	for _, q := range []k8s.SearchQuery{
+		{Keyword: " "},
+		{By: ByLabel, Keyword: "app in (foo"},
+		{By: "owner", Keyword: "foo"},
+		{Keyword: "foo", Kinds: "pod,deployment"},
+	} {
+		if _, err := Search(clusters, clientFor, q); err == nil {
+			t.Errorf("%+v: expected error", q)
+		}
+	}
+}
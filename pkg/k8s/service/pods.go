@@ -21,6 +21,7 @@ import (
 	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
 	"github.com/dnsjia/luban/pkg/k8s/dataselect"
 	"github.com/dnsjia/luban/pkg/k8s/event"
+	"github.com/dnsjia/luban/pkg/k8s/metrics"
 	"github.com/dnsjia/luban/pkg/k8s/pods"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -63,6 +64,8 @@ func GetServicePods(client *kubernetes.Clientset, namespace, name string, dsQuer
 		return &podList, err
 	}
 
-	podList = pods.ToPodList(apiPodList.Items, events, dsQuery)
+	podMetrics, metricsStatus := metrics.GetPodMetrics(client, namespace, labelSelector.String())
+	podList = pods.ToPodList(apiPodList.Items, events, podMetrics, dsQuery)
+	podList.MetricsStatus = metricsStatus
 	return &podList, nil
 }
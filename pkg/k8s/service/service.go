@@ -24,6 +24,7 @@ import (
 	"github.com/dnsjia/luban/models/k8s"
 	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
 	"github.com/dnsjia/luban/pkg/k8s/dataselect"
+	"github.com/dnsjia/luban/pkg/k8s/informer"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -67,7 +68,7 @@ func GetServiceList(client *kubernetes.Clientset, nsQuery *k8scommon.NamespaceQu
 	common.LOG.Info("Getting list of all services in the cluster")
 
 	channels := &k8scommon.ResourceChannels{
-		ServiceList: k8scommon.GetServiceListChannel(client, nsQuery, 1),
+		ServiceList: informer.GetServiceListChannel(client, nsQuery, 1),
 	}
 
 	return GetServiceListFromChannels(channels, dsQuery)
@@ -24,6 +24,7 @@ import (
 	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
 	"github.com/dnsjia/luban/pkg/k8s/dataselect"
 	"github.com/dnsjia/luban/pkg/k8s/event"
+	"github.com/dnsjia/luban/pkg/k8s/informer"
 	"go.uber.org/zap"
 	apps "k8s.io/api/apps/v1"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
@@ -91,9 +92,9 @@ func GetStatefulSetList(client *kubernetes.Clientset, nsQuery *k8scommon.Namespa
 	common.LOG.Info("Getting list of all pet sets in the cluster")
 
 	channels := &k8scommon.ResourceChannels{
-		StatefulSetList: k8scommon.GetStatefulSetListChannel(client, nsQuery, 1),
-		PodList:         k8scommon.GetPodListChannel(client, nsQuery, 1),
-		EventList:       k8scommon.GetEventListChannel(client, nsQuery, 1),
+		StatefulSetList: informer.GetStatefulSetListChannel(client, nsQuery, 1),
+		PodList:         informer.GetPodListChannel(client, nsQuery, 1),
+		EventList:       informer.GetEventListChannel(client, nsQuery, 1),
 	}
 
 	return GetStatefulSetListFromChannels(channels, dsQuery)
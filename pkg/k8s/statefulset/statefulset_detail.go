@@ -23,6 +23,7 @@ import (
 	k8scommon "github.com/dnsjia/luban/pkg/k8s/common"
 	"github.com/dnsjia/luban/pkg/k8s/dataselect"
 	"github.com/dnsjia/luban/pkg/k8s/event"
+	"github.com/dnsjia/luban/pkg/k8s/hpa"
 	"github.com/dnsjia/luban/pkg/k8s/service"
 	apps "k8s.io/api/apps/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -39,6 +40,8 @@ type StatefulSetDetail struct {
 	PodList *PodList `json:"podList"`
 
 	SvcList *service.ServiceList `json:"svcList"`
+
+	HorizontalPodAutoscalerList *hpa.HorizontalPodAutoscalerList `json:"horizontalPodAutoscalerList"`
 }
 
 // GetStatefulSetDetail gets Stateful Set details.
@@ -61,6 +64,7 @@ func GetStatefulSetDetail(client *kubernetes.Clientset, dsQuery *dataselect.Data
 
 	serviceList, _ := service.GetToService(client, namespace, name)
 	ssDetail := getStatefulSetDetail(ss, podInfo, events, serviceList, client)
+	ssDetail.HorizontalPodAutoscalerList, _ = hpa.GetHorizontalPodAutoscalerListForResource(client, namespace, "StatefulSet", name)
 	return &ssDetail, nil
 }
 
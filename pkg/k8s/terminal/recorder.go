@@ -0,0 +1,150 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terminal
+
+import (
+	"bytes"
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models"
+	"github.com/dnsjia/luban/pkg/asciicast2"
+	"github.com/dnsjia/luban/pkg/utils"
+	"sync"
+	"time"
+)
+
+// 默认终端大小, 与前端 xterm 的初始大小保持一致
+const (
+	defaultTerminalWidth  = 188
+	defaultTerminalHeight = 42
+	// defaultMaxRecordSize 单个会话录像的默认大小上限, 单位MB
+	defaultMaxRecordSize = 16
+)
+
+// truncatedNotice 录像达到上限后追加的提示, 回放时可见
+var truncatedNotice = []byte("\r\n[录像已达到大小上限, 之后的输出未记录]\r\n")
+
+// MaxRecordSize 单个会话录像的大小上限, 按终端输出的字节数计算, 单位字节
+func MaxRecordSize() int64 {
+	if common.CONFIG.Kubernetes.MaxRecordSize > 0 {
+		return common.CONFIG.Kubernetes.MaxRecordSize << 20
+	}
+	return defaultMaxRecordSize << 20
+}
+
+type recordData struct {
+	Time float64
+	Data []byte
+}
+
+// Recorder 记录容器终端的输出, 会话结束后以 asciicast v2 格式保存
+type Recorder struct {
+	sync.Mutex
+	sessionId string
+	options   ExecOptions
+	width     int
+	height    int
+	createdAt time.Time
+	records   []*recordData
+	size      int64
+	truncated bool
+	saved     bool
+}
+
+// NewRecorder 创建终端操作记录器
+func NewRecorder(sessionId string, options ExecOptions) *Recorder {
+	return &Recorder{
+		sessionId: sessionId,
+		options:   options,
+		width:     defaultTerminalWidth,
+		height:    defaultTerminalHeight,
+		createdAt: time.Now(),
+		records:   make([]*recordData, 0),
+	}
+}
+
+// Record 记录一次输出, 超过 MaxRecordSize 后不再记录, 避免长时间运行 tail -f 等命令时内存无限增长
+func (r *Recorder) Record(p []byte) {
+	r.Lock()
+	defer r.Unlock()
+	if r.truncated {
+		return
+	}
+	if r.size+int64(len(p)) > MaxRecordSize() {
+		r.truncated = true
+		r.records = append(r.records, &recordData{
+			Time: time.Since(r.createdAt).Seconds(),
+			Data: truncatedNotice,
+		})
+		return
+	}
+	r.size += int64(len(p))
+	data := make([]byte, len(p))
+	copy(data, p)
+	r.records = append(r.records, &recordData{
+		Time: time.Since(r.createdAt).Seconds(),
+		Data: data,
+	})
+}
+
+// Resize 记录终端大小的变化, 回放时使用最后一次的大小
+func (r *Recorder) Resize(cols, rows uint16) {
+	r.Lock()
+	defer r.Unlock()
+	if cols > 0 && rows > 0 {
+		r.width = int(cols)
+		r.height = int(rows)
+	}
+}
+
+// Save 将操作记录写入数据库, 一个会话只会写入一次
+func (r *Recorder) Save() error {
+	r.Lock()
+	defer r.Unlock()
+	if r.saved || len(r.records) == 0 {
+		return nil
+	}
+	r.saved = true
+
+	b := new(bytes.Buffer)
+	meta := asciicast2.CastV2Header{
+		Width:     r.width,
+		Height:    r.height,
+		Timestamp: r.createdAt.Unix(),
+		Title:     r.sessionId,
+		Env: &map[string]string{
+			"SHELL": r.options.Shell, "TERM": "xterm",
+		},
+	}
+	cast, buffer := asciicast2.NewCastV2(meta, b)
+	for _, v := range r.records {
+		cast.Record(v.Time, v.Data, "o")
+	}
+
+	record := models.PodTerminalRecord{
+		SessionID:   r.sessionId,
+		ClusterID:   r.options.ClusterID,
+		Namespace:   r.options.Namespace,
+		PodName:     r.options.PodName,
+		Container:   r.options.ContainerName,
+		UserName:    r.options.UserName,
+		ConnectTime: models.LocalTime{Time: r.createdAt},
+		LogoutTime:  models.LocalTime{Time: time.Now()},
+		Records:     utils.ZlibCompress(buffer.Bytes()),
+		Truncated:   r.truncated,
+	}
+	return common.DB.Create(&record).Error
+}
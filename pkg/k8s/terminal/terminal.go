@@ -21,11 +21,14 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"github.com/gin-gonic/gin"
+	"github.com/dnsjia/luban/common"
+	"go.uber.org/zap"
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/igm/sockjs-go.v2/sockjs"
 	v1 "k8s.io/api/core/v1"
@@ -37,6 +40,9 @@ import (
 
 const END_OF_TRANSMISSION = "\u0004"
 
+// bindTimeout is how long a created session waits for the SockJS client to bind
+const bindTimeout = time.Minute
+
 // PtyHandler is what remotecommand expects from a pty
 type PtyHandler interface {
 	io.Reader
@@ -47,10 +53,12 @@ type PtyHandler interface {
 // TerminalSession implements PtyHandler (using a SockJS connection)
 type TerminalSession struct {
 	id            string
+	userName      string
 	bound         chan error
 	sockJSSession sockjs.Session
 	sizeChan      chan remotecommand.TerminalSize
 	doneChan      chan struct{}
+	recorder      *Recorder
 }
 
 // TerminalMessage is the messaging protocol between ShellController and TerminalSession.
@@ -96,6 +104,9 @@ func (t TerminalSession) Read(p []byte) (int, error) {
 	case "stdin":
 		return copy(p, msg.Data), nil
 	case "resize":
+		if t.recorder != nil {
+			t.recorder.Resize(msg.Cols, msg.Rows)
+		}
 		t.sizeChan <- remotecommand.TerminalSize{Width: msg.Cols, Height: msg.Rows}
 		return 0, nil
 	default:
@@ -114,6 +125,9 @@ func (t TerminalSession) Write(p []byte) (int, error) {
 		return 0, err
 	}
 
+	if t.recorder != nil {
+		t.recorder.Record(p)
+	}
 	if err = t.sockJSSession.Send(string(msg)); err != nil {
 		return 0, err
 	}
@@ -161,26 +175,106 @@ func (sm *SessionMap) Set(sessionId string, session TerminalSession) {
 // Can happen if the process exits or if there is an error starting up the process
 // For now the status code is unused and reason is shown to the user (unless "")
 func (sm *SessionMap) Close(sessionId string, status uint32, reason string) {
+	sm.Lock.Lock()
+	session, ok := sm.Sessions[sessionId]
+	delete(sm.Sessions, sessionId)
+	sm.Lock.Unlock()
+	if !ok {
+		return
+	}
+
+	if session.sockJSSession != nil {
+		sockJSOwners.remove(session.sockJSSession.ID())
+		if err := session.sockJSSession.Close(status, reason); err != nil {
+			log.Println(err)
+		}
+	}
+	if session.doneChan != nil {
+		close(session.doneChan)
+	}
+	// Save the recorded output once the session is gone, outside of the lock
+	if session.recorder != nil {
+		if err := session.recorder.Save(); err != nil {
+			common.LOG.Error("保存容器终端操作记录失败", zap.Any("err", err))
+		}
+	}
+}
+
+// bind attaches the SockJS connection to a terminal session created by the same user.
+// A session can only be bound once, later binds are rejected.
+func (sm *SessionMap) bind(sessionId string, session sockjs.Session, userName string) error {
 	sm.Lock.Lock()
 	defer sm.Lock.Unlock()
-	err := sm.Sessions[sessionId].sockJSSession.Close(status, reason)
-	if err != nil {
-		log.Println(err)
+	terminalSession, ok := sm.Sessions[sessionId]
+	if !ok {
+		return fmt.Errorf("can't find session '%s'", sessionId)
+	}
+	if terminalSession.userName != userName {
+		return fmt.Errorf("session '%s' was not created by user '%s'", sessionId, userName)
+	}
+	if terminalSession.sockJSSession != nil {
+		return fmt.Errorf("session '%s' is already bound", sessionId)
 	}
 
-	delete(sm.Sessions, sessionId)
+	terminalSession.sockJSSession = session
+	sm.Sessions[sessionId] = terminalSession
+	// bound is buffered and only sent once, never block the SockJS handler
+	select {
+	case terminalSession.bound <- nil:
+	default:
+	}
+	return nil
 }
 
 var terminalSessions = SessionMap{Sessions: make(map[string]TerminalSession)}
 
+// ownerMap records the user who opened each SockJS session, keyed by the SockJS session id
+type ownerMap struct {
+	owners map[string]string
+	lock   sync.Mutex
+}
+
+// claim checks that a SockJS session belongs to userName. Unknown sessions are recorded as owned by
+// userName when create is true, send-only transports never create a session.
+func (om *ownerMap) claim(sockJSSessionId string, userName string, create bool) bool {
+	om.lock.Lock()
+	defer om.lock.Unlock()
+	if owner, ok := om.owners[sockJSSessionId]; ok {
+		return owner == userName
+	}
+	if create {
+		om.owners[sockJSSessionId] = userName
+	}
+	return true
+}
+
+func (om *ownerMap) get(sockJSSessionId string) string {
+	om.lock.Lock()
+	defer om.lock.Unlock()
+	return om.owners[sockJSSessionId]
+}
+
+func (om *ownerMap) remove(sockJSSessionId string) {
+	om.lock.Lock()
+	defer om.lock.Unlock()
+	delete(om.owners, sockJSSessionId)
+}
+
+var sockJSOwners = ownerMap{owners: make(map[string]string)}
+
 // handleTerminalSession is Called by net/http for any new /api/sockjs connections
 func handleTerminalSession(session sockjs.Session) {
 	var (
-		buf             string
-		err             error
-		msg             TerminalMessage
-		terminalSession TerminalSession
+		buf string
+		err error
+		msg TerminalMessage
 	)
+	bound := false
+	defer func() {
+		if !bound {
+			sockJSOwners.remove(session.ID())
+		}
+	}()
 
 	if buf, err = session.Recv(); err != nil {
 		log.Printf("handleTerminalSession: can't Recv: %v", err)
@@ -197,36 +291,61 @@ func handleTerminalSession(session sockjs.Session) {
 		return
 	}
 
-	if terminalSession = terminalSessions.Get(msg.SessionID); terminalSession.id == "" {
-		log.Printf("handleTerminalSession: can't find session '%s'", msg.SessionID)
+	if err = terminalSessions.bind(msg.SessionID, session, sockJSOwners.get(session.ID())); err != nil {
+		log.Printf("handleTerminalSession: %v", err)
+		_ = session.Close(2, "Session bind rejected")
 		return
 	}
+	bound = true
+}
 
-	terminalSession.sockJSSession = session
-	terminalSessions.Set(msg.SessionID, terminalSession)
-	terminalSession.bound <- nil
+// AttachHandler serves the SockJS connections of terminal sessions
+type AttachHandler struct {
+	prefix  string
+	handler http.Handler
 }
 
 // CreateAttachHandler is called from main for /api/sockjs
-func CreateAttachHandler(path string) http.Handler {
-	return sockjs.NewHandler(path, sockjs.DefaultOptions, handleTerminalSession)
+func CreateAttachHandler(path string) *AttachHandler {
+	return &AttachHandler{
+		prefix:  path,
+		handler: sockjs.NewHandler(path, sockjs.DefaultOptions, handleTerminalSession),
+	}
+}
+
+// ServeHTTP serves a SockJS request of the authenticated user. Every transport request carries the
+// SockJS session id in its path ({prefix}/{server}/{session}/{transport}), requests for a session opened
+// by another user are rejected so that nobody else can bind or send input to it.
+func (h *AttachHandler) ServeHTTP(w http.ResponseWriter, r *http.Request, userName string) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, h.prefix), "/"), "/")
+	if len(parts) == 3 && !sockJSOwners.claim(parts[1], userName, !strings.HasSuffix(parts[2], "_send")) {
+		http.Error(w, "session belongs to another user", http.StatusForbidden)
+		return
+	}
+	h.handler.ServeHTTP(w, r)
+}
+
+// ExecOptions describes the container a terminal session is attached to
+type ExecOptions struct {
+	ClusterID     uint
+	Namespace     string
+	PodName       string
+	ContainerName string
+	Shell         string
+	UserName      string
 }
 
 // startProcess is called by handleAttach
 // Executed cmd in the container specified in request and connects it up with the ptyHandler (a session)
-func startProcess(k8sClient kubernetes.Interface, cfg *rest.Config, request *gin.Context, cmd []string, ptyHandler PtyHandler) error {
-	namespace := request.Query("namespace")
-	podName := request.Query("pod")
-	containerName := request.Query("container")
-
+func startProcess(k8sClient kubernetes.Interface, cfg *rest.Config, options ExecOptions, cmd []string, ptyHandler PtyHandler) error {
 	req := k8sClient.CoreV1().RESTClient().Post().
 		Resource("pods").
-		Name(podName).
-		Namespace(namespace).
+		Name(options.PodName).
+		Namespace(options.Namespace).
 		SubResource("exec")
 
 	req.VersionedParams(&v1.PodExecOptions{
-		Container: containerName,
+		Container: options.ContainerName,
 		Command:   cmd,
 		Stdin:     true,
 		Stdout:    true,
@@ -277,27 +396,44 @@ func isValidShell(validShells []string, shell string) bool {
 	return false
 }
 
+// NewTerminalSession registers a new session waiting to be bound by the SockJS client
+// and starts the process in the background. The returned id must be sent with the 'bind' message.
+func NewTerminalSession(k8sClient kubernetes.Interface, cfg *rest.Config, options ExecOptions) (string, error) {
+	sessionId, err := genTerminalSessionId()
+	if err != nil {
+		return "", err
+	}
+
+	terminalSessions.Set(sessionId, TerminalSession{
+		id:       sessionId,
+		userName: options.UserName,
+		bound:    make(chan error, 1),
+		sizeChan: make(chan remotecommand.TerminalSize),
+		doneChan: make(chan struct{}),
+		recorder: NewRecorder(sessionId, options),
+	})
+
+	go WaitForTerminal(k8sClient, cfg, options, sessionId)
+	return sessionId, nil
+}
+
 // WaitForTerminal is called from apihandler.handleAttach as a goroutine
 // Waits for the SockJS connection to be opened by the client the session to be bound in handleTerminalSession
-func WaitForTerminal(k8sClient kubernetes.Interface, cfg *rest.Config, request *gin.Context, sessionId string) {
-	shell := request.Query("shell")
-
+func WaitForTerminal(k8sClient kubernetes.Interface, cfg *rest.Config, options ExecOptions, sessionId string) {
 	select {
 	case <-terminalSessions.Get(sessionId).bound:
-		close(terminalSessions.Get(sessionId).bound)
-
 		var err error
 		validShells := []string{"bash", "sh", "powershell", "cmd"}
 
-		if isValidShell(validShells, shell) {
-			cmd := []string{shell}
-			err = startProcess(k8sClient, cfg, request, cmd, terminalSessions.Get(sessionId))
+		if isValidShell(validShells, options.Shell) {
+			cmd := []string{options.Shell}
+			err = startProcess(k8sClient, cfg, options, cmd, terminalSessions.Get(sessionId))
 		} else {
 			// No shell given or it was not valid: try some shells until one succeeds or all fail
 			// FIXME: if the first shell fails then the first keyboard event is lost
 			for _, testShell := range validShells {
 				cmd := []string{testShell}
-				if err = startProcess(k8sClient, cfg, request, cmd, terminalSessions.Get(sessionId)); err == nil {
+				if err = startProcess(k8sClient, cfg, options, cmd, terminalSessions.Get(sessionId)); err == nil {
 					break
 				}
 			}
@@ -309,5 +445,9 @@ func WaitForTerminal(k8sClient kubernetes.Interface, cfg *rest.Config, request *
 		}
 
 		terminalSessions.Close(sessionId, 1, "Process exited")
+	case <-time.After(bindTimeout):
+		// The client never opened the SockJS connection, drop the session to avoid leaking it
+		common.LOG.Warn(fmt.Sprintf("terminal session %s was not bound in %v", sessionId, bindTimeout))
+		terminalSessions.Close(sessionId, 2, "Session bind timeout")
 	}
 }
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terminal
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dnsjia/luban/common"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+type fakeSockJSSession struct {
+	id string
+}
+
+func (s *fakeSockJSSession) ID() string                               { return s.id }
+func (s *fakeSockJSSession) Recv() (string, error)                    { return "", nil }
+func (s *fakeSockJSSession) Send(string) error                        { return nil }
+func (s *fakeSockJSSession) Close(status uint32, reason string) error { return nil }
+
+func TestSessionMapBind(t *testing.T) {
+	sm := SessionMap{Sessions: make(map[string]TerminalSession)}
+	sm.Set("s1", TerminalSession{
+		id:       "s1",
+		userName: "alice",
+		bound:    make(chan error, 1),
+		sizeChan: make(chan remotecommand.TerminalSize),
+		doneChan: make(chan struct{}),
+	})
+
+	if err := sm.bind("missing", &fakeSockJSSession{id: "a"}, "alice"); err == nil {
+		t.Errorf("bind of unknown session succeeded")
+	}
+	if err := sm.bind("s1", &fakeSockJSSession{id: "b"}, "bob"); err == nil {
+		t.Errorf("bind by another user succeeded")
+	}
+	if err := sm.bind("s1", &fakeSockJSSession{id: "a"}, "alice"); err != nil {
+		t.Fatalf("bind returned error: %v", err)
+	}
+	select {
+	case <-sm.Get("s1").bound:
+	default:
+		t.Errorf("bind did not signal the waiting terminal")
+	}
+	// A second bind must neither panic nor block, and keeps the first connection
+	if err := sm.bind("s1", &fakeSockJSSession{id: "c"}, "alice"); err == nil {
+		t.Errorf("second bind succeeded")
+	}
+	if id := sm.Get("s1").sockJSSession.ID(); id != "a" {
+		t.Errorf("bound SockJS session == %s, expected a", id)
+	}
+}
+
+func TestAttachHandlerOwner(t *testing.T) {
+	h := &AttachHandler{
+		prefix:  "/api/v1/k8s/sockjs",
+		handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	}
+	defer sockJSOwners.remove("abc")
+	cases := []struct {
+		path     string
+		userName string
+		expected int
+	}{
+		{path: "/api/v1/k8s/sockjs/info", userName: "bob", expected: http.StatusOK},
+		// send-only transports do not claim unknown sessions
+		{path: "/api/v1/k8s/sockjs/000/abc/xhr_send", userName: "bob", expected: http.StatusOK},
+		{path: "/api/v1/k8s/sockjs/000/abc/xhr", userName: "alice", expected: http.StatusOK},
+		{path: "/api/v1/k8s/sockjs/000/abc/xhr_send", userName: "alice", expected: http.StatusOK},
+		{path: "/api/v1/k8s/sockjs/000/abc/xhr_send", userName: "bob", expected: http.StatusForbidden},
+		{path: "/api/v1/k8s/sockjs/000/abc/websocket", userName: "bob", expected: http.StatusForbidden},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, c.path, nil), c.userName)
+		if w.Code != c.expected {
+			t.Errorf("%s by %s: status %d, expected %d", c.path, c.userName, w.Code, c.expected)
+		}
+	}
+	if owner := sockJSOwners.get("abc"); owner != "alice" {
+		t.Errorf("owner == %q, expected alice", owner)
+	}
+}
+
+func TestRecorderTruncate(t *testing.T) {
+	defer func(size int64) { common.CONFIG.Kubernetes.MaxRecordSize = size }(common.CONFIG.Kubernetes.MaxRecordSize)
+	common.CONFIG.Kubernetes.MaxRecordSize = 1
+
+	r := NewRecorder("s1", ExecOptions{})
+	chunk := bytes.Repeat([]byte("a"), 400<<10)
+	for i := 0; i < 5; i++ {
+		r.Record(chunk)
+	}
+	if !r.truncated {
+		t.Fatalf("expected recording to be truncated")
+	}
+	if r.size != 2*int64(len(chunk)) {
+		t.Errorf("recorded %d bytes, expected %d", r.size, 2*len(chunk))
+	}
+	if len(r.records) != 3 || !bytes.Equal(r.records[2].Data, truncatedNotice) {
+		t.Errorf("expected two chunks followed by the truncated notice, got %d records", len(r.records))
+	}
+}
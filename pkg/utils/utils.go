@@ -19,6 +19,7 @@ package utils
 import (
 	"bytes"
 	"compress/zlib"
+	"io"
 	"os"
 	"strconv"
 	"unsafe"
@@ -42,6 +43,21 @@ func ZlibCompress(src []byte) []byte {
 	return in.Bytes()
 }
 
+// ZlibUnCompress 进行zlib解压缩
+func ZlibUnCompress(compressSrc []byte) ([]byte, error) {
+	b := bytes.NewReader(compressSrc)
+	var out bytes.Buffer
+	r, err := zlib.NewReader(b)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if _, err := io.Copy(&out, r); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
 func Bytes2Str(b []byte) string {
 	return *(*string)(unsafe.Pointer(&b))
 }
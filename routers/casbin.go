@@ -25,6 +25,9 @@ func InitCasBinRouter(Router *gin.RouterGroup) {
 
 	CasBinRouter := Router.Group("casbin")
 	{
+		CasBinRouter.GET("", controller.GetCasBin)
 		CasBinRouter.POST("", controller.AddCasBin)
+		CasBinRouter.PUT("", controller.UpdateCasBin)
+		CasBinRouter.POST("delete", controller.DeleteCasBin)
 	}
 }
@@ -26,5 +26,9 @@ func InitCloudRouter(r *gin.RouterGroup) {
 	{
 		InitCloudRouter.GET("listPlatform", controller.ListPlatform)
 		InitCloudRouter.POST("account", controller.CloudPlatformAccount)
+		InitCloudRouter.PUT("schedule", controller.UpdateCloudSchedule)
+		InitCloudRouter.POST("sync", controller.SyncCloudAccount)
+		InitCloudRouter.GET("sync/jobs", controller.ListCloudSyncJob)
+		InitCloudRouter.GET("sync/job", controller.GetCloudSyncJob)
 	}
 }
@@ -29,7 +29,28 @@ func InitContainerRouter(r *gin.RouterGroup) {
 		K8sClusterRouter.GET("cluster/secret", k8s.ClusterSecret)
 		K8sClusterRouter.POST("cluster/delete", k8s.DelK8SCluster)
 		K8sClusterRouter.GET("cluster/detail", k8s.GetK8SClusterDetail)
+		K8sClusterRouter.PUT("cluster/metrics", k8s.UpdateK8SClusterMetricsSource)
+		K8sClusterRouter.GET("cluster/cache/status", k8s.GetClusterCacheStatus)
+		K8sClusterRouter.GET("search", k8s.SearchController)
 		K8sClusterRouter.GET("events", k8s.Events)
+		K8sClusterRouter.GET("events/archive", k8s.ListEventRecordController)
+		K8sClusterRouter.GET("events/archive/warnings", k8s.ListWorkloadWarningRecordController)
+		K8sClusterRouter.GET("alert/channel", k8s.ListAlertChannelController)
+		K8sClusterRouter.POST("alert/channel", k8s.CreateAlertChannelController)
+		K8sClusterRouter.PUT("alert/channel", k8s.UpdateAlertChannelController)
+		K8sClusterRouter.POST("alert/channel/delete", k8s.DeleteAlertChannelController)
+		K8sClusterRouter.POST("alert/channel/test", k8s.TestAlertChannelController)
+		K8sClusterRouter.GET("alert/rule", k8s.ListAlertRuleController)
+		K8sClusterRouter.POST("alert/rule", k8s.CreateAlertRuleController)
+		K8sClusterRouter.PUT("alert/rule", k8s.UpdateAlertRuleController)
+		K8sClusterRouter.POST("alert/rule/delete", k8s.DeleteAlertRuleController)
+		K8sClusterRouter.GET("alert/silence", k8s.ListAlertSilenceController)
+		K8sClusterRouter.POST("alert/silence", k8s.CreateAlertSilenceController)
+		K8sClusterRouter.POST("alert/silence/delete", k8s.DeleteAlertSilenceController)
+		K8sClusterRouter.GET("alert/record", k8s.ListAlertRecordController)
+		K8sClusterRouter.POST("apply", k8s.ApplyManifestController)
+		K8sClusterRouter.GET("resource/yaml", k8s.GetResourceYAMLController)
+		K8sClusterRouter.PUT("resource/yaml", k8s.UpdateResourceYAMLController)
 
 		K8sClusterRouter.GET("node", k8s.GetNodes)
 		K8sClusterRouter.DELETE("node", k8s.RemoveNode)
@@ -38,6 +59,16 @@ func InitContainerRouter(r *gin.RouterGroup) {
 		K8sClusterRouter.POST("node/collectionSchedule", k8s.CollectionNodeUnschedule)
 		K8sClusterRouter.GET("node/cordon", k8s.CordonNode)
 		K8sClusterRouter.POST("node/collectionCordon", k8s.CollectionCordonNode)
+		K8sClusterRouter.POST("node/drain", k8s.DrainNode)
+		K8sClusterRouter.GET("node/drain/job", k8s.GetDrainJob)
+		K8sClusterRouter.GET("node/drain/jobs", k8s.ListDrainJobs)
+		K8sClusterRouter.PUT("node/labels", k8s.UpdateNodeLabels)
+		K8sClusterRouter.PUT("node/collectionLabels", k8s.CollectionUpdateNodeLabels)
+		K8sClusterRouter.PUT("node/annotations", k8s.UpdateNodeAnnotations)
+		K8sClusterRouter.PUT("node/collectionAnnotations", k8s.CollectionUpdateNodeAnnotations)
+		K8sClusterRouter.PUT("node/taints", k8s.UpdateNodeTaints)
+		K8sClusterRouter.PUT("node/collectionTaints", k8s.CollectionUpdateNodeTaints)
+		K8sClusterRouter.POST("node/taints/preview", k8s.PreviewNodeTaints)
 
 		K8sClusterRouter.GET("deployment", k8s.GetDeploymentList)
 		K8sClusterRouter.POST("deployments", k8s.DeleteCollectionDeployment)
@@ -48,13 +79,44 @@ func InitContainerRouter(r *gin.RouterGroup) {
 		K8sClusterRouter.POST("deployment/restart", k8s.RestartDeploymentController)
 		K8sClusterRouter.POST("deployment/service", k8s.GetDeploymentToServiceController)
 		K8sClusterRouter.POST("deployment/rollback", k8s.RollBackDeploymentController)
+		K8sClusterRouter.GET("deployment/revisions", k8s.GetDeploymentRevisionsController)
+		K8sClusterRouter.GET("deployment/revision/diff", k8s.DiffDeploymentRevisionController)
+		K8sClusterRouter.POST("deployment/pause", k8s.PauseDeploymentController)
+		K8sClusterRouter.POST("deployment/resume", k8s.ResumeDeploymentController)
+		K8sClusterRouter.GET("deployment/rollout/status", k8s.GetDeploymentRolloutStatusController)
 
 		K8sClusterRouter.GET("namespace", k8s.GetNamespaceList)
+		K8sClusterRouter.GET("namespace/detail", k8s.DetailNamespaceController)
+		K8sClusterRouter.POST("namespace", k8s.CreateNamespaceController)
+		K8sClusterRouter.POST("namespace/delete", k8s.DeleteNamespaceController)
+		K8sClusterRouter.PUT("namespace/labels", k8s.UpdateNamespaceLabelsController)
+
+		K8sClusterRouter.GET("resourcequota", k8s.GetResourceQuotaListController)
+		K8sClusterRouter.GET("resourcequota/detail", k8s.DetailResourceQuotaController)
+		K8sClusterRouter.POST("resourcequota", k8s.CreateResourceQuotaController)
+		K8sClusterRouter.PUT("resourcequota", k8s.UpdateResourceQuotaController)
+		K8sClusterRouter.POST("resourcequota/delete", k8s.DeleteResourceQuotaController)
+
+		K8sClusterRouter.GET("limitrange", k8s.GetLimitRangeListController)
+		K8sClusterRouter.GET("limitrange/detail", k8s.DetailLimitRangeController)
+		K8sClusterRouter.POST("limitrange", k8s.CreateLimitRangeController)
+		K8sClusterRouter.PUT("limitrange", k8s.UpdateLimitRangeController)
+		K8sClusterRouter.POST("limitrange/delete", k8s.DeleteLimitRangeController)
 
 		K8sClusterRouter.GET("pod", k8s.GetPodsListController)
 		K8sClusterRouter.DELETE("pod", k8s.DeletePodController)
 		K8sClusterRouter.POST("pods", k8s.DeleteCollectionPodsController)
 		K8sClusterRouter.GET("pod/detail", k8s.DetailPodController)
+		K8sClusterRouter.POST("pod/terminal", k8s.PodTerminalController)
+		K8sClusterRouter.POST("pod/debug", k8s.DebugPodController)
+		K8sClusterRouter.GET("pod/terminal/record", k8s.ListPodTerminalRecordController)
+		K8sClusterRouter.GET("pod/terminal/record/replay", k8s.PodTerminalRecordReplayController)
+		K8sClusterRouter.Any("sockjs/*path", k8s.TerminalSockJSController)
+		K8sClusterRouter.GET("pod/portforward", k8s.PortForwardController)
+		K8sClusterRouter.GET("pod/portforward/record", k8s.ListPortForwardRecordController)
+		K8sClusterRouter.POST("pod/file/upload", k8s.UploadContainerFileController)
+		K8sClusterRouter.GET("pod/file/download", k8s.DownloadContainerFileController)
+		K8sClusterRouter.GET("pod/file/record", k8s.ListFileTransferRecordController)
 
 		K8sClusterRouter.GET("statefulset", k8s.GetStatefulSetListController)
 		K8sClusterRouter.DELETE("statefulset", k8s.DeleteStatefulSetController)
@@ -102,6 +164,35 @@ func InitContainerRouter(r *gin.RouterGroup) {
 		K8sClusterRouter.DELETE("network/ingress", k8s.DeleteIngressController)
 		K8sClusterRouter.POST("network/ingresss", k8s.DeleteCollectionIngressController)
 
+		K8sClusterRouter.GET("network/networkpolicy", k8s.GetNetworkPolicyListController)
+		K8sClusterRouter.GET("network/networkpolicy/detail", k8s.DetailNetworkPolicyController)
+		K8sClusterRouter.POST("network/networkpolicy", k8s.CreateNetworkPolicyController)
+		K8sClusterRouter.POST("network/networkpolicy/delete", k8s.DeleteNetworkPolicyController)
+		K8sClusterRouter.POST("network/networkpolicy/analyze", k8s.AnalyzeNetworkPolicyController)
+
+		K8sClusterRouter.GET("rbac/role", k8s.GetRoleListController)
+		K8sClusterRouter.GET("rbac/role/detail", k8s.DetailRoleController)
+		K8sClusterRouter.GET("rbac/clusterrole", k8s.GetClusterRoleListController)
+		K8sClusterRouter.GET("rbac/clusterrole/detail", k8s.DetailClusterRoleController)
+		K8sClusterRouter.GET("rbac/rolebinding", k8s.GetRoleBindingListController)
+		K8sClusterRouter.GET("rbac/rolebinding/detail", k8s.DetailRoleBindingController)
+		K8sClusterRouter.GET("rbac/clusterrolebinding", k8s.GetClusterRoleBindingListController)
+		K8sClusterRouter.GET("rbac/clusterrolebinding/detail", k8s.DetailClusterRoleBindingController)
+		K8sClusterRouter.GET("rbac/serviceaccount", k8s.GetServiceAccountListController)
+		K8sClusterRouter.GET("rbac/serviceaccount/detail", k8s.DetailServiceAccountController)
+		K8sClusterRouter.GET("rbac/serviceaccount/permissions", k8s.GetServiceAccountPermissionsController)
+		K8sClusterRouter.GET("rbac/whocan", k8s.WhoCanController)
+
+		K8sClusterRouter.GET("crd", k8s.GetCustomResourceDefinitionListController)
+		K8sClusterRouter.GET("crd/detail", k8s.DetailCustomResourceDefinitionController)
+		K8sClusterRouter.GET("crd/object", k8s.GetCustomObjectListController)
+		K8sClusterRouter.GET("crd/object/detail", k8s.DetailCustomObjectController)
+
+		K8sClusterRouter.GET("hpa", k8s.GetHorizontalPodAutoscalerListController)
+		K8sClusterRouter.GET("hpa/detail", k8s.DetailHorizontalPodAutoscalerController)
+		K8sClusterRouter.POST("hpa", k8s.CreateHorizontalPodAutoscalerController)
+		K8sClusterRouter.PUT("hpa", k8s.UpdateHorizontalPodAutoscalerController)
+		K8sClusterRouter.POST("hpa/delete", k8s.DeleteHorizontalPodAutoscalerController)
 		K8sClusterRouter.GET("config/configmap", k8s.GetConfigMapController)
 		K8sClusterRouter.GET("config/configmap/detail", k8s.DetailConfigMapController)
 		K8sClusterRouter.DELETE("config/configmap", k8s.DeleteConfigMapController)
@@ -116,5 +207,6 @@ func InitContainerRouter(r *gin.RouterGroup) {
 		K8sClusterRouter.GET("/log/:namespace/:pod", k8s.GetLogDetailController)
 		K8sClusterRouter.GET("/log/:namespace/:pod/:container", k8s.GetLogDetailController)
 		K8sClusterRouter.GET("/log/file/:namespace/:pod/:container", k8s.GetLogFileController)
+		K8sClusterRouter.GET("/log/follow/:namespace/:resourceName/:resourceType", k8s.FollowLogController)
 	}
 }
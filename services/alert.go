@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models"
+	"gorm.io/gorm"
+)
+
+func ListAlertChannel(channels *[]models.AlertChannel) error {
+	return common.DB.Order("id").Find(channels).Error
+}
+
+func GetAlertChannels(ids []uint) (channels []models.AlertChannel, err error) {
+	if len(ids) == 0 {
+		return channels, nil
+	}
+	err = common.DB.Where("id IN ?", ids).Find(&channels).Error
+	return channels, err
+}
+
+func GetAlertChannel(id uint) (channel models.AlertChannel, err error) {
+	err = common.DB.Where("id = ?", id).First(&channel).Error
+	return channel, err
+}
+
+func CreateAlertChannel(channel *models.AlertChannel) error {
+	return common.DB.Create(channel).Error
+}
+
+// UpdateAlertChannel 密钥与SMTP密码为空时保留原值
+func UpdateAlertChannel(channel *models.AlertChannel) error {
+	omit := make([]string, 0)
+	if channel.Secret == "" {
+		omit = append(omit, "secret")
+	}
+	if channel.SmtpPassword == "" {
+		omit = append(omit, "smtp_password")
+	}
+	tx := common.DB.Model(channel).Select("*").Omit(append(omit, "created_at")...).Updates(channel)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func DeleteAlertChannel(id uint) error {
+	return common.DB.Delete(&models.AlertChannel{}, id).Error
+}
+
+func ListAlertRule(rules *[]models.AlertRule) error {
+	return common.DB.Order("id").Find(rules).Error
+}
+
+func ListEnabledAlertRule() (rules []models.AlertRule, err error) {
+	err = common.DB.Where("enable = ?", true).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func CreateAlertRule(rule *models.AlertRule) error {
+	return common.DB.Create(rule).Error
+}
+
+func UpdateAlertRule(rule *models.AlertRule) error {
+	tx := common.DB.Model(rule).Select("*").Omit("created_at", "evaluated_at").Updates(rule)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func DeleteAlertRule(id uint) error {
+	return common.DB.Delete(&models.AlertRule{}, id).Error
+}
+
+func UpdateAlertRuleEvaluatedAt(id uint, t time.Time) error {
+	return common.DB.Model(&models.AlertRule{}).Where("id = ?", id).
+		UpdateColumn("evaluated_at", models.LocalTime{Time: t}).Error
+}
+
+func ListAlertSilence(silences *[]models.AlertSilence) error {
+	return common.DB.Order("ends_at desc").Find(silences).Error
+}
+
+// ListActiveAlertSilence 获取在 now 时刻生效的静默规则
+func ListActiveAlertSilence(now time.Time) (silences []models.AlertSilence, err error) {
+	err = common.DB.Where("ends_at > ? AND (starts_at IS NULL OR starts_at <= ?)", now, now).Find(&silences).Error
+	return silences, err
+}
+
+func CreateAlertSilence(silence *models.AlertSilence) error {
+	return common.DB.Create(silence).Error
+}
+
+func DeleteAlertSilence(id uint) error {
+	return common.DB.Delete(&models.AlertSilence{}, id).Error
+}
+
+// ListEventRecordUpdatedBetween 获取时间段内新增或次数增加的归档事件
+func ListEventRecordUpdatedBetween(clusterID uint, namespace string, start, end time.Time) (records []models.EventRecord, err error) {
+	tx := common.DB.Where("updated_at > ? AND updated_at <= ?", start, end)
+	if clusterID != 0 {
+		tx = tx.Where("cluster_id = ?", clusterID)
+	}
+	if namespace != "" {
+		tx = tx.Where("namespace = ?", namespace)
+	}
+	err = tx.Order("updated_at").Find(&records).Error
+	return records, err
+}
+
+// GetRecentAlertRecord 获取去重窗口内同一指纹的最近一条告警记录, 不存在时返回 nil
+func GetRecentAlertRecord(ruleID uint, fingerprint string, since time.Time) (*models.AlertRecord, error) {
+	var record models.AlertRecord
+	err := common.DB.Where("rule_id = ? AND fingerprint = ? AND fired_at > ?", ruleID, fingerprint, since).
+		Order("fired_at desc").First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func IncreaseAlertSuppressed(id uint) error {
+	return common.DB.Model(&models.AlertRecord{}).Where("id = ?", id).
+		UpdateColumn("suppressed", gorm.Expr("suppressed + ?", 1)).Error
+}
+
+func CreateAlertRecord(record *models.AlertRecord) error {
+	return common.DB.Create(record).Error
+}
+
+func ListAlertRecord(q *models.AlertRecordQuery, records *[]models.AlertRecord) (err error) {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Size < 1 {
+		q.Size = 10
+	}
+
+	tx := common.DB.Model(&models.AlertRecord{})
+	if q.RuleID != 0 {
+		tx = tx.Where("rule_id = ?", q.RuleID)
+	}
+	if q.ClusterID != 0 {
+		tx = tx.Where("cluster_id = ?", q.ClusterID)
+	}
+	if q.Namespace != "" {
+		tx = tx.Where("namespace = ?", q.Namespace)
+	}
+	if q.Status != "" {
+		tx = tx.Where("status = ?", q.Status)
+	}
+
+	if err := tx.Count(&q.Total).Error; err != nil {
+		return err
+	}
+	offset := q.Size * (q.Page - 1)
+	return tx.Order("id desc").Limit(q.Size).Offset(offset).Find(records).Error
+}
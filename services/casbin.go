@@ -17,21 +17,181 @@ limitations under the License.
 package services
 
 import (
+	"errors"
+	"fmt"
 	"github.com/casbin/casbin/util"
 	"github.com/casbin/casbin/v2"
 	gormAdapter "github.com/casbin/gorm-adapter/v3"
 	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/models"
 	_ "github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
 	"strings"
+	"sync"
+	"time"
 )
 
-func Casbin() *casbin.Enforcer {
-	admin := common.CONFIG.Mysql
-	a, _ := gormAdapter.NewAdapter(common.CONFIG.System.DbType, admin.Username+":"+admin.Password+"@("+admin.Path+")/"+admin.Dbname, true)
-	e, _ := casbin.NewEnforcer(common.CONFIG.Casbin.ModelPath, a)
+// enforcerRetryInterval 初始化失败后的重试间隔, 避免数据库不可用时每个请求都重新初始化
+const enforcerRetryInterval = 10 * time.Second
+
+var (
+	syncedEnforcer *casbin.SyncedEnforcer
+	enforcerMu     sync.Mutex
+	enforcerTried  time.Time
+)
+
+// Casbin 获取全局共享的 enforcer, 初始化成功后不再重新加载策略, 失败时在下次调用时重试
+func Casbin() *casbin.SyncedEnforcer {
+	enforcerMu.Lock()
+	defer enforcerMu.Unlock()
+	if syncedEnforcer != nil || time.Since(enforcerTried) < enforcerRetryInterval {
+		return syncedEnforcer
+	}
+	enforcerTried = time.Now()
+	e, err := newEnforcer()
+	if err != nil {
+		common.LOG.Error("casbin enforcer初始化失败", zap.Any("err", err))
+		return nil
+	}
+	syncedEnforcer = e
+	return syncedEnforcer
+}
+
+func newEnforcer() (*casbin.SyncedEnforcer, error) {
+	a, err := gormAdapter.NewAdapterByDB(common.DB)
+	if err != nil {
+		return nil, err
+	}
+	e, err := casbin.NewSyncedEnforcer(common.CONFIG.Casbin.ModelPath, a)
+	if err != nil {
+		return nil, err
+	}
 	e.AddFunction("ParamsMatch", ParamsMatchFunc)
-	_ = e.LoadPolicy()
-	return e
+	if err = e.LoadPolicy(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// GetPolicyPathByRole 获取角色拥有的权限列表, role为空时返回全部
+func GetPolicyPathByRole(role string) (pathMaps []models.CasBinModel) {
+	e := Casbin()
+	if e == nil {
+		return pathMaps
+	}
+	var list [][]string
+	if role == "" {
+		list = e.GetPolicy()
+	} else {
+		list = e.GetFilteredPolicy(0, role)
+	}
+	for _, v := range list {
+		pathMaps = append(pathMaps, models.CasBinModel{
+			PType:  "p",
+			Role:   v[0],
+			Path:   v[1],
+			Method: v[2],
+		})
+	}
+	return pathMaps
+}
+
+// RemoveCasBin 删除角色的指定权限
+func RemoveCasBin(role string, infos []models.CasBinInfo) error {
+	e := Casbin()
+	if e == nil {
+		return errors.New("casbin enforcer未初始化")
+	}
+	var rules [][]string
+	for _, v := range infos {
+		rules = append(rules, []string{role, v.Path, v.Method})
+	}
+	ok, err := e.RemovePolicies(rules)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("权限不存在")
+	}
+	return nil
+}
+
+// UpdateCasBin 使用新的权限列表替换角色现有的全部权限, 只增删有变化的规则, 添加失败时恢复已删除的规则
+func UpdateCasBin(role string, infos []models.CasBinInfo) error {
+	e := Casbin()
+	if e == nil {
+		return errors.New("casbin enforcer未初始化")
+	}
+	wanted := make(map[string]bool)
+	var added [][]string
+	for _, v := range deduplicateCasBinInfo(infos) {
+		wanted[v.Path+" "+v.Method] = true
+		if !e.HasPolicy(role, v.Path, v.Method) {
+			added = append(added, []string{role, v.Path, v.Method})
+		}
+	}
+	var removed [][]string
+	for _, rule := range e.GetFilteredPolicy(0, role) {
+		if !wanted[rule[1]+" "+rule[2]] {
+			removed = append(removed, rule)
+		}
+	}
+
+	if len(removed) > 0 {
+		if _, err := e.RemovePolicies(removed); err != nil {
+			return err
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	ok, err := e.AddPolicies(added)
+	if ok && err == nil {
+		return nil
+	}
+	if err == nil {
+		err = errors.New("存在相同的权限, 添加失败")
+	}
+	if len(removed) > 0 {
+		if _, rollbackErr := e.AddPolicies(removed); rollbackErr != nil {
+			common.LOG.Error(fmt.Sprintf("角色：%v, 恢复权限失败", role), zap.Any("err", rollbackErr))
+		}
+	}
+	return err
+}
+
+func deduplicateCasBinInfo(infos []models.CasBinInfo) []models.CasBinInfo {
+	seen := make(map[string]bool)
+	result := make([]models.CasBinInfo, 0, len(infos))
+	for _, v := range infos {
+		key := v.Path + " " + v.Method
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, v)
+	}
+	return result
+}
+
+// EnforceNamespace 校验角色在集群命名空间下对资源的操作权限, develop 环境下跳过校验.
+// 策略的 obj 格式为 /k8s/cluster/:clusterId/namespace/:namespace/:resource, 例如
+// p, develop, /k8s/cluster/*/namespace/default/pods/exec, POST
+func EnforceNamespace(role string, clusterId uint, namespace string, resource string, act string) bool {
+	if common.CONFIG.System.Env == "develop" {
+		return true
+	}
+	e := Casbin()
+	if e == nil {
+		return false
+	}
+	obj := fmt.Sprintf("/k8s/cluster/%d/namespace/%s/%s", clusterId, namespace, resource)
+	success, err := e.Enforce(role, obj, act)
+	if err != nil {
+		common.LOG.Error("权限校验出错", zap.Any("err", err))
+		return false
+	}
+	return success
 }
 
 func ParamsMatch(fullNameKey1 string, key2 string) bool {
@@ -63,3 +63,69 @@ func CreateCloudAccount(account *cmdb.CloudPlatform) (err error) {
 
 	return nil
 }
+
+func GetCloudAccount(id int) (account cmdb.CloudPlatform, err error) {
+	err = common.DB.Where("id = ?", id).First(&account).Error
+	return account, err
+}
+
+// ListEnabledCloudAccount 获取启用的云账号, 用于注册定时同步
+func ListEnabledCloudAccount() (accounts []cmdb.CloudPlatform, err error) {
+	err = common.DB.Where("enable = ?", true).Find(&accounts).Error
+	return accounts, err
+}
+
+// UpdateCloudAccountSchedule 更新云账号的同步周期与启用状态
+func UpdateCloudAccountSchedule(data cmdb.CloudScheduleData) error {
+	values := map[string]interface{}{"sync_cron": data.SyncCron}
+	if data.Enable != nil {
+		values["enable"] = *data.Enable
+	}
+	tx := common.DB.Model(&cmdb.CloudPlatform{}).Where("id = ?", data.ID).Updates(values)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		if _, err := GetCloudAccount(data.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func CreateCloudSyncJob(job *cmdb.CloudSyncJob) error {
+	return common.DB.Create(job).Error
+}
+
+func GetCloudSyncJob(id int) (job cmdb.CloudSyncJob, err error) {
+	err = common.DB.Where("id = ?", id).First(&job).Error
+	return job, err
+}
+
+func SaveCloudSyncJob(job *cmdb.CloudSyncJob) error {
+	return common.DB.Save(job).Error
+}
+
+func ListCloudSyncJob(q *cmdb.CloudSyncJobQuery, jobs *[]cmdb.CloudSyncJob) (err error) {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Size < 1 {
+		q.Size = 10
+	}
+
+	tx := common.DB.Model(&cmdb.CloudSyncJob{})
+	if q.PlatformID != 0 {
+		tx = tx.Where("platform_id = ?", q.PlatformID)
+	}
+	if q.Status != "" {
+		tx = tx.Where("status = ?", q.Status)
+	}
+
+	if err := tx.Count(&q.Total).Error; err != nil {
+		return err
+	}
+	offset := q.Size * (q.Page - 1)
+	// 列表不返回同步日志, 查看详情时再获取
+	return tx.Omit("log").Order("id desc").Limit(q.Size).Offset(offset).Find(jobs).Error
+}
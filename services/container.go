@@ -17,8 +17,11 @@ limitations under the License.
 package services
 
 import (
+	"errors"
 	"github.com/dnsjia/luban/common"
 	"github.com/dnsjia/luban/models"
+	"gorm.io/gorm"
+	"time"
 )
 
 func CreateK8SCluster(cluster models.K8SCluster) (err error) {
@@ -55,6 +58,12 @@ func ListK8SCluster(p *models.PaginationQ, k *[]models.K8SCluster) (err error) {
 	return nil
 }
 
+// ListAllK8SCluster 获取所有已注册的集群
+func ListAllK8SCluster() (clusters []models.K8SCluster, err error) {
+	err = common.DB.Order("id").Find(&clusters).Error
+	return clusters, err
+}
+
 func GetK8sCluster(id uint) (K8sCluster models.K8SCluster, err error) {
 	err = common.DB.Where("id = ?", id).First(&K8sCluster).Error
 	if err != nil {
@@ -63,6 +72,26 @@ func GetK8sCluster(id uint) (K8sCluster models.K8SCluster, err error) {
 	return K8sCluster, nil
 }
 
+// UpdateK8SClusterMetricsSource 更新集群指标来源, 使用map以便清空已配置的字段
+func UpdateK8SClusterMetricsSource(id uint, source models.ClusterMetricsSource) error {
+	tx := common.DB.Model(&models.K8SCluster{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"metrics_type":                  source.Type,
+		"metrics_ksm_service":           source.KsmService,
+		"metrics_prometheus_url":        source.PrometheusURL,
+		"metrics_cpu_request_query":     source.CpuRequestQuery,
+		"metrics_cpu_capacity_query":    source.CpuCapacityQuery,
+		"metrics_memory_request_query":  source.MemoryRequestQuery,
+		"metrics_memory_capacity_query": source.MemoryCapacityQuery,
+	})
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 func DelCluster(ids models.ClusterIds) (err error) {
 	var k models.K8SCluster
 
@@ -83,3 +112,213 @@ func DelCluster(ids models.ClusterIds) (err error) {
 	return nil
 
 }
+
+func ListPodTerminalRecord(q *models.PodTerminalRecordQuery, records *[]models.PodTerminalRecord) (err error) {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Size < 1 {
+		q.Size = 10
+	}
+
+	tx := common.DB.Model(&models.PodTerminalRecord{})
+	if q.ClusterID != 0 {
+		tx = tx.Where("cluster_id = ?", q.ClusterID)
+	}
+	if q.Namespace != "" {
+		tx = tx.Where("namespace = ?", q.Namespace)
+	}
+	if q.PodName != "" {
+		tx = tx.Where("pod_name like ?", "%"+q.PodName+"%")
+	}
+	if q.UserName != "" {
+		tx = tx.Where("user_name = ?", q.UserName)
+	}
+
+	if err := tx.Count(&q.Total).Error; err != nil {
+		return err
+	}
+	offset := q.Size * (q.Page - 1)
+	return tx.Omit("records").Order("id desc").Limit(q.Size).Offset(offset).Find(records).Error
+}
+
+func GetPodTerminalRecord(id uint) (record models.PodTerminalRecord, err error) {
+	err = common.DB.Where("id = ?", id).First(&record).Error
+	return record, err
+}
+
+func ListPortForwardRecord(q *models.PortForwardRecordQuery, records *[]models.PortForwardRecord) (err error) {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Size < 1 {
+		q.Size = 10
+	}
+
+	tx := common.DB.Model(&models.PortForwardRecord{})
+	if q.ClusterID != 0 {
+		tx = tx.Where("cluster_id = ?", q.ClusterID)
+	}
+	if q.Namespace != "" {
+		tx = tx.Where("namespace = ?", q.Namespace)
+	}
+	if q.PodName != "" {
+		tx = tx.Where("pod_name like ?", "%"+q.PodName+"%")
+	}
+	if q.UserName != "" {
+		tx = tx.Where("user_name = ?", q.UserName)
+	}
+
+	if err := tx.Count(&q.Total).Error; err != nil {
+		return err
+	}
+	offset := q.Size * (q.Page - 1)
+	return tx.Order("id desc").Limit(q.Size).Offset(offset).Find(records).Error
+}
+
+func ListFileTransferRecord(q *models.FileTransferRecordQuery, records *[]models.FileTransferRecord) (err error) {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Size < 1 {
+		q.Size = 10
+	}
+
+	tx := common.DB.Model(&models.FileTransferRecord{})
+	if q.ClusterID != 0 {
+		tx = tx.Where("cluster_id = ?", q.ClusterID)
+	}
+	if q.Namespace != "" {
+		tx = tx.Where("namespace = ?", q.Namespace)
+	}
+	if q.PodName != "" {
+		tx = tx.Where("pod_name like ?", "%"+q.PodName+"%")
+	}
+	if q.UserName != "" {
+		tx = tx.Where("user_name = ?", q.UserName)
+	}
+	if q.Direction != "" {
+		tx = tx.Where("direction = ?", q.Direction)
+	}
+
+	if err := tx.Count(&q.Total).Error; err != nil {
+		return err
+	}
+	offset := q.Size * (q.Page - 1)
+	return tx.Order("id desc").Limit(q.Size).Offset(offset).Find(records).Error
+}
+
+// SaveEventRecord 按集群与事件UID去重保存事件, 已存在且次数未增加时跳过, 返回是否写入
+func SaveEventRecord(record *models.EventRecord) (bool, error) {
+	var existing models.EventRecord
+	err := common.DB.Where("cluster_id = ? AND uid = ?", record.ClusterID, record.UID).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, common.DB.Create(record).Error
+	}
+	if err != nil {
+		return false, err
+	}
+	if record.Count <= existing.Count {
+		return false, nil
+	}
+	return true, common.DB.Model(&existing).Updates(map[string]interface{}{
+		"count":          record.Count,
+		"message":        record.Message,
+		"last_timestamp": record.LastTimestamp,
+	}).Error
+}
+
+func ListEventRecord(q *models.EventRecordQuery, records *[]models.EventRecord) (err error) {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Size < 1 {
+		q.Size = 10
+	}
+
+	tx := common.DB.Model(&models.EventRecord{})
+	if q.ClusterID != 0 {
+		tx = tx.Where("cluster_id = ?", q.ClusterID)
+	}
+	if q.Namespace != "" {
+		tx = tx.Where("namespace = ?", q.Namespace)
+	}
+	if q.Kind != "" {
+		tx = tx.Where("involved_kind = ?", q.Kind)
+	}
+	if q.Name != "" {
+		tx = tx.Where("involved_name like ?", "%"+q.Name+"%")
+	}
+	if q.Reason != "" {
+		tx = tx.Where("reason = ?", q.Reason)
+	}
+	if q.Type != "" {
+		tx = tx.Where("type = ?", q.Type)
+	}
+	if !q.StartTime.IsZero() {
+		tx = tx.Where("last_timestamp >= ?", q.StartTime)
+	}
+	if !q.EndTime.IsZero() {
+		tx = tx.Where("last_timestamp <= ?", q.EndTime)
+	}
+
+	if err := tx.Count(&q.Total).Error; err != nil {
+		return err
+	}
+	offset := q.Size * (q.Page - 1)
+	return tx.Order("last_timestamp desc").Limit(q.Size).Offset(offset).Find(records).Error
+}
+
+// ListWorkloadWarningRecord 查询工作负载本身以及归属于它的 ReplicaSet、Pod 的告警事件.
+// 现存的子对象按名称精确匹配, 已删除的子对象按控制器生成的名称格式匹配
+func ListWorkloadWarningRecord(q *models.WorkloadWarningQuery, records *[]models.EventRecord) error {
+	if q.Limit < 1 || q.Limit > 500 {
+		q.Limit = 100
+	}
+	owned := common.DB.Where("involved_kind = ? AND involved_name = ?", q.Kind, q.Name)
+	if len(q.ReplicaSets) > 0 {
+		owned = owned.Or("involved_kind = ? AND involved_name IN ?", "ReplicaSet", q.ReplicaSets)
+	}
+	if len(q.Pods) > 0 {
+		owned = owned.Or("involved_kind = ? AND involved_name IN ?", "Pod", q.Pods)
+	}
+	if q.ReplicaSetPattern != "" {
+		owned = owned.Or("involved_kind = ? AND involved_name REGEXP ?", "ReplicaSet", q.ReplicaSetPattern)
+	}
+	if q.PodPattern != "" {
+		owned = owned.Or("involved_kind = ? AND involved_name REGEXP ?", "Pod", q.PodPattern)
+	}
+	tx := common.DB.Model(&models.EventRecord{}).
+		Where("cluster_id = ? AND namespace = ? AND type = ?", q.ClusterID, q.Namespace, "Warning").
+		Where(owned)
+	if !q.StartTime.IsZero() {
+		tx = tx.Where("last_timestamp >= ?", q.StartTime)
+	}
+	return tx.Order("last_timestamp desc").Limit(q.Limit).Find(records).Error
+}
+
+// DeleteEventRecordBefore 物理删除最近发生时间早于 before 的事件
+func DeleteEventRecordBefore(before time.Time) (int64, error) {
+	tx := common.DB.Unscoped().Where("last_timestamp < ?", before).Delete(&models.EventRecord{})
+	return tx.RowsAffected, tx.Error
+}
+
+func CreateNodeDrainJob(job *models.NodeDrainJob) error {
+	return common.DB.Create(job).Error
+}
+
+// SaveNodeDrainJob 写入排空任务的最新进度, 同时刷新 updated_at 作为执行实例的心跳
+func SaveNodeDrainJob(job *models.NodeDrainJob) error {
+	job.UpdatedAt = models.LocalTime{Time: time.Now()}
+	return common.DB.Model(job).Select("status", "message", "pods", "finished_at", "updated_at").Updates(job).Error
+}
+
+func GetNodeDrainJob(clusterID uint, jobID string) (job models.NodeDrainJob, err error) {
+	err = common.DB.Where("cluster_id = ? AND job_id = ?", clusterID, jobID).First(&job).Error
+	return
+}
+
+// ListNodeDrainJob 查询集群最近的排空任务, 按开始时间倒序
+func ListNodeDrainJob(clusterID uint, limit int, jobs *[]models.NodeDrainJob) error {
+	return common.DB.Where("cluster_id = ?", clusterID).Order("started_at desc").Limit(limit).Find(jobs).Error
+}
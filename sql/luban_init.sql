@@ -30,20 +30,43 @@ CREATE TABLE `casbin_rule` (
   `v5` varchar(100) DEFAULT NULL,
   PRIMARY KEY (`id`),
   UNIQUE KEY `idx_casbin_rule` (`ptype`,`v0`,`v1`,`v2`,`v3`,`v4`,`v5`)
-) ENGINE=InnoDB AUTO_INCREMENT=84 DEFAULT CHARSET=utf8mb4;
+) ENGINE=InnoDB AUTO_INCREMENT=183 DEFAULT CHARSET=utf8mb4;
 
 -- ----------------------------
 -- Records of casbin_rule
 -- ----------------------------
+INSERT INTO `casbin_rule` VALUES ('84', 'p', 'develop', '/api/v1/casbin', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('81', 'p', 'develop', '/api/v1/casbin', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('85', 'p', 'develop', '/api/v1/casbin', 'PUT', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('86', 'p', 'develop', '/api/v1/casbin/delete', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('30', 'p', 'develop', '/api/v1/cloud/account', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('167', 'p', 'develop', '/api/v1/cloud/schedule', 'PUT', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('168', 'p', 'develop', '/api/v1/cloud/sync', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('170', 'p', 'develop', '/api/v1/cloud/sync/job', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('169', 'p', 'develop', '/api/v1/cloud/sync/jobs', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('6', 'p', 'develop', '/api/v1/cmdb/host/group', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('7', 'p', 'develop', '/api/v1/cmdb/host/group', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('19', 'p', 'develop', '/api/v1/cmdb/host/server', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('154', 'p', 'develop', '/api/v1/k8s/alert/channel', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('155', 'p', 'develop', '/api/v1/k8s/alert/channel', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('156', 'p', 'develop', '/api/v1/k8s/alert/channel', 'PUT', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('157', 'p', 'develop', '/api/v1/k8s/alert/channel/delete', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('158', 'p', 'develop', '/api/v1/k8s/alert/channel/test', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('166', 'p', 'develop', '/api/v1/k8s/alert/record', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('159', 'p', 'develop', '/api/v1/k8s/alert/rule', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('160', 'p', 'develop', '/api/v1/k8s/alert/rule', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('161', 'p', 'develop', '/api/v1/k8s/alert/rule', 'PUT', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('162', 'p', 'develop', '/api/v1/k8s/alert/rule/delete', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('163', 'p', 'develop', '/api/v1/k8s/alert/silence', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('164', 'p', 'develop', '/api/v1/k8s/alert/silence', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('165', 'p', 'develop', '/api/v1/k8s/alert/silence/delete', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('87', 'p', 'develop', '/api/v1/k8s/apply', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('3', 'p', 'develop', '/api/v1/k8s/cluster', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('2', 'p', 'develop', '/api/v1/k8s/cluster', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('102', 'p', 'develop', '/api/v1/k8s/cluster/cache/status', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('4', 'p', 'develop', '/api/v1/k8s/cluster/delete', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('5', 'p', 'develop', '/api/v1/k8s/cluster/detail', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('150', 'p', 'develop', '/api/v1/k8s/cluster/metrics', 'PUT', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('10', 'p', 'develop', '/api/v1/k8s/cluster/secret', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('71', 'p', 'develop', '/api/v1/k8s/config/configmap', 'DELETE', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('68', 'p', 'develop', '/api/v1/k8s/config/configmap', 'GET', null, null, null);
@@ -53,6 +76,10 @@ INSERT INTO `casbin_rule` VALUES ('73', 'p', 'develop', '/api/v1/k8s/config/secr
 INSERT INTO `casbin_rule` VALUES ('72', 'p', 'develop', '/api/v1/k8s/config/secret', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('74', 'p', 'develop', '/api/v1/k8s/config/secret/detail', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('75', 'p', 'develop', '/api/v1/k8s/config/secrets', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('135', 'p', 'develop', '/api/v1/k8s/crd', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('136', 'p', 'develop', '/api/v1/k8s/crd/detail', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('137', 'p', 'develop', '/api/v1/k8s/crd/object', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('138', 'p', 'develop', '/api/v1/k8s/crd/object/detail', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('50', 'p', 'develop', '/api/v1/k8s/cronjob', 'DELETE', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('48', 'p', 'develop', '/api/v1/k8s/cronjob', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('51', 'p', 'develop', '/api/v1/k8s/cronjob/detail', 'GET', null, null, null);
@@ -65,41 +92,109 @@ INSERT INTO `casbin_rule` VALUES ('39', 'p', 'develop', '/api/v1/k8s/daemonsets'
 INSERT INTO `casbin_rule` VALUES ('17', 'p', 'develop', '/api/v1/k8s/deployment', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('24', 'p', 'develop', '/api/v1/k8s/deployment/delete', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('25', 'p', 'develop', '/api/v1/k8s/deployment/detail', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('99', 'p', 'develop', '/api/v1/k8s/deployment/pause', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('22', 'p', 'develop', '/api/v1/k8s/deployment/restart', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('100', 'p', 'develop', '/api/v1/k8s/deployment/resume', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('98', 'p', 'develop', '/api/v1/k8s/deployment/revision/diff', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('97', 'p', 'develop', '/api/v1/k8s/deployment/revisions', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('26', 'p', 'develop', '/api/v1/k8s/deployment/rollback', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('101', 'p', 'develop', '/api/v1/k8s/deployment/rollout/status', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('21', 'p', 'develop', '/api/v1/k8s/deployment/scale', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('23', 'p', 'develop', '/api/v1/k8s/deployment/service', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('20', 'p', 'develop', '/api/v1/k8s/deployments', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('8', 'p', 'develop', '/api/v1/k8s/events', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('152', 'p', 'develop', '/api/v1/k8s/events/archive', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('153', 'p', 'develop', '/api/v1/k8s/events/archive/warnings', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('113', 'p', 'develop', '/api/v1/k8s/hpa', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('115', 'p', 'develop', '/api/v1/k8s/hpa', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('116', 'p', 'develop', '/api/v1/k8s/hpa', 'PUT', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('117', 'p', 'develop', '/api/v1/k8s/hpa/delete', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('114', 'p', 'develop', '/api/v1/k8s/hpa/detail', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('44', 'p', 'develop', '/api/v1/k8s/job', 'DELETE', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('43', 'p', 'develop', '/api/v1/k8s/job', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('47', 'p', 'develop', '/api/v1/k8s/job/detail', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('46', 'p', 'develop', '/api/v1/k8s/job/scale', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('45', 'p', 'develop', '/api/v1/k8s/jobs', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('121', 'p', 'develop', '/api/v1/k8s/limitrange', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('178', 'p', 'develop', '/api/v1/k8s/limitrange', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('179', 'p', 'develop', '/api/v1/k8s/limitrange', 'PUT', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('180', 'p', 'develop', '/api/v1/k8s/limitrange/delete', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('122', 'p', 'develop', '/api/v1/k8s/limitrange/detail', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('77', 'p', 'develop', '/api/v1/k8s/log/:namespace/:pod', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('78', 'p', 'develop', '/api/v1/k8s/log/:namespace/:pod/:container', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('79', 'p', 'develop', '/api/v1/k8s/log/file/:namespace/:pod/:container', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('96', 'p', 'develop', '/api/v1/k8s/log/follow/:namespace/:resourceName/:resourceType', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('76', 'p', 'develop', '/api/v1/k8s/log/source/:namespace/:resourceName/:resourceType', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('18', 'p', 'develop', '/api/v1/k8s/namespace', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('172', 'p', 'develop', '/api/v1/k8s/namespace', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('173', 'p', 'develop', '/api/v1/k8s/namespace/delete', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('118', 'p', 'develop', '/api/v1/k8s/namespace/detail', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('174', 'p', 'develop', '/api/v1/k8s/namespace/labels', 'PUT', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('67', 'p', 'develop', '/api/v1/k8s/network/ingress', 'DELETE', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('64', 'p', 'develop', '/api/v1/k8s/network/ingress', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('65', 'p', 'develop', '/api/v1/k8s/network/ingress/detail', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('66', 'p', 'develop', '/api/v1/k8s/network/ingresss', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('139', 'p', 'develop', '/api/v1/k8s/network/networkpolicy', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('181', 'p', 'develop', '/api/v1/k8s/network/networkpolicy', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('141', 'p', 'develop', '/api/v1/k8s/network/networkpolicy/analyze', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('182', 'p', 'develop', '/api/v1/k8s/network/networkpolicy/delete', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('140', 'p', 'develop', '/api/v1/k8s/network/networkpolicy/detail', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('62', 'p', 'develop', '/api/v1/k8s/network/service', 'DELETE', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('60', 'p', 'develop', '/api/v1/k8s/network/service', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('63', 'p', 'develop', '/api/v1/k8s/network/service/detail', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('61', 'p', 'develop', '/api/v1/k8s/network/services', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('14', 'p', 'develop', '/api/v1/k8s/node', 'DELETE', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('9', 'p', 'develop', '/api/v1/k8s/node', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('108', 'p', 'develop', '/api/v1/k8s/node/annotations', 'PUT', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('109', 'p', 'develop', '/api/v1/k8s/node/collectionAnnotations', 'PUT', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('16', 'p', 'develop', '/api/v1/k8s/node/collectionCordon', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('107', 'p', 'develop', '/api/v1/k8s/node/collectionLabels', 'PUT', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('15', 'p', 'develop', '/api/v1/k8s/node/collectionSchedule', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('111', 'p', 'develop', '/api/v1/k8s/node/collectionTaints', 'PUT', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('13', 'p', 'develop', '/api/v1/k8s/node/cordon', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('11', 'p', 'develop', '/api/v1/k8s/node/detail', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('103', 'p', 'develop', '/api/v1/k8s/node/drain', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('104', 'p', 'develop', '/api/v1/k8s/node/drain/job', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('105', 'p', 'develop', '/api/v1/k8s/node/drain/jobs', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('106', 'p', 'develop', '/api/v1/k8s/node/labels', 'PUT', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('12', 'p', 'develop', '/api/v1/k8s/node/schedule', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('110', 'p', 'develop', '/api/v1/k8s/node/taints', 'PUT', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('112', 'p', 'develop', '/api/v1/k8s/node/taints/preview', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('29', 'p', 'develop', '/api/v1/k8s/pod', 'DELETE', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('27', 'p', 'develop', '/api/v1/k8s/pod', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('148', 'p', 'develop', '/api/v1/k8s/pod/debug', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('31', 'p', 'develop', '/api/v1/k8s/pod/detail', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('146', 'p', 'develop', '/api/v1/k8s/pod/file/download', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('147', 'p', 'develop', '/api/v1/k8s/pod/file/record', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('145', 'p', 'develop', '/api/v1/k8s/pod/file/upload', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('142', 'p', 'develop', '/api/v1/k8s/pod/portforward', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('143', 'p', 'develop', '/api/v1/k8s/pod/portforward/record', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('90', 'p', 'develop', '/api/v1/k8s/pod/terminal', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('91', 'p', 'develop', '/api/v1/k8s/pod/terminal/record', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('92', 'p', 'develop', '/api/v1/k8s/pod/terminal/record/replay', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('28', 'p', 'develop', '/api/v1/k8s/pods', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('125', 'p', 'develop', '/api/v1/k8s/rbac/clusterrole', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('126', 'p', 'develop', '/api/v1/k8s/rbac/clusterrole/detail', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('129', 'p', 'develop', '/api/v1/k8s/rbac/clusterrolebinding', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('130', 'p', 'develop', '/api/v1/k8s/rbac/clusterrolebinding/detail', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('123', 'p', 'develop', '/api/v1/k8s/rbac/role', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('124', 'p', 'develop', '/api/v1/k8s/rbac/role/detail', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('127', 'p', 'develop', '/api/v1/k8s/rbac/rolebinding', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('128', 'p', 'develop', '/api/v1/k8s/rbac/rolebinding/detail', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('131', 'p', 'develop', '/api/v1/k8s/rbac/serviceaccount', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('132', 'p', 'develop', '/api/v1/k8s/rbac/serviceaccount/detail', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('133', 'p', 'develop', '/api/v1/k8s/rbac/serviceaccount/permissions', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('134', 'p', 'develop', '/api/v1/k8s/rbac/whocan', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('88', 'p', 'develop', '/api/v1/k8s/resource/yaml', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('89', 'p', 'develop', '/api/v1/k8s/resource/yaml', 'PUT', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('119', 'p', 'develop', '/api/v1/k8s/resourcequota', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('175', 'p', 'develop', '/api/v1/k8s/resourcequota', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('176', 'p', 'develop', '/api/v1/k8s/resourcequota', 'PUT', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('177', 'p', 'develop', '/api/v1/k8s/resourcequota/delete', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('120', 'p', 'develop', '/api/v1/k8s/resourcequota/detail', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('151', 'p', 'develop', '/api/v1/k8s/search', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('93', 'p', 'develop', '/api/v1/k8s/sockjs/*', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('94', 'p', 'develop', '/api/v1/k8s/sockjs/*', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('34', 'p', 'develop', '/api/v1/k8s/statefulset', 'DELETE', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('32', 'p', 'develop', '/api/v1/k8s/statefulset', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('37', 'p', 'develop', '/api/v1/k8s/statefulset/detail', 'GET', null, null, null);
@@ -115,6 +210,10 @@ INSERT INTO `casbin_rule` VALUES ('53', 'p', 'develop', '/api/v1/k8s/storage/pvc
 INSERT INTO `casbin_rule` VALUES ('58', 'p', 'develop', '/api/v1/k8s/storage/sc', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('59', 'p', 'develop', '/api/v1/k8s/storage/sc/detail', 'GET', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('1', 'p', 'develop', '/api/v1/user/info', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('149', 'p', 'develop', '/k8s/cluster/*/namespace/*/pods/ephemeralcontainers', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('95', 'p', 'develop', '/k8s/cluster/*/namespace/*/pods/exec', 'POST', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('171', 'p', 'develop', '/k8s/cluster/*/namespace/*/pods/log', 'GET', null, null, null);
+INSERT INTO `casbin_rule` VALUES ('144', 'p', 'develop', '/k8s/cluster/*/namespace/*/pods/portforward', 'POST', null, null, null);
 INSERT INTO `casbin_rule` VALUES ('83', 'p', 'test', '/api/v1/user/info', 'GET', '', '', '');
 
 -- ----------------------------
@@ -136,12 +235,44 @@ CREATE TABLE `cloud_platform` (
   `updated_at` datetime DEFAULT NULL,
   `sync_time` datetime DEFAULT NULL,
   `enable` tinyint(1) DEFAULT NULL,
+  `sync_status` varchar(64) DEFAULT NULL COMMENT '同步状态',
+  `sync_summary` text COMMENT '同步结果',
+  `sync_cron` varchar(64) DEFAULT NULL COMMENT '同步周期',
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB AUTO_INCREMENT=13 DEFAULT CHARSET=utf8mb4;
 
 -- ----------------------------
 -- Records of cloud_platform
 -- ----------------------------
+-- ----------------------------
+-- Table structure for cloud_sync_job
+-- ----------------------------
+DROP TABLE IF EXISTS `cloud_sync_job`;
+CREATE TABLE `cloud_sync_job` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `platform_id` bigint(20) DEFAULT NULL COMMENT '云账号ID',
+  `platform_name` varchar(128) DEFAULT NULL COMMENT '云账号名称',
+  `vendor` varchar(32) DEFAULT NULL COMMENT '云厂商',
+  `trigger` varchar(32) DEFAULT NULL COMMENT '触发方式 schedule/manual',
+  `operator` varchar(128) DEFAULT NULL COMMENT '触发人',
+  `status` varchar(64) DEFAULT NULL COMMENT '同步状态',
+  `regions` bigint(20) DEFAULT NULL COMMENT '成功同步的地域数',
+  `total` bigint(20) DEFAULT NULL COMMENT '云上主机数',
+  `added` bigint(20) DEFAULT NULL COMMENT '新增主机数',
+  `updated` bigint(20) DEFAULT NULL COMMENT '更新主机数',
+  `removed` bigint(20) DEFAULT NULL COMMENT '删除主机数',
+  `error` varchar(1024) DEFAULT NULL COMMENT '错误信息',
+  `log` text COMMENT '同步日志',
+  `started_at` datetime DEFAULT NULL COMMENT '开始时间',
+  `finished_at` datetime DEFAULT NULL COMMENT '结束时间',
+  `duration` varchar(64) DEFAULT NULL COMMENT '耗时',
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_cloud_sync_job_platform_id` (`platform_id`),
+  KEY `idx_cloud_sync_job_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
 -- ----------------------------
 -- Table structure for cloud_virtual_machine
 -- ----------------------------
@@ -171,7 +302,10 @@ CREATE TABLE `cloud_virtual_machine` (
   `password` varchar(191) DEFAULT NULL,
   `user_name` varchar(191) DEFAULT NULL,
   `username` varchar(191) DEFAULT NULL COMMENT '''用户''',
-  PRIMARY KEY (`id`)
+  `platform_id` bigint(20) DEFAULT NULL COMMENT '''云账号ID''',
+  `cloud_region` varchar(191) DEFAULT NULL COMMENT '''云地域''',
+  PRIMARY KEY (`id`),
+  KEY `idx_cloud_virtual_machine_platform_id` (`platform_id`)
 ) ENGINE=InnoDB AUTO_INCREMENT=67 DEFAULT CHARSET=utf8mb4;
 
 -- ----------------------------
@@ -244,6 +378,13 @@ CREATE TABLE `k8s_cluster` (
   `kube_config` varchar(12800) DEFAULT NULL COMMENT '集群凭证',
   `cluster_version` varchar(191) DEFAULT NULL COMMENT '集群版本',
   `node_number` tinyint(4) DEFAULT NULL COMMENT '节点数',
+  `metrics_type` varchar(191) DEFAULT NULL COMMENT '指标来源 ksm/prometheus',
+  `metrics_ksm_service` varchar(191) DEFAULT NULL COMMENT 'kube-state-metrics服务, 格式 namespace/name:port',
+  `metrics_prometheus_url` varchar(191) DEFAULT NULL COMMENT 'Prometheus地址',
+  `metrics_cpu_request_query` varchar(1024) DEFAULT NULL COMMENT 'CPU请求量PromQL',
+  `metrics_cpu_capacity_query` varchar(1024) DEFAULT NULL COMMENT 'CPU容量PromQL',
+  `metrics_memory_request_query` varchar(1024) DEFAULT NULL COMMENT '内存请求量PromQL',
+  `metrics_memory_capacity_query` varchar(1024) DEFAULT NULL COMMENT '内存容量PromQL',
   `created_at` datetime DEFAULT NULL,
   `updated_at` datetime DEFAULT NULL,
   `deleted_at` datetime DEFAULT NULL,
@@ -18,35 +18,46 @@ package tasks
 
 import (
 	"github.com/dnsjia/luban/common"
-	"github.com/dnsjia/luban/models/cmdb"
 	"github.com/hibiken/asynq"
 	"log"
+	"time"
 )
 
-func TaskBeta() {
+func redisClientOpt() asynq.RedisClientOpt {
+	config := common.CONFIG
+	return asynq.RedisClientOpt{
+		Addr:     config.Redis.Host,
+		Username: config.Redis.UserName,
+		Password: config.Redis.PassWord,
+		DB:       config.Redis.DB,
+	}
+}
+
+// TaskBeta 启动周期任务调度, 不阻塞
+func TaskBeta() (*asynq.Scheduler, error) {
 
 	config := common.CONFIG
-	// 周期性任务
-	scheduler := asynq.NewScheduler(
-		asynq.RedisClientOpt{
-			Addr:     config.Redis.Host,
-			Username: config.Redis.UserName,
-			Password: config.Redis.PassWord,
-			DB:       config.Redis.DB,
-		}, nil)
-
-	// 每隔5分钟同步一次
-	var account cmdb.CloudPlatform
-	common.DB.Table("cloud_platform").Where("enable != ? and type = ?", 0, "aliyun").Find(&account)
-	syncResource := NewAliCloudTask(&account)
-	entryID, err := scheduler.Register(config.Crontab.AliYun, syncResource)
+	// 周期性任务, cron 表达式按服务器时区解析
+	scheduler := asynq.NewScheduler(redisClientOpt(), &asynq.SchedulerOpts{Location: time.Local})
 
+	// 告警规则评估, 上一次评估未结束时不重复入队
+	alertSpec := config.Crontab.Alert
+	if alertSpec == "" {
+		alertSpec = "@every 1m"
+	}
+	entryID, err := scheduler.Register(alertSpec, NewK8SAlertTask(), asynq.Unique(time.Minute), asynq.MaxRetry(0))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	log.Printf("registered an entry: %q\n", entryID)
 
-	if err := scheduler.Run(); err != nil {
-		log.Fatal(err)
+	// 每个启用的云账号按各自的周期同步
+	cloud := newCloudSchedule(scheduler)
+	cloud.reload()
+
+	if err := scheduler.Start(); err != nil {
+		return nil, err
 	}
+	go cloud.run()
+	return scheduler, nil
 }
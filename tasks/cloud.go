@@ -0,0 +1,164 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dnsjia/luban/common"
+	"github.com/dnsjia/luban/inner/cloud/cloudsync"
+	"github.com/dnsjia/luban/models/cmdb"
+	"github.com/dnsjia/luban/services"
+	"github.com/hibiken/asynq"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	// cloudScheduleInterval 定时重新加载云账号同步周期, 独立运行的 worker 依赖它感知账号变化
+	cloudScheduleInterval = time.Minute
+	// cloudSyncTimeout 单次同步的超时时间, 与同步中状态的过期时间一致
+	cloudSyncTimeout = time.Hour
+)
+
+var cloudScheduleReload = make(chan struct{}, 1)
+
+// ReloadCloudSchedule 云账号或同步周期变化后通知调度器立即重新加载
+func ReloadCloudSchedule() {
+	select {
+	case cloudScheduleReload <- struct{}{}:
+	default:
+	}
+}
+
+// defaultCloudSyncSpec 云账号未设置同步周期时使用的默认周期
+func defaultCloudSyncSpec() string {
+	if spec := common.CONFIG.Crontab.CloudSync; spec != "" {
+		return spec
+	}
+	if spec := common.CONFIG.Crontab.AliYun; spec != "" {
+		return spec
+	}
+	return "0 */2 * * *"
+}
+
+// ValidateCloudSyncCron 校验同步周期, 支持标准 cron 表达式与 @every 等描述符, 为空表示使用默认周期
+func ValidateCloudSyncCron(spec string) error {
+	if spec == "" {
+		return nil
+	}
+	if _, err := cron.ParseStandard(spec); err != nil {
+		return fmt.Errorf("同步周期不合法: %v", err)
+	}
+	return nil
+}
+
+type cloudEntry struct {
+	spec    string
+	entryID string
+}
+
+// cloudSchedule 为每个启用的云账号注册一个定时同步任务, 账号新增、停用、删除或修改周期后重新注册
+type cloudSchedule struct {
+	scheduler *asynq.Scheduler
+	mu        sync.Mutex
+	entries   map[int]cloudEntry
+}
+
+func newCloudSchedule(scheduler *asynq.Scheduler) *cloudSchedule {
+	return &cloudSchedule{scheduler: scheduler, entries: make(map[int]cloudEntry)}
+}
+
+func (s *cloudSchedule) run() {
+	ticker := time.NewTicker(cloudScheduleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-cloudScheduleReload:
+		}
+		s.reload()
+	}
+}
+
+func (s *cloudSchedule) reload() {
+	accounts, err := services.ListEnabledCloudAccount()
+	if err != nil {
+		common.LOG.Error("获取云账号失败", zap.Any("err", err))
+		return
+	}
+	desired := make(map[int]string, len(accounts))
+	for _, account := range accounts {
+		spec := account.SyncCron
+		if spec == "" {
+			spec = defaultCloudSyncSpec()
+		}
+		desired[account.ID] = spec
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, entry := range s.entries {
+		if spec, ok := desired[id]; ok && spec == entry.spec {
+			continue
+		}
+		if err := s.scheduler.Unregister(entry.entryID); err != nil {
+			common.LOG.Error(fmt.Sprintf("移除云账号%d的定时同步失败", id), zap.Any("err", err))
+			continue
+		}
+		delete(s.entries, id)
+		common.LOG.Info(fmt.Sprintf("移除云账号%d的定时同步", id))
+	}
+	for id, spec := range desired {
+		if _, ok := s.entries[id]; ok {
+			continue
+		}
+		// 上一次同步未结束时不重复入队
+		task := NewCloudSyncTask(CloudSyncPayload{PlatformID: id})
+		entryID, err := s.scheduler.Register(spec, task,
+			asynq.Unique(cloudSyncTimeout), asynq.Timeout(cloudSyncTimeout), asynq.MaxRetry(0))
+		if err != nil {
+			common.LOG.Error(fmt.Sprintf("注册云账号%d的定时同步失败, 周期: %s", id, spec), zap.Any("err", err))
+			continue
+		}
+		s.entries[id] = cloudEntry{spec: spec, entryID: entryID}
+		common.LOG.Info(fmt.Sprintf("注册云账号%d的定时同步, 周期: %s", id, spec))
+	}
+}
+
+// SyncCloudNow 立即同步云账号: 创建同步记录并投递到任务队列, 任务队列不可用时在当前进程中执行
+func SyncCloudNow(account *cmdb.CloudPlatform, operator string) (*cmdb.CloudSyncJob, error) {
+	job, err := cloudsync.NewJob(account, cmdb.CloudSyncTriggerManual, operator)
+	if err != nil {
+		return nil, err
+	}
+
+	client := asynq.NewClient(redisClientOpt())
+	defer client.Close()
+	task := NewCloudSyncTask(CloudSyncPayload{PlatformID: account.ID, JobID: job.ID})
+	if _, err := client.Enqueue(task, asynq.Timeout(cloudSyncTimeout), asynq.MaxRetry(0)); err != nil {
+		common.LOG.Warn("投递云资产同步任务失败, 在当前进程中同步", zap.Any("err", err))
+		go func() {
+			if err := cloudsync.RunJob(account, job); err != nil {
+				common.LOG.Error(err.Error())
+			}
+		}()
+	}
+	return job, nil
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2021 The DnsJia Authors.
+WebSite:  https://github.com/dnsjia/luban
+Email:    OpenSource@dnsjia.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"testing"
+
+	"github.com/dnsjia/luban/common"
+)
+
+func TestValidateCloudSyncCron(t *testing.T) {
+	for spec, valid := range map[string]bool{
+		"":               true,
+		"00 */2 * * *":   true,
+		"@every 30m":     true,
+		"@daily":         true,
+		"* * * *":        false,
+		"0 0 */2 * * *":  false,
+		"61 * * * *":     false,
+		"@every 30 mins": false,
+	} {
+		if err := ValidateCloudSyncCron(spec); (err == nil) != valid {
+			t.Errorf("ValidateCloudSyncCron(%q) = %v, want valid %v", spec, err, valid)
+		}
+	}
+}
+
+func TestDefaultCloudSyncSpec(t *testing.T) {
+	saved := common.CONFIG.Crontab
+	defer func() { common.CONFIG.Crontab = saved }()
+
+	common.CONFIG.Crontab.CloudSync, common.CONFIG.Crontab.AliYun = "", ""
+	if got := defaultCloudSyncSpec(); got != "0 */2 * * *" {
+		t.Errorf("default spec = %q", got)
+	}
+	common.CONFIG.Crontab.AliYun = "@every 1h"
+	if got := defaultCloudSyncSpec(); got != "@every 1h" {
+		t.Errorf("legacy aliyun spec = %q", got)
+	}
+	common.CONFIG.Crontab.CloudSync = "@every 2h"
+	if got := defaultCloudSyncSpec(); got != "@every 2h" {
+		t.Errorf("cloud-sync spec = %q", got)
+	}
+}
@@ -19,51 +19,78 @@ package tasks
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/dnsjia/luban/inner/cloud/cloudsync"
-	"github.com/dnsjia/luban/inner/cloud/cloudvendor"
 	"github.com/dnsjia/luban/models/cmdb"
+	"github.com/dnsjia/luban/pkg/k8s/alert"
+	"github.com/dnsjia/luban/services"
 	"github.com/hibiken/asynq"
+	"gorm.io/gorm"
 	"log"
 )
 
 const (
-	SyncAliYunCloud  = "cmdb:aliyun"
-	SyncTencentCloud = "cmdb:tencent"
+	SyncCloudAccount = "cmdb:cloud_sync"
+	EvaluateK8SAlert = "k8s:alert"
 )
 
-// NewAliCloudTask 同步阿里云资产同步任务
-func NewAliCloudTask(conf *cmdb.CloudPlatform) *asynq.Task {
-	payload, err := json.Marshal(conf)
+// CloudSyncPayload 云资产同步任务参数, 只传递账号ID, 密钥在执行时从数据库读取
+type CloudSyncPayload struct {
+	PlatformID int `json:"platform_id"`
+	// JobID 手动触发时预先创建的同步记录, 定时触发时为0
+	JobID int `json:"job_id"`
+}
+
+// NewCloudSyncTask 云资产同步任务
+func NewCloudSyncTask(payload CloudSyncPayload) *asynq.Task {
+	data, err := json.Marshal(payload)
 	if err != nil {
 		panic(err)
 	}
-	return asynq.NewTask(SyncAliYunCloud, payload)
+	return asynq.NewTask(SyncCloudAccount, data)
 }
 
-func HandleAliCloudTask(ctx context.Context, t *asynq.Task) error {
+func HandleCloudSyncTask(ctx context.Context, t *asynq.Task) error {
 
-	var a cmdb.CloudPlatform
-	if err := json.Unmarshal(t.Payload(), &a); err != nil {
+	var p CloudSyncPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		return err
 	}
 
-	_, err := cloudvendor.GetVendorClient(&a)
+	account, err := services.GetCloudAccount(p.PlatformID)
 	if err != nil {
-		log.Fatalf("AccountVerify GetVendorClient failed，%v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 账号已删除, 调度器会在下次重新加载时移除
+			log.Printf("cloud account %d not found, skip sync", p.PlatformID)
+			return nil
+		}
 		return err
 	}
 
-	cloudsync.SyncAliYunHost(&a)
+	var job *cmdb.CloudSyncJob
+	if p.JobID == 0 {
+		if !account.Enable {
+			return nil
+		}
+		if job, err = cloudsync.NewJob(&account, cmdb.CloudSyncTriggerSchedule, ""); err != nil {
+			return err
+		}
+	} else {
+		record, err := services.GetCloudSyncJob(p.JobID)
+		if err != nil {
+			return fmt.Errorf("get cloud sync job %d failed: %v", p.JobID, err)
+		}
+		job = &record
+	}
+	return cloudsync.RunJob(&account, job)
+}
 
-	log.Printf("Aliyun Cloud assets are successfully synchronized...")
-	return nil
+// NewK8SAlertTask 评估k8s告警规则任务
+func NewK8SAlertTask() *asynq.Task {
+	return asynq.NewTask(EvaluateK8SAlert, nil)
 }
 
-// NewTencentCloudTask 腾讯云资产同步任务
-func NewTencentCloudTask() *asynq.Task {
-	//payload, err := json.Marshal(&AK{})
-	//if err != nil {
-	//	panic(err)
-	//}
-	return asynq.NewTask(SyncTencentCloud, nil)
+func HandleK8SAlertTask(ctx context.Context, t *asynq.Task) error {
+	return alert.Evaluate(ctx)
 }
@@ -18,7 +18,9 @@ package tasks
 
 import (
 	"context"
-	"github.com/dnsjia/luban/common"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/hibiken/asynq"
@@ -39,40 +41,54 @@ func loggingMiddleware(h asynq.Handler) asynq.Handler {
 	})
 }
 
-func TaskWorker() {
-	config := common.CONFIG
+// TaskWorker 启动任务 worker, 不阻塞
+func TaskWorker() (*asynq.Server, error) {
 	srv := asynq.NewServer(
-		asynq.RedisClientOpt{
-			Addr:     config.Redis.Host,
-			Username: config.Redis.UserName,
-			Password: config.Redis.PassWord,
-			DB:       config.Redis.DB,
-		},
+		redisClientOpt(),
 		asynq.Config{Concurrency: 20},
 	)
 
 	mux := asynq.NewServeMux()
 	mux.Use(loggingMiddleware)
 	//
-	mux.HandleFunc(SyncAliYunCloud, HandleAliCloudTask)
+	mux.HandleFunc(SyncCloudAccount, HandleCloudSyncTask)
+	mux.HandleFunc(EvaluateK8SAlert, HandleK8SAlertTask)
 
-	// start server
-	if err := srv.Run(mux); err != nil {
-		log.Fatalf("could not start server: %v", err)
+	if err := srv.Start(mux); err != nil {
+		return nil, err
 	}
+	return srv, nil
+}
 
-	// Wait for termination signal.
-	//sigs := make(chan os.Signal, 1)
-	//signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT, syscall.SIGTSTP)
-	//for {
-	//	s := <-sigs
-	//	if s == syscall.SIGTSTP {
-	//		srv.Shutdown()
-	//		continue
-	//	}
-	//	break
-	//}
-	//
-	//// Stop worker server.
-	//srv.Stop()
+// Start 在服务进程中启动 worker 与周期任务调度
+func Start() error {
+	srv, err := TaskWorker()
+	if err != nil {
+		return err
+	}
+	if _, err := TaskBeta(); err != nil {
+		srv.Shutdown()
+		return err
+	}
+	return nil
+}
+
+// Run 独立运行 worker 与周期任务调度, 收到退出信号后等待执行中的任务结束
+func Run() error {
+	srv, err := TaskWorker()
+	if err != nil {
+		return err
+	}
+	scheduler, err := TaskBeta()
+	if err != nil {
+		srv.Shutdown()
+		return err
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	<-sigs
+	scheduler.Shutdown()
+	srv.Shutdown()
+	return nil
 }